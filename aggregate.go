@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// aggregateJSON is the JSON output of the aggregate subcommand
+type aggregateJSON struct {
+	Before   int      `json:"before"`
+	After    int      `json:"after"`
+	Prefixes []string `json:"prefixes"`
+}
+
+// newAggregateCommand creates the aggregate subcommand
+func newAggregateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "aggregate [file]...",
+		Short: "Collapse contained and adjacent CIDRs into the smallest equivalent set",
+		Long: `Read CIDRs and print the smallest set of CIDRs covering exactly the same
+addresses, dropping duplicates and prefixes contained in others and merging
+adjacent prefixes. The CIDRs are read from the given files, "-" meaning stdin,
+as with audit --allowlist. Without files, the published ranges of --area are
+aggregated.
+
+The number of CIDRs before and after aggregation is printed to stderr, so that
+stdout only has the reduced set.`,
+		Args:         usageArgs(cobra.ArbitraryArgs),
+		RunE:         runAggregate,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringSliceP("area", "a", nil, "Functional areas to aggregate without files, e.g. hooks,git (default all)")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runAggregate(cmd *cobra.Command, args []string) error {
+	areaNames, _ := cmd.Flags().GetStringSlice("area")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+	if len(args) > 0 && len(areaNames) > 0 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--area can't be combined with files"))
+	}
+
+	var cidrs []string
+	for _, path := range args {
+		entries, err := readAllowlist(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			cidrs = append(cidrs, entry.Prefix.String())
+		}
+	}
+	if len(args) == 0 {
+		meta, err := NewIPChecker().Meta()
+		if err != nil {
+			return err
+		}
+		areas, err := selectAreas(meta.Areas(), areaNames)
+		if err != nil {
+			return withCategory(errorCategoryUsage, err)
+		}
+		for _, area := range areas {
+			cidrs = append(cidrs, area.Ranges...)
+		}
+	}
+
+	prefixes := aggregateCIDRs(cidrs)
+	if prefixes == nil {
+		prefixes = []string{}
+	}
+	return writeAggregate(os.Stdout, os.Stderr, output, len(cidrs), prefixes)
+}
+
+// writeAggregate writes the aggregated prefixes to w, one per line, and the counts
+// to stderr, or both to w as JSON
+func writeAggregate(w, stderr io.Writer, output string, before int, prefixes []string) error {
+	if output == outputJSON {
+		out, err := marshalExportJSON(aggregateJSON{before, len(prefixes), prefixes})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+
+	if len(prefixes) > 0 {
+		fmt.Fprintln(w, strings.Join(prefixes, "\n"))
+	}
+	fmt.Fprintf(stderr, "Aggregated %d CIDRs into %d (%d IPv4, %d IPv6)\n",
+		before, len(prefixes), len(ipv4Ranges(prefixes)), len(ipv6Ranges(prefixes)))
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWriteAggregate(t *testing.T) {
+	var out, stderr bytes.Buffer
+	if err := writeAggregate(&out, &stderr, outputText, 5, []string{"192.30.252.0/22", "2a0a:a440::/29"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "192.30.252.0/22\n2a0a:a440::/29\n"; out.String() != want {
+		t.Errorf("writeAggregate() stdout = %q, want %q", out.String(), want)
+	}
+	if want := "Aggregated 5 CIDRs into 2 (1 IPv4, 1 IPv6)\n"; stderr.String() != want {
+		t.Errorf("writeAggregate() stderr = %q, want %q", stderr.String(), want)
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if err := writeAggregate(&out, &stderr, outputJSON, 0, []string{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"before\": 0,\n  \"after\": 0,\n  \"prefixes\": []\n}\n"; out.String() != want || stderr.Len() != 0 {
+		t.Errorf("writeAggregate() JSON = %q, stderr %q", out.String(), stderr.String())
+	}
+}
+
+func TestRunAggregate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["192.30.252.0/22", "140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.txt")
+	if err := os.WriteFile(valid, []byte("10.0.0.0/25\n10.0.0.128/25, 10.0.0.7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalid, []byte("10.0.0.0/33\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		areas        []string
+		wantCategory string
+	}{
+		{name: "Meta ranges"},
+		{name: "Meta area", areas: []string{"hooks"}},
+		{name: "File", args: []string{valid}},
+		{name: "Invalid CIDR", args: []string{invalid}, wantCategory: errorCategoryInput},
+		{name: "Area with files", args: []string{valid}, areas: []string{"hooks"}, wantCategory: errorCategoryUsage},
+		{name: "Unknown area", areas: []string{"nope"}, wantCategory: errorCategoryUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().StringSlice("area", tt.areas, "")
+			cmd.Flags().StringP("output", "o", outputJSON, "")
+
+			err := runAggregate(cmd, tt.args)
+			if (err != nil) != (tt.wantCategory != "") {
+				t.Fatalf("runAggregate() error = %v, want category %q", err, tt.wantCategory)
+			}
+			if err != nil && errorCategory(err) != tt.wantCategory {
+				t.Errorf("runAggregate() error category = %q, want %q", errorCategory(err), tt.wantCategory)
+			}
+		})
+	}
+}
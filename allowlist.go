@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// allowlistJSON is the JSON output of the allowlist subcommand
+type allowlistJSON struct {
+	Areas    []string `json:"areas"`
+	Ranges   int      `json:"ranges"` // Distinct published ranges before aggregation
+	Prefixes []string `json:"prefixes"`
+}
+
+// newAllowlistCommand creates the allowlist subcommand
+func newAllowlistCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "allowlist [file]",
+		Short: "Generate a minimal aggregated allowlist for selected areas",
+		Long: `Merge the published ranges of the selected functional areas, collapsing
+overlapping and adjacent ranges, and print the smallest set of CIDRs covering
+exactly the same addresses, one per line. This suits firewalls that limit the
+number of rules or entries. The output is written to the given file, or to
+stdout if no file is provided.`,
+		Args:         usageArgs(cobra.MaximumNArgs(1)),
+		RunE:         runAllowlist,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringSliceP("area", "a", nil, "Functional areas to allow, e.g. git,api,packages (default all)")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runAllowlist(cmd *cobra.Command, args []string) error {
+	areaNames, _ := cmd.Flags().GetStringSlice("area")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	meta, err := NewIPChecker().Meta()
+	if err != nil {
+		return err
+	}
+	areas, err := selectAreas(meta.Areas(), areaNames)
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+
+	ranges := uniqueRanges(areas)
+	prefixes := aggregateCIDRs(ranges)
+
+	var out []byte
+	if output == outputJSON {
+		keys := make([]string, len(areas))
+		for i, area := range areas {
+			keys[i] = area.Key
+		}
+		if prefixes == nil {
+			prefixes = []string{}
+		}
+		out, err = marshalExportJSON(allowlistJSON{keys, len(ranges), prefixes})
+		if err != nil {
+			return err
+		}
+	} else if len(prefixes) > 0 {
+		out = []byte(strings.Join(prefixes, "\n") + "\n")
+	}
+	return writeExport(args, out)
+}
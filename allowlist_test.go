@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunAllowlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"hooks": ["192.30.252.0/22"],
+			"api": ["192.30.252.0/22", "140.82.112.0/21", "2a0a:a440::/29"],
+			"git": ["140.82.120.0/21", "140.82.121.4/32"],
+			"packages": ["140.82.128.0/24"]
+		}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name    string
+		areas   []string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Selected areas",
+			areas: []string{"git", "api"},
+			want:  "140.82.112.0/20\n192.30.252.0/22\n2a0a:a440::/29\n",
+		},
+		{
+			name:   "JSON",
+			areas:  []string{"git", "packages"},
+			output: outputJSON,
+			want:   `{"areas":["git","packages"],"ranges":3,"prefixes":["140.82.120.0/21","140.82.128.0/24"]}`,
+		},
+		{
+			name:    "Unknown area",
+			areas:   []string{"nope"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := tt.output
+			if output == "" {
+				output = outputText
+			}
+			cmd := &cobra.Command{}
+			cmd.Flags().StringSlice("area", tt.areas, "")
+			cmd.Flags().StringP("output", "o", output, "")
+
+			path := filepath.Join(t.TempDir(), "allowlist.txt")
+			err := runAllowlist(cmd, []string{path})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runAllowlist() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output == outputJSON {
+				var gotJSON, wantJSON interface{}
+				json.Unmarshal(got, &gotJSON)
+				json.Unmarshal([]byte(tt.want), &wantJSON)
+				if !reflect.DeepEqual(gotJSON, wantJSON) {
+					t.Errorf("runAllowlist() wrote %s, want %s", got, tt.want)
+				}
+			} else if string(got) != tt.want {
+				t.Errorf("runAllowlist() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
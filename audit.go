@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// allowlistEntry is a CIDR read from an allowlist file
+type allowlistEntry struct {
+	Line   int
+	Prefix netip.Prefix
+}
+
+// auditReport is the result of auditing an allowlist against the published ranges
+type auditReport struct {
+	Missing []auditMissing `json:"missing"`
+	Stale   []auditEntry   `json:"stale"`
+	Broad   []auditEntry   `json:"overly_broad"`
+}
+
+// auditMissing is a published range that the allowlist doesn't fully cover
+type auditMissing struct {
+	Range   string   `json:"range"`
+	Areas   []string `json:"areas"`
+	Partial bool     `json:"partial"` // Some of the range's addresses are allowed
+}
+
+// auditEntry is an allowlist entry the audit reports
+type auditEntry struct {
+	Line      int     `json:"line"`
+	Entry     string  `json:"entry"`
+	Published float64 `json:"published_percent"` // Share of the entry's addresses in published ranges
+}
+
+// ok reports whether the audit found nothing to fix
+func (r auditReport) ok() bool {
+	return len(r.Missing) == 0 && len(r.Stale) == 0 && len(r.Broad) == 0
+}
+
+// newAuditCommand creates the audit subcommand
+func newAuditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Verify that a firewall allowlist covers GitHub's ranges",
+		Long: `Compare an allowlist of CIDRs, such as a firewall's current configuration,
+against GitHub's published ranges and report:
+
+  - published ranges of the selected areas the allowlist doesn't fully cover
+  - stale entries that no longer overlap any published range
+  - overly broad entries that also allow addresses outside the published ranges
+
+The allowlist has CIDRs or single addresses separated by newlines, commas or
+spaces. Blank lines and text after "#" are ignored. Exits with status 1 if the
+audit finds anything to fix.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runAudit,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().String("allowlist", "", "Allowlist file to audit (\"-\" for stdin)")
+	cmd.Flags().StringSliceP("area", "a", nil, "Functional areas the allowlist should cover, e.g. hooks,git (default all)")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+	cmd.MarkFlagRequired("allowlist")
+
+	return cmd
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	path, _ := cmd.Flags().GetString("allowlist")
+	areaNames, _ := cmd.Flags().GetStringSlice("area")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	entries, err := readAllowlist(path)
+	if err != nil {
+		return err
+	}
+
+	meta, err := NewIPChecker().Meta()
+	if err != nil {
+		return err
+	}
+	all := meta.Areas()
+	areas, err := selectAreas(all, areaNames)
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+
+	report := auditAllowlist(entries, areas, all)
+
+	switch output {
+	case outputJSON:
+		out, err := marshalExportJSON(report)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(out)
+	default:
+		writeAudit(os.Stdout, report, all)
+	}
+
+	if !report.ok() {
+		return notGitHubError(fmt.Sprintf("the allowlist is missing %d published ranges and has %d stale and %d overly broad entries",
+			len(report.Missing), len(report.Stale), len(report.Broad)))
+	}
+	return nil
+}
+
+// readAllowlist reads the CIDRs of an allowlist file, "-" meaning stdin
+func readAllowlist(path string) ([]allowlistEntry, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to open %s: %w", path, err))
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var entries []allowlistEntry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			prefix, err := parsePrefix(field)
+			if err != nil {
+				return nil, withCategory(errorCategoryInput, fmt.Errorf("%s:%d: %w", path, line, err))
+			}
+			entries = append(entries, allowlistEntry{line, prefix})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// auditAllowlist compares the allowlist entries with the ranges of the selected
+// areas. Entries are only stale or broad relative to the ranges of all areas,
+// since an entry for an area that isn't selected is still legitimate.
+func auditAllowlist(entries []allowlistEntry, selected, all []Area) auditReport {
+	report := auditReport{Stale: []auditEntry{}, Broad: []auditEntry{}}
+
+	prefixes := make([]netip.Prefix, len(entries))
+	for i, entry := range entries {
+		prefixes[i] = entry.Prefix
+	}
+	allowed := newPrefixSet(prefixes)
+
+	report.Missing = missingRanges(allowed, selected)
+
+	publishedSet := newPrefixSet(parsePrefixes(uniqueRanges(all)))
+
+	for _, entry := range entries {
+		r := prefixRange(entry.Prefix)
+		gaps := publishedSet.subtract(r)
+		if len(gaps) == 0 {
+			continue
+		}
+
+		size := r.size()
+		published := new(big.Int).Set(size)
+		for _, gap := range gaps {
+			published.Sub(published, gap.size())
+		}
+
+		item := auditEntry{entry.Line, entry.Prefix.String(), percentOf(published, size)}
+		if publishedSet.overlaps(r) {
+			report.Broad = append(report.Broad, item)
+		} else {
+			report.Stale = append(report.Stale, item)
+		}
+	}
+	return report
+}
+
+// missingRanges returns the ranges of areas that aren't fully in the allowed set
+func missingRanges(allowed rangeSet, areas []Area) []auditMissing {
+	missing := []auditMissing{}
+	keys := areaKeysByRange(areas)
+	for _, cidr := range uniqueRanges(areas) {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		r := prefixRange(prefix)
+		gaps := allowed.subtract(r)
+		if len(gaps) == 0 {
+			continue
+		}
+		partial := len(gaps) > 1 || gaps[0] != r
+		missing = append(missing, auditMissing{cidr, keys[cidr], partial})
+	}
+	return missing
+}
+
+// writeAudit writes the audit findings as text
+func writeAudit(w io.Writer, report auditReport, areas []Area) {
+	if report.ok() {
+		fmt.Fprintln(w, "The allowlist covers the published ranges with no stale or overly broad entries")
+		return
+	}
+
+	writeMissingRanges(w, report.Missing, areas)
+	if len(report.Stale) > 0 {
+		fmt.Fprintf(w, "Stale allowlist entries (%d):\n", len(report.Stale))
+		for _, e := range report.Stale {
+			fmt.Fprintf(w, "  line %d: %s\n", e.Line, e.Entry)
+		}
+	}
+	if len(report.Broad) > 0 {
+		fmt.Fprintf(w, "Overly broad allowlist entries (%d):\n", len(report.Broad))
+		for _, e := range report.Broad {
+			fmt.Fprintf(w, "  line %d: %s (%.1f%% published)\n", e.Line, e.Entry, e.Published)
+		}
+	}
+}
+
+// writeMissingRanges writes the published ranges an allowlist is missing, if any
+func writeMissingRanges(w io.Writer, missing []auditMissing, areas []Area) {
+	if len(missing) == 0 {
+		return
+	}
+
+	names := make(map[string]string)
+	for _, area := range areas {
+		names[area.Key] = area.Name
+	}
+
+	fmt.Fprintf(w, "Missing published ranges (%d):\n", len(missing))
+	for _, m := range missing {
+		areaNames := make([]string, len(m.Areas))
+		for i, key := range m.Areas {
+			areaNames[i] = names[key]
+		}
+		line := fmt.Sprintf("  %-20s %s", m.Range, strings.Join(areaNames, ", "))
+		if m.Partial {
+			line += " (partially allowed)"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestReadAllowlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	content := "# GitHub\n192.30.252.0/22, 185.199.108.0/22\n\n140.82.112.3 # git\n2a0a:a440::/29\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := readAllowlist(path)
+	if err != nil {
+		t.Fatalf("readAllowlist() error = %v", err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, fmt.Sprintf("%s@%d", entry.Prefix, entry.Line))
+	}
+	want := []string{"192.30.252.0/22@2", "185.199.108.0/22@2", "140.82.112.3/32@4", "2a0a:a440::/29@5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readAllowlist() = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("192.30.252.0/22\nnot-a-cidr\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = readAllowlist(path)
+	if err == nil || !strings.Contains(err.Error(), ":2: invalid CIDR") || errorCategory(err) != errorCategoryInput {
+		t.Errorf("readAllowlist() of an invalid entry error = %v", err)
+	}
+}
+
+func TestAuditAllowlist(t *testing.T) {
+	all := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22"}},
+		{Key: "web", Name: "Web", Ranges: []string{"192.30.252.0/22", "140.82.112.0/20"}},
+		{Key: "pages", Name: "Pages", Ranges: []string{"185.199.108.0/22"}},
+	}
+	var entries []allowlistEntry
+	for i, cidr := range []string{"192.30.252.0/22", "140.82.112.0/21", "185.199.0.0/16", "198.51.100.0/24"} {
+		prefix, _ := parsePrefix(cidr)
+		entries = append(entries, allowlistEntry{i + 1, prefix})
+	}
+
+	report := auditAllowlist(entries, all[:2], all)
+	want := auditReport{
+		Missing: []auditMissing{{"140.82.112.0/20", []string{"web"}, true}},
+		Stale:   []auditEntry{{4, "198.51.100.0/24", 0}},
+		Broad:   []auditEntry{{3, "185.199.0.0/16", 1.5}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("auditAllowlist() = %+v, want %+v", report, want)
+	}
+
+	var buf bytes.Buffer
+	writeAudit(&buf, report, all)
+	for _, line := range []string{
+		"Missing published ranges (1):",
+		"  140.82.112.0/20      Web (partially allowed)",
+		"  line 4: 198.51.100.0/24",
+		"  line 3: 185.199.0.0/16 (1.5% published)",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("writeAudit() output missing %q:\n%s", line, buf.String())
+		}
+	}
+
+	// The Pages entry isn't stale even though Pages isn't audited
+	report = auditAllowlist(entries[:1], all[:1], all)
+	if !report.ok() {
+		t.Errorf("auditAllowlist() = %+v, want no findings", report)
+	}
+}
+
+func TestRunAudit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte("192.30.252.0/22\n140.82.112.0/20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		areas         []string
+		wantErr       bool
+		wantNotGitHub bool
+	}{
+		{name: "Covered", areas: nil},
+		{name: "Covered area", areas: []string{"git"}},
+		{name: "Unknown area", areas: []string{"nope"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().String("allowlist", path, "")
+			cmd.Flags().StringSlice("area", tt.areas, "")
+			cmd.Flags().StringP("output", "o", outputJSON, "")
+
+			err := runAudit(cmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runAudit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(notGitHubError); ok != tt.wantNotGitHub {
+				t.Errorf("runAudit() error = %v, want notGitHubError %v", err, tt.wantNotGitHub)
+			}
+		})
+	}
+
+	if err := os.WriteFile(path, []byte("192.30.252.0/22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := &cobra.Command{}
+	cmd.Flags().String("allowlist", path, "")
+	cmd.Flags().StringSlice("area", nil, "")
+	cmd.Flags().StringP("output", "o", outputJSON, "")
+	if _, ok := runAudit(cmd, nil).(notGitHubError); !ok {
+		t.Error("runAudit() of an allowlist missing a range should return notGitHubError")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// For testing purposes
+var auditNow = time.Now
+
+// Verdicts of audit log records
+const (
+	auditGitHub    = "github"
+	auditNotGitHub = "not_github"
+	auditError     = "error"
+)
+
+// auditLog appends a JSON line per check to a file, as evidence of what was
+// verified against which ranges. Its methods do nothing on a nil log.
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// auditRecord is a line of the audit log
+type auditRecord struct {
+	Time       time.Time  `json:"time"`
+	Input      string     `json:"input"`
+	Verdict    string     `json:"verdict"` // github, not_github or error
+	Area       string     `json:"area,omitempty"`
+	AreaKey    string     `json:"area_key,omitempty"`
+	Range      string     `json:"range,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Snapshot   *time.Time `json:"snapshot,omitempty"`    // When the ranges checked against were published
+	SourceHash string     `json:"source_hash,omitempty"` // SHA-256 of the meta document checked against
+	Caller     string     `json:"caller,omitempty"`      // Client address in serve mode
+}
+
+// openAuditLog opens the audit log at path for appending, creating it if needed
+func openAuditLog(path string) (*auditLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to open audit log: %w", err))
+	}
+	return &auditLog{path: path, file: file}, nil
+}
+
+// applyAuditLog makes checker record its checks in the --audit-log file
+func applyAuditLog(cmd *cobra.Command, checker *IPChecker) error {
+	path, _ := cmd.Flags().GetString("audit-log")
+	if path == "" {
+		return nil
+	}
+	log, err := openAuditLog(path)
+	if err != nil {
+		return err
+	}
+	checker.audit = log
+	return nil
+}
+
+// record appends the outcome of checking input against checker's ranges. A
+// check that can't be recorded fails, since it would leave no evidence.
+func (l *auditLog) record(checker *IPChecker, input string, result *CheckResult, err error, caller string) error {
+	if l == nil {
+		return nil
+	}
+	rec := auditRecord{Time: auditNow().UTC(), Input: input, Caller: caller}
+	switch {
+	case err != nil:
+		rec.Verdict, rec.Error = auditError, err.Error()
+	case result.IsGitHubIP:
+		rec.Verdict, rec.Area, rec.AreaKey, rec.Range = auditGitHub, result.FunctionalArea, result.AreaKey, result.Range
+	default:
+		rec.Verdict = auditNotGitHub
+	}
+	if snapshot := checker.SnapshotTime(); !snapshot.IsZero() {
+		rec.Snapshot = &snapshot
+		rec.SourceHash = checker.SourceHash()
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// A single write per line, so concurrent writers to the file don't interleave
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return withCategory(errorCategoryInternal, fmt.Errorf("failed to write audit log: %w", err))
+	}
+	return nil
+}
+
+// Reopen opens the audit log's path again, so records go to a new file once
+// the current one was rotated away. The current file is kept if the path can't
+// be opened.
+func (l *auditLog) Reopen() error {
+	if l == nil {
+		return nil
+	}
+	reopened, err := openAuditLog(l.path)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	old := l.file
+	l.file = reopened.file
+	l.mu.Unlock()
+	return old.Close()
+}
+
+// Close closes the audit log
+func (l *auditLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// readAuditLog returns the records of the audit log at path
+func readAuditLog(t *testing.T, path string) []auditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var records []auditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("audit log line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestAuditLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Mon, 02 Jun 2025 00:00:00 GMT")
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldURL, oldNow := githubMetaURL, auditNow
+	githubMetaURL = server.URL
+	now := time.Date(2025, 6, 3, 12, 0, 0, 0, time.UTC)
+	auditNow = func() time.Time { return now }
+	defer func() { githubMetaURL, auditNow = oldURL, oldNow }()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	// Records are appended to an existing log
+	if err := os.WriteFile(path, []byte(`{"time":"2025-06-01T00:00:00Z","input":"8.8.4.4","verdict":"not_github"}`+"\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	log, err := openAuditLog(path)
+	if err != nil {
+		t.Fatalf("openAuditLog() error = %v", err)
+	}
+	checker := NewIPChecker()
+	checker.audit = log
+
+	// An address that can't be checked is recorded before the ranges are fetched
+	checker.CheckIP("10.0.0.1")
+	checker.CheckIP("192.30.252.1")
+	checker.CheckIP("8.8.8.8")
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	hash := checker.SourceHash()
+	want := []auditRecord{
+		{Time: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), Input: "8.8.4.4", Verdict: auditNotGitHub},
+		{Time: now, Input: "10.0.0.1", Verdict: auditError, Error: "IP address is a private address (10.0.0.0/8, RFC 1918), not a public one"},
+		{Time: now, Input: "192.30.252.1", Verdict: auditGitHub, Area: "Hooks", AreaKey: "hooks", Range: "192.30.252.0/22", Snapshot: &snapshot, SourceHash: hash},
+		{Time: now, Input: "8.8.8.8", Verdict: auditNotGitHub, Snapshot: &snapshot, SourceHash: hash},
+	}
+	if got := readAuditLog(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("audit log = %+v, want %+v", got, want)
+	}
+
+	// Checks can't go unrecorded
+	if _, err := checker.CheckIP("192.30.252.1"); err == nil || errorCategory(err) != errorCategoryInternal {
+		t.Errorf("CheckIP() with a closed audit log error = %v, want an %s error", err, errorCategoryInternal)
+	}
+	if _, err := openAuditLog(filepath.Join(t.TempDir(), "missing", "audit.jsonl")); err == nil || errorCategory(err) != errorCategoryInput {
+		t.Errorf("openAuditLog() in a missing directory error = %v, want an %s error", err, errorCategoryInput)
+	}
+}
+
+func TestServer_AuditLog(t *testing.T) {
+	s := newTestServer(t, false, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.audit = log
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	// The second check of the address is answered from the result cache, and
+	// recorded all the same
+	for _, ip := range []string{"192.30.252.1", "192.30.252.1", "not-an-ip"} {
+		resp, err := http.Get(ts.URL + "/v1/check?ip=" + ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	log.Close()
+
+	records := readAuditLog(t, path)
+	if len(records) != 3 {
+		t.Fatalf("audit log has %d records, want 3", len(records))
+	}
+	for i, verdict := range []string{auditGitHub, auditGitHub, auditError} {
+		if rec := records[i]; rec.Verdict != verdict || rec.Caller != "127.0.0.1" {
+			t.Errorf("audit record %d = %+v, want verdict %s from 127.0.0.1", i, rec, verdict)
+		}
+	}
+}
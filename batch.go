@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// readBatchInput reads the addresses listed in the file at path, or stdin if path
+// is "-". The file has an address per line; blank lines and lines starting with
+// "#" are skipped.
+func readBatchInput(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to open %s: %w", path, err))
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var inputs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inputs = append(inputs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return inputs, nil
+}
+
+// uniqueInputs returns the distinct inputs in the order first seen, along with
+// the number of times each one occurs
+func uniqueInputs(inputs []string) ([]string, map[string]int) {
+	counts := make(map[string]int)
+	var unique []string
+	for _, input := range inputs {
+		if counts[input] == 0 {
+			unique = append(unique, input)
+		}
+		counts[input]++
+	}
+	return unique, counts
+}
+
+// sortInputs sorts the inputs in address order, with inputs that aren't IP
+// addresses last in lexical order
+func sortInputs(inputs []string) {
+	sort.SliceStable(inputs, func(i, j int) bool {
+		a, errA := netip.ParseAddr(inputs[i])
+		b, errB := netip.ParseAddr(inputs[j])
+		switch {
+		case errA != nil && errB != nil:
+			return inputs[i] < inputs[j]
+		case errA != nil || errB != nil:
+			return errB != nil
+		}
+		return a.Unmap().Less(b.Unmap())
+	})
+}
+
+// rootArgs requires an address argument unless addresses are read with --input
+func rootArgs(cmd *cobra.Command, args []string) error {
+	if input, _ := cmd.Flags().GetString("input"); input != "" {
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// runBatch checks each of the inputs in turn and reports a result per address.
+// Addresses that can't be checked are reported without stopping the run, unless
+// --fail-fast is set, which stops at the first address that isn't GitHub-owned
+// or can't be checked.
+func runBatch(cmd *cobra.Command, checker *IPChecker, output string, inputs []string) error {
+	silent, _ := cmd.Flags().GetBool("silent")
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+	monitoring := output == outputNagios || output == outputCheckmk
+
+	var counts map[string]int
+	if unique, _ := cmd.Flags().GetBool("unique"); unique {
+		inputs, counts = uniqueInputs(inputs)
+	}
+	if sorted, _ := cmd.Flags().GetBool("sort"); sorted {
+		inputs = append([]string{}, inputs...)
+		sortInputs(inputs)
+	}
+
+	// Fetch the ranges up front, so a failure aborts the run once instead of
+	// being reported for every address
+	metaErr := applyAsOf(cmd, checker)
+	if metaErr == nil {
+		_, metaErr = checker.Meta()
+	}
+	if metaErr != nil && !monitoring {
+		return metaErr
+	}
+
+	var checked []checkedIP
+	for _, input := range inputs {
+		item := checkedIP{IP: input, Err: metaErr, Count: counts[input]}
+		if metaErr == nil {
+			item.Result, item.Err = checker.CheckIP(input)
+		}
+		checked = append(checked, item)
+
+		if !silent && !monitoring {
+			writeBatchResult(os.Stdout, os.Stderr, output, item)
+		}
+		if failFast && !item.isGitHub() {
+			break
+		}
+	}
+
+	if monitoring {
+		maxAge, _ := cmd.Flags().GetDuration("nagios-max-age")
+		return reportBatchMonitoring(os.Stdout, output, checked, checker.SnapshotTime(), maxAge)
+	}
+
+	if report, _ := cmd.Flags().GetBool("report"); report {
+		w := os.Stdout
+		if output != outputText && output != outputJSON {
+			w = os.Stderr
+		}
+		writeBatchReport(w, output, newBatchReport(checked, len(inputs)))
+	}
+
+	if summary, _ := cmd.Flags().GetString("summary"); summary != "" {
+		if err := appendSummary(summary, checked); err != nil {
+			return err
+		}
+	}
+
+	return batchError(checked, len(inputs))
+}
+
+// isGitHub reports whether the address was checked and is GitHub-owned
+func (c checkedIP) isGitHub() bool {
+	return c.Err == nil && c.Result.IsGitHubIP
+}
+
+// writeBatchResult writes the result of checking a batch input. Unlike a single
+// check, text output also describes addresses that aren't GitHub-owned, and
+// addresses that can't be checked are reported on stderr, or as a JSON object
+// with an error message on stdout with --output json. With --unique, text and
+// JSON output include the number of occurrences of the input.
+func writeBatchResult(stdout, stderr io.Writer, format string, item checkedIP) {
+	occurrences := ""
+	if item.Count > 1 {
+		occurrences = fmt.Sprintf(" (%d occurrences)", item.Count)
+	}
+
+	switch {
+	case format == outputJSON:
+		out := resultJSON{IP: item.IP, Count: item.Count}
+		if item.Err != nil {
+			out.Error = item.Err.Error()
+		} else {
+			out = newResultJSON(item.IP, item.Result)
+			out.Count = item.Count
+		}
+		json.NewEncoder(stdout).Encode(out)
+	case item.Err != nil:
+		fmt.Fprintf(stderr, "Error: %s: %v%s\n", item.IP, item.Err, occurrences)
+	case format == outputText && item.Result.Hint != nil:
+		fmt.Fprintf(stdout, "IP %s is %s%s\n", item.IP, formatHint(item.Result.Hint), occurrences)
+	case format == outputText && !item.Result.IsGitHubIP:
+		fmt.Fprintf(stdout, "IP %s is not a GitHub-owned address%s\n", item.IP, occurrences)
+	case format == outputText:
+		fmt.Fprintf(stdout, "IP %s belongs to GitHub's %s range (%s)%s%s%s\n", item.IP, item.Result.FunctionalArea,
+			item.Result.Range, formatSource(item.Result.Source), formatAlsoMatches(item.Result.Also), occurrences)
+	default:
+		writeResult(stdout, format, item.IP, item.Result)
+	}
+}
+
+// batchError returns the error ending a batch run of total inputs, if any of the
+// checked addresses failed or aren't GitHub-owned. Addresses that couldn't be
+// checked take precedence, since the run didn't verify them.
+func batchError(checked []checkedIP, total int) error {
+	failed, notGitHub := 0, 0
+	for _, c := range checked {
+		switch {
+		case c.Err != nil:
+			failed++
+		case !c.Result.IsGitHubIP:
+			notGitHub++
+		}
+	}
+
+	stopped := ""
+	if len(checked) < total {
+		last := checked[len(checked)-1].IP
+		stopped = fmt.Sprintf(", stopped at %s after %d of %d addresses", last, len(checked), total)
+	}
+	switch {
+	case failed > 0:
+		return withCategory(errorCategoryInput, fmt.Errorf("%d of %d addresses could not be checked%s", failed, len(checked), stopped))
+	case notGitHub > 0:
+		return notGitHubError(fmt.Sprintf("%d of %d IP addresses are not GitHub-owned%s", notGitHub, len(checked), stopped))
+	}
+	return nil
+}
+
+// batchReport is the overview of a batch run printed with --report
+type batchReport struct {
+	Total     int         `json:"total"`
+	GitHub    int         `json:"github"`
+	Areas     []areaCount `json:"areas"`
+	NotGitHub int         `json:"not_github"`
+	Invalid   int         `json:"invalid"`
+	Skipped   int         `json:"skipped"` // Not checked because of --fail-fast
+	Ranges    []string    `json:"ranges"`  // Unique ranges matched, in the order first matched
+}
+
+// areaCount is the number of matched addresses in a functional area
+type areaCount struct {
+	Key   string `json:"key"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// newBatchReport summarizes the addresses checked out of total inputs
+func newBatchReport(checked []checkedIP, total int) batchReport {
+	report := batchReport{
+		Total:   len(checked),
+		Areas:   []areaCount{},
+		Skipped: total - len(checked),
+		Ranges:  []string{},
+	}
+	areas := make(map[string]int)
+	ranges := make(map[string]bool)
+	for _, c := range checked {
+		switch {
+		case c.Err != nil:
+			report.Invalid++
+		case !c.Result.IsGitHubIP:
+			report.NotGitHub++
+		default:
+			report.GitHub++
+			i, ok := areas[c.Result.AreaKey]
+			if !ok {
+				i = len(report.Areas)
+				areas[c.Result.AreaKey] = i
+				report.Areas = append(report.Areas, areaCount{Key: c.Result.AreaKey, Name: c.Result.FunctionalArea})
+			}
+			report.Areas[i].Count++
+			if !ranges[c.Result.Range] {
+				ranges[c.Result.Range] = true
+				report.Ranges = append(report.Ranges, c.Result.Range)
+			}
+		}
+	}
+
+	// Busiest areas first
+	sort.SliceStable(report.Areas, func(i, j int) bool {
+		return report.Areas[i].Count > report.Areas[j].Count
+	})
+	return report
+}
+
+// writeBatchReport writes the report as a {"summary": ...} JSON object with
+// --output json, and as an indented text overview otherwise
+func writeBatchReport(w io.Writer, format string, report batchReport) {
+	if format == outputJSON {
+		json.NewEncoder(w).Encode(struct {
+			Summary batchReport `json:"summary"`
+		}{report})
+		return
+	}
+
+	fmt.Fprintf(w, "Checked %d addresses:\n", report.Total)
+	fmt.Fprintf(w, "  GitHub-owned: %d\n", report.GitHub)
+	for _, area := range report.Areas {
+		fmt.Fprintf(w, "    %s: %d\n", area.Name, area.Count)
+	}
+	fmt.Fprintf(w, "  Not GitHub-owned: %d\n", report.NotGitHub)
+	fmt.Fprintf(w, "  Could not be checked: %d\n", report.Invalid)
+	if report.Skipped > 0 {
+		fmt.Fprintf(w, "  Skipped by --fail-fast: %d\n", report.Skipped)
+	}
+	fmt.Fprintf(w, "  Unique ranges matched: %d\n", len(report.Ranges))
+}
+
+// Nagios states ordered from best to worst, for combining the states of several checks
+var nagiosSeverity = map[int]int{nagiosOK: 0, nagiosWarning: 1, nagiosUnknown: 2, nagiosCritical: 3}
+
+// worseState returns the worse of two Nagios states
+func worseState(a, b int) int {
+	if nagiosSeverity[b] > nagiosSeverity[a] {
+		return b
+	}
+	return a
+}
+
+// batchStatus combines the monitoring states of checking several addresses into
+// a single status, reporting the number of addresses that aren't GitHub-owned
+func batchStatus(checked []checkedIP, snapshot time.Time, maxAge time.Duration) monitorStatus {
+	status := monitorStatus{State: nagiosOK, Age: time.Since(snapshot).Truncate(time.Second)}
+	var failed []error
+	var unmatched []string
+	for _, c := range checked {
+		s := checkStatus(c.IP, c.Result, c.Err, snapshot, maxAge)
+		status.State = worseState(status.State, s.State)
+		switch {
+		case c.Err != nil:
+			failed = append(failed, c.Err)
+		case !c.Result.IsGitHubIP:
+			unmatched = append(unmatched, c.IP)
+		}
+	}
+	status.Unmatched = len(unmatched)
+
+	switch {
+	case len(unmatched) > 0:
+		status.Message = fmt.Sprintf("%d of %d addresses are not GitHub-owned: %s", len(unmatched), len(checked), strings.Join(unmatched, ", "))
+	case len(failed) > 0:
+		status.Message = fmt.Sprintf("%d of %d addresses could not be checked: %v", len(failed), len(checked), failed[0])
+	default:
+		status.Message = fmt.Sprintf("all %d addresses belong to GitHub's ranges", len(checked))
+		if status.State == nagiosWarning {
+			status.Message += fmt.Sprintf(", but the ranges snapshot is %s old", status.Age)
+		}
+	}
+	return status
+}
+
+// reportBatchMonitoring prints the monitoring status of a batch run, a single
+// combined Nagios status line or a Checkmk local check line per address, and
+// returns an error exiting with the worst state, if it isn't OK
+func reportBatchMonitoring(w io.Writer, format string, checked []checkedIP, snapshot time.Time, maxAge time.Duration) error {
+	state := nagiosOK
+	if format == outputCheckmk {
+		for _, c := range checked {
+			status := checkStatus(c.IP, c.Result, c.Err, snapshot, maxAge)
+			fmt.Fprintln(w, formatCheckmk(c.IP, status, maxAge))
+			state = worseState(state, status.State)
+		}
+	} else {
+		status := batchStatus(checked, snapshot, maxAge)
+		fmt.Fprintln(w, formatNagios(status, maxAge))
+		state = status.State
+	}
+	if state != nagiosOK {
+		return exitStatusError(state)
+	}
+	return nil
+}
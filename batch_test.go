@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestReadBatchInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ips.txt")
+	content := "# Egress addresses\n192.30.252.1\n\n  8.8.8.8  \n#10.0.0.1\ninvalid-ip\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readBatchInput(path)
+	if err != nil {
+		t.Fatalf("readBatchInput() error = %v", err)
+	}
+	want := []string{"192.30.252.1", "8.8.8.8", "invalid-ip"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readBatchInput() = %q, want %q", got, want)
+	}
+
+	_, err = readBatchInput(filepath.Join(t.TempDir(), "missing.txt"))
+	if err == nil || errorCategory(err) != errorCategoryInput {
+		t.Errorf("readBatchInput() of a missing file error = %v, want an %s error", err, errorCategoryInput)
+	}
+}
+
+func TestUniqueInputs(t *testing.T) {
+	unique, counts := uniqueInputs([]string{"8.8.8.8", "192.30.252.1", "8.8.8.8", "invalid-ip", "8.8.8.8"})
+	if want := []string{"8.8.8.8", "192.30.252.1", "invalid-ip"}; !reflect.DeepEqual(unique, want) {
+		t.Errorf("uniqueInputs() = %q, want %q", unique, want)
+	}
+	if want := map[string]int{"8.8.8.8": 3, "192.30.252.1": 1, "invalid-ip": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("uniqueInputs() counts = %v, want %v", counts, want)
+	}
+}
+
+func TestSortInputs(t *testing.T) {
+	inputs := []string{"invalid-ip", "192.30.252.10", "8.8.8.8", "192.30.252.9", "2001:db8::1", "abc", "8.8.8.8"}
+	sortInputs(inputs)
+	want := []string{"8.8.8.8", "8.8.8.8", "192.30.252.9", "192.30.252.10", "2001:db8::1", "abc", "invalid-ip"}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("sortInputs() = %q, want %q", inputs, want)
+	}
+}
+
+func TestWriteBatchResult(t *testing.T) {
+	github := &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", AreaKey: "hooks", Range: "192.30.252.0/22"}
+	invalid := fmt.Errorf("invalid IP address format")
+
+	tests := []struct {
+		name       string
+		format     string
+		item       checkedIP
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "Text GitHub IP",
+			format:     outputText,
+			item:       checkedIP{IP: "192.30.252.1", Result: github},
+			wantStdout: "IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)\n",
+		},
+		{
+			name:       "Text non-GitHub IP",
+			format:     outputText,
+			item:       checkedIP{IP: "8.8.8.8", Result: &CheckResult{}},
+			wantStdout: "IP 8.8.8.8 is not a GitHub-owned address\n",
+		},
+		{
+			name:       "Text error",
+			format:     outputText,
+			item:       checkedIP{IP: "invalid-ip", Err: invalid},
+			wantStderr: "Error: invalid-ip: invalid IP address format\n",
+		},
+		{
+			name:       "JSON error",
+			format:     outputJSON,
+			item:       checkedIP{IP: "invalid-ip", Err: invalid},
+			wantStdout: `{"ip":"invalid-ip","is_github":false,"error":"invalid IP address format"}` + "\n",
+		},
+		{
+			name:       "Text occurrences",
+			format:     outputText,
+			item:       checkedIP{IP: "8.8.8.8", Result: &CheckResult{}, Count: 3},
+			wantStdout: "IP 8.8.8.8 is not a GitHub-owned address (3 occurrences)\n",
+		},
+		{
+			name:   "Text occurrences with other matches",
+			format: outputText,
+			item: checkedIP{IP: "192.30.252.1", Count: 2, Result: &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", Range: "192.30.252.0/22",
+				Also: []RangeMatch{{FunctionalArea: "Web", Range: "192.30.252.0/22"}, {FunctionalArea: "Git", Range: "192.30.252.0/22"}}}},
+			wantStdout: "IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22), also in Web, Git (192.30.252.0/22) (2 occurrences)\n",
+		},
+		{
+			name:       "JSON occurrences",
+			format:     outputJSON,
+			item:       checkedIP{IP: "192.30.252.1", Result: github, Count: 2},
+			wantStdout: `{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22","count":2}` + "\n",
+		},
+		{
+			name:       "Porcelain non-GitHub IP",
+			format:     outputPorcelain,
+			item:       checkedIP{IP: "8.8.8.8", Result: &CheckResult{}},
+			wantStdout: "not-github\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			writeBatchResult(&stdout, &stderr, tt.format, tt.item)
+			if stdout.String() != tt.wantStdout {
+				t.Errorf("writeBatchResult() stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if stderr.String() != tt.wantStderr {
+				t.Errorf("writeBatchResult() stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	github := checkedIP{IP: "192.30.252.1", Result: &CheckResult{IsGitHubIP: true}}
+	notGitHub := checkedIP{IP: "8.8.8.8", Result: &CheckResult{}}
+	invalid := checkedIP{IP: "invalid-ip", Err: fmt.Errorf("invalid IP address format")}
+
+	tests := []struct {
+		name          string
+		checked       []checkedIP
+		total         int
+		wantMessage   string
+		wantNotGitHub bool
+	}{
+		{
+			name:    "All GitHub",
+			checked: []checkedIP{github, github},
+			total:   2,
+		},
+		{
+			name:          "Non-GitHub IP",
+			checked:       []checkedIP{github, notGitHub},
+			total:         2,
+			wantMessage:   "1 of 2 IP addresses are not GitHub-owned",
+			wantNotGitHub: true,
+		},
+		{
+			name:        "Errors take precedence",
+			checked:     []checkedIP{notGitHub, invalid, github},
+			total:       3,
+			wantMessage: "1 of 3 addresses could not be checked",
+		},
+		{
+			name:          "Stopped early",
+			checked:       []checkedIP{github, notGitHub},
+			total:         5,
+			wantMessage:   "1 of 2 IP addresses are not GitHub-owned, stopped at 8.8.8.8 after 2 of 5 addresses",
+			wantNotGitHub: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := batchError(tt.checked, tt.total)
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Errorf("batchError() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantMessage {
+				t.Fatalf("batchError() error = %v, want %q", err, tt.wantMessage)
+			}
+			var notGitHubErr notGitHubError
+			if errors.As(err, &notGitHubErr) != tt.wantNotGitHub {
+				t.Errorf("batchError() error is notGitHubError = %v, want %v", !tt.wantNotGitHub, tt.wantNotGitHub)
+			}
+		})
+	}
+}
+
+func TestBatchReport(t *testing.T) {
+	hooks := &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", AreaKey: "hooks", Range: "192.30.252.0/22"}
+	actions := &CheckResult{IsGitHubIP: true, FunctionalArea: "Actions", AreaKey: "actions", Range: "4.148.0.0/16"}
+	checked := []checkedIP{
+		{IP: "192.30.252.1", Result: hooks},
+		{IP: "4.148.0.1", Result: actions},
+		{IP: "8.8.8.8", Result: &CheckResult{}},
+		{IP: "4.148.0.2", Result: actions},
+		{IP: "invalid-ip", Err: fmt.Errorf("invalid IP address format")},
+	}
+
+	report := newBatchReport(checked, 7)
+	want := batchReport{
+		Total:     5,
+		GitHub:    3,
+		Areas:     []areaCount{{"actions", "Actions", 2}, {"hooks", "Hooks", 1}},
+		NotGitHub: 1,
+		Invalid:   1,
+		Skipped:   2,
+		Ranges:    []string{"192.30.252.0/22", "4.148.0.0/16"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("newBatchReport() = %+v, want %+v", report, want)
+	}
+
+	var buf bytes.Buffer
+	writeBatchReport(&buf, outputText, report)
+	wantText := "Checked 5 addresses:\n" +
+		"  GitHub-owned: 3\n" +
+		"    Actions: 2\n" +
+		"    Hooks: 1\n" +
+		"  Not GitHub-owned: 1\n" +
+		"  Could not be checked: 1\n" +
+		"  Skipped by --fail-fast: 2\n" +
+		"  Unique ranges matched: 2\n"
+	if buf.String() != wantText {
+		t.Errorf("writeBatchReport() text = %q, want %q", buf.String(), wantText)
+	}
+
+	buf.Reset()
+	writeBatchReport(&buf, outputJSON, newBatchReport(checked[2:3], 1))
+	wantJSON := `{"summary":{"total":1,"github":0,"areas":[],"not_github":1,"invalid":0,"skipped":0,"ranges":[]}}` + "\n"
+	if buf.String() != wantJSON {
+		t.Errorf("writeBatchReport() JSON = %q, want %q", buf.String(), wantJSON)
+	}
+}
+
+func TestBatchMonitoring(t *testing.T) {
+	github := checkedIP{IP: "192.30.252.1", Result: &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", Range: "192.30.252.0/22"}}
+	notGitHub := checkedIP{IP: "8.8.8.8", Result: &CheckResult{}}
+	invalid := checkedIP{IP: "invalid-ip", Err: fmt.Errorf("invalid IP address format")}
+	snapshot := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name        string
+		checked     []checkedIP
+		maxAge      time.Duration
+		wantState   int
+		wantNagios  string
+		wantCheckmk []string
+	}{
+		{
+			name:      "All GitHub",
+			checked:   []checkedIP{github, github},
+			wantState: nagiosOK,
+			wantNagios: "GITHUB-IP OK - all 2 addresses belong to GitHub's ranges" +
+				" | snapshot_age=7200s;;;0 unmatched=0;;1;0",
+		},
+		{
+			name:      "Stale snapshot",
+			checked:   []checkedIP{github},
+			maxAge:    time.Hour,
+			wantState: nagiosWarning,
+			wantNagios: "GITHUB-IP WARNING - all 1 addresses belong to GitHub's ranges, but the ranges snapshot is 2h0m0s old" +
+				" | snapshot_age=7200s;3600;;0 unmatched=0;;1;0",
+		},
+		{
+			name:      "Non-GitHub outranks errors",
+			checked:   []checkedIP{github, invalid, notGitHub},
+			wantState: nagiosCritical,
+			wantNagios: "GITHUB-IP CRITICAL - 1 of 3 addresses are not GitHub-owned: 8.8.8.8" +
+				" | snapshot_age=7200s;;;0 unmatched=1;;1;0",
+			wantCheckmk: []string{
+				`0 "GitHub IP 192.30.252.1" snapshot_age=7200|unmatched=0;;1 IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)`,
+				`3 "GitHub IP invalid-ip" - invalid IP address format`,
+				`2 "GitHub IP 8.8.8.8" snapshot_age=7200|unmatched=1;;1 IP 8.8.8.8 is not a GitHub-owned address`,
+			},
+		},
+		{
+			name:       "Errors",
+			checked:    []checkedIP{github, invalid},
+			wantState:  nagiosUnknown,
+			wantNagios: "GITHUB-IP UNKNOWN - 1 of 2 addresses could not be checked: invalid IP address format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := reportBatchMonitoring(&buf, outputNagios, tt.checked, snapshot, tt.maxAge)
+			if got := strings.TrimSuffix(buf.String(), "\n"); got != tt.wantNagios {
+				t.Errorf("nagios output = %q, want %q", got, tt.wantNagios)
+			}
+			var status exitStatusError
+			if tt.wantState == nagiosOK {
+				if err != nil {
+					t.Errorf("reportBatchMonitoring() error = %v, want nil", err)
+				}
+			} else if !errors.As(err, &status) || int(status) != tt.wantState {
+				t.Errorf("reportBatchMonitoring() error = %v, want exit status %d", err, tt.wantState)
+			}
+
+			if tt.wantCheckmk != nil {
+				buf.Reset()
+				reportBatchMonitoring(&buf, outputCheckmk, tt.checked, snapshot, tt.maxAge)
+				want := strings.Join(tt.wantCheckmk, "\n") + "\n"
+				if buf.String() != want {
+					t.Errorf("checkmk output = %q, want %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	inputs := []string{"192.30.252.1", "8.8.8.8", "invalid-ip", "192.30.252.2"}
+
+	tests := []struct {
+		name       string
+		failFast   bool
+		unique     bool
+		sort       bool
+		wantStdout string
+		wantStderr string
+		wantErr    string
+	}{
+		{
+			name: "Checks every address",
+			wantStdout: "IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)\n" +
+				"IP 8.8.8.8 is not a GitHub-owned address\n" +
+				"IP 192.30.252.2 belongs to GitHub's Hooks range (192.30.252.0/22)\n",
+			wantStderr: "Error: invalid-ip: invalid IP address format\n",
+			wantErr:    "1 of 4 addresses could not be checked",
+		},
+		{
+			name:     "Fail fast",
+			failFast: true,
+			wantStdout: "IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)\n" +
+				"IP 8.8.8.8 is not a GitHub-owned address\n",
+			wantErr: "1 of 2 IP addresses are not GitHub-owned, stopped at 8.8.8.8 after 2 of 4 addresses",
+		},
+		{
+			name:       "Unique sorted",
+			failFast:   true,
+			unique:     true,
+			sort:       true,
+			wantStdout: "IP 8.8.8.8 is not a GitHub-owned address (2 occurrences)\n",
+			wantErr:    "1 of 1 IP addresses are not GitHub-owned, stopped at 8.8.8.8 after 1 of 4 addresses",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldStdout := os.Stdout
+			oldStderr := os.Stderr
+			rOut, wOut, _ := os.Pipe()
+			rErr, wErr, _ := os.Pipe()
+			os.Stdout = wOut
+			os.Stderr = wErr
+
+			cmd := &cobra.Command{}
+			cmd.Flags().Bool("fail-fast", tt.failFast, "")
+			cmd.Flags().Bool("unique", tt.unique, "")
+			cmd.Flags().Bool("sort", tt.sort, "")
+			in := inputs
+			if tt.unique {
+				in = append(in, "8.8.8.8")
+			}
+			err := runBatch(cmd, NewIPChecker(), outputText, in)
+
+			wOut.Close()
+			wErr.Close()
+			os.Stdout = oldStdout
+			os.Stderr = oldStderr
+
+			var bufOut, bufErr bytes.Buffer
+			bufOut.ReadFrom(rOut)
+			bufErr.ReadFrom(rErr)
+
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("runBatch() error = %v, want %q", err, tt.wantErr)
+			}
+			if bufOut.String() != tt.wantStdout {
+				t.Errorf("runBatch() stdout = %q, want %q", bufOut.String(), tt.wantStdout)
+			}
+			if bufErr.String() != tt.wantStderr {
+				t.Errorf("runBatch() stderr = %q, want %q", bufErr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"reflect"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// benchMissAddresses are public addresses outside GitHub's ranges, so lookups
+// that find nothing are measured too
+var benchMissAddresses = []string{"8.8.8.8", "1.1.1.1", "9.9.9.9", "208.67.222.222", "151.101.1.195"}
+
+// benchReport is the result of the bench command
+type benchReport struct {
+	GoVersion string         `json:"go_version"`
+	Platform  string         `json:"platform"`
+	CPUs      int            `json:"cpus"`
+	MetaBytes int            `json:"meta_bytes"`
+	Fetch     float64        `json:"fetch_ms"`
+	Parse     float64        `json:"parse_ms"`
+	Areas     int            `json:"areas"`
+	Ranges    int            `json:"ranges"`
+	Addresses int            `json:"addresses"` // Sample addresses looked up round-robin
+	Matchers  []benchMatcher `json:"matchers"`
+}
+
+// benchMatcher is the measured performance of a matcher
+type benchMatcher struct {
+	Name             string   `json:"name"`
+	Build            *float64 `json:"build_ms,omitempty"` // Time to build the matcher's index, if it has one
+	Lookups          int      `json:"lookups"`
+	LookupsPerSecond float64  `json:"lookups_per_second"`
+	AllocsPerLookup  float64  `json:"allocs_per_lookup"`
+	BytesPerLookup   float64  `json:"bytes_per_lookup"`
+}
+
+// newBenchCommand creates the bench subcommand
+func newBenchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure range lookup performance on this machine",
+		Long: `Fetch the current ranges and measure how long fetching and parsing the meta
+document takes, then how many lookups per second, and how many allocations per
+lookup, the linear matcher used for one-off checks and the trie matcher achieve
+on this machine. Use it to size deployments that check many addresses.
+
+The lookups cycle through an address in each published IPv4 range and a few
+addresses outside them. The trie's results are checked against the linear
+matcher's before measuring.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runBench,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().Duration("duration", time.Second, "How long to measure each matcher")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	duration, _ := cmd.Flags().GetDuration("duration")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+	if duration <= 0 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--duration must be positive"))
+	}
+
+	report, err := runBenchmarks(duration)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		out, err := marshalExportJSON(report)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	writeBenchReport(os.Stdout, report)
+	return nil
+}
+
+// runBenchmarks fetches the meta document and measures the matchers for the
+// given duration each
+func runBenchmarks(duration time.Duration) (benchReport, error) {
+	report := benchReport{
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+	}
+
+	start := time.Now()
+	body, _, err := fetchMetaDocument(metaClient, githubMetaURL)
+	if err != nil {
+		return report, err
+	}
+	report.Fetch = milliseconds(time.Since(start))
+	report.MetaBytes = len(body)
+
+	start = time.Now()
+	var meta GitHubMeta
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return report, withCategory(errorCategoryAPI, fmt.Errorf("failed to decode GitHub meta response: %w", err))
+	}
+	report.Parse = milliseconds(time.Since(start))
+
+	areas := meta.Areas()
+	for _, area := range areas {
+		if len(area.Ranges) > 0 {
+			report.Areas++
+		}
+		report.Ranges += len(area.Ranges)
+	}
+
+	var addrs []netip.Addr
+	for _, addr := range append(sampleAddresses(uniqueRanges(areas), report.Ranges), benchMissAddresses...) {
+		addrs = append(addrs, netip.MustParseAddr(addr))
+	}
+	report.Addresses = len(addrs)
+
+	linear := func(addr netip.Addr) []RangeMatch { return matchLinear(areas, addr) }
+	report.Matchers = append(report.Matchers, measureMatcher("linear", linear, addrs, duration))
+
+	start = time.Now()
+	trie := newRangeTrie(areas)
+	build := milliseconds(time.Since(start))
+	for _, ip := range addrs {
+		if want, got := linear(ip), trie.match(ip); !reflect.DeepEqual(want, got) {
+			return report, withCategory(errorCategoryInternal, fmt.Errorf("trie matcher found %v for %s, linear matcher %v", got, ip, want))
+		}
+	}
+	m := measureMatcher("trie", trie.match, addrs, duration)
+	m.Build = &build
+	report.Matchers = append(report.Matchers, m)
+
+	return report, nil
+}
+
+// measureMatcher looks up the addresses round-robin for at least duration,
+// counting the lookups and the allocations they made
+func measureMatcher(name string, match func(netip.Addr) []RangeMatch, addrs []netip.Addr, duration time.Duration) benchMatcher {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	lookups := 0
+	start := time.Now()
+	for time.Since(start) < duration {
+		for _, ip := range addrs {
+			match(ip)
+		}
+		lookups += len(addrs)
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return benchMatcher{
+		Name:             name,
+		Lookups:          lookups,
+		LookupsPerSecond: float64(lookups) / elapsed.Seconds(),
+		AllocsPerLookup:  float64(after.Mallocs-before.Mallocs) / float64(lookups),
+		BytesPerLookup:   float64(after.TotalAlloc-before.TotalAlloc) / float64(lookups),
+	}
+}
+
+// writeBenchReport writes the measurements as text
+func writeBenchReport(w io.Writer, report benchReport) {
+	fmt.Fprintf(w, "Go %s on %s, %d CPUs\n", report.GoVersion, report.Platform, report.CPUs)
+	fmt.Fprintf(w, "Meta fetch: %.3fms (%d bytes)\n", report.Fetch, report.MetaBytes)
+	fmt.Fprintf(w, "Meta parse: %.3fms\n", report.Parse)
+	fmt.Fprintf(w, "Ranges: %d in %d areas, looked up with %d addresses\n\n", report.Ranges, report.Areas, report.Addresses)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Matcher\tBuild\tLookups/sec\tAllocs/lookup\tBytes/lookup")
+	for _, m := range report.Matchers {
+		build := "-"
+		if m.Build != nil {
+			build = fmt.Sprintf("%.3fms", *m.Build)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%.1f\t%.0f\n", m.Name, build, m.LookupsPerSecond, m.AllocsPerLookup, m.BytesPerLookup)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRunBenchmarks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22", "2a0a:a440::/29"], "web": ["192.30.252.0/22", "140.82.112.0/20"], "git": ["140.82.121.3/32"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	report, err := runBenchmarks(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("runBenchmarks() error = %v", err)
+	}
+	if report.Areas != 3 || report.Ranges != 5 || report.Addresses != 3+len(benchMissAddresses) {
+		t.Errorf("runBenchmarks() areas = %d, ranges = %d, addresses = %d", report.Areas, report.Ranges, report.Addresses)
+	}
+	if len(report.Matchers) != 2 || report.Matchers[0].Name != "linear" || report.Matchers[1].Name != "trie" {
+		t.Fatalf("runBenchmarks() matchers = %+v", report.Matchers)
+	}
+	for _, m := range report.Matchers {
+		if m.Lookups == 0 || m.LookupsPerSecond <= 0 {
+			t.Errorf("matcher %s made %d lookups at %f/s", m.Name, m.Lookups, m.LookupsPerSecond)
+		}
+	}
+	if report.Matchers[0].Build != nil || report.Matchers[1].Build == nil {
+		t.Errorf("only the trie should report a build time")
+	}
+
+	var buf bytes.Buffer
+	writeBenchReport(&buf, report)
+	for _, want := range []string{"Meta fetch: ", "Ranges: 5 in 3 areas, looked up with 8 addresses\n", "Matcher  Build", "\nlinear   -  ", "\ntrie     "} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeBenchReport() = %q, want it to contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestRunBenchErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		output   string
+		category string
+	}{
+		{name: "Unsupported output", duration: time.Millisecond, output: "csv", category: errorCategoryUsage},
+		{name: "Zero duration", output: outputText, category: errorCategoryUsage},
+		{name: "Invalid meta", duration: time.Millisecond, output: outputJSON, category: errorCategoryAPI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().Duration("duration", tt.duration, "")
+			cmd.Flags().String("output", tt.output, "")
+
+			err := runBench(cmd, nil)
+			if err == nil {
+				t.Fatal("runBench() should fail")
+			}
+			if errorCategory(err) != tt.category {
+				t.Errorf("runBench() error category = %s, want %s", errorCategory(err), tt.category)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The functions below are the entry points of the WebAssembly and C library
+// builds. They exchange JSON documents, so that hosts in other languages depend
+// on the same output as --output json rather than on Go types.
+
+// areaRangesJSON is an area and its ranges, as listed by the library builds
+type areaRangesJSON struct {
+	Key    string   `json:"key"`
+	Name   string   `json:"name"`
+	Ranges []string `json:"ranges"`
+}
+
+// newMetaChecker returns a checker for a meta document supplied by the caller
+// rather than fetched, for hosts that fetch and cache it themselves
+func newMetaChecker(metaJSON []byte) (*IPChecker, error) {
+	var meta GitHubMeta
+	if err := json.Unmarshal(metaJSON, &meta); err != nil {
+		return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to decode GitHub meta document: %w", err))
+	}
+	checker := NewIPChecker()
+	checker.setMeta(&meta)
+	return checker, nil
+}
+
+// checkIPJSON checks ip, returning the result as the JSON check output
+func checkIPJSON(checker *IPChecker, ip string) ([]byte, error) {
+	result, err := checker.CheckIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(newResultJSON(ip, result))
+}
+
+// listRangesJSON returns the named areas and their ranges as a JSON array, or
+// every area publishing ranges if names is empty
+func listRangesJSON(checker *IPChecker, names []string) ([]byte, error) {
+	areas, err := checker.Areas()
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectAreas(areas, names)
+	if err != nil {
+		return nil, withCategory(errorCategoryUsage, err)
+	}
+
+	list := []areaRangesJSON{}
+	for _, area := range selected {
+		if len(names) == 0 && len(area.Ranges) == 0 {
+			continue
+		}
+		list = append(list, areaRangesJSON{area.Key, area.Name, append([]string{}, area.Ranges...)})
+	}
+	return json.Marshal(list)
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestCheckIPJSON(t *testing.T) {
+	checker, err := newMetaChecker([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["192.30.252.0/22"]}`))
+	if err != nil {
+		t.Fatalf("newMetaChecker() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ip       string
+		want     string
+		category string
+	}{
+		{name: "GitHub IP", ip: "192.30.252.1",
+			want: `{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22","also":[{"area":"Web","range":"192.30.252.0/22"}]}`},
+		{name: "Non-GitHub IP", ip: "8.8.8.8", want: `{"ip":"8.8.8.8","is_github":false}`},
+		{name: "Invalid IP", ip: "nope", category: errorCategoryInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := checkIPJSON(checker, tt.ip)
+			if tt.category != "" {
+				if err == nil || errorCategory(err) != tt.category {
+					t.Fatalf("checkIPJSON() error = %v, want category %s", err, tt.category)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkIPJSON() error = %v", err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("checkIPJSON() = %s, want %s", out, tt.want)
+			}
+		})
+	}
+
+	if _, err := newMetaChecker([]byte(`<html>`)); err == nil || errorCategory(err) != errorCategoryInput {
+		t.Errorf("newMetaChecker() with invalid JSON error = %v, want category %s", err, errorCategoryInput)
+	}
+}
+
+func TestListRangesJSON(t *testing.T) {
+	checker, err := newMetaChecker([]byte(`{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20", "143.55.64.0/20"]}`))
+	if err != nil {
+		t.Fatalf("newMetaChecker() error = %v", err)
+	}
+
+	tests := []struct {
+		names    []string
+		want     string
+		category string
+	}{
+		{want: `[{"key":"hooks","name":"Hooks","ranges":["192.30.252.0/22"]},{"key":"git","name":"Git","ranges":["140.82.112.0/20","143.55.64.0/20"]}]`},
+		{names: []string{"git"}, want: `[{"key":"git","name":"Git","ranges":["140.82.112.0/20","143.55.64.0/20"]}]`},
+		{names: []string{"pages"}, want: `[{"key":"pages","name":"Pages","ranges":[]}]`},
+		{names: []string{"nope"}, category: errorCategoryUsage},
+	}
+	for _, tt := range tests {
+		out, err := listRangesJSON(checker, tt.names)
+		if tt.category != "" {
+			if err == nil || errorCategory(err) != tt.category {
+				t.Errorf("listRangesJSON(%v) error = %v, want category %s", tt.names, err, tt.category)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("listRangesJSON(%v) error = %v", tt.names, err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("listRangesJSON(%v) = %s, want %s", tt.names, out, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerRetryInterval is how soon a failed refresh is retried while the
+// breaker is closed
+const breakerRetryInterval = time.Minute
+
+// Circuit breaker states
+const (
+	breakerClosed = "closed"
+	breakerOpen   = "open"
+)
+
+// breaker is the circuit breaker around serve's meta refreshes. Failed
+// refreshes are retried every breakerRetryInterval until threshold of them
+// failed in a row, which opens the breaker: refreshes are then only attempted
+// every probe interval, so an outage of GitHub's API isn't hammered, until one
+// succeeds and closes it again.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	probe     time.Duration
+	failures  int // Consecutive failed refreshes
+	openedAt  time.Time
+	lastError string
+	next      time.Time // When the next refresh is attempted
+}
+
+// breakerJSON is the state of the breaker reported by /healthz
+type breakerJSON struct {
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	NextProbe *time.Time `json:"next_probe,omitempty"`
+}
+
+// newBreaker returns a closed breaker
+func newBreaker(threshold int, probe time.Duration) *breaker {
+	return &breaker{threshold: threshold, probe: probe}
+}
+
+// record records the outcome of a refresh at now, returning how long to wait
+// before the next one given the refresh interval
+func (b *breaker) record(err error, now time.Time, interval time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wait := interval
+	switch {
+	case err == nil:
+		b.failures = 0
+		b.openedAt = time.Time{}
+		b.lastError = ""
+	default:
+		b.failures++
+		b.lastError = err.Error()
+		wait = min(breakerRetryInterval, interval)
+		if b.failures >= b.threshold {
+			if b.openedAt.IsZero() {
+				b.openedAt = now
+			}
+			wait = b.probe
+		}
+	}
+	b.next = now.Add(wait)
+	return wait
+}
+
+// stats returns the breaker's state for /healthz
+func (b *breaker) stats() breakerJSON {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := breakerJSON{State: breakerClosed, Failures: b.failures, LastError: b.lastError}
+	if !b.openedAt.IsZero() {
+		opened, next := b.openedAt, b.next
+		out.State, out.OpenedAt, out.NextProbe = breakerOpen, &opened, &next
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker(3, 10*time.Minute)
+	start := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	errAPI := errors.New("GitHub API returned status code 503")
+
+	steps := []struct {
+		err       error
+		wantWait  time.Duration
+		wantState string
+	}{
+		{nil, time.Hour, breakerClosed},
+		{errAPI, time.Minute, breakerClosed},
+		{errAPI, time.Minute, breakerClosed},
+		{errAPI, 10 * time.Minute, breakerOpen},
+		{errAPI, 10 * time.Minute, breakerOpen},
+		{nil, time.Hour, breakerClosed},
+		{errAPI, time.Minute, breakerClosed},
+	}
+	now := start
+	for i, step := range steps {
+		wait := b.record(step.err, now, time.Hour)
+		stats := b.stats()
+		if wait != step.wantWait || stats.State != step.wantState {
+			t.Fatalf("step %d: record() = %s, state %s, want %s, %s", i, wait, stats.State, step.wantWait, step.wantState)
+		}
+		if stats.State == breakerOpen {
+			// The breaker stays open from the refresh that opened it
+			if opened := start.Add(time.Hour + 2*time.Minute); !stats.OpenedAt.Equal(opened) {
+				t.Errorf("step %d: opened at %s, want %s", i, stats.OpenedAt, opened)
+			}
+			if next := now.Add(wait); !stats.NextProbe.Equal(next) {
+				t.Errorf("step %d: next probe %s, want %s", i, stats.NextProbe, next)
+			}
+			if stats.LastError != errAPI.Error() {
+				t.Errorf("step %d: last error %q, want %q", i, stats.LastError, errAPI)
+			}
+		}
+		now = now.Add(wait)
+	}
+
+	// A refresh interval shorter than the retry interval isn't lengthened
+	b = newBreaker(3, 10*time.Minute)
+	if wait := b.record(errAPI, start, 30*time.Second); wait != 30*time.Second {
+		t.Errorf("record() = %s, want 30s", wait)
+	}
+}
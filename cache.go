@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// metaCacheDir holds the cached meta documents, one file per meta URL
+var metaCacheDir = defaultMetaCacheDir()
+
+// metaCache is how the command line uses the meta document cache, or nil to
+// always fetch the document without caching it, as the library builds do
+var metaCache *metaCachePolicy
+
+// metaCachePolicy decides when a cached meta document is used instead of
+// fetching it
+type metaCachePolicy struct {
+	read    bool          // Whether cached copies are used at all
+	maxAge  time.Duration // Oldest cached copy used, if set
+	honor   bool          // Use cached copies while their Cache-Control max-age holds
+	offline bool          // Use cached copies of any age, never fetching
+}
+
+// metaCacheEntry is a cached meta document
+type metaCacheEntry struct {
+	URL          string          `json:"url"`
+	Fetched      time.Time       `json:"fetched"`
+	LastModified string          `json:"last_modified,omitempty"`
+	MaxAge       int64           `json:"max_age,omitempty"` // Seconds, from the response's Cache-Control
+	Document     json.RawMessage `json:"document"`
+}
+
+// defaultMetaCacheDir returns the cache directory in the user's cache
+// directory, or "" if there is none
+func defaultMetaCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gh-check-github-ip-ranges")
+}
+
+// defaultACMECacheDir returns the directory of serve's ACME certificates in
+// the cache directory, or "" if there is none
+func defaultACMECacheDir() string {
+	if dir := defaultMetaCacheDir(); dir != "" {
+		return filepath.Join(dir, "acme")
+	}
+	return ""
+}
+
+// addCacheFlags adds the flags controlling the meta document cache, shared by
+// all commands
+func addCacheFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("no-cache", false, "Fetch the meta document even if a fresh copy is cached")
+	cmd.PersistentFlags().Duration("max-age", 0, "Use a cached meta document up to this old instead of fetching it, e.g. 24h (default GitHub's Cache-Control max-age)")
+	cmd.PersistentFlags().Bool("prefer-cache", false, "Use the cached meta document whatever its age and never fetch it, e.g. offline")
+}
+
+// applyCacheFlags selects the cache policy of the command line from the flags
+func applyCacheFlags(cmd *cobra.Command) error {
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	preferCache, _ := cmd.Flags().GetBool("prefer-cache")
+	maxAge, _ := cmd.Flags().GetDuration("max-age")
+	withMaxAge := cmd.Flags().Changed("max-age")
+
+	switch {
+	case noCache && (preferCache || withMaxAge):
+		return withCategory(errorCategoryUsage, fmt.Errorf("--no-cache can't be combined with --prefer-cache or --max-age"))
+	case preferCache && withMaxAge:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--prefer-cache can't be combined with --max-age"))
+	case maxAge < 0:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--max-age must not be negative"))
+	}
+
+	metaCache = &metaCachePolicy{
+		read:    !noCache,
+		maxAge:  maxAge,
+		honor:   !withMaxAge,
+		offline: preferCache,
+	}
+	return nil
+}
+
+// metaCachePath returns the cache file of the meta document at url
+func metaCachePath(url string) string {
+	return filepath.Join(metaCacheDir, fmt.Sprintf("meta-%x.json", sha256.Sum256([]byte(url))))
+}
+
+// readMetaCache returns the cached copy of the meta document at url, or nil
+func readMetaCache(url string) *metaCacheEntry {
+	if metaCacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(metaCachePath(url))
+	if err != nil {
+		return nil
+	}
+	var entry metaCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	return &entry
+}
+
+// writeMetaCache caches a fetched meta document. The cache only saves fetches,
+// so failing to write it isn't an error.
+func writeMetaCache(url string, body []byte, header http.Header, fetched time.Time) {
+	if metaCacheDir == "" || !json.Valid(body) {
+		return
+	}
+	data, err := json.Marshal(metaCacheEntry{
+		URL:          url,
+		Fetched:      fetched,
+		LastModified: header.Get("Last-Modified"),
+		MaxAge:       cacheControlMaxAge(header.Get("Cache-Control")),
+		Document:     body,
+	})
+	if err != nil || os.MkdirAll(metaCacheDir, 0o755) != nil {
+		return
+	}
+
+	// Write a temporary file, so concurrent runs never read a partial entry
+	tmp, err := os.CreateTemp(metaCacheDir, "meta-*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(tmp.Name(), metaCachePath(url)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// cacheControlMaxAge returns the max-age of a Cache-Control header in seconds,
+// or 0 if it has none or forbids caching
+func cacheControlMaxAge(value string) int64 {
+	var maxAge int64
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return 0
+		case "max-age":
+			if n, err := strconv.ParseInt(arg, 10, 64); err == nil && n > 0 {
+				maxAge = n
+			}
+		}
+	}
+	return maxAge
+}
+
+// cached returns the cached copy of the meta document at url to use instead of
+// fetching it, if any. A refresh only uses the cache with --prefer-cache, where
+// a missing copy is an error.
+func (p *metaCachePolicy) cached(url string, now time.Time, refresh bool) (*metaCacheEntry, error) {
+	if p == nil || !p.read || (refresh && !p.offline) {
+		return nil, nil
+	}
+	entry := readMetaCache(url)
+	switch {
+	case p.offline && entry == nil:
+		return nil, withCategory(errorCategoryInput, fmt.Errorf("no cached meta document for %s; run without --prefer-cache to fetch it", url))
+	case entry == nil:
+		return nil, nil
+	case p.offline:
+		return entry, nil
+	}
+
+	maxAge := p.maxAge
+	if p.honor {
+		maxAge = time.Duration(entry.MaxAge) * time.Second
+	}
+	if now.Sub(entry.Fetched) > maxAge {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// writeCacheNote notes on w that the checks used a cached meta document, and
+// how old it was
+func writeCacheNote(w io.Writer, checker *IPChecker) {
+	if !checker.Cached() {
+		return
+	}
+	fmt.Fprintf(w, "Note: using the meta document cached at %s (%s old); --no-cache fetches it\n",
+		checker.FetchTime().Format(time.RFC3339), time.Since(checker.FetchTime()).Round(time.Second))
+}
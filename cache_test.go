@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// useCacheFlags applies the cache flags in args, restoring the policy and
+// cache directory when the test ends
+func useCacheFlags(t *testing.T, args ...string) error {
+	t.Helper()
+	oldCache, oldDir := metaCache, metaCacheDir
+	metaCacheDir = t.TempDir()
+	t.Cleanup(func() { metaCache, metaCacheDir = oldCache, oldDir })
+
+	cmd := &cobra.Command{}
+	addCacheFlags(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return applyCacheFlags(cmd)
+}
+
+func TestApplyCacheFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    metaCachePolicy
+		wantErr bool
+	}{
+		{name: "Default", want: metaCachePolicy{read: true, honor: true}},
+		{name: "No cache", args: []string{"--no-cache"}, want: metaCachePolicy{honor: true}},
+		{name: "Max age", args: []string{"--max-age", "24h"}, want: metaCachePolicy{read: true, maxAge: 24 * time.Hour}},
+		{name: "Prefer cache", args: []string{"--prefer-cache"}, want: metaCachePolicy{read: true, honor: true, offline: true}},
+		{name: "No cache and prefer cache", args: []string{"--no-cache", "--prefer-cache"}, wantErr: true},
+		{name: "No cache and max age", args: []string{"--no-cache", "--max-age", "1h"}, wantErr: true},
+		{name: "Prefer cache and max age", args: []string{"--prefer-cache", "--max-age", "1h"}, wantErr: true},
+		{name: "Negative max age", args: []string{"--max-age", "-1h"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := useCacheFlags(t, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyCacheFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if errorCategory(err) != errorCategoryUsage {
+					t.Errorf("applyCacheFlags() error category = %s, want %s", errorCategory(err), errorCategoryUsage)
+				}
+				return
+			}
+			if *metaCache != tt.want {
+				t.Errorf("applyCacheFlags() policy = %+v, want %+v", *metaCache, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPChecker_MetaCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		cacheControl string
+		age          time.Duration // Age of the cached copy at the second check
+		wantCached   bool
+	}{
+		{name: "Fresh per Cache-Control", cacheControl: "public, max-age=60, s-maxage=60", age: 30 * time.Second, wantCached: true},
+		{name: "Stale per Cache-Control", cacheControl: "public, max-age=60", age: 2 * time.Minute},
+		{name: "No Cache-Control", age: time.Second},
+		{name: "No cache", args: []string{"--no-cache"}, cacheControl: "max-age=60", age: time.Second},
+		{name: "Within max age", args: []string{"--max-age", "24h"}, age: 12 * time.Hour, wantCached: true},
+		{name: "Beyond max age", args: []string{"--max-age", "24h"}, cacheControl: "max-age=604800", age: 48 * time.Hour},
+		{name: "Prefer cache", args: []string{"--prefer-cache"}, age: 30 * 24 * time.Hour, wantCached: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Cache-Control", tt.cacheControl)
+				w.Header().Set("Last-Modified", "Mon, 02 Jun 2025 00:00:00 GMT")
+				w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+			}))
+			defer server.Close()
+
+			oldURL := githubMetaURL
+			githubMetaURL = server.URL
+			defer func() { githubMetaURL = oldURL }()
+
+			// Populate the cache as if a previous run fetched the document age ago
+			if err := useCacheFlags(t, "--no-cache"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewIPChecker().Meta(); err != nil {
+				t.Fatalf("Meta() error = %v", err)
+			}
+			entry := readMetaCache(server.URL)
+			if entry == nil {
+				t.Fatal("Meta() didn't cache the document")
+			}
+			dir := metaCacheDir
+			entry.Fetched = entry.Fetched.Add(-tt.age)
+			writeMetaCache(entry.URL, entry.Document, http.Header{
+				"Cache-Control": {tt.cacheControl},
+				"Last-Modified": {entry.LastModified},
+			}, entry.Fetched)
+
+			if err := useCacheFlags(t, tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			metaCacheDir = dir
+			checker := NewIPChecker()
+			result, err := checker.CheckIP("192.30.252.1")
+			if err != nil {
+				t.Fatalf("CheckIP() error = %v", err)
+			}
+			if !result.IsGitHubIP || checker.Cached() != tt.wantCached {
+				t.Errorf("CheckIP() = %+v, cached %t, want cached %t", result, checker.Cached(), tt.wantCached)
+			}
+			if wantRequests := map[bool]int{true: 1, false: 2}[tt.wantCached]; requests != wantRequests {
+				t.Errorf("meta requests = %d, want %d", requests, wantRequests)
+			}
+			if got := checker.SnapshotTime().Format(http.TimeFormat); got != "Mon, 02 Jun 2025 00:00:00 GMT" {
+				t.Errorf("SnapshotTime() = %s, want the cached Last-Modified", got)
+			}
+
+			var buf bytes.Buffer
+			writeCacheNote(&buf, checker)
+			if got := strings.Contains(buf.String(), "cached at"); got != tt.wantCached {
+				t.Errorf("writeCacheNote() = %q, want a note %t", buf.String(), tt.wantCached)
+			}
+		})
+	}
+}
+
+func TestIPChecker_MetaCacheRefresh(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	if err := useCacheFlags(t, "--prefer-cache"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewIPChecker().Meta()
+	if err == nil || errorCategory(err) != errorCategoryInput {
+		t.Fatalf("Meta() with --prefer-cache and nothing cached error = %v, want an %s error", err, errorCategoryInput)
+	}
+
+	metaCache.offline = false
+	checker := NewIPChecker()
+	if _, err := checker.Meta(); err != nil {
+		t.Fatalf("Meta() error = %v", err)
+	}
+	if _, err := NewIPChecker().Meta(); err != nil || requests != 1 {
+		t.Fatalf("Meta() of a fresh cached copy error = %v after %d requests, want 1", err, requests)
+	}
+	// Refreshing is asking for the current document, so it skips the cache
+	if err := checker.Refresh(); err != nil || requests != 2 || checker.Cached() {
+		t.Errorf("Refresh() error = %v after %d requests, cached %t, want 2 requests", err, requests, checker.Cached())
+	}
+}
+
+func TestCacheControlMaxAge(t *testing.T) {
+	tests := map[string]int64{
+		"":                                0,
+		"public, max-age=60, s-maxage=60": 60,
+		"max-age=3600":                    3600,
+		"Max-Age=120, private":            120,
+		"no-store":                        0,
+		"max-age=60, no-cache":            0,
+		"max-age=soon":                    0,
+	}
+	for value, want := range tests {
+		if got := cacheControlMaxAge(value); got != want {
+			t.Errorf("cacheControlMaxAge(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Result is the outcome of checking one address of a batch
+type Result struct {
+	Input  string
+	Result *CheckResult // nil if the address couldn't be checked
+	Err    error
+}
+
+// CheckMany checks a batch of addresses concurrently against one snapshot of
+// the ranges, returning a result per address in input order. The returned
+// error joins the errors of the addresses that couldn't be checked, each
+// prefixed with its input, so it is nil only if every address was checked; a
+// failure to fetch the ranges fails the whole batch. Addresses not yet checked
+// when ctx is done fail with its error.
+func (c *IPChecker) CheckMany(ctx context.Context, ips []string) ([]Result, error) {
+	// Load every source up front, so the workers only read the checker
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(ips))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(ips)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result, err := c.CheckIP(ips[i])
+				results[i] = Result{ips[i], result, err}
+			}
+		}()
+	}
+	for i := range ips {
+		if ctx.Err() == nil {
+			select {
+			case indexes <- i:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		results[i] = Result{Input: ips[i], Err: ctx.Err()}
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Input, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// load fetches GitHub's meta document and reads the further sources, if not
+// already loaded
+func (c *IPChecker) load() error {
+	if _, err := c.Meta(); err != nil {
+		return err
+	}
+	for _, source := range c.sources {
+		if source.areas == nil {
+			if err := c.loadSource(source); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
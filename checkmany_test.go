@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIPChecker_CheckMany(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	ips := []string{"192.30.252.1", "not-an-ip", "140.82.112.1", "8.8.8.8", "10.0.0.1"}
+	for range 50 {
+		ips = append(ips, "140.82.113.1")
+	}
+	results, err := NewIPChecker().CheckMany(context.Background(), ips)
+	if err == nil || errorCategory(err) != errorCategoryInput {
+		t.Errorf("CheckMany() error = %v, want an %s error", err, errorCategoryInput)
+	}
+	if err != nil && (!strings.Contains(err.Error(), "not-an-ip: ") || !strings.Contains(err.Error(), "10.0.0.1: ")) {
+		t.Errorf("CheckMany() error = %q, want the errors of not-an-ip and 10.0.0.1", err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("CheckMany() fetched the meta document %d times, want 1", n)
+	}
+
+	if len(results) != len(ips) {
+		t.Fatalf("CheckMany() returned %d results, want %d", len(results), len(ips))
+	}
+	for i, r := range results {
+		if r.Input != ips[i] {
+			t.Errorf("result %d is of %s, want %s", i, r.Input, ips[i])
+		}
+	}
+	for i, want := range []string{"hooks", "", "web", "", ""} {
+		r := results[i]
+		if failed := i == 1 || i == 4; failed != (r.Err != nil) || failed != (r.Result == nil) {
+			t.Errorf("result of %s = %+v, %v", r.Input, r.Result, r.Err)
+			continue
+		}
+		if r.Result != nil && r.Result.AreaKey != want {
+			t.Errorf("result of %s is in %q, want %q", r.Input, r.Result.AreaKey, want)
+		}
+	}
+}
+
+func TestIPChecker_CheckManyCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	checker := NewIPChecker()
+	if _, err := checker.Meta(); err != nil {
+		t.Fatal(err)
+	}
+	results, err := checker.CheckMany(ctx, []string{"192.30.252.1", "192.30.252.2"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("CheckMany() of a canceled context error = %v, want %v", err, context.Canceled)
+	}
+	if len(results) != 2 {
+		t.Errorf("CheckMany() returned %d results, want 2", len(results))
+	}
+
+	// A batch fails as a whole if the ranges can't be fetched
+	githubMetaURL = "http://127.0.0.1:0"
+	if results, err := NewIPChecker().CheckMany(context.Background(), []string{"192.30.252.1"}); results != nil || err == nil {
+		t.Errorf("CheckMany() without ranges = %v, %v, want an error", results, err)
+	}
+}
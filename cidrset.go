@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// addrRange is an inclusive range of addresses of a single family
+type addrRange struct {
+	first, last netip.Addr
+}
+
+// prefixRange returns the addresses of a prefix
+func prefixRange(p netip.Prefix) addrRange {
+	p = p.Masked()
+	first := p.Addr()
+	bytes := first.AsSlice()
+	hostBits := first.BitLen() - p.Bits()
+	for i := len(bytes) - 1; hostBits > 0; i-- {
+		if hostBits >= 8 {
+			bytes[i] = 0xff
+		} else {
+			bytes[i] |= byte(1<<hostBits - 1)
+		}
+		hostBits -= 8
+	}
+	last, _ := netip.AddrFromSlice(bytes)
+	return addrRange{first, last}
+}
+
+// parsePrefix parses a CIDR or a single address, which is treated as a /32 or
+// /128 prefix
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", s)
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", s)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// parsePrefixes parses CIDRs, skipping invalid ones
+func parsePrefixes(cidrs []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, cidr := range cidrs {
+		if p, err := parsePrefix(cidr); err == nil {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// size returns the number of addresses in the range
+func (r addrRange) size() *big.Int {
+	first := new(big.Int).SetBytes(r.first.AsSlice())
+	last := new(big.Int).SetBytes(r.last.AsSlice())
+	return last.Sub(last, first).Add(last, big.NewInt(1))
+}
+
+// rangeSet is a set of addresses, held as sorted ranges that neither overlap nor
+// touch
+type rangeSet []addrRange
+
+// newRangeSet returns the union of ranges
+func newRangeSet(ranges []addrRange) rangeSet {
+	sorted := append([]addrRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].first.Compare(sorted[j].first); c != 0 {
+			return c < 0
+		}
+		return sorted[i].last.Compare(sorted[j].last) > 0
+	})
+
+	var set rangeSet
+	for _, r := range sorted {
+		if n := len(set); n > 0 && set[n-1].last.Is4() == r.first.Is4() {
+			prev := &set[n-1]
+			// Merge overlapping ranges and ranges starting right after prev
+			if next := prev.last.Next(); !next.IsValid() || r.first.Compare(next) <= 0 {
+				if r.last.Compare(prev.last) > 0 {
+					prev.last = r.last
+				}
+				continue
+			}
+		}
+		set = append(set, r)
+	}
+	return set
+}
+
+// newPrefixSet returns the union of prefixes
+func newPrefixSet(prefixes []netip.Prefix) rangeSet {
+	ranges := make([]addrRange, len(prefixes))
+	for i, p := range prefixes {
+		ranges[i] = prefixRange(p)
+	}
+	return newRangeSet(ranges)
+}
+
+// family returns the IPv4 or IPv6 ranges of the set
+func (s rangeSet) family(ipv4 bool) rangeSet {
+	var ranges rangeSet
+	for _, r := range s {
+		if r.first.Is4() == ipv4 {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// size returns the number of addresses in the set
+func (s rangeSet) size() *big.Int {
+	total := new(big.Int)
+	for _, r := range s {
+		total.Add(total, r.size())
+	}
+	return total
+}
+
+// intersectionSize returns the number of addresses in both sets
+func (s rangeSet) intersectionSize(o rangeSet) *big.Int {
+	total := s.size()
+	for _, r := range s {
+		for _, gap := range o.subtract(r) {
+			total.Sub(total, gap.size())
+		}
+	}
+	return total
+}
+
+// percentOf returns part as a percentage of whole, rounded down to a tenth so
+// that a nearly complete share doesn't read as 100%
+func percentOf(part, whole *big.Int) float64 {
+	if whole.Sign() == 0 {
+		return 0
+	}
+	share, _ := new(big.Rat).SetFrac(part, whole).Float64()
+	return math.Floor(share*1000) / 10
+}
+
+// overlaps reports whether any address of r is in the set
+func (s rangeSet) overlaps(r addrRange) bool {
+	for _, x := range s {
+		if x.first.Is4() == r.first.Is4() && x.first.Compare(r.last) <= 0 && r.first.Compare(x.last) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// subtract returns the parts of r that are not in the set, in address order
+func (s rangeSet) subtract(r addrRange) []addrRange {
+	var gaps []addrRange
+	cursor := r.first
+	for _, x := range s {
+		if x.first.Is4() != r.first.Is4() || x.last.Compare(cursor) < 0 {
+			continue
+		}
+		if x.first.Compare(r.last) > 0 {
+			break
+		}
+		if x.first.Compare(cursor) > 0 {
+			gaps = append(gaps, addrRange{cursor, x.first.Prev()})
+		}
+		if x.last.Compare(r.last) >= 0 {
+			return gaps
+		}
+		cursor = x.last.Next()
+	}
+	return append(gaps, addrRange{cursor, r.last})
+}
+
+// prefixes returns the smallest set of prefixes covering exactly the set
+func (s rangeSet) prefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, r := range s {
+		prefixes = append(prefixes, rangePrefixes(r)...)
+	}
+	return prefixes
+}
+
+// rangePrefixes returns the smallest set of prefixes covering exactly r, by
+// repeatedly taking the largest prefix that starts at the first remaining
+// address and ends within r
+func rangePrefixes(r addrRange) []netip.Prefix {
+	var prefixes []netip.Prefix
+	first := r.first
+	for first.IsValid() && first.Compare(r.last) <= 0 {
+		for bits := 0; bits <= first.BitLen(); bits++ {
+			p := netip.PrefixFrom(first, bits)
+			if p.Masked().Addr() != first {
+				continue
+			}
+			pr := prefixRange(p)
+			if pr.last.Compare(r.last) > 0 {
+				continue
+			}
+			prefixes = append(prefixes, p)
+			// Next is invalid past the last address of the family, ending the loop
+			first = pr.last.Next()
+			break
+		}
+	}
+	return prefixes
+}
+
+// aggregateCIDRs returns the smallest set of CIDRs covering the same addresses as
+// cidrs, in address order with IPv4 first. Invalid CIDRs are skipped.
+func aggregateCIDRs(cidrs []string) []string {
+	var aggregated []string
+	for _, p := range newPrefixSet(parsePrefixes(cidrs)).prefixes() {
+		aggregated = append(aggregated, p.String())
+	}
+	return aggregated
+}
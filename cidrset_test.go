@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+)
+
+// testRanges formats ranges as "first-last" strings
+func testRanges(ranges []addrRange) string {
+	var s []string
+	for _, r := range ranges {
+		s = append(s, r.first.String()+"-"+r.last.String())
+	}
+	return fmt.Sprint(s)
+}
+
+func TestPrefixRange(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"192.30.252.0/22", "[192.30.252.0-192.30.255.255]"},
+		{"192.30.252.7/22", "[192.30.252.0-192.30.255.255]"},
+		{"20.201.28.151/32", "[20.201.28.151-20.201.28.151]"},
+		{"0.0.0.0/0", "[0.0.0.0-255.255.255.255]"},
+		{"2a0a:a440::/29", "[2a0a:a440::-2a0a:a447:ffff:ffff:ffff:ffff:ffff:ffff]"},
+	}
+	for _, tt := range tests {
+		got := testRanges([]addrRange{prefixRange(netip.MustParsePrefix(tt.prefix))})
+		if got != tt.want {
+			t.Errorf("prefixRange(%s) = %s, want %s", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	for input, want := range map[string]string{
+		"192.30.252.0/22": "192.30.252.0/22",
+		"192.30.253.1/22": "192.30.252.0/22",
+		"140.82.112.3":    "140.82.112.3/32",
+		"2a0a:a440::1":    "2a0a:a440::1/128",
+	} {
+		got, err := parsePrefix(input)
+		if err != nil || got.String() != want {
+			t.Errorf("parsePrefix(%q) = %v, %v, want %s", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "192.30.252.0/33", "github.com"} {
+		if _, err := parsePrefix(input); err == nil {
+			t.Errorf("parsePrefix(%q) should fail", input)
+		}
+	}
+}
+
+func TestRangeSet(t *testing.T) {
+	set := newPrefixSet([]netip.Prefix{
+		netip.MustParsePrefix("192.30.254.0/24"),
+		netip.MustParsePrefix("2a0a:a440::/29"),
+		netip.MustParsePrefix("192.30.252.0/24"),
+		netip.MustParsePrefix("192.30.253.0/24"),
+		netip.MustParsePrefix("192.30.252.128/25"),
+		netip.MustParsePrefix("255.255.255.255/32"),
+		netip.MustParsePrefix("255.255.255.0/24"),
+	})
+	want := "[192.30.252.0-192.30.254.255 255.255.255.0-255.255.255.255 2a0a:a440::-2a0a:a447:ffff:ffff:ffff:ffff:ffff:ffff]"
+	if got := testRanges(set); got != want {
+		t.Errorf("newPrefixSet() = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		prefix       string
+		wantOverlaps bool
+		wantGaps     string
+	}{
+		{"192.30.252.0/22", true, "[192.30.255.0-192.30.255.255]"},
+		{"192.30.253.0/24", true, "[]"},
+		{"192.30.248.0/21", true, "[192.30.248.0-192.30.251.255 192.30.255.0-192.30.255.255]"},
+		{"140.82.112.0/20", false, "[140.82.112.0-140.82.127.255]"},
+		{"255.255.255.0/24", true, "[]"},
+		{"2a0a:a440::/32", true, "[]"},
+		{"::ffff:192.30.252.0/120", false, "[::ffff:192.30.252.0-::ffff:192.30.252.255]"},
+	}
+	for _, tt := range tests {
+		r := prefixRange(netip.MustParsePrefix(tt.prefix))
+		if got := set.overlaps(r); got != tt.wantOverlaps {
+			t.Errorf("overlaps(%s) = %t, want %t", tt.prefix, got, tt.wantOverlaps)
+		}
+		if got := testRanges(set.subtract(r)); got != tt.wantGaps {
+			t.Errorf("subtract(%s) = %s, want %s", tt.prefix, got, tt.wantGaps)
+		}
+	}
+}
+
+func TestAddrRangeSize(t *testing.T) {
+	if got := prefixRange(netip.MustParsePrefix("192.30.252.0/22")).size().String(); got != "1024" {
+		t.Errorf("size() = %s, want 1024", got)
+	}
+	if got := prefixRange(netip.MustParsePrefix("::/0")).size().String(); got != "340282366920938463463374607431768211456" {
+		t.Errorf("size() = %s, want 2^128", got)
+	}
+}
+
+func TestAggregateCIDRs(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs []string
+		want  []string
+	}{
+		{
+			name:  "Adjacent ranges",
+			cidrs: []string{"192.30.253.0/24", "192.30.252.0/24", "192.30.254.0/23"},
+			want:  []string{"192.30.252.0/22"},
+		},
+		{
+			name:  "Contained ranges",
+			cidrs: []string{"140.82.112.0/20", "140.82.121.3/32", "140.82.114.0/24"},
+			want:  []string{"140.82.112.0/20"},
+		},
+		{
+			name:  "Unaligned union",
+			cidrs: []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/30", "10.0.0.8/32"},
+			want:  []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/30", "10.0.0.8/32"},
+		},
+		{
+			name:  "Both families",
+			cidrs: []string{"2a0a:a440::/30", "185.199.108.0/22", "2a0a:a444::/30", "invalid"},
+			want:  []string{"185.199.108.0/22", "2a0a:a440::/29"},
+		},
+		{
+			name:  "Whole address space",
+			cidrs: []string{"128.0.0.0/1", "0.0.0.0/1"},
+			want:  []string{"0.0.0.0/0"},
+		},
+		{
+			name:  "Last address",
+			cidrs: []string{"255.255.255.254/32", "255.255.255.255/32"},
+			want:  []string{"255.255.255.254/31"},
+		},
+		{
+			name: "Empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := aggregateCIDRs(tt.cidrs); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("aggregateCIDRs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package client is a Go client of the check API of gh-check-github-ip-ranges
+// serve, as specified by its OpenAPI document at /openapi.json (api/openapi.json
+// in the repository).
+//
+//	c := client.New("http://localhost:8080")
+//	result, err := c.Check(ctx, "192.30.252.1")
+//
+// Errors returned by the server are returned as *Error, so callers can tell
+// invalid input from a server that can't answer.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error categories, as in the command line's JSON error output
+const (
+	CategoryInput    = "invalid_input"
+	CategoryUsage    = "usage"
+	CategoryNetwork  = "network"
+	CategoryAPI      = "api"
+	CategoryInternal = "internal"
+)
+
+// CheckResult is the result of checking an address (schema CheckResult)
+type CheckResult struct {
+	IP       string      `json:"ip"`
+	IsGitHub bool        `json:"is_github"`
+	Area     string      `json:"area,omitempty"`   // Functional area of the most specific range containing the address
+	Range    string      `json:"range,omitempty"`  // Most specific published range containing the address
+	Source   string      `json:"source,omitempty"` // Meta source publishing the range, if several are checked
+	Also     []AlsoMatch `json:"also,omitempty"`   // Other ranges containing the address, most specific first
+	Hint     *Hint       `json:"hint,omitempty"`   // Heuristic match of an address that isn't GitHub-owned
+	Error    string      `json:"error,omitempty"`  // Why an address of a batch couldn't be checked
+}
+
+// AlsoMatch is another range containing a checked address (schema AlsoMatch)
+type AlsoMatch struct {
+	Area   string `json:"area"`
+	Range  string `json:"range"`
+	Source string `json:"source,omitempty"`
+}
+
+// Hint is a heuristic match of an address that isn't GitHub-owned (schema Hint)
+type Hint struct {
+	Heuristic   bool   `json:"heuristic"`
+	Source      string `json:"source"`
+	Description string `json:"description"`
+	Tag         string `json:"tag"`
+	Range       string `json:"range"`
+}
+
+// Health is the state of the server (schema Health)
+type Health struct {
+	Status      string       `json:"status"` // ok, degraded or stale
+	Snapshot    time.Time    `json:"snapshot"`
+	Fetched     time.Time    `json:"fetched"`
+	SourceHash  string       `json:"source_hash"`
+	Breaker     Breaker      `json:"breaker"`
+	ResultCache *ResultCache `json:"result_cache,omitempty"`
+	RateLimit   *RateLimit   `json:"rate_limit,omitempty"`
+}
+
+// Breaker is the state of the server's circuit breaker around meta refreshes
+// (schema Breaker)
+type Breaker struct {
+	State     string     `json:"state"` // closed or open
+	Failures  int        `json:"failures"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	NextProbe *time.Time `json:"next_probe,omitempty"`
+}
+
+// ResultCache is the state of the server's result cache (schema ResultCache)
+type ResultCache struct {
+	Size    int    `json:"size"`
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// RateLimit is the state of the server's per-client rate limit (schema
+// RateLimit)
+type RateLimit struct {
+	Rate    float64 `json:"rate"`
+	Burst   int     `json:"burst"`
+	Clients int     `json:"clients"`
+	Allowed uint64  `json:"allowed"`
+	Limited uint64  `json:"limited"`
+}
+
+// Error is an error response of the server (schema Error)
+type Error struct {
+	StatusCode int
+	Category   string
+	Message    string
+	RetryAfter time.Duration // With status code 429, how long until the server answers again
+}
+
+func (e *Error) Error() string {
+	if e.Category == "" {
+		return fmt.Sprintf("server returned status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s (%s, status code %d)", e.Message, e.Category, e.StatusCode)
+}
+
+// Client calls the check API of a server
+type Client struct {
+	BaseURL    string       // URL the server is reached at, e.g. http://localhost:8080
+	HTTPClient *http.Client // Client making the requests; http.DefaultClient if nil
+}
+
+// New returns a client of the server at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Check checks an address (operation check)
+func (c *Client) Check(ctx context.Context, ip string) (*CheckResult, error) {
+	var result CheckResult
+	if err := c.get(ctx, "/v1/check?ip="+url.QueryEscape(ip), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CheckBatch checks a batch of addresses in one request (operation
+// checkBatch), returning their results in the same order. An address that
+// couldn't be checked has a result with Error set.
+func (c *Client) CheckBatch(ctx context.Context, ips []string) ([]CheckResult, error) {
+	body, err := json.Marshal(ips)
+	if err != nil {
+		return nil, err
+	}
+	var results []CheckResult
+	if err := c.do(ctx, http.MethodPost, "/v1/check", body, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Health reports the snapshot being served (operation health). A server whose
+// ranges are too stale to answer checks returns its health with an *Error.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var health Health
+	err := c.get(ctx, "/healthz", &health)
+	if e, ok := err.(*Error); ok && e.StatusCode == http.StatusServiceUnavailable {
+		return &health, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// Meta returns the meta document of a server run with --mirror (operation meta)
+func (c *Client) Meta(ctx context.Context) (json.RawMessage, error) {
+	var meta json.RawMessage
+	if err := c.get(ctx, "/meta", &meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// get decodes the JSON response to a GET of path into out
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	return c.do(ctx, http.MethodGet, path, nil, out)
+}
+
+// do decodes the JSON response to a request of path, with body as JSON if
+// set, into out. Error responses are returned as *Error, having decoded out if
+// the body holds it.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		var errBody struct {
+			Error struct {
+				Category string `json:"category"`
+				Message  string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errBody) == nil {
+			apiErr.Category, apiErr.Message = errBody.Error.Category, errBody.Error.Message
+		}
+		json.Unmarshal(respBody, out)
+		return apiErr
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/check":
+			if r.Method == http.MethodPost {
+				var ips []string
+				if json.NewDecoder(r.Body).Decode(&ips); r.Header.Get("Content-Type") != "application/json" || len(ips) != 2 {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error":{"category":"invalid_input","message":"invalid batch"}}`))
+					return
+				}
+				w.Write([]byte(`[{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22"},{"ip":"10.0.0.1","is_github":false,"error":"private"}]`))
+				return
+			}
+			if ip := r.URL.Query().Get("ip"); ip == "192.30.252.2" {
+				w.Header().Set("Retry-After", "3")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":{"category":"usage","message":"too many requests"}}`))
+				return
+			} else if ip != "192.30.252.1" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":{"category":"invalid_input","message":"invalid IP address format"}}`))
+				return
+			}
+			w.Write([]byte(`{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22","also":[{"area":"Web","range":"192.30.252.0/22"}]}`))
+		case "/healthz":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"stale","snapshot":"2025-06-02T00:00:00Z","fetched":"2025-06-02T01:00:00Z","source_hash":"abc","breaker":{"state":"open","failures":4}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := New(server.URL + "/")
+
+	result, err := c.Check(ctx, "192.30.252.1")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	want := &CheckResult{IP: "192.30.252.1", IsGitHub: true, Area: "Hooks", Range: "192.30.252.0/22",
+		Also: []AlsoMatch{{Area: "Web", Range: "192.30.252.0/22"}}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Check() = %+v, want %+v", result, want)
+	}
+
+	results, err := c.CheckBatch(ctx, []string{"192.30.252.1", "10.0.0.1"})
+	wantBatch := []CheckResult{{IP: "192.30.252.1", IsGitHub: true, Area: "Hooks", Range: "192.30.252.0/22"}, {IP: "10.0.0.1", Error: "private"}}
+	if err != nil || !reflect.DeepEqual(results, wantBatch) {
+		t.Errorf("CheckBatch() = %+v, %v, want %+v", results, err, wantBatch)
+	}
+
+	_, err = c.Check(ctx, "not an ip")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Category != CategoryInput {
+		t.Errorf("Check() of invalid input error = %v, want an %s *Error", err, CategoryInput)
+	}
+
+	_, err = c.Check(ctx, "192.30.252.2")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != 3*time.Second {
+		t.Errorf("Check() of a rate limited client error = %+v, want status code 429 retrying after 3s", err)
+	}
+
+	health, err := c.Health(ctx)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Health() of a stale server error = %v, want status code 503", err)
+	}
+	if health == nil || health.Status != "stale" || health.Breaker.State != "open" || health.Breaker.Failures != 4 {
+		t.Errorf("Health() of a stale server = %+v, want its health", health)
+	}
+
+	if _, err := c.Meta(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Meta() of a server without --mirror error = %v, want status code 404", err)
+	}
+}
+
+// jsonFields returns the JSON field names of a struct type
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestClientMatchesSpec(t *testing.T) {
+	data, err := os.ReadFile("../api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	types := map[string]reflect.Type{
+		"CheckResult": reflect.TypeFor[CheckResult](),
+		"AlsoMatch":   reflect.TypeFor[AlsoMatch](),
+		"Hint":        reflect.TypeFor[Hint](),
+		"Health":      reflect.TypeFor[Health](),
+		"Breaker":     reflect.TypeFor[Breaker](),
+		"ResultCache": reflect.TypeFor[ResultCache](),
+		"RateLimit":   reflect.TypeFor[RateLimit](),
+	}
+	for name, typ := range types {
+		var want []string
+		for property := range spec.Components.Schemas[name].Properties {
+			want = append(want, property)
+		}
+		sort.Strings(want)
+		if got := jsonFields(typ); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s fields = %v, want the properties of schema %s %v", typ, got, name, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corsPolicy lets the browser pages of the allowed origins call the API. Its
+// methods let no origin in on a nil policy.
+type corsPolicy struct {
+	origins []string // Exact origins, https://*.example.com patterns, or *
+	headers []string // Request headers allowed besides the CORS-safelisted ones
+	maxAge  time.Duration
+}
+
+// corsExposedHeaders are the response headers scripts may read besides the
+// CORS-safelisted ones
+const corsExposedHeaders = "ETag, Retry-After"
+
+// newCORSPolicy validates the --cors-origin patterns, returning nil if there
+// are none
+func newCORSPolicy(origins, headers []string, maxAge time.Duration) (*corsPolicy, error) {
+	if len(origins) == 0 {
+		if len(headers) > 0 {
+			return nil, withCategory(errorCategoryUsage, fmt.Errorf("--cors-header requires --cors-origin"))
+		}
+		return nil, nil
+	}
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return nil, withCategory(errorCategoryUsage, fmt.Errorf("invalid --cors-origin %q: want an origin such as https://dashboard.example.com", origin))
+		}
+		if host := u.Hostname(); strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+			return nil, withCategory(errorCategoryUsage, fmt.Errorf("invalid --cors-origin %q: * is only allowed as the first label", origin))
+		}
+	}
+	if maxAge < 0 {
+		return nil, withCategory(errorCategoryUsage, fmt.Errorf("--cors-max-age must not be negative"))
+	}
+	return &corsPolicy{origins: origins, headers: headers, maxAge: maxAge}, nil
+}
+
+// allowed reports whether the policy lets origin in
+func (p *corsPolicy) allowed(origin string) bool {
+	if p == nil || origin == "" {
+		return false
+	}
+	for _, pattern := range p.origins {
+		if pattern == "*" || strings.EqualFold(pattern, origin) {
+			return true
+		}
+		// https://*.example.com matches the subdomains of example.com, not itself
+		scheme, host, ok := strings.Cut(pattern, "://*.")
+		if ok && len(origin) > len(scheme)+len("://")+len(host) &&
+			strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(host)) {
+			return true
+		}
+	}
+	return false
+}
+
+// handler adds the CORS headers to the responses to allowed origins, and
+// answers their preflight requests, before passing requests to h
+func (p *corsPolicy) handler(h http.Handler) http.Handler {
+	if p == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if !p.allowed(origin) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if slices.Contains(p.origins, "*") {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// Batches are posted as JSON, which isn't a safelisted content type
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(append([]string{"Content-Type"}, p.headers...), ", "))
+		if p.maxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.maxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
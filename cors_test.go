@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewCORSPolicy(t *testing.T) {
+	for _, origins := range [][]string{
+		{"dashboard.example.com"},
+		{"https://dashboard.example.com/"},
+		{"ftp://dashboard.example.com"},
+		{"https://dash*.example.com"},
+		{"https://a.*.example.com"},
+	} {
+		if _, err := newCORSPolicy(origins, nil, 0); err == nil || errorCategory(err) != errorCategoryUsage {
+			t.Errorf("newCORSPolicy(%q) error = %v, want a usage error", origins, err)
+		}
+	}
+	if _, err := newCORSPolicy(nil, []string{"Authorization"}, 0); err == nil {
+		t.Error("newCORSPolicy() of headers without origins error = nil")
+	}
+	if p, err := newCORSPolicy(nil, nil, 0); p != nil || err != nil {
+		t.Errorf("newCORSPolicy() without origins = %v, %v, want nil", p, err)
+	}
+}
+
+func TestCORSPolicy_Allowed(t *testing.T) {
+	p, err := newCORSPolicy([]string{"https://dashboard.example.com", "https://*.internal.example", "http://localhost:3000"}, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://dashboard.example.com", true},
+		{"https://DASHBOARD.example.com", true},
+		{"http://dashboard.example.com", false},
+		{"https://evil.example.com", false},
+		{"https://grafana.internal.example", true},
+		{"https://a.b.internal.example", true},
+		{"https://internal.example", false},
+		{"https://evilinternal.example", false},
+		{"http://grafana.internal.example", false},
+		{"http://localhost:3000", true},
+		{"http://localhost:3001", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := p.allowed(tt.origin); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestServer_CORS(t *testing.T) {
+	s := newTestServer(t, false, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	s.cors, _ = newCORSPolicy([]string{"https://dashboard.example.com"}, []string{"Authorization"}, 10*time.Minute)
+	s.debug = true
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	do := func(method, path, origin string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+			req.Header.Set("Access-Control-Request-Headers", "authorization")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := do(http.MethodOptions, "/v1/check?ip=192.30.252.1", "https://dashboard.example.com")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "https://dashboard.example.com" ||
+		resp.Header.Get("Access-Control-Allow-Methods") != "GET, POST" || resp.Header.Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" ||
+		resp.Header.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("preflight = %d %v, want 204 allowing the origin", resp.StatusCode, resp.Header)
+	}
+
+	resp = do(http.MethodGet, "/v1/check?ip=192.30.252.1", "https://dashboard.example.com")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Access-Control-Allow-Origin") != "https://dashboard.example.com" ||
+		resp.Header.Get("Access-Control-Expose-Headers") != corsExposedHeaders || resp.Header.Get("Vary") != "Origin" {
+		t.Errorf("GET from an allowed origin = %d %v, want the CORS headers", resp.StatusCode, resp.Header)
+	}
+
+	for _, origin := range []string{"https://evil.example.com", ""} {
+		resp = do(http.MethodGet, "/v1/check?ip=192.30.252.1", origin)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("GET from origin %q = %d %v, want no CORS headers", origin, resp.StatusCode, resp.Header)
+		}
+	}
+	if resp = do(http.MethodOptions, "/v1/check", "https://evil.example.com"); resp.StatusCode == http.StatusNoContent {
+		t.Errorf("preflight from a disallowed origin status = %d", resp.StatusCode)
+	}
+
+	// Not for the debug endpoints
+	if resp = do(http.MethodGet, "/debug/vars", "https://dashboard.example.com"); resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("GET /debug/vars from an allowed origin = %v, want no CORS headers", resp.Header)
+	}
+
+	s.cors, _ = newCORSPolicy([]string{"*"}, nil, 0)
+	ts2 := httptest.NewServer(s.handler())
+	defer ts2.Close()
+	req, _ := http.NewRequest(http.MethodGet, ts2.URL+"/healthz", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("GET with --cors-origin * = %v, want Access-Control-Allow-Origin *", resp.Header)
+	}
+}
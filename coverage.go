@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// areaCoverage is the share of a functional area's addresses that the rules allow
+type areaCoverage struct {
+	Key       string   `json:"key"`
+	Name      string   `json:"name"`
+	IPv4      *float64 `json:"ipv4_percent"` // Null if the area has no IPv4 ranges
+	IPv6      *float64 `json:"ipv6_percent"` // Null if the area has no IPv6 ranges
+	Uncovered []string `json:"uncovered"`    // Smallest set of prefixes the rules don't allow
+}
+
+// complete reports whether the rules allow all of the area's addresses
+func (c areaCoverage) complete() bool {
+	return len(c.Uncovered) == 0
+}
+
+// newCoverageCommand creates the coverage subcommand
+func newCoverageCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "coverage",
+		Short: "Report how much of each area a file of CIDRs allows",
+		Long: `Compute what share of each functional area's published addresses a file of
+CIDRs, such as a firewall's egress rules, allows, separately for IPv4 and IPv6,
+and list the prefixes of each area that remain uncovered. The output can be
+attached to a change review as evidence of what the rules reach.
+
+The file has CIDRs or single addresses separated by newlines, commas or spaces.
+Blank lines and text after "#" are ignored. Exits with status 1 if any selected
+area is not fully covered.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runCoverage,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("file", "f", "", "File of CIDRs to evaluate (\"-\" for stdin)")
+	cmd.Flags().StringSliceP("area", "a", nil, "Functional areas to report, e.g. hooks,git (default all)")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func runCoverage(cmd *cobra.Command, args []string) error {
+	path, _ := cmd.Flags().GetString("file")
+	areaNames, _ := cmd.Flags().GetStringSlice("area")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	entries, err := readAllowlist(path)
+	if err != nil {
+		return err
+	}
+
+	meta, err := NewIPChecker().Meta()
+	if err != nil {
+		return err
+	}
+	areas, err := selectAreas(meta.Areas(), areaNames)
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+
+	report := computeCoverage(entries, areas)
+
+	if output == outputJSON {
+		out, err := marshalExportJSON(report)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(out)
+	} else {
+		writeCoverage(os.Stdout, report)
+	}
+
+	incomplete := 0
+	for _, c := range report {
+		if !c.complete() {
+			incomplete++
+		}
+	}
+	if incomplete > 0 {
+		return notGitHubError(fmt.Sprintf("the rules don't fully cover %d of the %d areas", incomplete, len(report)))
+	}
+	return nil
+}
+
+// computeCoverage computes the coverage of each area by the allowed entries
+func computeCoverage(entries []allowlistEntry, areas []Area) []areaCoverage {
+	prefixes := make([]netip.Prefix, len(entries))
+	for i, entry := range entries {
+		prefixes[i] = entry.Prefix
+	}
+	allowed := newPrefixSet(prefixes)
+
+	report := []areaCoverage{}
+	for _, area := range areas {
+		set := newPrefixSet(parsePrefixes(area.Ranges))
+		c := areaCoverage{Key: area.Key, Name: area.Name, Uncovered: []string{}}
+		c.IPv4 = familyCoverage(set.family(true), allowed)
+		c.IPv6 = familyCoverage(set.family(false), allowed)
+		for _, r := range set {
+			for _, gap := range allowed.subtract(r) {
+				for _, p := range rangePrefixes(gap) {
+					c.Uncovered = append(c.Uncovered, p.String())
+				}
+			}
+		}
+		report = append(report, c)
+	}
+	return report
+}
+
+// familyCoverage returns the percentage of the addresses of set that are
+// allowed, or nil if the set is empty
+func familyCoverage(set, allowed rangeSet) *float64 {
+	size := set.size()
+	if size.Sign() == 0 {
+		return nil
+	}
+	percent := percentOf(set.intersectionSize(allowed), size)
+	return &percent
+}
+
+// writeCoverage writes the coverage of each area as a table, followed by the
+// uncovered prefixes of each area
+func writeCoverage(w io.Writer, report []areaCoverage) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Area\tIPv4 covered\tIPv6 covered\tUncovered prefixes")
+	for _, c := range report {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", c.Name, formatCoverage(c.IPv4), formatCoverage(c.IPv6), len(c.Uncovered))
+	}
+	tw.Flush()
+
+	for _, c := range report {
+		if c.complete() {
+			continue
+		}
+		fmt.Fprintf(w, "\nUncovered %s prefixes:\n  %s\n", c.Name, strings.Join(c.Uncovered, "\n  "))
+	}
+}
+
+// formatCoverage formats a coverage percentage, or "-" for a family without ranges
+func formatCoverage(percent *float64) string {
+	if percent == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", *percent)
+}
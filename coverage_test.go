@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestComputeCoverage(t *testing.T) {
+	entries := []allowlistEntry{
+		{1, netip.MustParsePrefix("192.30.252.0/23")},
+		{2, netip.MustParsePrefix("140.82.112.0/20")},
+		{3, netip.MustParsePrefix("2a0a:a440::/29")},
+	}
+	areas := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22", "2a0a:a440::/29"}},
+		{Key: "git", Name: "Git", Ranges: []string{"140.82.112.0/20"}},
+		{Key: "pages", Name: "Pages", Ranges: []string{"185.199.108.0/22"}},
+	}
+
+	report := computeCoverage(entries, areas)
+	full, half, none := 100.0, 50.0, 0.0
+	want := []areaCoverage{
+		{Key: "hooks", Name: "Hooks", IPv4: &half, IPv6: &full, Uncovered: []string{"192.30.254.0/23"}},
+		{Key: "git", Name: "Git", IPv4: &full, Uncovered: []string{}},
+		{Key: "pages", Name: "Pages", IPv4: &none, Uncovered: []string{"185.199.108.0/22"}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("computeCoverage() = %+v, want %+v", report, want)
+	}
+
+	var buf bytes.Buffer
+	writeCoverage(&buf, report)
+	wantText := `Area   IPv4 covered  IPv6 covered  Uncovered prefixes
+Hooks  50.0%         100.0%        1
+Git    100.0%        -             0
+Pages  0.0%          -             1
+
+Uncovered Hooks prefixes:
+  192.30.254.0/23
+
+Uncovered Pages prefixes:
+  185.199.108.0/22
+`
+	if buf.String() != wantText {
+		t.Errorf("writeCoverage() = %q, want %q", buf.String(), wantText)
+	}
+}
+
+func TestRunCoverage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	if err := os.WriteFile(path, []byte("192.30.252.0/22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		file          string
+		areas         []string
+		output        string
+		wantErr       bool
+		wantNotGitHub bool
+	}{
+		{name: "Covered area", file: path, areas: []string{"hooks"}, output: outputText},
+		{name: "Uncovered area", file: path, output: outputJSON, wantErr: true, wantNotGitHub: true},
+		{name: "Missing file", file: filepath.Join(t.TempDir(), "missing.txt"), output: outputText, wantErr: true},
+		{name: "Unknown area", file: path, areas: []string{"nope"}, output: outputText, wantErr: true},
+		{name: "Unsupported output", file: path, output: "csv", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().String("file", tt.file, "")
+			cmd.Flags().StringSlice("area", tt.areas, "")
+			cmd.Flags().StringP("output", "o", tt.output, "")
+
+			err := runCoverage(cmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runCoverage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(notGitHubError); ok != tt.wantNotGitHub {
+				t.Errorf("runCoverage() error = %v, want notGitHubError %v", err, tt.wantNotGitHub)
+			}
+		})
+	}
+}
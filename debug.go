@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// debugMetricsName is the expvar map serve --debug publishes the checks and
+// fetches of its checkers under
+const debugMetricsName = "checker"
+
+// debugHandler serves expvar's /debug/vars and pprof's /debug/pprof/, requiring
+// token as a bearer token if set
+func debugHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	if token == "" {
+		return mux
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="debug"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// publishServeVars publishes the server's checker metrics and the state of
+// its circuit breaker, result cache and rate limiter with expvar. It can only be called
+// once per process.
+func publishServeVars(s *server) {
+	s.metrics = NewExpvarMetrics(debugMetricsName)
+	expvar.Publish("breaker", expvar.Func(func() any { return s.breaker.stats() }))
+	expvar.Publish("result_cache", expvar.Func(func() any { return s.results.stats() }))
+	expvar.Publish("rate_limit", expvar.Func(func() any { return s.limiter.stats() }))
+}
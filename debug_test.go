@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_Debug(t *testing.T) {
+	s := newTestServer(t, false, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	plain := httptest.NewServer(s.handler())
+	defer plain.Close()
+	if resp, err := http.Get(plain.URL + "/debug/vars"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /debug/vars without --debug = %v, %v, want 404", resp.StatusCode, err)
+	}
+
+	s.debug, s.debugToken = true, "s3cret"
+	publishServeVars(s)
+	s.current().SetMetrics(s.metrics)
+	s.current().CheckIP("192.30.252.1")
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	get := func(path, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		resp := get("/debug/vars", token)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("GET /debug/vars with token %q status = %d, want 401", token, resp.StatusCode)
+		}
+	}
+
+	resp := get("/debug/vars", "s3cret")
+	defer resp.Body.Close()
+	var vars struct {
+		Checker struct {
+			Checks  int            `json:"checks"`
+			Matches map[string]int `json:"matches"`
+		} `json:"checker"`
+		Breaker     breakerJSON      `json:"breaker"`
+		ResultCache *resultCacheJSON `json:"result_cache"`
+		Cmdline     []string         `json:"cmdline"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatalf("GET /debug/vars isn't JSON: %v", err)
+	}
+	if vars.Checker.Checks != 1 || vars.Checker.Matches["hooks"] != 1 || vars.Breaker.State != breakerClosed || vars.ResultCache == nil || len(vars.Cmdline) == 0 {
+		t.Errorf("GET /debug/vars = %+v", vars)
+	}
+
+	if resp := get("/debug/pprof/", "s3cret"); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /debug/pprof/ status = %d, want 200", resp.StatusCode)
+	}
+	if resp := get("/debug/pprof/goroutine?debug=1", "s3cret"); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /debug/pprof/goroutine status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestServeCommand_DebugTokenHelp(t *testing.T) {
+	t.Setenv("GH_IP_DEBUG_TOKEN", "s3cret")
+	if usage := newServeCommand().UsageString(); strings.Contains(usage, "s3cret") {
+		t.Errorf("serve --help shows $GH_IP_DEBUG_TOKEN:\n%s", usage)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorTarget is an endpoint probed by the doctor subcommand
+type doctorTarget struct {
+	Host string
+	Port int
+	Area string // Key of the area whose ranges the endpoint should resolve into
+}
+
+// doctorTargets are representative endpoints of each functional area
+var doctorTargets = []doctorTarget{
+	{"api.github.com", 443, "api"},
+	{"github.com", 443, "web"},
+	{"github.com", 22, "git"},
+	{"ssh.github.com", 443, "git"},
+	{"ghcr.io", 443, "packages"},
+	{"npm.pkg.github.com", 443, "packages"},
+	{"pipelines.actions.githubusercontent.com", 443, "actions"},
+	{"results-receiver.actions.githubusercontent.com", 443, "actions"},
+	{"github.github.io", 443, "pages"},
+}
+
+// For testing purposes
+var (
+	lookupIP = func(host string) ([]netip.Addr, error) {
+		addrs, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip", host)
+		for i := range addrs {
+			addrs[i] = addrs[i].Unmap()
+		}
+		return addrs, err
+	}
+	dialTCP = func(address string, timeout time.Duration) error {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+)
+
+// doctorResult is the outcome of probing a target
+type doctorResult struct {
+	Endpoint  string          `json:"endpoint"`
+	Area      string          `json:"area"`
+	Reachable bool            `json:"reachable"`
+	Error     string          `json:"error,omitempty"`
+	Addresses []doctorAddress `json:"addresses"`
+}
+
+// doctorAddress is a resolved IPv4 address of a target and where it belongs
+type doctorAddress struct {
+	IP       string `json:"ip"`
+	IsGitHub bool   `json:"is_github"`
+	Area     string `json:"area,omitempty"` // Area containing the address, preferring the target's area
+}
+
+// newDoctorCommand creates the doctor subcommand
+func newDoctorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Test connectivity to GitHub services",
+		Long: `Probe a representative endpoint of each functional area, such as
+api.github.com:443, github.com:22 and the Actions and Packages hosts. For each
+endpoint, report whether it is reachable over TCP, the IPv4 addresses it
+resolves to and whether those addresses fall in GitHub's published ranges.
+
+Exits with status 1 if an endpoint resolves outside the ranges, which points
+at a proxy or DNS override, and with status 2 if an endpoint is unreachable.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runDoctor,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+	cmd.Flags().Duration("timeout", 5*time.Second, "Timeout for resolving and connecting to each endpoint")
+
+	return cmd
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	checker := NewIPChecker()
+	meta, err := checker.Meta()
+	if err != nil {
+		return err
+	}
+	results := probeTargets(checker, meta, doctorTargets, timeout)
+
+	switch output {
+	case outputJSON:
+		out, err := marshalExportJSON(results)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(out)
+	default:
+		writeDoctor(os.Stdout, meta, results)
+	}
+
+	unreachable, outside := 0, 0
+	for _, r := range results {
+		if !r.Reachable {
+			unreachable++
+		}
+		for _, addr := range r.Addresses {
+			if !addr.IsGitHub {
+				outside++
+			}
+		}
+	}
+	switch {
+	case unreachable > 0:
+		return withCategory(errorCategoryNetwork, fmt.Errorf("%d of %d endpoints are unreachable", unreachable, len(results)))
+	case outside > 0:
+		return notGitHubError(fmt.Sprintf("%d resolved addresses are outside GitHub's published ranges", outside))
+	}
+	return nil
+}
+
+// probeTargets resolves and connects to the targets in parallel, returning the
+// results in target order
+func probeTargets(checker *IPChecker, meta *GitHubMeta, targets []doctorTarget, timeout time.Duration) []doctorResult {
+	areas := make(map[string]Area)
+	for _, area := range meta.Areas() {
+		areas[area.Key] = area
+	}
+
+	results := make([]doctorResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeTarget(checker, areas[target.Area], target, timeout)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// probeTarget resolves target and connects to its first address
+func probeTarget(checker *IPChecker, area Area, target doctorTarget, timeout time.Duration) doctorResult {
+	result := doctorResult{
+		Endpoint:  net.JoinHostPort(target.Host, strconv.Itoa(target.Port)),
+		Area:      target.Area,
+		Addresses: []doctorAddress{},
+	}
+
+	ips, err := lookupIP(target.Host)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var first string
+	for _, ip := range ips {
+		if !ip.Is4() {
+			continue
+		}
+		if first == "" {
+			first = ip.String()
+		}
+		result.Addresses = append(result.Addresses, classifyAddress(checker, area, ip))
+	}
+	if first == "" {
+		result.Error = "no IPv4 addresses"
+		return result
+	}
+
+	if err := dialTCP(net.JoinHostPort(first, strconv.Itoa(target.Port)), timeout); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Reachable = true
+	return result
+}
+
+// classifyAddress reports whether ip is in the ranges of area, or otherwise which
+// area, if any, it belongs to
+func classifyAddress(checker *IPChecker, area Area, ip netip.Addr) doctorAddress {
+	addr := doctorAddress{IP: ip.String()}
+	matches, _ := checker.CheckAll(addr.IP)
+	for _, m := range matches {
+		if m.AreaKey == area.Key {
+			addr.IsGitHub, addr.Area = true, area.Key
+			return addr
+		}
+	}
+	if len(matches) > 0 {
+		addr.IsGitHub, addr.Area = true, matches[0].AreaKey
+	}
+	return addr
+}
+
+// writeDoctor writes the probe results as a table
+func writeDoctor(w io.Writer, meta *GitHubMeta, results []doctorResult) {
+	names := make(map[string]string)
+	for _, area := range meta.Areas() {
+		names[area.Key] = area.Name
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Endpoint\tArea\tReachable\tAddresses")
+	for _, r := range results {
+		reachable := "yes"
+		if !r.Reachable {
+			reachable = "no: " + r.Error
+		}
+
+		var addrs []string
+		for _, addr := range r.Addresses {
+			switch {
+			case !addr.IsGitHub:
+				addrs = append(addrs, addr.IP+" (not GitHub-owned)")
+			case addr.Area != r.Area:
+				addrs = append(addrs, addr.IP+" ("+names[addr.Area]+")")
+			default:
+				addrs = append(addrs, addr.IP)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Endpoint, names[r.Area], reachable, strings.Join(addrs, ", "))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// fakeDoctorNetwork replaces name resolution and dialing, resolving hosts to
+// addrs and failing to connect to the addresses in unreachable
+func fakeDoctorNetwork(t *testing.T, addrs map[string][]string, unreachable ...string) {
+	t.Helper()
+	oldLookup, oldDial := lookupIP, dialTCP
+	t.Cleanup(func() { lookupIP, dialTCP = oldLookup, oldDial })
+
+	lookupIP = func(host string) ([]netip.Addr, error) {
+		if _, ok := addrs[host]; !ok {
+			return nil, fmt.Errorf("lookup %s: no such host", host)
+		}
+		var ips []netip.Addr
+		for _, addr := range addrs[host] {
+			ips = append(ips, netip.MustParseAddr(addr))
+		}
+		return ips, nil
+	}
+	dialTCP = func(address string, timeout time.Duration) error {
+		for _, u := range unreachable {
+			if address == u {
+				return errors.New("connection refused")
+			}
+		}
+		return nil
+	}
+}
+
+func TestProbeTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"api": ["192.30.252.0/22"], "git": ["140.82.112.0/20"], "web": ["140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	fakeDoctorNetwork(t, map[string][]string{
+		"api.example":   {"2606:50c0::1", "192.30.252.1"},
+		"git.example":   {"140.82.112.3"},
+		"proxy.example": {"203.0.113.7"},
+		"v6.example":    {"2606:50c0::1"},
+	}, "140.82.112.3:22")
+
+	checker := NewIPChecker()
+	meta, err := checker.Meta()
+	if err != nil {
+		t.Fatalf("Meta() error = %v", err)
+	}
+	results := probeTargets(checker, meta, []doctorTarget{
+		{"api.example", 443, "api"},
+		{"git.example", 22, "git"},
+		{"git.example", 443, "web"},
+		{"proxy.example", 443, "api"},
+		{"v6.example", 443, "api"},
+		{"missing.example", 443, "api"},
+	}, time.Second)
+
+	tests := []struct {
+		endpoint  string
+		reachable bool
+		err       string
+		addresses []doctorAddress
+	}{
+		{"api.example:443", true, "", []doctorAddress{{"192.30.252.1", true, "api"}}},
+		{"git.example:22", false, "connection refused", []doctorAddress{{"140.82.112.3", true, "git"}}},
+		{"git.example:443", true, "", []doctorAddress{{"140.82.112.3", true, "web"}}},
+		{"proxy.example:443", true, "", []doctorAddress{{"203.0.113.7", false, ""}}},
+		{"v6.example:443", false, "no IPv4 addresses", []doctorAddress{}},
+		{"missing.example:443", false, "no such host", []doctorAddress{}},
+	}
+	for i, tt := range tests {
+		r := results[i]
+		if r.Endpoint != tt.endpoint || r.Reachable != tt.reachable || !strings.Contains(r.Error, tt.err) {
+			t.Errorf("result %d = %+v, want endpoint %s, reachable %t, error %q", i, r, tt.endpoint, tt.reachable, tt.err)
+		}
+		if fmt.Sprint(r.Addresses) != fmt.Sprint(tt.addresses) {
+			t.Errorf("result %d addresses = %v, want %v", i, r.Addresses, tt.addresses)
+		}
+	}
+
+	var buf bytes.Buffer
+	writeDoctor(&buf, meta, results[1:4])
+	for _, want := range []string{
+		"git.example:22 ",
+		"no: connection refused",
+		"203.0.113.7 (not GitHub-owned)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeDoctor() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestClassifyAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "api": ["192.30.252.0/22", "20.201.28.0/24"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	checker := NewIPChecker()
+	meta, err := checker.Meta()
+	if err != nil {
+		t.Fatalf("Meta() error = %v", err)
+	}
+	var api Area
+	for _, area := range meta.Areas() {
+		if area.Key == "api" {
+			api = area
+		}
+	}
+
+	// The target's area is preferred over the first matching area
+	if got := classifyAddress(checker, api, netip.MustParseAddr("192.30.252.1")); got.Area != "api" {
+		t.Errorf("classifyAddress() area = %q, want api", got.Area)
+	}
+	if got := classifyAddress(checker, Area{}, netip.MustParseAddr("192.30.252.1")); got.Area != "hooks" {
+		t.Errorf("classifyAddress() area = %q, want hooks", got.Area)
+	}
+	if got := classifyAddress(checker, api, netip.MustParseAddr("198.51.100.1")); got.IsGitHub {
+		t.Errorf("classifyAddress() = %+v, want not GitHub-owned", got)
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"api": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	oldTargets := doctorTargets
+	doctorTargets = []doctorTarget{{"api.example", 443, "api"}}
+	defer func() { doctorTargets = oldTargets }()
+
+	tests := []struct {
+		name          string
+		addr          string
+		unreachable   bool
+		wantErr       bool
+		wantNotGitHub bool
+		wantCategory  string
+	}{
+		{
+			name: "Reachable GitHub address",
+			addr: "192.30.252.1",
+		},
+		{
+			name:          "Resolves outside the ranges",
+			addr:          "203.0.113.7",
+			wantErr:       true,
+			wantNotGitHub: true,
+		},
+		{
+			name:         "Unreachable",
+			addr:         "192.30.252.1",
+			unreachable:  true,
+			wantErr:      true,
+			wantCategory: errorCategoryNetwork,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var unreachable []string
+			if tt.unreachable {
+				unreachable = append(unreachable, tt.addr+":443")
+			}
+			fakeDoctorNetwork(t, map[string][]string{"api.example": {tt.addr}}, unreachable...)
+
+			cmd := &cobra.Command{}
+			cmd.Flags().StringP("output", "o", outputJSON, "")
+			cmd.Flags().Duration("timeout", time.Second, "")
+
+			err := runDoctor(cmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runDoctor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(notGitHubError); ok != tt.wantNotGitHub {
+				t.Errorf("runDoctor() error = %v, want notGitHubError %v", err, tt.wantNotGitHub)
+			}
+			if tt.wantCategory != "" && errorCategory(err) != tt.wantCategory {
+				t.Errorf("runDoctor() error category = %q, want %q", errorCategory(err), tt.wantCategory)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// domainMatch is a published domain pattern matching a hostname
+type domainMatch struct {
+	Service string `json:"service"` // Key of the domains section, e.g. "actions_inbound"
+	Pattern string `json:"pattern"`
+}
+
+// domainResultJSON is the JSON output of check-domain
+type domainResultJSON struct {
+	Hostname string        `json:"hostname"`
+	IsGitHub bool          `json:"is_github"`
+	Matches  []domainMatch `json:"matches"`
+}
+
+// newCheckDomainCommand creates the check-domain subcommand
+func newCheckDomainCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-domain <hostname>",
+		Short: "Check if a hostname matches GitHub's published domains",
+		Long: `Check if a hostname matches one of the domains GitHub publishes in the
+"domains" section of its /meta API endpoint, such as *.actions.githubusercontent.com,
+so egress policies can verify hostnames as well as IP addresses. Exits with
+status 1 if the hostname doesn't match any published domain.`,
+		Args:         usageArgs(cobra.ExactArgs(1)),
+		RunE:         runCheckDomain,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().BoolP("silent", "s", false, "Silent mode - only use exit codes")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runCheckDomain(cmd *cobra.Command, args []string) error {
+	hostname := args[0]
+	silent, _ := cmd.Flags().GetBool("silent")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	matches, err := NewIPChecker().CheckDomain(hostname)
+	if err != nil {
+		return err
+	}
+
+	if !silent {
+		switch output {
+		case outputJSON:
+			json.NewEncoder(os.Stdout).Encode(domainResultJSON{
+				Hostname: hostname,
+				IsGitHub: len(matches) > 0,
+				Matches:  append([]domainMatch{}, matches...),
+			})
+		default:
+			for _, m := range matches {
+				fmt.Printf("Hostname %s matches GitHub's %s domain %s\n", hostname, m.Service, m.Pattern)
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return notGitHubError("the provided hostname does not match any GitHub domain")
+	}
+	return nil
+}
+
+// CheckDomain returns the published domain patterns matching hostname, across
+// all services in the domains section of the meta response
+func (c *IPChecker) CheckDomain(hostname string) ([]domainMatch, error) {
+	host := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(hostname), "."))
+	if _, err := netip.ParseAddr(host); err == nil {
+		return nil, withCategory(errorCategoryInput, fmt.Errorf("%s is an IP address, check it without check-domain", hostname))
+	}
+	if !validHostname(host) {
+		return nil, withCategory(errorCategoryInput, fmt.Errorf("invalid hostname %q", hostname))
+	}
+
+	meta, err := c.Meta()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := meta.DomainGroups()
+	services := make([]string, 0, len(groups))
+	for service := range groups {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	var matches []domainMatch
+	for _, service := range services {
+		for _, pattern := range groups[service] {
+			if matchDomainPattern(pattern, host) {
+				matches = append(matches, domainMatch{service, pattern})
+			}
+		}
+	}
+	return matches, nil
+}
+
+// validHostname reports whether host is a syntactically valid DNS name
+func validHostname(host string) bool {
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchDomainPattern reports whether host matches a published domain pattern. A
+// leading "*." matches one or more labels, so *.github.com matches
+// api.github.com and uploads.api.github.com but not github.com itself. Other
+// wildcards match within a single label.
+func matchDomainPattern(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		labels := strings.Split(host, ".")
+		n := strings.Count(suffix, ".") + 1
+		return len(labels) > n && matchLabels(suffix, strings.Join(labels[len(labels)-n:], "."))
+	}
+	return matchLabels(pattern, host)
+}
+
+// matchLabels matches host against pattern label by label, with "*" matching
+// within a label
+func matchLabels(pattern, host string) bool {
+	patternLabels := strings.Split(pattern, ".")
+	hostLabels := strings.Split(host, ".")
+	if len(patternLabels) != len(hostLabels) {
+		return false
+	}
+	for i, label := range patternLabels {
+		if ok, err := path.Match(label, hostLabels[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMatchDomainPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{"github.com", "github.com", true},
+		{"github.com", "api.github.com", false},
+		{"*.github.com", "api.github.com", true},
+		{"*.github.com", "uploads.api.github.com", true},
+		{"*.github.com", "github.com", false},
+		{"*.github.com", "notgithub.com", false},
+		{"*.GitHub.com.", "api.github.com", true},
+		{"productionresultssa*.blob.core.windows.net", "productionresultssa12.blob.core.windows.net", true},
+		{"productionresultssa*.blob.core.windows.net", "a.productionresultssa12.blob.core.windows.net", false},
+		{"*.blob*.core.windows.net", "x.blob1.core.windows.net", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchDomainPattern(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("matchDomainPattern(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestValidHostname(t *testing.T) {
+	for _, host := range []string{"github.com", "api.github.com", "_dmarc.github.com", "a-b.example"} {
+		if !validHostname(host) {
+			t.Errorf("validHostname(%q) = false, want true", host)
+		}
+	}
+	for _, host := range []string{"", "github..com", "-github.com", "git hub.com", "https://github.com"} {
+		if validHostname(host) {
+			t.Errorf("validHostname(%q) = true, want false", host)
+		}
+	}
+}
+
+func TestIPChecker_CheckDomain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "domains": {
+			"website": ["*.github.com", "github.com"],
+			"copilot": ["*.githubcopilot.com"],
+			"actions_inbound": {"full_domains": ["github.com"], "wildcard_domains": ["*.actions.githubusercontent.com"]}
+		}}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name     string
+		hostname string
+		want     []domainMatch
+		wantErr  bool
+	}{
+		{
+			name:     "Matches several services",
+			hostname: "GitHub.com.",
+			want:     []domainMatch{{"actions_inbound", "github.com"}, {"website", "github.com"}},
+		},
+		{
+			name:     "Wildcard",
+			hostname: "api.individual.githubcopilot.com",
+			want:     []domainMatch{{"copilot", "*.githubcopilot.com"}},
+		},
+		{
+			name:     "No match",
+			hostname: "example.com",
+		},
+		{
+			name:     "IP address",
+			hostname: "192.30.252.1",
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid hostname",
+			hostname: "not a hostname",
+			wantErr:  true,
+		},
+	}
+
+	checker := NewIPChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checker.CheckDomain(tt.hostname)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckDomain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if errorCategory(err) != errorCategoryInput {
+					t.Errorf("CheckDomain() error category = %s, want %s", errorCategory(err), errorCategoryInput)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckDomain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultEgressDetector returns the caller's public IP address as plain text
+const defaultEgressDetector = "https://api.ipify.org"
+
+// STUN protocol constants (RFC 5389)
+const (
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMagicCookie     = 0x2112A442
+
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXORMappedAddress = 0x0020
+
+	stunDefaultPort = "3478"
+)
+
+// egressTimeout bounds the egress IP detection
+var egressTimeout = 10 * time.Second
+
+// newSelfCommand creates the self subcommand
+func newSelfCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self",
+		Short: "Check whether this machine's egress IP address is GitHub-owned",
+		Long: `Detect this machine's public egress IP address and check it against GitHub's
+published IP ranges, e.g. to verify that a self-hosted runner's traffic leaves
+through the expected network.
+
+The address is detected with --detector, either an HTTP(S) URL returning the
+caller's address as plain text, such as https://api.ipify.org, or a STUN server
+given as stun:host[:port], such as stun:stun.l.google.com:19302.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runSelf,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().BoolP("silent", "s", false, "Silent mode - only use exit codes")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+	cmd.Flags().String("detector", defaultEgressDetector, "HTTP(S) URL or stun:host[:port] server used to detect the egress IP address")
+
+	return cmd
+}
+
+func runSelf(cmd *cobra.Command, args []string) error {
+	silent, _ := cmd.Flags().GetBool("silent")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+	detector, _ := cmd.Flags().GetString("detector")
+
+	ip, err := detectEgressIP(detector)
+	if err != nil {
+		return err
+	}
+
+	result, err := NewIPChecker().CheckIP(ip)
+	if err != nil {
+		return fmt.Errorf("egress IP address %s can't be checked: %w", ip, err)
+	}
+
+	if !silent {
+		switch {
+		case output == outputJSON:
+			json.NewEncoder(os.Stdout).Encode(newResultJSON(ip, result))
+		case result.IsGitHubIP:
+			fmt.Printf("Egress IP %s belongs to GitHub's %s range (%s)\n", ip, result.FunctionalArea, result.Range)
+		default:
+			fmt.Printf("Egress IP %s is not a GitHub-owned address\n", ip)
+		}
+	}
+
+	if !result.IsGitHubIP {
+		return notGitHubError("this machine's egress IP address is not a GitHub-owned address")
+	}
+	return nil
+}
+
+// detectEgressIP returns this machine's public IP address as seen by detector,
+// an HTTP(S) URL or a stun:host[:port] server
+func detectEgressIP(detector string) (string, error) {
+	if server, ok := strings.CutPrefix(detector, "stun:"); ok {
+		return stunEgressIP(server)
+	}
+	if !strings.HasPrefix(detector, "http://") && !strings.HasPrefix(detector, "https://") {
+		return "", withCategory(errorCategoryUsage, fmt.Errorf("unsupported egress IP detector %q: must be an HTTP(S) URL or stun:host[:port]", detector))
+	}
+	return httpEgressIP(detector)
+}
+
+// httpEgressIP fetches the caller's address from a plain text HTTP endpoint
+func httpEgressIP(url string) (string, error) {
+	client := &http.Client{Timeout: egressTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", withCategory(errorCategoryNetwork, fmt.Errorf("failed to detect egress IP address: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", withCategory(errorCategoryAPI, fmt.Errorf("egress IP detector returned status code %d", resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", withCategory(errorCategoryNetwork, fmt.Errorf("failed to read egress IP detector response: %w", err))
+	}
+	ip := strings.TrimSpace(string(body))
+	if _, err := netip.ParseAddr(ip); err != nil {
+		return "", withCategory(errorCategoryAPI, fmt.Errorf("egress IP detector returned %q, not an IP address", ip))
+	}
+	return ip, nil
+}
+
+// stunEgressIP asks a STUN server for the address it sees the request coming from
+func stunEgressIP(server string) (string, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, stunDefaultPort)
+	}
+
+	conn, err := net.DialTimeout("udp", server, egressTimeout)
+	if err != nil {
+		return "", withCategory(errorCategoryNetwork, fmt.Errorf("failed to contact STUN server: %w", err))
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(egressTimeout))
+
+	request := make([]byte, 20)
+	binary.BigEndian.PutUint16(request[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:], stunMagicCookie)
+	if _, err := rand.Read(request[8:20]); err != nil {
+		return "", fmt.Errorf("failed to generate STUN transaction ID: %w", err)
+	}
+	if _, err := conn.Write(request); err != nil {
+		return "", withCategory(errorCategoryNetwork, fmt.Errorf("failed to send STUN request: %w", err))
+	}
+
+	response := make([]byte, 1500)
+	n, err := conn.Read(response)
+	if err != nil {
+		return "", withCategory(errorCategoryNetwork, fmt.Errorf("failed to read STUN response: %w", err))
+	}
+
+	ip, err := parseSTUNResponse(response[:n], request[8:20])
+	if err != nil {
+		return "", withCategory(errorCategoryAPI, err)
+	}
+	return ip.String(), nil
+}
+
+// parseSTUNResponse returns the mapped address of a STUN binding response to the
+// request with the given transaction ID
+func parseSTUNResponse(msg, transactionID []byte) (netip.Addr, error) {
+	if len(msg) < 20 || binary.BigEndian.Uint16(msg[0:]) != stunBindingResponse ||
+		binary.BigEndian.Uint32(msg[4:]) != stunMagicCookie || !bytes.Equal(msg[8:20], transactionID) {
+		return netip.Addr{}, fmt.Errorf("invalid STUN binding response")
+	}
+
+	length := int(binary.BigEndian.Uint16(msg[2:]))
+	attrs := msg[20:]
+	if length < len(attrs) {
+		attrs = attrs[:length]
+	}
+
+	var mapped []byte
+	for len(attrs) >= 4 {
+		typ := binary.BigEndian.Uint16(attrs[0:])
+		size := int(binary.BigEndian.Uint16(attrs[2:]))
+		if 4+size > len(attrs) {
+			break
+		}
+		value := attrs[4 : 4+size]
+
+		switch typ {
+		case stunAttrXORMappedAddress:
+			// The address is XORed with the magic cookie, followed by the
+			// transaction ID for IPv6
+			if ip := stunAddress(value); ip != nil {
+				key := append(binary.BigEndian.AppendUint32(nil, stunMagicCookie), transactionID...)
+				for i := range ip {
+					ip[i] ^= key[i]
+				}
+				addr, _ := netip.AddrFromSlice(ip)
+				return addr, nil
+			}
+		case stunAttrMappedAddress:
+			mapped = stunAddress(value)
+		}
+
+		// Attributes are padded to a multiple of 4 bytes
+		next := 4 + (size+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+
+	if mapped == nil {
+		return netip.Addr{}, fmt.Errorf("STUN response has no mapped address")
+	}
+	addr, _ := netip.AddrFromSlice(mapped)
+	return addr, nil
+}
+
+// stunAddress returns the address bytes of a (XOR-)MAPPED-ADDRESS attribute
+// value, as a copy that is safe to modify
+func stunAddress(value []byte) []byte {
+	if len(value) < 4 {
+		return nil
+	}
+	switch family := value[1]; {
+	case family == 0x01 && len(value) >= 8:
+		return append([]byte{}, value[4:8]...)
+	case family == 0x02 && len(value) >= 20:
+		return append([]byte{}, value[4:20]...)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// stunResponse builds a binding response to the request with the given
+// transaction ID, mapping ip:port as an XOR-MAPPED-ADDRESS
+func stunResponse(transactionID []byte, ip netip.Addr, port int) []byte {
+	family := byte(0x01)
+	if ip.Is6() {
+		family = 0x02
+	}
+	addr := ip.AsSlice()
+	key := append(binary.BigEndian.AppendUint32(nil, stunMagicCookie), transactionID...)
+	value := []byte{0, family}
+	value = binary.BigEndian.AppendUint16(value, uint16(port)^uint16(stunMagicCookie>>16))
+	for i, b := range addr {
+		value = append(value, b^key[i])
+	}
+
+	// An unknown attribute with padding comes first, to exercise skipping it
+	attrs := []byte{0x80, 0x22, 0, 3, 'g', 'o', '!', 0}
+	attrs = binary.BigEndian.AppendUint16(attrs, stunAttrXORMappedAddress)
+	attrs = binary.BigEndian.AppendUint16(attrs, uint16(len(value)))
+	attrs = append(attrs, value...)
+
+	msg := binary.BigEndian.AppendUint16(nil, stunBindingResponse)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(attrs)))
+	msg = binary.BigEndian.AppendUint32(msg, stunMagicCookie)
+	msg = append(msg, transactionID...)
+	return append(msg, attrs...)
+}
+
+func TestParseSTUNResponse(t *testing.T) {
+	id := []byte("0123456789ab")
+	for _, want := range []string{"192.30.252.1", "2a0a:a440::1"} {
+		ip, err := parseSTUNResponse(stunResponse(id, netip.MustParseAddr(want), 54321), id)
+		if err != nil {
+			t.Fatalf("parseSTUNResponse() error = %v", err)
+		}
+		if ip.String() != want {
+			t.Errorf("parseSTUNResponse() = %s, want %s", ip, want)
+		}
+	}
+
+	if _, err := parseSTUNResponse(stunResponse(id, netip.MustParseAddr("192.30.252.1"), 1), []byte("another-id!!")); err == nil {
+		t.Error("parseSTUNResponse() should reject a response to another transaction")
+	}
+	mappedOnly := stunResponse(id, netip.MustParseAddr("192.30.252.1"), 1)[:28]
+	binary.BigEndian.PutUint16(mappedOnly[2:], 8)
+	if _, err := parseSTUNResponse(mappedOnly, id); err == nil {
+		t.Error("parseSTUNResponse() should fail without a mapped address")
+	}
+}
+
+func TestSTUNEgressIP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 1500)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil || n < 20 {
+			return
+		}
+		udp := addr.(*net.UDPAddr).AddrPort()
+		conn.WriteTo(stunResponse(buf[8:20], udp.Addr().Unmap(), int(udp.Port())), addr)
+	}()
+
+	ip, err := detectEgressIP("stun:" + conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("detectEgressIP() error = %v", err)
+	}
+	if ip != "127.0.0.1" {
+		t.Errorf("detectEgressIP() = %q, want 127.0.0.1", ip)
+	}
+}
+
+func TestHTTPEgressIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "Address", status: http.StatusOK, body: "192.30.252.1\n", want: "192.30.252.1"},
+		{name: "Error status", status: http.StatusServiceUnavailable, wantErr: true},
+		{name: "Not an address", status: http.StatusOK, body: "<html>", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			got, err := detectEgressIP(server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectEgressIP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("detectEgressIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := detectEgressIP("ftp://example.com"); err == nil {
+		t.Error("detectEgressIP() should reject unsupported detectors")
+	}
+}
+
+func TestRunSelf(t *testing.T) {
+	meta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer meta.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = meta.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	for _, tt := range []struct {
+		egress        string
+		wantNotGitHub bool
+	}{
+		{"192.30.252.1", false},
+		{"8.8.8.8", true},
+	} {
+		detector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tt.egress))
+		}))
+
+		cmd := &cobra.Command{}
+		cmd.Flags().BoolP("silent", "s", true, "")
+		cmd.Flags().String("output", outputText, "")
+		cmd.Flags().String("detector", detector.URL, "")
+		err := runSelf(cmd, nil)
+		detector.Close()
+
+		if _, ok := err.(notGitHubError); ok != tt.wantNotGitHub || (err != nil && !ok) {
+			t.Errorf("runSelf() with egress %s error = %v, want notGitHubError %v", tt.egress, err, tt.wantNotGitHub)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+)
+
+// Error categories reported by JSON error output
+const (
+	errorCategoryNotGitHub = "not_github"
+	errorCategoryInput     = "invalid_input"
+	errorCategoryUsage     = "usage"
+	errorCategoryNetwork   = "network"
+	errorCategoryAPI       = "api"
+	errorCategoryInternal  = "internal"
+)
+
+// categoryError tags an error with the category automation can branch on
+type categoryError struct {
+	category string
+	err      error
+}
+
+func (e *categoryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *categoryError) Unwrap() error {
+	return e.err
+}
+
+// withCategory tags err with category, keeping its message
+func withCategory(category string, err error) error {
+	return &categoryError{category: category, err: err}
+}
+
+// errorCategory returns the category of err, or internal if it has none
+func errorCategory(err error) string {
+	var notGitHub notGitHubError
+	if errors.As(err, &notGitHub) {
+		return errorCategoryNotGitHub
+	}
+	var categorized *categoryError
+	if errors.As(err, &categorized) {
+		return categorized.category
+	}
+	return errorCategoryInternal
+}
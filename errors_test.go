@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Not GitHub", notGitHubError("the provided IP address is not a GitHub-owned address"), errorCategoryNotGitHub},
+		{"Tagged", withCategory(errorCategoryInput, errors.New("invalid IP address format")), errorCategoryInput},
+		{"Wrapped", fmt.Errorf("failed to fetch GitHub meta: %w", withCategory(errorCategoryNetwork, errors.New("timeout"))), errorCategoryNetwork},
+		{"Untagged", errors.New("boom"), errorCategoryInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCategory(tt.err); got != tt.want {
+				t.Errorf("errorCategory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithCategoryKeepsMessage(t *testing.T) {
+	inner := errors.New("invalid IP address format")
+	err := withCategory(errorCategoryInput, inner)
+	if err.Error() != inner.Error() || !errors.Is(err, inner) {
+		t.Errorf("withCategory() = %v, want it to wrap %v", err, inner)
+	}
+}
+
+func TestCheckIPErrorCategories(t *testing.T) {
+	checker := NewIPChecker()
+	for _, ip := range []string{"invalid-ip", "2001:db8::1", "10.0.0.1"} {
+		_, err := checker.CheckIP(ip)
+		if got := errorCategory(err); got != errorCategoryInput {
+			t.Errorf("CheckIP(%q) error category = %q, want %q", ip, got, errorCategoryInput)
+		}
+	}
+}
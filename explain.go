@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// explainNow returns the current time, against which the snapshot's age is
+// reported. For testing purposes.
+var explainNow = time.Now
+
+// explanation is the decision trace of a check, written with --explain
+type explanation struct {
+	Input      string        `json:"input"`
+	Normalized string        `json:"normalized,omitempty"` // Address checked, once parsed and unmapped
+	Steps      []explainStep `json:"steps"`
+
+	// The snapshot of the ranges the address was checked against
+	Snapshot    *time.Time `json:"snapshot,omitempty"`
+	SnapshotAge string     `json:"snapshot_age,omitempty"`
+	Fetched     *time.Time `json:"fetched,omitempty"`
+	Cached      bool       `json:"cached,omitempty"`
+	SourceHash  string     `json:"source_hash,omitempty"`
+
+	Areas      int            `json:"areas_evaluated"`
+	Prefixes   int            `json:"prefixes_evaluated"`
+	Candidates []explainMatch `json:"candidates"` // Ranges containing the address, most specific first
+
+	Verdict string `json:"verdict"` // github, not_github or error, as in the audit log
+	Hint    string `json:"hint,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// explainStep is a validation step of a check
+type explainStep struct {
+	Step   string `json:"step"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// explainMatch is a range containing the checked address
+type explainMatch struct {
+	Area      string `json:"area"`
+	AreaKey   string `json:"area_key"`
+	Range     string `json:"range"`
+	PrefixLen int    `json:"prefix_len"`
+	Source    string `json:"source,omitempty"`
+	Selected  bool   `json:"selected,omitempty"` // Whether it is the answer
+}
+
+// step records a validation step, if e is set
+func (e *explanation) step(name string, passed bool, detail string) {
+	if e != nil {
+		e.Steps = append(e.Steps, explainStep{name, passed, detail})
+	}
+}
+
+// explainIP checks ipStr as CheckIP does, also returning the trace of how the
+// verdict was reached. The trace is nil only if the check couldn't be recorded
+// in the audit log.
+func (c *IPChecker) explainIP(ipStr string) (*CheckResult, *explanation, error) {
+	trace := &explanation{Input: ipStr, Candidates: []explainMatch{}}
+	result, err := c.checkIP(ipStr, trace)
+	if auditErr := c.audit.record(c, ipStr, result, err, ""); auditErr != nil {
+		return nil, nil, auditErr
+	}
+
+	if c.meta != nil {
+		if snapshot := c.SnapshotTime(); !snapshot.IsZero() {
+			trace.Snapshot = &snapshot
+			trace.SnapshotAge = explainNow().Sub(snapshot).Round(time.Second).String()
+		}
+		if fetched := c.FetchTime(); !fetched.IsZero() {
+			trace.Fetched = &fetched
+		}
+		trace.Cached, trace.SourceHash = c.Cached(), c.SourceHash()
+		areas := c.meta.Areas()
+		for _, source := range c.sources {
+			areas = append(areas, source.areas...)
+		}
+		for _, area := range areas {
+			if len(area.Ranges) > 0 {
+				trace.Areas++
+			}
+			trace.Prefixes += len(area.Ranges)
+		}
+	}
+
+	trace.verdict(result, err)
+	return result, trace, err
+}
+
+// verdict records the outcome of the check
+func (e *explanation) verdict(result *CheckResult, err error) {
+	switch {
+	case err != nil:
+		e.Verdict, e.Error = auditError, err.Error()
+	case result.IsGitHubIP:
+		e.Verdict = auditGitHub
+		all := append([]RangeMatch{{result.FunctionalArea, result.AreaKey, result.Range, result.Source}}, result.Also...)
+		for i, m := range all {
+			match := newMatch(m)
+			e.Candidates = append(e.Candidates, explainMatch{match.Area, match.AreaKey, match.CIDR, match.PrefixLen, match.Source, i == 0})
+		}
+	default:
+		e.Verdict = auditNotGitHub
+		if result.Hint != nil {
+			e.Hint = formatHint(result.Hint)
+		}
+	}
+}
+
+// runExplain checks ip, writing the decision trace instead of the result
+func runExplain(checker *IPChecker, output string, silent bool, ip string) error {
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--explain requires text or json output"))
+	}
+	result, trace, err := checker.explainIP(ip)
+	if trace != nil && !silent {
+		writeExplanation(os.Stdout, output, trace)
+	}
+	if err != nil {
+		return err
+	}
+	if !result.IsGitHubIP {
+		return notGitHubError("the provided IP address is not a GitHub-owned address")
+	}
+	return nil
+}
+
+// writeExplanation writes the decision trace in the given output format
+func writeExplanation(w io.Writer, format string, trace *explanation) {
+	if format == outputJSON {
+		json.NewEncoder(w).Encode(trace)
+		return
+	}
+
+	fmt.Fprintf(w, "Input: %s\n", trace.Input)
+	if trace.Normalized != "" && trace.Normalized != trace.Input {
+		fmt.Fprintf(w, "Checked as: %s\n", trace.Normalized)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range trace.Steps {
+		status := "pass"
+		if !s.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", s.Step, status, s.Detail)
+	}
+	tw.Flush()
+
+	if trace.SourceHash != "" {
+		snapshot := "unknown"
+		if trace.Snapshot != nil {
+			snapshot = fmt.Sprintf("%s (%s old)", trace.Snapshot.Format(time.RFC3339), trace.SnapshotAge)
+		}
+		fmt.Fprintf(w, "Snapshot: %s, sha256 %s", snapshot, trace.SourceHash)
+		if trace.Cached {
+			fmt.Fprint(w, ", cached")
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Evaluated %d prefixes in %d areas, %d containing the address\n", trace.Prefixes, trace.Areas, len(trace.Candidates))
+	}
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, m := range trace.Candidates {
+		marker := " "
+		if m.Selected {
+			marker = "*"
+		}
+		fmt.Fprintf(tw, "  %s %s\t%s%s\n", marker, m.Range, m.Area, formatSource(m.Source))
+	}
+	tw.Flush()
+
+	switch trace.Verdict {
+	case auditGitHub:
+		fmt.Fprintf(w, "Verdict: GitHub-owned, the most specific range is the answer (*)\n")
+	case auditNotGitHub:
+		fmt.Fprintf(w, "Verdict: not GitHub-owned\n")
+		if trace.Hint != "" {
+			fmt.Fprintf(w, "Hint: %s\n", trace.Hint)
+		}
+	default:
+		fmt.Fprintf(w, "Verdict: error\n")
+	}
+}
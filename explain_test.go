@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIPChecker_ExplainIP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Mon, 02 Jun 2025 00:00:00 GMT")
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["192.30.0.0/16", "140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL, oldNow := githubMetaURL, explainNow
+	githubMetaURL = server.URL
+	explainNow = func() time.Time { return time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC) }
+	defer func() { githubMetaURL, explainNow = oldURL, oldNow }()
+
+	checker := NewIPChecker()
+	result, trace, err := checker.explainIP("::ffff:192.30.252.1")
+	if err != nil || !result.IsGitHubIP {
+		t.Fatalf("explainIP() = %+v, %v, want a GitHub-owned address", result, err)
+	}
+	snapshot := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	fetched := checker.FetchTime()
+	want := &explanation{
+		Input:      "::ffff:192.30.252.1",
+		Normalized: "192.30.252.1",
+		Steps: []explainStep{
+			{"parse", true, "parsed as ::ffff:192.30.252.1"},
+			{"unmap", true, "IPv4-mapped address, checked as 192.30.252.1"},
+			{"family", true, "IPv4 address"},
+			{"public", true, "not in a special-purpose range"},
+		},
+		Snapshot:    &snapshot,
+		SnapshotAge: "3h0m0s",
+		Fetched:     &fetched,
+		SourceHash:  checker.SourceHash(),
+		Areas:       2,
+		Prefixes:    3,
+		Candidates: []explainMatch{
+			{"Hooks", "hooks", "192.30.252.0/22", 22, "", true},
+			{"Web", "web", "192.30.0.0/16", 16, "", false},
+		},
+		Verdict: auditGitHub,
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("explainIP() trace = %+v, want %+v", trace, want)
+	}
+
+	_, trace, err = checker.explainIP("10.0.0.1")
+	if err == nil || trace.Verdict != auditError || trace.Error != err.Error() {
+		t.Errorf("explainIP() of a private address = %+v, %v", trace, err)
+	}
+	if last := trace.Steps[len(trace.Steps)-1]; last != (explainStep{"public", false, "private address (10.0.0.0/8, RFC 1918)"}) {
+		t.Errorf("explainIP() of a private address failed at %+v, want the public step", last)
+	}
+
+	checker.AllowNonPublic()
+	_, trace, _ = checker.explainIP("10.0.0.1")
+	if trace.Verdict != auditNotGitHub || trace.Steps[2].Detail != "private address (10.0.0.0/8, RFC 1918), allowed by --allow-non-public" {
+		t.Errorf("explainIP() of an allowed private address = %+v", trace)
+	}
+}
+
+func TestWriteExplanation(t *testing.T) {
+	snapshot := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	trace := &explanation{
+		Input:      "192.30.252.1",
+		Normalized: "192.30.252.1",
+		Steps: []explainStep{
+			{"parse", true, "parsed as 192.30.252.1"},
+			{"family", true, "IPv4 address"},
+			{"public", true, "not in a special-purpose range"},
+		},
+		Snapshot:    &snapshot,
+		SnapshotAge: "3h0m0s",
+		SourceHash:  "abc",
+		Areas:       2,
+		Prefixes:    3,
+		Candidates: []explainMatch{
+			{"Hooks", "hooks", "192.30.252.0/22", 22, "", true},
+			{"Web", "web", "192.30.0.0/16", 16, "", false},
+		},
+		Verdict: auditGitHub,
+	}
+	var buf bytes.Buffer
+	writeExplanation(&buf, outputText, trace)
+	want := `Input: 192.30.252.1
+  parse   pass  parsed as 192.30.252.1
+  family  pass  IPv4 address
+  public  pass  not in a special-purpose range
+Snapshot: 2025-06-02T00:00:00Z (3h0m0s old), sha256 abc
+Evaluated 3 prefixes in 2 areas, 2 containing the address
+  * 192.30.252.0/22  Hooks
+    192.30.0.0/16    Web
+Verdict: GitHub-owned, the most specific range is the answer (*)
+`
+	if buf.String() != want {
+		t.Errorf("writeExplanation() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeExplanation(&buf, outputText, &explanation{
+		Input:   "invalid",
+		Steps:   []explainStep{{"parse", false, "not an IP address"}},
+		Verdict: auditError,
+		Error:   "invalid IP address format",
+	})
+	want = "Input: invalid\n  parse  FAIL  not an IP address\nVerdict: error\n"
+	if buf.String() != want {
+		t.Errorf("writeExplanation() of an invalid input = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/netip"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// exportOptions contains the settings shared by all export formats
+type exportOptions struct {
+	Areas    []Area
+	Domains  []string
+	SSHKeys  []string
+	Name     string
+	Apply    bool
+	Snapshot time.Time
+
+	// Generated is when the export was produced and SourceHash the SHA-256 of
+	// the meta document it was produced from
+	Generated  time.Time
+	SourceHash string
+
+	// Format specific settings
+	Azure      azureOptions
+	GCP        gcpOptions
+	Cloudflare cloudflareOptions
+	Fastly     fastlyOptions
+	K8s        k8sOptions
+	RPZ        rpzOptions
+	IDS        idsOptions
+	SQLite     sqliteOptions
+
+	Elasticsearch elasticsearchOptions
+}
+
+// exporter produces an export format from the selected ranges. Formats are
+// registered by name with registerExporter, in the file implementing them.
+type exporter interface {
+	// Render returns the export, written to the output file or stdout
+	Render(opts exportOptions) ([]byte, error)
+}
+
+// applier is implemented by exporters that can push the export to the service
+// consuming it, with --apply
+type applier interface {
+	Apply(opts exportOptions) error
+}
+
+// fileWriter is implemented by exporters that update an existing output file
+// rather than replacing it
+type fileWriter interface {
+	WriteFile(path string, opts exportOptions) error
+}
+
+// registeredExporter is an export format and its description
+type registeredExporter struct {
+	exporter    exporter
+	description string
+}
+
+// exporters are the export formats by name
+var exporters = map[string]registeredExporter{}
+
+// registerExporter makes an export format available under name. Registering a
+// name twice is a programming error, so it panics.
+func registerExporter(name, description string, e exporter) {
+	if _, ok := exporters[name]; ok {
+		panic(fmt.Sprintf("export format %q registered twice", name))
+	}
+	exporters[name] = registeredExporter{e, description}
+}
+
+// renderFunc is an exporter that only renders
+type renderFunc func(opts exportOptions) ([]byte, error)
+
+func (f renderFunc) Render(opts exportOptions) ([]byte, error) { return f(opts) }
+
+// applyingExporter is an exporter that can also apply the export
+type applyingExporter struct {
+	render func(opts exportOptions) ([]byte, error)
+	apply  func(opts exportOptions) error
+}
+
+func (e applyingExporter) Render(opts exportOptions) ([]byte, error) { return e.render(opts) }
+func (e applyingExporter) Apply(opts exportOptions) error            { return e.apply(opts) }
+
+// exporterList lists the export formats and their descriptions in name order
+func exporterList() string {
+	names := slices.Sorted(maps.Keys(exporters))
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		e := exporters[name]
+		apply := ""
+		if _, ok := e.exporter.(applier); ok {
+			apply = " (supports --apply)"
+		}
+		fmt.Fprintf(tw, "  %s\t%s%s\n", name, e.description, apply)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// newExportCommand creates the export subcommand
+func newExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export GitHub's IP ranges for use in other tools",
+		Long: `Export GitHub's published IP ranges in a format consumable by firewalls,
+cloud providers and other tools. The output is written to the given file, or to
+stdout if no file is provided. --list-formats lists the formats, including those
+provided by plugins: executables on PATH named gh-check-github-ip-ranges-export-<format>,
+which read the ranges as JSON on stdin and write the export to stdout.
+
+Supported formats:
+` + exporterList(),
+		Args:         cobra.MaximumNArgs(1),
+		RunE:         runExport,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("format", "f", "", "Export format")
+	cmd.Flags().Bool("list-formats", false, "List the export formats and exit")
+	cmd.Flags().StringSliceP("area", "a", nil, "Functional areas to export, e.g. hooks,git (default all)")
+	cmd.Flags().String("name", "", "Name of the generated rule or resource (default derived from the areas)")
+	cmd.Flags().Bool("apply", false, "Push the export to the target service instead of printing it")
+	cmd.Flags().String("reload", "", "Command to run after the export file is written, e.g. to reload a service")
+	cmd.Flags().String("schedule", "", `Keep running, exporting at the times of this cron expression, e.g. "*/30 * * * *" or @hourly`)
+	addAzureFlags(cmd)
+	addGCPFlags(cmd)
+	addCloudflareFlags(cmd)
+	addFastlyFlags(cmd)
+	addK8sFlags(cmd)
+	addRPZFlags(cmd)
+	addIDSFlags(cmd)
+	addSQLiteFlags(cmd)
+	addElasticsearchFlags(cmd)
+
+	return cmd
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	apply, _ := cmd.Flags().GetBool("apply")
+	reload, _ := cmd.Flags().GetString("reload")
+
+	if list, _ := cmd.Flags().GetBool("list-formats"); list {
+		fmt.Print(exporterList() + pluginList())
+		return nil
+	}
+	if format == "" {
+		return withCategory(errorCategoryUsage, fmt.Errorf(`required flag(s) "format" not set`))
+	}
+	e, ok := exporters[format]
+	if !ok {
+		// Formats that aren't built in may be provided by a plugin
+		if plugin, found := lookupExportPlugin(format); found {
+			e, ok = registeredExporter{plugin, "plugin " + plugin.path}, true
+		}
+	}
+	if !ok {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported export format %q; --list-formats lists the formats", format))
+	}
+	if _, ok := e.exporter.(applier); apply && !ok {
+		return withCategory(errorCategoryUsage, fmt.Errorf("export format %q doesn't support --apply", format))
+	}
+
+	if reload != "" && len(args) == 0 {
+		return fmt.Errorf("--reload requires an output file")
+	}
+
+	if spec, _ := cmd.Flags().GetString("schedule"); spec != "" {
+		s, err := validateSchedule(spec)
+		if err != nil {
+			return err
+		}
+		return runScheduled(s, "export", func() error {
+			return exportOnce(cmd, args, e.exporter)
+		})
+	}
+	return exportOnce(cmd, args, e.exporter)
+}
+
+// exportOnce fetches the ranges and exports them with e, running the reload
+// command after writing a file
+func exportOnce(cmd *cobra.Command, args []string, e exporter) error {
+	areaNames, _ := cmd.Flags().GetStringSlice("area")
+	name, _ := cmd.Flags().GetString("name")
+	apply, _ := cmd.Flags().GetBool("apply")
+	reload, _ := cmd.Flags().GetString("reload")
+
+	checker := NewIPChecker()
+	meta, err := checker.Meta()
+	if err != nil {
+		return err
+	}
+
+	areas, err := selectAreas(meta.Areas(), areaNames)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = defaultExportName(areas, len(areaNames) == 0)
+	}
+
+	opts := exportOptions{
+		Areas:    areas,
+		Domains:  relevantDomains(meta.DomainGroups(), areas),
+		SSHKeys:  meta.SSHKeys,
+		Name:     name,
+		Apply:    apply,
+		Snapshot: checker.SnapshotTime(),
+
+		Generated:  time.Now().UTC(),
+		SourceHash: checker.SourceHash(),
+
+		Azure:      azureOptionsFromFlags(cmd),
+		GCP:        gcpOptionsFromFlags(cmd),
+		Cloudflare: cloudflareOptionsFromFlags(cmd),
+		Fastly:     fastlyOptionsFromFlags(cmd),
+		K8s:        k8sOptionsFromFlags(cmd),
+		RPZ:        rpzOptionsFromFlags(cmd),
+		IDS:        idsOptionsFromFlags(cmd),
+		SQLite:     sqliteOptionsFromFlags(cmd),
+
+		Elasticsearch: elasticsearchOptionsFromFlags(cmd),
+	}
+
+	if apply {
+		return e.(applier).Apply(opts)
+	}
+	if w, ok := e.(fileWriter); ok && len(args) > 0 {
+		err = w.WriteFile(args[0], opts)
+	} else {
+		var out []byte
+		if out, err = e.Render(opts); err == nil {
+			err = writeExport(args, out)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if reload != "" {
+		return runReloadCommand(reload)
+	}
+	return nil
+}
+
+// runReloadCommand runs the user's reload command through the shell
+func runReloadCommand(command string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("reload command failed: %w", err)
+	}
+	return nil
+}
+
+// writeExport writes the rendered export to the file in args, or stdout if none is given
+func writeExport(args []string, out []byte) error {
+	if len(args) == 0 {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+
+	if err := os.WriteFile(args[0], out, 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// marshalExportJSON encodes v as indented JSON with a trailing newline
+func marshalExportJSON(v interface{}) ([]byte, error) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+// quoteList renders values as a comma separated list of quoted strings
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// normalizeAreaName converts user input such as "Actions IPv4" or "actions-ipv4"
+// into the meta field name form used by Area.Key
+func normalizeAreaName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// selectAreas filters areas down to the requested names, returning all areas
+// if no names are given
+func selectAreas(areas []Area, names []string) ([]Area, error) {
+	if len(names) == 0 {
+		return areas, nil
+	}
+
+	var selected []Area
+	for _, name := range names {
+		key := normalizeAreaName(name)
+		found := false
+		for _, area := range areas {
+			if area.Key == key || normalizeAreaName(area.Name) == key || slices.Contains(areaAliases(area.Key), key) {
+				selected = append(selected, area)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown functional area %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// defaultExportName derives a resource name from the selected areas
+func defaultExportName(areas []Area, all bool) string {
+	if all {
+		return "github"
+	}
+
+	parts := []string{"github"}
+	for _, area := range areas {
+		parts = append(parts, strings.ReplaceAll(area.Key, "_", "-"))
+	}
+	return strings.Join(parts, "-")
+}
+
+// uniqueRanges returns the distinct CIDRs across the given areas, in order of first appearance
+func uniqueRanges(areas []Area) []string {
+	seen := make(map[string]bool)
+	var ranges []string
+	for _, area := range areas {
+		for _, cidr := range area.Ranges {
+			if seen[cidr] {
+				continue
+			}
+			seen[cidr] = true
+			ranges = append(ranges, cidr)
+		}
+	}
+	return ranges
+}
+
+// splitCIDR returns the network address and prefix length of cidr
+func splitCIDR(cidr string) (string, int, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", 0, err
+	}
+	return prefix.Masked().Addr().String(), prefix.Bits(), nil
+}
+
+// relevantDomains returns the distinct published domains of the services matching
+// the given areas
+func relevantDomains(groups map[string][]string, areas []Area) []string {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, area := range areas {
+		service := area.Key
+		if service == "web" {
+			service = "website"
+		}
+		for _, domain := range groups[service] {
+			if seen[domain] {
+				continue
+			}
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// ipv4Ranges returns only the IPv4 CIDRs from ranges
+func ipv4Ranges(ranges []string) []string {
+	var v4 []string
+	for _, cidr := range ranges {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || !prefix.Addr().Is4() {
+			continue
+		}
+		v4 = append(v4, cidr)
+	}
+	return v4
+}
+
+// ipv6Ranges returns only the IPv6 CIDRs from ranges
+func ipv6Ranges(ranges []string) []string {
+	var v6 []string
+	for _, cidr := range ranges {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || !prefix.Addr().Is6() {
+			continue
+		}
+		v6 = append(v6, cidr)
+	}
+	return v6
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("azure-nsg", "Azure Network Security Group rule (JSON)", applyingExporter{renderAzureNSGRule, applyAzureNSGRule})
+	registerExporter("azure-ipgroup", "Azure IP Group ARM template resource (JSON, IPv4 only)", applyingExporter{renderAzureIPGroupARM, applyAzureIPGroup})
+	registerExporter("azure-ipgroup-bicep", "Azure IP Group Bicep resource (IPv4 only)", applyingExporter{renderAzureIPGroupBicep, applyAzureIPGroup})
+}
+
+var azureManagementURL = "https://management.azure.com"
+
+const azureNetworkAPIVersion = "2023-09-01"
+
+// azureOptions contains the settings for the Azure export formats
+type azureOptions struct {
+	Subscription  string
+	ResourceGroup string
+	NSG           string
+	Location      string
+	Priority      int
+	Direction     string
+}
+
+// azureSecurityRule is a security rule of an Azure Network Security Group
+type azureSecurityRule struct {
+	Name       string                      `json:"name,omitempty"`
+	Properties azureSecurityRuleProperties `json:"properties"`
+}
+
+type azureSecurityRuleProperties struct {
+	Description                string   `json:"description"`
+	Priority                   int      `json:"priority"`
+	Direction                  string   `json:"direction"`
+	Access                     string   `json:"access"`
+	Protocol                   string   `json:"protocol"`
+	SourceAddressPrefix        string   `json:"sourceAddressPrefix,omitempty"`
+	SourceAddressPrefixes      []string `json:"sourceAddressPrefixes,omitempty"`
+	SourcePortRange            string   `json:"sourcePortRange"`
+	DestinationAddressPrefix   string   `json:"destinationAddressPrefix,omitempty"`
+	DestinationAddressPrefixes []string `json:"destinationAddressPrefixes,omitempty"`
+	DestinationPortRange       string   `json:"destinationPortRange"`
+}
+
+// azureIPGroup is an Azure IP Group resource
+type azureIPGroup struct {
+	Type       string                 `json:"type,omitempty"`
+	APIVersion string                 `json:"apiVersion,omitempty"`
+	Name       string                 `json:"name,omitempty"`
+	Location   string                 `json:"location"`
+	Properties azureIPGroupProperties `json:"properties"`
+}
+
+type azureIPGroupProperties struct {
+	IPAddresses []string `json:"ipAddresses"`
+}
+
+func addAzureFlags(cmd *cobra.Command) {
+	cmd.Flags().String("azure-subscription", "", "Azure subscription ID (used with --apply)")
+	cmd.Flags().String("azure-resource-group", "", "Azure resource group (used with --apply)")
+	cmd.Flags().String("azure-nsg", "", "Azure Network Security Group to update (used with --apply)")
+	cmd.Flags().String("azure-location", "[resourceGroup().location]", "Azure location of the IP Group")
+	cmd.Flags().Int("azure-priority", 100, "Priority of the Azure NSG rule")
+	cmd.Flags().String("azure-direction", "Inbound", "Direction of the Azure NSG rule (Inbound or Outbound)")
+}
+
+func azureOptionsFromFlags(cmd *cobra.Command) azureOptions {
+	var opts azureOptions
+	opts.Subscription, _ = cmd.Flags().GetString("azure-subscription")
+	opts.ResourceGroup, _ = cmd.Flags().GetString("azure-resource-group")
+	opts.NSG, _ = cmd.Flags().GetString("azure-nsg")
+	opts.Location, _ = cmd.Flags().GetString("azure-location")
+	opts.Priority, _ = cmd.Flags().GetInt("azure-priority")
+	opts.Direction, _ = cmd.Flags().GetString("azure-direction")
+	return opts
+}
+
+// azureNSGRule builds the NSG security rule allowing the selected areas
+func azureNSGRule(opts exportOptions) (*azureSecurityRule, error) {
+	ranges := uniqueRanges(opts.Areas)
+	props := azureSecurityRuleProperties{
+		Description:          "GitHub IP ranges generated by gh-check-github-ip-ranges",
+		Priority:             opts.Azure.Priority,
+		Direction:            opts.Azure.Direction,
+		Access:               "Allow",
+		Protocol:             "*",
+		SourcePortRange:      "*",
+		DestinationPortRange: "*",
+	}
+
+	switch opts.Azure.Direction {
+	case "Inbound":
+		props.SourceAddressPrefixes = ranges
+		props.DestinationAddressPrefix = "*"
+	case "Outbound":
+		props.SourceAddressPrefix = "*"
+		props.DestinationAddressPrefixes = ranges
+	default:
+		return nil, fmt.Errorf("invalid Azure NSG rule direction %q: must be Inbound or Outbound", opts.Azure.Direction)
+	}
+
+	return &azureSecurityRule{Name: opts.Name, Properties: props}, nil
+}
+
+// renderAzureNSGRule renders the NSG security rule as JSON
+func renderAzureNSGRule(opts exportOptions) ([]byte, error) {
+	rule, err := azureNSGRule(opts)
+	if err != nil {
+		return nil, err
+	}
+	return marshalExportJSON(rule)
+}
+
+// azureIPGroupResource builds the IP Group resource for the selected areas.
+// IP Groups only support IPv4, so IPv6 ranges are left out.
+func azureIPGroupResource(opts exportOptions) *azureIPGroup {
+	return &azureIPGroup{
+		Type:       "Microsoft.Network/ipGroups",
+		APIVersion: azureNetworkAPIVersion,
+		Name:       opts.Name,
+		Location:   opts.Azure.Location,
+		Properties: azureIPGroupProperties{
+			IPAddresses: ipv4Ranges(uniqueRanges(opts.Areas)),
+		},
+	}
+}
+
+// renderAzureIPGroupARM renders the IP Group as an ARM template resource
+func renderAzureIPGroupARM(opts exportOptions) ([]byte, error) {
+	return marshalExportJSON(azureIPGroupResource(opts))
+}
+
+// renderAzureIPGroupBicep renders the IP Group as a Bicep resource declaration
+func renderAzureIPGroupBicep(opts exportOptions) ([]byte, error) {
+	group := azureIPGroupResource(opts)
+
+	location := fmt.Sprintf("'%s'", group.Location)
+	if strings.HasPrefix(group.Location, "[") && strings.HasSuffix(group.Location, "]") {
+		location = strings.Trim(group.Location, "[]")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "resource githubIpGroup 'Microsoft.Network/ipGroups@%s' = {\n", azureNetworkAPIVersion)
+	fmt.Fprintf(&b, "  name: '%s'\n", group.Name)
+	fmt.Fprintf(&b, "  location: %s\n", location)
+	b.WriteString("  properties: {\n")
+	b.WriteString("    ipAddresses: [\n")
+	for _, cidr := range group.Properties.IPAddresses {
+		fmt.Fprintf(&b, "      '%s'\n", cidr)
+	}
+	b.WriteString("    ]\n")
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+// applyAzureNSGRule creates or updates the security rule in an existing NSG
+func applyAzureNSGRule(opts exportOptions) error {
+	if opts.Azure.Subscription == "" || opts.Azure.ResourceGroup == "" || opts.Azure.NSG == "" {
+		return fmt.Errorf("--azure-subscription, --azure-resource-group and --azure-nsg are required with --apply")
+	}
+
+	rule, err := azureNSGRule(opts)
+	if err != nil {
+		return err
+	}
+	rule.Name = ""
+
+	path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/networkSecurityGroups/%s/securityRules/%s",
+		opts.Azure.Subscription, opts.Azure.ResourceGroup, opts.Azure.NSG, opts.Name)
+	return azurePut(path, rule)
+}
+
+// applyAzureIPGroup creates or updates the IP Group
+func applyAzureIPGroup(opts exportOptions) error {
+	if opts.Azure.Subscription == "" || opts.Azure.ResourceGroup == "" {
+		return fmt.Errorf("--azure-subscription and --azure-resource-group are required with --apply")
+	}
+	if strings.HasPrefix(opts.Azure.Location, "[") {
+		return fmt.Errorf("--azure-location must be set to a region with --apply")
+	}
+
+	group := azureIPGroupResource(opts)
+	group.Type, group.APIVersion, group.Name = "", "", ""
+
+	path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/ipGroups/%s",
+		opts.Azure.Subscription, opts.Azure.ResourceGroup, opts.Name)
+	return azurePut(path, group)
+}
+
+// azureAccessToken returns a token for the Azure Resource Manager API, taken from
+// AZURE_ACCESS_TOKEN or the Azure CLI's current login
+func azureAccessToken() (string, error) {
+	if token := os.Getenv("AZURE_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	out, err := exec.Command("az", "account", "get-access-token",
+		"--resource", "https://management.azure.com/", "--query", "accessToken", "-o", "tsv").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get Azure access token (set AZURE_ACCESS_TOKEN or run 'az login'): %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// azurePut sends a PUT request for the resource at path to the Azure Resource Manager API
+func azurePut(path string, resource interface{}) error {
+	token, err := azureAccessToken()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(resource)
+	if err != nil {
+		return fmt.Errorf("failed to encode Azure request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s%s?api-version=%s", azureManagementURL, path, azureNetworkAPIVersion)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Azure request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update Azure resource: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("Azure API returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderAzureNSGRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		direction string
+		wantErr   bool
+	}{
+		{name: "Inbound", direction: "Inbound"},
+		{name: "Outbound", direction: "Outbound"},
+		{name: "Invalid direction", direction: "Sideways", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := exportOptions{
+				Areas: testAreas()[:1],
+				Name:  "github-hooks",
+				Azure: azureOptions{Priority: 200, Direction: tt.direction},
+			}
+
+			out, err := renderAzureNSGRule(opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderAzureNSGRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var rule azureSecurityRule
+			if err := json.Unmarshal(out, &rule); err != nil {
+				t.Fatalf("renderAzureNSGRule() produced invalid JSON: %v", err)
+			}
+
+			want := []string{"192.30.252.0/22", "2a0a:a440::/29"}
+			got := rule.Properties.SourceAddressPrefixes
+			if tt.direction == "Outbound" {
+				got = rule.Properties.DestinationAddressPrefixes
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("renderAzureNSGRule() prefixes = %v, want %v", got, want)
+			}
+			if rule.Name != "github-hooks" || rule.Properties.Priority != 200 || rule.Properties.Access != "Allow" {
+				t.Errorf("renderAzureNSGRule() rule = %+v", rule)
+			}
+		})
+	}
+}
+
+func TestRenderAzureIPGroup(t *testing.T) {
+	opts := exportOptions{
+		Areas: testAreas(),
+		Name:  "github",
+		Azure: azureOptions{Location: "[resourceGroup().location]"},
+	}
+
+	out, err := renderAzureIPGroupARM(opts)
+	if err != nil {
+		t.Fatalf("renderAzureIPGroupARM() error = %v", err)
+	}
+	var group azureIPGroup
+	if err := json.Unmarshal(out, &group); err != nil {
+		t.Fatalf("renderAzureIPGroupARM() produced invalid JSON: %v", err)
+	}
+	want := []string{"192.30.252.0/22", "140.82.112.0/20", "4.148.0.0/16"}
+	if !reflect.DeepEqual(group.Properties.IPAddresses, want) {
+		t.Errorf("renderAzureIPGroupARM() ipAddresses = %v, want %v", group.Properties.IPAddresses, want)
+	}
+	if group.Type != "Microsoft.Network/ipGroups" {
+		t.Errorf("renderAzureIPGroupARM() type = %q", group.Type)
+	}
+
+	out, err = renderAzureIPGroupBicep(opts)
+	if err != nil {
+		t.Fatalf("renderAzureIPGroupBicep() error = %v", err)
+	}
+	for _, want := range []string{"name: 'github'", "location: resourceGroup().location", "      '140.82.112.0/20'\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderAzureIPGroupBicep() = %s, should contain %q", out, want)
+		}
+	}
+	if strings.Contains(string(out), "2a0a:a440::/29") {
+		t.Errorf("renderAzureIPGroupBicep() should not contain IPv6 ranges")
+	}
+}
+
+func TestApplyAzure(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	oldURL := azureManagementURL
+	azureManagementURL = server.URL
+	defer func() { azureManagementURL = oldURL }()
+	t.Setenv("AZURE_ACCESS_TOKEN", "test-token")
+
+	opts := exportOptions{
+		Areas: testAreas()[:1],
+		Name:  "github-hooks",
+		Azure: azureOptions{
+			Subscription:  "sub",
+			ResourceGroup: "rg",
+			NSG:           "nsg",
+			Location:      "westeurope",
+			Priority:      100,
+			Direction:     "Inbound",
+		},
+	}
+
+	if err := applyAzureNSGRule(opts); err != nil {
+		t.Fatalf("applyAzureNSGRule() error = %v", err)
+	}
+	wantPath := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg/securityRules/github-hooks"
+	if gotPath != wantPath {
+		t.Errorf("applyAzureNSGRule() path = %q, want %q", gotPath, wantPath)
+	}
+	if gotAuth != "Bearer test-token" {
+		t.Errorf("applyAzureNSGRule() Authorization = %q", gotAuth)
+	}
+	if _, ok := gotBody["properties"]; !ok {
+		t.Errorf("applyAzureNSGRule() body missing properties: %v", gotBody)
+	}
+
+	if err := applyAzureIPGroup(opts); err != nil {
+		t.Fatalf("applyAzureIPGroup() error = %v", err)
+	}
+	wantPath = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/ipGroups/github-hooks"
+	if gotPath != wantPath {
+		t.Errorf("applyAzureIPGroup() path = %q, want %q", gotPath, wantPath)
+	}
+	if gotBody["location"] != "westeurope" {
+		t.Errorf("applyAzureIPGroup() location = %v", gotBody["location"])
+	}
+
+	opts.Azure.Subscription = ""
+	if err := applyAzureIPGroup(opts); err == nil {
+		t.Errorf("applyAzureIPGroup() without subscription should fail")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("cloudflare-list", "Cloudflare custom IP list items (JSON)", applyingExporter{renderCloudflareList, applyCloudflareList})
+}
+
+var cloudflareAPIURL = "https://api.cloudflare.com/client/v4"
+
+// cloudflareOptions contains the settings for the Cloudflare export format
+type cloudflareOptions struct {
+	Account string
+}
+
+// cloudflareListItem is an entry of a Cloudflare custom IP list
+type cloudflareListItem struct {
+	IP      string `json:"ip"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// cloudflareList is a Cloudflare custom list
+type cloudflareList struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Description string `json:"description,omitempty"`
+}
+
+// cloudflareResponse is the envelope of every Cloudflare API response
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+func addCloudflareFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cloudflare-account", os.Getenv("CLOUDFLARE_ACCOUNT_ID"), "Cloudflare account ID (used with --apply)")
+}
+
+func cloudflareOptionsFromFlags(cmd *cobra.Command) cloudflareOptions {
+	var opts cloudflareOptions
+	opts.Account, _ = cmd.Flags().GetString("cloudflare-account")
+	return opts
+}
+
+// cloudflareListName converts a name to the form Cloudflare accepts for lists:
+// lowercase letters, numbers and underscores
+func cloudflareListName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "-", "_"))
+}
+
+// cloudflareListItems builds the list entries for the selected areas, noting
+// the functional areas of each range in its comment
+func cloudflareListItems(areas []Area) []cloudflareListItem {
+	names := make(map[string][]string)
+	for _, area := range areas {
+		for _, cidr := range area.Ranges {
+			names[cidr] = append(names[cidr], area.Name)
+		}
+	}
+
+	var items []cloudflareListItem
+	for _, cidr := range uniqueRanges(areas) {
+		items = append(items, cloudflareListItem{
+			IP:      cidr,
+			Comment: "GitHub " + strings.Join(names[cidr], ", "),
+		})
+	}
+	return items
+}
+
+// renderCloudflareList renders the list items as the JSON body accepted by the
+// Cloudflare list items API
+func renderCloudflareList(opts exportOptions) ([]byte, error) {
+	return marshalExportJSON(cloudflareListItems(opts.Areas))
+}
+
+// applyCloudflareList creates the custom IP list if needed and replaces its items
+func applyCloudflareList(opts exportOptions) error {
+	if opts.Cloudflare.Account == "" {
+		return fmt.Errorf("--cloudflare-account or CLOUDFLARE_ACCOUNT_ID is required with --apply")
+	}
+	token := os.Getenv("CLOUDFLARE_API_TOKEN")
+	if token == "" {
+		return fmt.Errorf("CLOUDFLARE_API_TOKEN must be set with --apply")
+	}
+
+	basePath := fmt.Sprintf("/accounts/%s/rules/lists", opts.Cloudflare.Account)
+	name := cloudflareListName(opts.Name)
+
+	var lists []cloudflareList
+	if err := cloudflareRequest(token, http.MethodGet, basePath, nil, &lists); err != nil {
+		return err
+	}
+
+	var listID string
+	for _, list := range lists {
+		if list.Name == name {
+			listID = list.ID
+			break
+		}
+	}
+
+	if listID == "" {
+		var created cloudflareList
+		newList := cloudflareList{
+			Name:        name,
+			Kind:        "ip",
+			Description: "GitHub IP ranges managed by gh-check-github-ip-ranges",
+		}
+		if err := cloudflareRequest(token, http.MethodPost, basePath, newList, &created); err != nil {
+			return err
+		}
+		listID = created.ID
+	}
+
+	return cloudflareRequest(token, http.MethodPut, basePath+"/"+listID+"/items", cloudflareListItems(opts.Areas), nil)
+}
+
+// cloudflareRequest sends a request to the Cloudflare API, decoding the result into out if set
+func cloudflareRequest(token, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode Cloudflare request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, cloudflareAPIURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create Cloudflare request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Cloudflare API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope cloudflareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("Cloudflare API returned status code %d", resp.StatusCode)
+	}
+	if !envelope.Success {
+		if len(envelope.Errors) > 0 {
+			return fmt.Errorf("Cloudflare API error: %s", envelope.Errors[0].Message)
+		}
+		return fmt.Errorf("Cloudflare API returned status code %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(envelope.Result, out); err != nil {
+			return fmt.Errorf("failed to decode Cloudflare response: %w", err)
+		}
+	}
+	return nil
+}
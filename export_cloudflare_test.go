@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCloudflareListItems(t *testing.T) {
+	items := cloudflareListItems(testAreas())
+	if len(items) != 4 {
+		t.Fatalf("cloudflareListItems() = %d items, want 4", len(items))
+	}
+	if items[0].IP != "192.30.252.0/22" || items[0].Comment != "GitHub Hooks, Web" {
+		t.Errorf("cloudflareListItems()[0] = %+v", items[0])
+	}
+	if got := cloudflareListName("github-actions-ipv4"); got != "github_actions_ipv4" {
+		t.Errorf("cloudflareListName() = %q, want %q", got, "github_actions_ipv4")
+	}
+}
+
+func TestApplyCloudflareList(t *testing.T) {
+	tests := []struct {
+		name        string
+		lists       string
+		wantCreated bool
+		wantListID  string
+	}{
+		{
+			name:       "Existing list",
+			lists:      `[{"id": "abc", "name": "github_hooks", "kind": "ip"}]`,
+			wantListID: "abc",
+		},
+		{
+			name:        "New list",
+			lists:       `[{"id": "other", "name": "other", "kind": "ip"}]`,
+			wantCreated: true,
+			wantListID:  "new",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created bool
+			var itemsPath string
+			var items []cloudflareListItem
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer test-token" {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte(`{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`))
+					return
+				}
+				switch r.Method {
+				case http.MethodGet:
+					w.Write([]byte(`{"success": true, "result": ` + tt.lists + `}`))
+				case http.MethodPost:
+					created = true
+					w.Write([]byte(`{"success": true, "result": {"id": "new", "name": "github_hooks", "kind": "ip"}}`))
+				case http.MethodPut:
+					itemsPath = r.URL.Path
+					body, _ := io.ReadAll(r.Body)
+					json.Unmarshal(body, &items)
+					w.Write([]byte(`{"success": true, "result": {"operation_id": "op"}}`))
+				}
+			}))
+			defer server.Close()
+
+			oldURL := cloudflareAPIURL
+			cloudflareAPIURL = server.URL
+			defer func() { cloudflareAPIURL = oldURL }()
+			t.Setenv("CLOUDFLARE_API_TOKEN", "test-token")
+
+			opts := exportOptions{
+				Areas:      testAreas()[:1],
+				Name:       "github-hooks",
+				Cloudflare: cloudflareOptions{Account: "acct"},
+			}
+			if err := applyCloudflareList(opts); err != nil {
+				t.Fatalf("applyCloudflareList() error = %v", err)
+			}
+
+			if created != tt.wantCreated {
+				t.Errorf("applyCloudflareList() created = %v, want %v", created, tt.wantCreated)
+			}
+			if want := "/accounts/acct/rules/lists/" + tt.wantListID + "/items"; itemsPath != want {
+				t.Errorf("applyCloudflareList() items path = %q, want %q", itemsPath, want)
+			}
+			if len(items) != 2 {
+				t.Errorf("applyCloudflareList() items = %v, want 2 entries", items)
+			}
+
+			t.Setenv("CLOUDFLARE_API_TOKEN", "wrong-token")
+			if err := applyCloudflareList(opts); err == nil || err.Error() != "Cloudflare API error: Authentication error" {
+				t.Errorf("applyCloudflareList() error = %v, want authentication error", err)
+			}
+		})
+	}
+}
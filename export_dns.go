@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("rpz", "BIND response policy zone for the domains and ranges", renderFunc(renderRPZ))
+}
+
+// rpzOptions contains the settings for the RPZ export format
+type rpzOptions struct {
+	DefaultDeny bool
+}
+
+func addRPZFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("rpz-default-deny", false, "Make the RPZ zone answer NXDOMAIN for every name that isn't GitHub's")
+}
+
+func rpzOptionsFromFlags(cmd *cobra.Command) rpzOptions {
+	var opts rpzOptions
+	opts.DefaultDeny, _ = cmd.Flags().GetBool("rpz-default-deny")
+	return opts
+}
+
+// rpzIPTrigger returns the owner name of an RPZ response IP trigger for cidr, e.g.
+// "22.0.252.30.192.rpz-ip" for 192.30.252.0/22
+func rpzIPTrigger(cidr string) (string, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", err
+	}
+	prefix = prefix.Masked()
+	ones := prefix.Bits()
+
+	var labels []string
+	if prefix.Addr().Is4() {
+		ip := prefix.Addr().As4()
+		for i := len(ip) - 1; i >= 0; i-- {
+			labels = append(labels, fmt.Sprintf("%d", ip[i]))
+		}
+	} else {
+		ip := prefix.Addr().As16()
+		words := make([]uint16, 8)
+		for i := range words {
+			words[i] = uint16(ip[2*i])<<8 | uint16(ip[2*i+1])
+		}
+
+		// Find the longest run of zero words to replace with "zz", as "::" would be
+		zeroStart, zeroLen := -1, 0
+		for i := 0; i < len(words); {
+			if words[i] != 0 {
+				i++
+				continue
+			}
+			j := i
+			for j < len(words) && words[j] == 0 {
+				j++
+			}
+			if j-i > zeroLen && j-i >= 2 {
+				zeroStart, zeroLen = i, j-i
+			}
+			i = j
+		}
+
+		for i := len(words) - 1; i >= 0; i-- {
+			if i >= zeroStart && i < zeroStart+zeroLen {
+				if i == zeroStart {
+					labels = append(labels, "zz")
+				}
+				continue
+			}
+			labels = append(labels, fmt.Sprintf("%x", words[i]))
+		}
+	}
+
+	return fmt.Sprintf("%d.%s.rpz-ip", ones, strings.Join(labels, ".")), nil
+}
+
+// renderRPZ renders a response policy zone passing through GitHub's domains and
+// any answers within its ranges, optionally blocking everything else
+func renderRPZ(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("; " + exportHeader + "\n")
+	b.WriteString("$TTL 300\n")
+	fmt.Fprintf(&b, "@ IN SOA localhost. hostmaster.localhost. %d 3600 600 86400 300\n", opts.Snapshot.Unix())
+	b.WriteString("  IN NS  localhost.\n")
+
+	if len(opts.Domains) > 0 {
+		b.WriteString("\n; GitHub domains\n")
+		for _, domain := range opts.Domains {
+			fmt.Fprintf(&b, "%s CNAME rpz-passthru.\n", strings.TrimSuffix(domain, "."))
+		}
+	}
+
+	b.WriteString("\n; GitHub IP ranges\n")
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		trigger, err := rpzIPTrigger(cidr)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s CNAME rpz-passthru.\n", trigger)
+	}
+
+	if opts.RPZ.DefaultDeny {
+		b.WriteString("\n; Everything else\n")
+		b.WriteString("* CNAME .\n")
+	}
+	return []byte(b.String()), nil
+}
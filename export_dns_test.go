@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRPZIPTrigger(t *testing.T) {
+	tests := []struct {
+		cidr    string
+		want    string
+		wantErr bool
+	}{
+		{cidr: "192.30.252.0/22", want: "22.0.252.30.192.rpz-ip"},
+		{cidr: "140.82.121.4/32", want: "32.4.121.82.140.rpz-ip"},
+		{cidr: "2a0a:a440::/29", want: "29.zz.a440.2a0a.rpz-ip"},
+		{cidr: "2001:db8:0:1::/64", want: "64.zz.1.0.db8.2001.rpz-ip"},
+		{cidr: "2001:db8::1:0:0:1/128", want: "128.1.0.0.1.zz.db8.2001.rpz-ip"},
+		{cidr: "invalid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			got, err := rpzIPTrigger(tt.cidr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rpzIPTrigger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("rpzIPTrigger() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderRPZ(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[:1],
+		Domains:  []string{"github.com", "*.github.com"},
+		Snapshot: time.Unix(1791979200, 0),
+	}
+
+	out, err := renderRPZ(opts)
+	if err != nil {
+		t.Fatalf("renderRPZ() error = %v", err)
+	}
+	for _, want := range []string{
+		"@ IN SOA localhost. hostmaster.localhost. 1791979200 3600 600 86400 300\n",
+		"github.com CNAME rpz-passthru.\n*.github.com CNAME rpz-passthru.\n",
+		"22.0.252.30.192.rpz-ip CNAME rpz-passthru.\n29.zz.a440.2a0a.rpz-ip CNAME rpz-passthru.\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderRPZ() = %s, should contain %q", out, want)
+		}
+	}
+	if strings.Contains(string(out), "* CNAME .") {
+		t.Errorf("renderRPZ() should not block other names by default")
+	}
+
+	opts.RPZ.DefaultDeny = true
+	out, _ = renderRPZ(opts)
+	if !strings.HasSuffix(string(out), "* CNAME .\n") {
+		t.Errorf("renderRPZ() = %s, should end with the default deny rule", out)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("fastly-acl", "Fastly ACL entries batch update (JSON)", applyingExporter{renderFastlyACL, applyFastlyACL})
+	registerExporter("fastly-vcl", "Fastly VCL acl declaration", applyingExporter{renderFastlyVCL, applyFastlyACL})
+}
+
+var fastlyAPIURL = "https://api.fastly.com"
+
+// fastlyMaxBatch is the maximum number of operations in one ACL entries batch request
+const fastlyMaxBatch = 1000
+
+// fastlyOptions contains the settings for the Fastly export formats
+type fastlyOptions struct {
+	Service string
+	ACL     string
+}
+
+// fastlyACLEntry is an entry of a Fastly ACL, or an operation on one in a batch update
+type fastlyACLEntry struct {
+	Op      string `json:"op,omitempty"`
+	ID      string `json:"id,omitempty"`
+	IP      string `json:"ip,omitempty"`
+	Subnet  *int   `json:"subnet,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+func addFastlyFlags(cmd *cobra.Command) {
+	cmd.Flags().String("fastly-service", "", "Fastly service ID (used with --apply)")
+	cmd.Flags().String("fastly-acl", "", "Fastly ACL ID (used with --apply)")
+}
+
+func fastlyOptionsFromFlags(cmd *cobra.Command) fastlyOptions {
+	var opts fastlyOptions
+	opts.Service, _ = cmd.Flags().GetString("fastly-service")
+	opts.ACL, _ = cmd.Flags().GetString("fastly-acl")
+	return opts
+}
+
+// fastlyEntries converts the selected areas into ACL entries
+func fastlyEntries(areas []Area) []fastlyACLEntry {
+	var entries []fastlyACLEntry
+	for _, cidr := range uniqueRanges(areas) {
+		ip, ones, err := splitCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, fastlyACLEntry{
+			IP:      ip,
+			Subnet:  &ones,
+			Comment: "GitHub",
+		})
+	}
+	return entries
+}
+
+// renderFastlyVCL renders the ranges as a VCL acl declaration
+func renderFastlyVCL(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "acl %s {\n", strings.ReplaceAll(opts.Name, "-", "_"))
+	for _, entry := range fastlyEntries(opts.Areas) {
+		fmt.Fprintf(&b, "  \"%s\"/%d;\n", entry.IP, *entry.Subnet)
+	}
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+// renderFastlyACL renders the ranges as the body of an ACL entries batch update
+// creating each entry
+func renderFastlyACL(opts exportOptions) ([]byte, error) {
+	entries := fastlyEntries(opts.Areas)
+	for i := range entries {
+		entries[i].Op = "create"
+	}
+	return marshalExportJSON(map[string][]fastlyACLEntry{"entries": entries})
+}
+
+// applyFastlyACL syncs an existing Fastly ACL with the ranges, creating missing
+// entries and deleting entries that are no longer published
+func applyFastlyACL(opts exportOptions) error {
+	if opts.Fastly.Service == "" || opts.Fastly.ACL == "" {
+		return fmt.Errorf("--fastly-service and --fastly-acl are required with --apply")
+	}
+	token := os.Getenv("FASTLY_API_TOKEN")
+	if token == "" {
+		return fmt.Errorf("FASTLY_API_TOKEN must be set with --apply")
+	}
+
+	path := fmt.Sprintf("/service/%s/acl/%s/entries", opts.Fastly.Service, opts.Fastly.ACL)
+
+	var existing []fastlyACLEntry
+	if err := fastlyRequest(token, http.MethodGet, path+"?per_page=10000", nil, &existing); err != nil {
+		return err
+	}
+
+	ops := fastlyDiff(existing, fastlyEntries(opts.Areas))
+	for i := 0; i < len(ops); i += fastlyMaxBatch {
+		end := min(i+fastlyMaxBatch, len(ops))
+		body := map[string][]fastlyACLEntry{"entries": ops[i:end]}
+		if err := fastlyRequest(token, http.MethodPatch, path, body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fastlyDiff returns the batch operations turning existing into wanted
+func fastlyDiff(existing, wanted []fastlyACLEntry) []fastlyACLEntry {
+	key := func(e fastlyACLEntry) string {
+		subnet := 32
+		if strings.Contains(e.IP, ":") {
+			subnet = 128
+		}
+		if e.Subnet != nil {
+			subnet = *e.Subnet
+		}
+		return fmt.Sprintf("%s/%d", e.IP, subnet)
+	}
+
+	want := make(map[string]bool)
+	for _, e := range wanted {
+		want[key(e)] = true
+	}
+
+	have := make(map[string]bool)
+	var ops []fastlyACLEntry
+	for _, e := range existing {
+		have[key(e)] = true
+		if !want[key(e)] {
+			ops = append(ops, fastlyACLEntry{Op: "delete", ID: e.ID})
+		}
+	}
+	for _, e := range wanted {
+		if !have[key(e)] {
+			e.Op = "create"
+			ops = append(ops, e)
+		}
+	}
+	return ops
+}
+
+// fastlyRequest sends a request to the Fastly API, decoding the response into out if set
+func fastlyRequest(token, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode Fastly request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fastlyAPIURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create Fastly request: %w", err)
+	}
+	req.Header.Set("Fastly-Key", token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Fastly API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Fastly API returned status code %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode Fastly response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderFastly(t *testing.T) {
+	opts := exportOptions{Areas: testAreas()[:1], Name: "github-hooks"}
+
+	out, err := renderFastlyVCL(opts)
+	if err != nil {
+		t.Fatalf("renderFastlyVCL() error = %v", err)
+	}
+	want := "acl github_hooks {\n  \"192.30.252.0\"/22;\n  \"2a0a:a440::\"/29;\n}\n"
+	if string(out) != want {
+		t.Errorf("renderFastlyVCL() = %q, want %q", out, want)
+	}
+
+	out, err = renderFastlyACL(opts)
+	if err != nil {
+		t.Fatalf("renderFastlyACL() error = %v", err)
+	}
+	var body map[string][]fastlyACLEntry
+	if err := json.Unmarshal(out, &body); err != nil {
+		t.Fatalf("renderFastlyACL() produced invalid JSON: %v", err)
+	}
+	entries := body["entries"]
+	if len(entries) != 2 || entries[0].Op != "create" || entries[0].IP != "192.30.252.0" || *entries[0].Subnet != 22 {
+		t.Errorf("renderFastlyACL() entries = %+v", entries)
+	}
+}
+
+func TestApplyFastlyACL(t *testing.T) {
+	var ops []fastlyACLEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Fastly-Key") != "test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/service/svc/acl/acl1/entries" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`[
+				{"id": "keep", "ip": "192.30.252.0", "subnet": 22},
+				{"id": "stale", "ip": "1.2.3.4", "subnet": null}
+			]`))
+		case http.MethodPatch:
+			var body map[string][]fastlyACLEntry
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &body)
+			ops = append(ops, body["entries"]...)
+			w.Write([]byte(`{"status": "ok"}`))
+		}
+	}))
+	defer server.Close()
+
+	oldURL := fastlyAPIURL
+	fastlyAPIURL = server.URL
+	defer func() { fastlyAPIURL = oldURL }()
+	t.Setenv("FASTLY_API_TOKEN", "test-token")
+
+	opts := exportOptions{
+		Areas:  testAreas()[:1],
+		Fastly: fastlyOptions{Service: "svc", ACL: "acl1"},
+	}
+	if err := applyFastlyACL(opts); err != nil {
+		t.Fatalf("applyFastlyACL() error = %v", err)
+	}
+
+	if len(ops) != 2 {
+		t.Fatalf("applyFastlyACL() ops = %+v, want 2", ops)
+	}
+	if ops[0].Op != "delete" || ops[0].ID != "stale" {
+		t.Errorf("applyFastlyACL() first op = %+v, want delete of stale", ops[0])
+	}
+	if ops[1].Op != "create" || ops[1].IP != "2a0a:a440::" {
+		t.Errorf("applyFastlyACL() second op = %+v, want create of 2a0a:a440::", ops[1])
+	}
+
+	t.Setenv("FASTLY_API_TOKEN", "wrong")
+	if err := applyFastlyACL(opts); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("applyFastlyACL() error = %v, want status 401", err)
+	}
+}
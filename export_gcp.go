@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("gcp-firewall-gcloud", "gcloud command creating a GCP firewall rule", applyingExporter{renderGCPFirewallGcloud, applyGCPFirewall})
+	registerExporter("gcp-firewall-terraform", "Terraform google_compute_firewall resource", applyingExporter{renderGCPFirewallTerraform, applyGCPFirewall})
+	registerExporter("gcp-cloud-armor", "Cloud Armor security policy allowing only the ranges (JSON)", renderFunc(renderGCPCloudArmor))
+}
+
+var gcpComputeURL = "https://compute.googleapis.com/compute/v1"
+
+// gcpOptions contains the settings for the GCP export formats
+type gcpOptions struct {
+	Project   string
+	Network   string
+	Direction string
+	Allow     []string
+	Priority  int
+}
+
+// gcpAllowRule is a protocol and optional port list allowed by a firewall rule
+type gcpAllowRule struct {
+	Protocol string   `json:"IPProtocol"`
+	Ports    []string `json:"ports,omitempty"`
+}
+
+func addGCPFlags(cmd *cobra.Command) {
+	cmd.Flags().String("gcp-project", "", "GCP project ID (used with --apply)")
+	cmd.Flags().String("gcp-network", "default", "VPC network of the GCP firewall rule")
+	cmd.Flags().String("gcp-direction", "INGRESS", "Direction of the GCP firewall rule (INGRESS or EGRESS)")
+	cmd.Flags().StringSlice("gcp-allow", []string{"tcp:443"}, "Protocols and ports allowed by the GCP firewall rule, e.g. tcp:22,tcp:443")
+	cmd.Flags().Int("gcp-priority", 1000, "Priority of the GCP firewall rule or Cloud Armor rule")
+}
+
+func gcpOptionsFromFlags(cmd *cobra.Command) gcpOptions {
+	var opts gcpOptions
+	opts.Project, _ = cmd.Flags().GetString("gcp-project")
+	opts.Network, _ = cmd.Flags().GetString("gcp-network")
+	opts.Direction, _ = cmd.Flags().GetString("gcp-direction")
+	opts.Allow, _ = cmd.Flags().GetStringSlice("gcp-allow")
+	opts.Priority, _ = cmd.Flags().GetInt("gcp-priority")
+	return opts
+}
+
+// gcpAllowRules parses allow entries of the form protocol[:port[,port...]], grouping
+// ports by protocol
+func gcpAllowRules(allow []string) ([]gcpAllowRule, error) {
+	var rules []gcpAllowRule
+	index := make(map[string]int)
+	for _, entry := range allow {
+		protocol, port, hasPort := strings.Cut(entry, ":")
+		if protocol == "" || (hasPort && port == "") {
+			return nil, fmt.Errorf("invalid GCP allow rule %q: must be protocol[:port]", entry)
+		}
+
+		i, ok := index[protocol]
+		if !ok {
+			i = len(rules)
+			index[protocol] = i
+			rules = append(rules, gcpAllowRule{Protocol: protocol})
+		}
+		if hasPort {
+			rules[i].Ports = append(rules[i].Ports, port)
+		}
+	}
+	return rules, nil
+}
+
+// gcpRangesField returns the firewall field holding the ranges for the direction
+func gcpRangesField(direction string) (string, error) {
+	switch direction {
+	case "INGRESS":
+		return "sourceRanges", nil
+	case "EGRESS":
+		return "destinationRanges", nil
+	default:
+		return "", fmt.Errorf("invalid GCP firewall direction %q: must be INGRESS or EGRESS", direction)
+	}
+}
+
+// renderGCPFirewallGcloud renders a gcloud command creating the firewall rule
+func renderGCPFirewallGcloud(opts exportOptions) ([]byte, error) {
+	if _, err := gcpRangesField(opts.GCP.Direction); err != nil {
+		return nil, err
+	}
+	if _, err := gcpAllowRules(opts.GCP.Allow); err != nil {
+		return nil, err
+	}
+
+	rangesFlag := "--source-ranges"
+	if opts.GCP.Direction == "EGRESS" {
+		rangesFlag = "--destination-ranges"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "gcloud compute firewall-rules create %s \\\n", opts.Name)
+	if opts.GCP.Project != "" {
+		fmt.Fprintf(&b, "  --project=%s \\\n", opts.GCP.Project)
+	}
+	fmt.Fprintf(&b, "  --network=%s \\\n", opts.GCP.Network)
+	fmt.Fprintf(&b, "  --direction=%s \\\n", opts.GCP.Direction)
+	fmt.Fprintf(&b, "  --priority=%d \\\n", opts.GCP.Priority)
+	b.WriteString("  --action=ALLOW \\\n")
+	fmt.Fprintf(&b, "  --rules=%s \\\n", strings.Join(opts.GCP.Allow, ","))
+	b.WriteString("  --description=\"GitHub IP ranges generated by gh-check-github-ip-ranges\" \\\n")
+	fmt.Fprintf(&b, "  %s=%s\n", rangesFlag, strings.Join(uniqueRanges(opts.Areas), ","))
+	return []byte(b.String()), nil
+}
+
+// renderGCPFirewallTerraform renders a google_compute_firewall Terraform resource
+func renderGCPFirewallTerraform(opts exportOptions) ([]byte, error) {
+	field, err := gcpRangesField(opts.GCP.Direction)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := gcpAllowRules(opts.GCP.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	attr := "source_ranges"
+	if field == "destinationRanges" {
+		attr = "destination_ranges"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "resource \"google_compute_firewall\" %q {\n", strings.ReplaceAll(opts.Name, "-", "_"))
+	fmt.Fprintf(&b, "  name        = %q\n", opts.Name)
+	if opts.GCP.Project != "" {
+		fmt.Fprintf(&b, "  project     = %q\n", opts.GCP.Project)
+	}
+	fmt.Fprintf(&b, "  network     = %q\n", opts.GCP.Network)
+	fmt.Fprintf(&b, "  direction   = %q\n", opts.GCP.Direction)
+	fmt.Fprintf(&b, "  priority    = %d\n", opts.GCP.Priority)
+	b.WriteString("  description = \"GitHub IP ranges generated by gh-check-github-ip-ranges\"\n")
+	for _, rule := range rules {
+		b.WriteString("\n  allow {\n")
+		fmt.Fprintf(&b, "    protocol = %q\n", rule.Protocol)
+		if len(rule.Ports) > 0 {
+			fmt.Fprintf(&b, "    ports    = [%s]\n", quoteList(rule.Ports))
+		}
+		b.WriteString("  }\n")
+	}
+	fmt.Fprintf(&b, "\n  %s = [\n", attr)
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		fmt.Fprintf(&b, "    %q,\n", cidr)
+	}
+	b.WriteString("  ]\n")
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+// applyGCPFirewall updates the ranges of an existing firewall rule in place
+func applyGCPFirewall(opts exportOptions) error {
+	if opts.GCP.Project == "" {
+		return fmt.Errorf("--gcp-project is required with --apply")
+	}
+	field, err := gcpRangesField(opts.GCP.Direction)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/projects/%s/global/firewalls/%s", opts.GCP.Project, opts.Name)
+	return gcpRequest(http.MethodPatch, gcpComputeURL+path, map[string][]string{
+		field: uniqueRanges(opts.Areas),
+	})
+}
+
+// gcpAccessToken returns a Google Cloud access token, taken from
+// GOOGLE_OAUTH_ACCESS_TOKEN or the gcloud CLI's current login
+func gcpAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	out, err := exec.Command("gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get GCP access token (set GOOGLE_OAUTH_ACCESS_TOKEN or run 'gcloud auth login'): %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gcpRequest sends a JSON request to a Google Cloud API
+func gcpRequest(method, url string, body interface{}) error {
+	token, err := gcpAccessToken()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode GCP request: %w", err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create GCP request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update GCP resource: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GCP API returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// cloudArmorMaxRanges is the maximum number of source ranges in a basic Cloud Armor match
+const cloudArmorMaxRanges = 10
+
+// cloudArmorPolicy is a Cloud Armor security policy in gcloud import format
+type cloudArmorPolicy struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Rules       []cloudArmorRule `json:"rules"`
+}
+
+type cloudArmorRule struct {
+	Priority    int             `json:"priority"`
+	Action      string          `json:"action"`
+	Description string          `json:"description"`
+	Match       cloudArmorMatch `json:"match"`
+}
+
+type cloudArmorMatch struct {
+	VersionedExpr string                `json:"versionedExpr"`
+	Config        cloudArmorMatchConfig `json:"config"`
+}
+
+type cloudArmorMatchConfig struct {
+	SrcIPRanges []string `json:"srcIpRanges"`
+}
+
+// renderGCPCloudArmor renders a Cloud Armor security policy allowing the selected
+// areas and denying all other sources. Basic matches are limited to ten ranges,
+// so the ranges are split across consecutive rules starting at the configured priority.
+func renderGCPCloudArmor(opts exportOptions) ([]byte, error) {
+	policy := cloudArmorPolicy{
+		Name:        opts.Name,
+		Description: "GitHub IP ranges generated by gh-check-github-ip-ranges",
+	}
+
+	ranges := uniqueRanges(opts.Areas)
+	for i := 0; i < len(ranges); i += cloudArmorMaxRanges {
+		end := min(i+cloudArmorMaxRanges, len(ranges))
+		policy.Rules = append(policy.Rules, cloudArmorRule{
+			Priority:    opts.GCP.Priority + i/cloudArmorMaxRanges,
+			Action:      "allow",
+			Description: "Allow GitHub",
+			Match: cloudArmorMatch{
+				VersionedExpr: "SRC_IPS_V1",
+				Config:        cloudArmorMatchConfig{SrcIPRanges: ranges[i:end]},
+			},
+		})
+	}
+
+	policy.Rules = append(policy.Rules, cloudArmorRule{
+		Priority:    2147483647,
+		Action:      "deny(403)",
+		Description: "Deny all other sources",
+		Match: cloudArmorMatch{
+			VersionedExpr: "SRC_IPS_V1",
+			Config:        cloudArmorMatchConfig{SrcIPRanges: []string{"*"}},
+		},
+	})
+
+	return marshalExportJSON(policy)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGCPAllowRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		want    []gcpAllowRule
+		wantErr bool
+	}{
+		{
+			name:  "Ports grouped by protocol",
+			allow: []string{"tcp:22", "tcp:443", "icmp"},
+			want: []gcpAllowRule{
+				{Protocol: "tcp", Ports: []string{"22", "443"}},
+				{Protocol: "icmp"},
+			},
+		},
+		{
+			name:    "Missing port",
+			allow:   []string{"tcp:"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing protocol",
+			allow:   []string{":443"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gcpAllowRules(tt.allow)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("gcpAllowRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gcpAllowRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderGCPFirewall(t *testing.T) {
+	opts := exportOptions{
+		Areas: testAreas()[:1],
+		Name:  "github-hooks",
+		GCP: gcpOptions{
+			Project:   "my-project",
+			Network:   "default",
+			Direction: "INGRESS",
+			Allow:     []string{"tcp:443"},
+			Priority:  1000,
+		},
+	}
+
+	out, err := renderGCPFirewallGcloud(opts)
+	if err != nil {
+		t.Fatalf("renderGCPFirewallGcloud() error = %v", err)
+	}
+	for _, want := range []string{
+		"gcloud compute firewall-rules create github-hooks",
+		"--project=my-project",
+		"--rules=tcp:443",
+		"--source-ranges=192.30.252.0/22,2a0a:a440::/29\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderGCPFirewallGcloud() = %s, should contain %q", out, want)
+		}
+	}
+
+	out, err = renderGCPFirewallTerraform(opts)
+	if err != nil {
+		t.Fatalf("renderGCPFirewallTerraform() error = %v", err)
+	}
+	for _, want := range []string{
+		`resource "google_compute_firewall" "github_hooks" {`,
+		`    ports    = ["443"]`,
+		`  source_ranges = [`,
+		`    "2a0a:a440::/29",`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderGCPFirewallTerraform() = %s, should contain %q", out, want)
+		}
+	}
+
+	opts.GCP.Direction = "EGRESS"
+	out, err = renderGCPFirewallTerraform(opts)
+	if err != nil {
+		t.Fatalf("renderGCPFirewallTerraform() error = %v", err)
+	}
+	if !strings.Contains(string(out), "destination_ranges = [") {
+		t.Errorf("renderGCPFirewallTerraform() = %s, should use destination_ranges", out)
+	}
+
+	opts.GCP.Direction = "SIDEWAYS"
+	if _, err := renderGCPFirewallGcloud(opts); err == nil {
+		t.Errorf("renderGCPFirewallGcloud() with invalid direction should fail")
+	}
+}
+
+func TestApplyGCPFirewall(t *testing.T) {
+	var gotMethod, gotPath string
+	var gotBody map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	oldURL := gcpComputeURL
+	gcpComputeURL = server.URL
+	defer func() { gcpComputeURL = oldURL }()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "test-token")
+
+	opts := exportOptions{
+		Areas: testAreas()[:1],
+		Name:  "github-hooks",
+		GCP:   gcpOptions{Project: "my-project", Direction: "INGRESS"},
+	}
+	if err := applyGCPFirewall(opts); err != nil {
+		t.Fatalf("applyGCPFirewall() error = %v", err)
+	}
+
+	if gotMethod != http.MethodPatch {
+		t.Errorf("applyGCPFirewall() method = %q, want PATCH", gotMethod)
+	}
+	if gotPath != "/projects/my-project/global/firewalls/github-hooks" {
+		t.Errorf("applyGCPFirewall() path = %q", gotPath)
+	}
+	want := map[string][]string{"sourceRanges": {"192.30.252.0/22", "2a0a:a440::/29"}}
+	if !reflect.DeepEqual(gotBody, want) {
+		t.Errorf("applyGCPFirewall() body = %v, want %v", gotBody, want)
+	}
+
+	opts.GCP.Project = ""
+	if err := applyGCPFirewall(opts); err == nil {
+		t.Errorf("applyGCPFirewall() without project should fail")
+	}
+}
+
+func TestRenderGCPCloudArmor(t *testing.T) {
+	var ranges []string
+	for i := 0; i < 12; i++ {
+		ranges = append(ranges, fmt.Sprintf("192.30.%d.0/24", i))
+	}
+	opts := exportOptions{
+		Areas: []Area{{Key: "hooks", Name: "Hooks", Ranges: ranges}},
+		Name:  "github-hooks",
+		GCP:   gcpOptions{Priority: 1000},
+	}
+
+	out, err := renderGCPCloudArmor(opts)
+	if err != nil {
+		t.Fatalf("renderGCPCloudArmor() error = %v", err)
+	}
+
+	var policy cloudArmorPolicy
+	if err := json.Unmarshal(out, &policy); err != nil {
+		t.Fatalf("renderGCPCloudArmor() produced invalid JSON: %v", err)
+	}
+	if len(policy.Rules) != 3 {
+		t.Fatalf("renderGCPCloudArmor() rules = %d, want 3", len(policy.Rules))
+	}
+
+	first, second, deny := policy.Rules[0], policy.Rules[1], policy.Rules[2]
+	if first.Priority != 1000 || len(first.Match.Config.SrcIPRanges) != 10 || first.Action != "allow" {
+		t.Errorf("renderGCPCloudArmor() first rule = %+v", first)
+	}
+	if second.Priority != 1001 || !reflect.DeepEqual(second.Match.Config.SrcIPRanges, ranges[10:]) {
+		t.Errorf("renderGCPCloudArmor() second rule = %+v", second)
+	}
+	if deny.Action != "deny(403)" || !reflect.DeepEqual(deny.Match.Config.SrcIPRanges, []string{"*"}) {
+		t.Errorf("renderGCPCloudArmor() default rule = %+v", deny)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerExporter("terraform", "Terraform locals mapping areas to ranges", renderFunc(renderTerraformLocals))
+	registerExporter("cloudformation", "CloudFormation Mappings section (JSON)", renderFunc(renderCloudFormationMappings))
+	registerExporter("cdk-context", "AWS CDK context file (JSON)", renderFunc(renderCDKContext))
+	registerExporter("ansible", "Ansible variables file (YAML)", renderFunc(renderAnsibleVars))
+	registerExporter("hiera", "Puppet Hiera data (YAML)", renderFunc(renderHiera))
+	registerExporter("chef-databag", "Chef data bag item (JSON)", renderFunc(renderChefDataBag))
+}
+
+// identifierName converts the export name into an identifier usable by
+// configuration languages that don't allow dashes
+func identifierName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// renderTerraformLocals renders a Terraform file with a locals map of area to
+// CIDR list, plus the snapshot time of the ranges
+func renderTerraformLocals(opts exportOptions) ([]byte, error) {
+	local := identifierName(opts.Name) + "_ip_ranges"
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	b.WriteString("locals {\n")
+	fmt.Fprintf(&b, "  %s_snapshot = %q\n\n", local, opts.Snapshot.Format(time.RFC3339))
+	fmt.Fprintf(&b, "  %s = {\n", local)
+	for _, area := range opts.Areas {
+		fmt.Fprintf(&b, "    %s = [\n", area.Key)
+		for _, cidr := range area.Ranges {
+			fmt.Fprintf(&b, "      %q,\n", cidr)
+		}
+		b.WriteString("    ]\n")
+	}
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
+
+// cfnName converts a name to the alphanumeric form CloudFormation requires,
+// capitalizing each word, e.g. "actions-ipv4" becomes "ActionsIpv4"
+func cfnName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// renderCloudFormationMappings renders a CloudFormation Mappings section with the
+// IPv4 and IPv6 ranges of each area, for use with Fn::FindInMap
+func renderCloudFormationMappings(opts exportOptions) ([]byte, error) {
+	mapping := make(map[string]map[string][]string)
+	for _, area := range opts.Areas {
+		ipv4 := ipv4Ranges(area.Ranges)
+		ipv6 := ipv6Ranges(area.Ranges)
+		if ipv4 == nil {
+			ipv4 = []string{}
+		}
+		if ipv6 == nil {
+			ipv6 = []string{}
+		}
+		mapping[cfnName(area.Name)] = map[string][]string{"IPv4": ipv4, "IPv6": ipv6}
+	}
+
+	return marshalExportJSON(map[string]interface{}{
+		"Mappings": map[string]interface{}{
+			cfnName(opts.Name) + "IPRanges": mapping,
+		},
+	})
+}
+
+// renderCDKContext renders a CDK context file with the ranges of each area and
+// the snapshot time, read in CDK apps with tryGetContext
+func renderCDKContext(opts exportOptions) ([]byte, error) {
+	ranges := make(map[string][]string)
+	for _, area := range opts.Areas {
+		ranges[area.Key] = area.Ranges
+	}
+
+	return marshalExportJSON(map[string]interface{}{
+		opts.Name + "-ip-ranges":          ranges,
+		opts.Name + "-ip-ranges-snapshot": opts.Snapshot.Format(time.RFC3339),
+	})
+}
+
+// renderAnsibleVars renders an Ansible variables file with the ranges keyed by
+// area, along with when it was generated and the hash of the source data so
+// roles can skip work when nothing changed
+func renderAnsibleVars(opts exportOptions) ([]byte, error) {
+	prefix := identifierName(opts.Name) + "_ip_ranges"
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "%s_generated: %q\n", prefix, opts.Generated.Format(time.RFC3339))
+	fmt.Fprintf(&b, "%s_source_sha256: %q\n", prefix, opts.SourceHash)
+	fmt.Fprintf(&b, "%s:\n", prefix)
+	writeYAMLAreaRanges(&b, opts.Areas)
+	return []byte(b.String()), nil
+}
+
+// writeYAMLAreaRanges writes a YAML mapping of area to ranges, indented one level
+func writeYAMLAreaRanges(b *strings.Builder, areas []Area) {
+	for _, area := range areas {
+		if len(area.Ranges) == 0 {
+			fmt.Fprintf(b, "  %s: []\n", area.Key)
+			continue
+		}
+		fmt.Fprintf(b, "  %s:\n", area.Key)
+		for _, cidr := range area.Ranges {
+			fmt.Fprintf(b, "    - %q\n", cidr)
+		}
+	}
+}
+
+// renderHiera renders Hiera data with the ranges keyed by area under a
+// namespaced key, along with the generation time and source hash
+func renderHiera(opts exportOptions) ([]byte, error) {
+	prefix := identifierName(opts.Name) + "_ip_ranges"
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "%s::generated: %q\n", prefix, opts.Generated.Format(time.RFC3339))
+	fmt.Fprintf(&b, "%s::source_sha256: %q\n", prefix, opts.SourceHash)
+	fmt.Fprintf(&b, "%s::ranges:\n", prefix)
+	writeYAMLAreaRanges(&b, opts.Areas)
+	return []byte(b.String()), nil
+}
+
+// renderChefDataBag renders a Chef data bag item with the ranges keyed by area,
+// along with the generation time and source hash
+func renderChefDataBag(opts exportOptions) ([]byte, error) {
+	ranges := make(map[string][]string)
+	for _, area := range opts.Areas {
+		ranges[area.Key] = area.Ranges
+		if area.Ranges == nil {
+			ranges[area.Key] = []string{}
+		}
+	}
+
+	return marshalExportJSON(map[string]interface{}{
+		"id":            opts.Name,
+		"generated":     opts.Generated.Format(time.RFC3339),
+		"source_sha256": opts.SourceHash,
+		"ranges":        ranges,
+	})
+}
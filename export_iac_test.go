@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRenderTerraformLocals(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[1:],
+		Name:     "github",
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := renderTerraformLocals(opts)
+	if err != nil {
+		t.Fatalf("renderTerraformLocals() error = %v", err)
+	}
+	want := `# GitHub IP ranges generated by gh-check-github-ip-ranges
+locals {
+  github_ip_ranges_snapshot = "2026-10-14T12:00:00Z"
+
+  github_ip_ranges = {
+    web = [
+      "192.30.252.0/22",
+      "140.82.112.0/20",
+    ]
+    actions_ipv4 = [
+      "4.148.0.0/16",
+    ]
+  }
+}
+`
+	if string(out) != want {
+		t.Errorf("renderTerraformLocals() = %s, want %s", out, want)
+	}
+}
+
+func TestCfnName(t *testing.T) {
+	tests := map[string]string{
+		"github":       "Github",
+		"Actions IPv4": "ActionsIPv4",
+		"github-hooks": "GithubHooks",
+		"API":          "API",
+	}
+	for in, want := range tests {
+		if got := cfnName(in); got != want {
+			t.Errorf("cfnName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRenderCloudFormationMappings(t *testing.T) {
+	out, err := renderCloudFormationMappings(exportOptions{Areas: testAreas(), Name: "github"})
+	if err != nil {
+		t.Fatalf("renderCloudFormationMappings() error = %v", err)
+	}
+
+	var got struct {
+		Mappings map[string]map[string]map[string][]string
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("renderCloudFormationMappings() produced invalid JSON: %v", err)
+	}
+	mapping := got.Mappings["GithubIPRanges"]
+	if !reflect.DeepEqual(mapping["Hooks"]["IPv4"], []string{"192.30.252.0/22"}) ||
+		!reflect.DeepEqual(mapping["Hooks"]["IPv6"], []string{"2a0a:a440::/29"}) {
+		t.Errorf("renderCloudFormationMappings() Hooks = %v", mapping["Hooks"])
+	}
+	if ipv6, ok := mapping["ActionsIPv4"]["IPv6"]; !ok || len(ipv6) != 0 {
+		t.Errorf("renderCloudFormationMappings() ActionsIPv4 IPv6 = %v, want empty list", ipv6)
+	}
+}
+
+func TestRenderCDKContext(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[:1],
+		Name:     "github",
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+	}
+	out, err := renderCDKContext(opts)
+	if err != nil {
+		t.Fatalf("renderCDKContext() error = %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("renderCDKContext() produced invalid JSON: %v", err)
+	}
+	if got["github-ip-ranges-snapshot"] != "2026-10-14T12:00:00Z" {
+		t.Errorf("renderCDKContext() snapshot = %v", got["github-ip-ranges-snapshot"])
+	}
+	ranges, _ := got["github-ip-ranges"].(map[string]interface{})
+	if len(ranges["hooks"].([]interface{})) != 2 {
+		t.Errorf("renderCDKContext() ranges = %v", ranges)
+	}
+}
+
+func TestRenderAnsibleVars(t *testing.T) {
+	opts := exportOptions{
+		Areas:      append(testAreas()[:1], Area{Key: "pages", Name: "Pages"}),
+		Name:       "github",
+		Generated:  time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		SourceHash: "abc123",
+	}
+
+	out, err := renderAnsibleVars(opts)
+	if err != nil {
+		t.Fatalf("renderAnsibleVars() error = %v", err)
+	}
+	want := `# GitHub IP ranges generated by gh-check-github-ip-ranges
+---
+github_ip_ranges_generated: "2026-10-14T12:00:00Z"
+github_ip_ranges_source_sha256: "abc123"
+github_ip_ranges:
+  hooks:
+    - "192.30.252.0/22"
+    - "2a0a:a440::/29"
+  pages: []
+`
+	if string(out) != want {
+		t.Errorf("renderAnsibleVars() = %s, want %s", out, want)
+	}
+}
+
+func TestRenderHieraAndChefDataBag(t *testing.T) {
+	opts := exportOptions{
+		Areas:      append(testAreas()[:1], Area{Key: "pages", Name: "Pages"}),
+		Name:       "github",
+		Generated:  time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		SourceHash: "abc123",
+	}
+
+	out, err := renderHiera(opts)
+	if err != nil {
+		t.Fatalf("renderHiera() error = %v", err)
+	}
+	want := `# GitHub IP ranges generated by gh-check-github-ip-ranges
+---
+github_ip_ranges::generated: "2026-10-14T12:00:00Z"
+github_ip_ranges::source_sha256: "abc123"
+github_ip_ranges::ranges:
+  hooks:
+    - "192.30.252.0/22"
+    - "2a0a:a440::/29"
+  pages: []
+`
+	if string(out) != want {
+		t.Errorf("renderHiera() = %s, want %s", out, want)
+	}
+
+	out, err = renderChefDataBag(opts)
+	if err != nil {
+		t.Fatalf("renderChefDataBag() error = %v", err)
+	}
+	var item struct {
+		ID           string              `json:"id"`
+		Generated    string              `json:"generated"`
+		SourceSHA256 string              `json:"source_sha256"`
+		Ranges       map[string][]string `json:"ranges"`
+	}
+	if err := json.Unmarshal(out, &item); err != nil {
+		t.Fatalf("renderChefDataBag() produced invalid JSON: %v", err)
+	}
+	if item.ID != "github" || item.Generated != "2026-10-14T12:00:00Z" || item.SourceSHA256 != "abc123" {
+		t.Errorf("renderChefDataBag() item = %+v", item)
+	}
+	if len(item.Ranges["hooks"]) != 2 || item.Ranges["pages"] == nil {
+		t.Errorf("renderChefDataBag() ranges = %v", item.Ranges)
+	}
+}
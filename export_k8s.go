@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("k8s-networkpolicy", "Kubernetes NetworkPolicy with ipBlock peers (YAML)", renderFunc(renderK8sNetworkPolicy))
+	registerExporter("cilium", "CiliumNetworkPolicy with CIDR sets (YAML)", renderFunc(renderCiliumNetworkPolicy))
+	registerExporter("calico", "Calico GlobalNetworkSet (YAML)", applyingExporter{renderCalicoGlobalNetworkSet, applyCalicoGlobalNetworkSet})
+	registerExporter("istio", "Istio ServiceEntry resources for the ranges and domains (YAML)", renderFunc(renderIstioServiceEntries))
+}
+
+// k8sServiceAccountDir holds the credentials of the pod's service account when running in a cluster
+var k8sServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// k8sFieldManager identifies this tool as the owner of applied fields
+const k8sFieldManager = "gh-check-github-ip-ranges"
+
+// k8sOptions contains the settings for the Kubernetes export formats
+type k8sOptions struct {
+	Namespace   string
+	PodSelector string
+	PolicyType  string
+	Server      string
+	Ports       []int
+}
+
+func addK8sFlags(cmd *cobra.Command) {
+	cmd.Flags().String("k8s-namespace", "default", "Namespace of the generated Kubernetes resources")
+	cmd.Flags().String("k8s-pod-selector", "", "Label selector of the pods the policy applies to, e.g. app=runner (default all pods)")
+	cmd.Flags().String("k8s-policy-type", "egress", "Traffic the policy restricts (ingress, egress or both)")
+	cmd.Flags().IntSlice("k8s-ports", []int{443}, "Ports of the generated Istio ServiceEntry resources")
+	cmd.Flags().String("k8s-server", "", "Kubernetes API server, e.g. from kubectl proxy (used with --apply, default in-cluster)")
+}
+
+func k8sOptionsFromFlags(cmd *cobra.Command) k8sOptions {
+	var opts k8sOptions
+	opts.Namespace, _ = cmd.Flags().GetString("k8s-namespace")
+	opts.PodSelector, _ = cmd.Flags().GetString("k8s-pod-selector")
+	opts.PolicyType, _ = cmd.Flags().GetString("k8s-policy-type")
+	opts.Server, _ = cmd.Flags().GetString("k8s-server")
+	opts.Ports, _ = cmd.Flags().GetIntSlice("k8s-ports")
+	return opts
+}
+
+// parseLabelSelector parses a selector of the form key=value[,key=value...]
+func parseLabelSelector(selector string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if strings.TrimSpace(selector) == "" {
+		return labels, nil
+	}
+
+	for _, part := range strings.Split(selector, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label selector %q: must be key=value[,key=value]", selector)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// k8sPolicyDirections returns whether a policy type covers ingress and egress
+func k8sPolicyDirections(policyType string) (ingress, egress bool, err error) {
+	switch strings.ToLower(policyType) {
+	case "ingress":
+		return true, false, nil
+	case "egress":
+		return false, true, nil
+	case "both":
+		return true, true, nil
+	default:
+		return false, false, fmt.Errorf("invalid policy type %q: must be ingress, egress or both", policyType)
+	}
+}
+
+// writeYAMLLabels writes labels as a YAML mapping under key, sorted and indented by indent spaces
+func writeYAMLLabels(b *strings.Builder, key string, labels map[string]string, indent int) {
+	pad := strings.Repeat(" ", indent)
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(b, "%s%s:\n", pad, key)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s  %s: %q\n", pad, k, labels[k])
+	}
+}
+
+// writeK8sSelector writes a label selector matching labels, or selecting everything if there are none
+func writeK8sSelector(b *strings.Builder, key string, labels map[string]string, indent int) {
+	pad := strings.Repeat(" ", indent)
+	if len(labels) == 0 {
+		fmt.Fprintf(b, "%s%s: {}\n", pad, key)
+		return
+	}
+	fmt.Fprintf(b, "%s%s:\n", pad, key)
+	writeYAMLLabels(b, "matchLabels", labels, indent+2)
+}
+
+// renderK8sNetworkPolicy renders a NetworkPolicy whose ipBlock peers are the ranges
+func renderK8sNetworkPolicy(opts exportOptions) ([]byte, error) {
+	labels, err := parseLabelSelector(opts.K8s.PodSelector)
+	if err != nil {
+		return nil, err
+	}
+	ingress, egress, err := k8sPolicyDirections(opts.K8s.PolicyType)
+	if err != nil {
+		return nil, err
+	}
+
+	writePeers := func(b *strings.Builder, peerKey string) {
+		fmt.Fprintf(b, "  - %s:\n", peerKey)
+		for _, cidr := range uniqueRanges(opts.Areas) {
+			b.WriteString("    - ipBlock:\n")
+			fmt.Fprintf(b, "        cidr: %q\n", cidr)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	b.WriteString("apiVersion: networking.k8s.io/v1\n")
+	b.WriteString("kind: NetworkPolicy\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", opts.Name)
+	fmt.Fprintf(&b, "  namespace: %s\n", opts.K8s.Namespace)
+	b.WriteString("spec:\n")
+	writeK8sSelector(&b, "podSelector", labels, 2)
+	b.WriteString("  policyTypes:\n")
+	if ingress {
+		b.WriteString("  - Ingress\n")
+	}
+	if egress {
+		b.WriteString("  - Egress\n")
+	}
+	if ingress {
+		b.WriteString("  ingress:\n")
+		writePeers(&b, "from")
+	}
+	if egress {
+		b.WriteString("  egress:\n")
+		writePeers(&b, "to")
+	}
+	return []byte(b.String()), nil
+}
+
+// renderCiliumNetworkPolicy renders a CiliumNetworkPolicy whose CIDR sets are the
+// ranges, labeled with the date of the snapshot they were taken from
+func renderCiliumNetworkPolicy(opts exportOptions) ([]byte, error) {
+	labels, err := parseLabelSelector(opts.K8s.PodSelector)
+	if err != nil {
+		return nil, err
+	}
+	ingress, egress, err := k8sPolicyDirections(opts.K8s.PolicyType)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotDate := opts.Snapshot.Format("2006-01-02")
+
+	writeCIDRSet := func(b *strings.Builder, key string) {
+		fmt.Fprintf(b, "  - %s:\n", key)
+		for _, cidr := range uniqueRanges(opts.Areas) {
+			fmt.Fprintf(b, "    - cidr: %q\n", cidr)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	b.WriteString("apiVersion: cilium.io/v2\n")
+	b.WriteString("kind: CiliumNetworkPolicy\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", opts.Name)
+	fmt.Fprintf(&b, "  namespace: %s\n", opts.K8s.Namespace)
+	b.WriteString("  labels:\n")
+	b.WriteString("    app.kubernetes.io/managed-by: gh-check-github-ip-ranges\n")
+	fmt.Fprintf(&b, "    github-ip-ranges/snapshot-date: %q\n", snapshotDate)
+	b.WriteString("spec:\n")
+	fmt.Fprintf(&b, "  description: \"GitHub IP ranges as of %s\"\n", snapshotDate)
+	writeK8sSelector(&b, "endpointSelector", labels, 2)
+	if ingress {
+		b.WriteString("  ingress:\n")
+		writeCIDRSet(&b, "fromCIDRSet")
+	}
+	if egress {
+		b.WriteString("  egress:\n")
+		writeCIDRSet(&b, "toCIDRSet")
+	}
+	return []byte(b.String()), nil
+}
+
+// calicoNetworkSetLabel is the label Calico policies can select the network set by
+const calicoNetworkSetLabel = "github-ips"
+
+// calicoGlobalNetworkSet builds the GlobalNetworkSet resource for the ranges
+func calicoGlobalNetworkSet(opts exportOptions) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "projectcalico.org/v3",
+		"kind":       "GlobalNetworkSet",
+		"metadata": map[string]interface{}{
+			"name": opts.Name,
+			"labels": map[string]string{
+				calicoNetworkSetLabel: opts.Name,
+			},
+		},
+		"spec": map[string]interface{}{
+			"nets": uniqueRanges(opts.Areas),
+		},
+	}
+}
+
+// renderCalicoGlobalNetworkSet renders a Calico GlobalNetworkSet containing the ranges
+func renderCalicoGlobalNetworkSet(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	b.WriteString("apiVersion: projectcalico.org/v3\n")
+	b.WriteString("kind: GlobalNetworkSet\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", opts.Name)
+	writeYAMLLabels(&b, "labels", map[string]string{calicoNetworkSetLabel: opts.Name}, 2)
+	b.WriteString("spec:\n")
+	b.WriteString("  nets:\n")
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		fmt.Fprintf(&b, "  - %q\n", cidr)
+	}
+	return []byte(b.String()), nil
+}
+
+// applyCalicoGlobalNetworkSet creates or updates the GlobalNetworkSet with a
+// server-side apply through the Calico API server
+func applyCalicoGlobalNetworkSet(opts exportOptions) error {
+	path := "/apis/projectcalico.org/v3/globalnetworksets/" + opts.Name
+	return k8sApply(opts.K8s, path, calicoGlobalNetworkSet(opts))
+}
+
+// k8sConnection returns the API server, bearer token and client to use for the
+// Kubernetes API, from --k8s-server and KUBE_TOKEN or the in-cluster service account
+func k8sConnection(opts k8sOptions) (string, string, *http.Client, error) {
+	if opts.Server != "" {
+		return strings.TrimSuffix(opts.Server, "/"), os.Getenv("KUBE_TOKEN"), http.DefaultClient, nil
+	}
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return "", "", nil, fmt.Errorf("--k8s-server is required with --apply when not running in a cluster")
+	}
+
+	token, err := os.ReadFile(filepath.Join(k8sServiceAccountDir, "token"))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	ca, err := os.ReadFile(filepath.Join(k8sServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+	return "https://" + host + ":" + port, strings.TrimSpace(string(token)), client, nil
+}
+
+// k8sApply server-side applies resource at path
+func k8sApply(opts k8sOptions, path string, resource interface{}) error {
+	server, token, client, err := k8sConnection(opts)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(resource)
+	if err != nil {
+		return fmt.Errorf("failed to encode Kubernetes resource: %w", err)
+	}
+
+	url := server + path + "?fieldManager=" + k8sFieldManager + "&force=true"
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/apply-patch+yaml")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to apply Kubernetes resource: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("Kubernetes API returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// renderIstioServiceEntries renders MESH_EXTERNAL ServiceEntry resources covering
+// the ranges and, if any are published for the selected areas, their domains
+func renderIstioServiceEntries(opts exportOptions) ([]byte, error) {
+	writeEntry := func(b *strings.Builder, name string, hosts, addresses []string) {
+		b.WriteString("apiVersion: networking.istio.io/v1\n")
+		b.WriteString("kind: ServiceEntry\n")
+		b.WriteString("metadata:\n")
+		fmt.Fprintf(b, "  name: %s\n", name)
+		fmt.Fprintf(b, "  namespace: %s\n", opts.K8s.Namespace)
+		b.WriteString("spec:\n")
+		b.WriteString("  hosts:\n")
+		for _, host := range hosts {
+			fmt.Fprintf(b, "  - %q\n", host)
+		}
+		if len(addresses) > 0 {
+			b.WriteString("  addresses:\n")
+			for _, address := range addresses {
+				fmt.Fprintf(b, "  - %q\n", address)
+			}
+		}
+		b.WriteString("  ports:\n")
+		for _, port := range opts.K8s.Ports {
+			name, protocol := fmt.Sprintf("tcp-%d", port), "TCP"
+			if port == 443 {
+				name, protocol = "tls-443", "TLS"
+			}
+			fmt.Fprintf(b, "  - number: %d\n    name: %s\n    protocol: %s\n", port, name, protocol)
+		}
+		b.WriteString("  location: MESH_EXTERNAL\n")
+		b.WriteString("  resolution: NONE\n")
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	writeEntry(&b, opts.Name+"-ips", []string{opts.Name + ".ip-ranges.github.internal"}, uniqueRanges(opts.Areas))
+	if len(opts.Domains) > 0 {
+		b.WriteString("---\n")
+		writeEntry(&b, opts.Name+"-domains", opts.Domains, nil)
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{name: "Empty", selector: "", want: map[string]string{}},
+		{name: "Single label", selector: "app=runner", want: map[string]string{"app": "runner"}},
+		{name: "Multiple labels", selector: "app=runner, tier=ci", want: map[string]string{"app": "runner", "tier": "ci"}},
+		{name: "Missing value separator", selector: "app", wantErr: true},
+		{name: "Missing key", selector: "=runner", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLabelSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLabelSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLabelSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderK8sNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		k8s        k8sOptions
+		wantErr    bool
+		wantOutput []string
+		dontWant   []string
+	}{
+		{
+			name: "Egress for all pods",
+			k8s:  k8sOptions{Namespace: "default", PolicyType: "egress"},
+			wantOutput: []string{
+				"kind: NetworkPolicy\n",
+				"  namespace: default\n",
+				"  podSelector: {}\n",
+				"  policyTypes:\n  - Egress\n  egress:\n  - to:\n    - ipBlock:\n        cidr: \"192.30.252.0/22\"\n",
+			},
+			dontWant: []string{"ingress:"},
+		},
+		{
+			name: "Both directions with selector",
+			k8s:  k8sOptions{Namespace: "runners", PodSelector: "tier=ci,app=runner", PolicyType: "both"},
+			wantOutput: []string{
+				"  podSelector:\n    matchLabels:\n      app: \"runner\"\n      tier: \"ci\"\n",
+				"  - Ingress\n  - Egress\n",
+				"  ingress:\n  - from:\n",
+				"  egress:\n  - to:\n",
+			},
+		},
+		{
+			name:    "Invalid policy type",
+			k8s:     k8sOptions{PolicyType: "sideways"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := renderK8sNetworkPolicy(exportOptions{Areas: testAreas()[:1], Name: "github-hooks", K8s: tt.k8s})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderK8sNetworkPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("renderK8sNetworkPolicy() = %s, should contain %q", out, want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if strings.Contains(string(out), dontWant) {
+					t.Errorf("renderK8sNetworkPolicy() = %s, should not contain %q", out, dontWant)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderCiliumNetworkPolicy(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[:1],
+		Name:     "github-hooks",
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		K8s:      k8sOptions{Namespace: "runners", PodSelector: "app=runner", PolicyType: "egress"},
+	}
+
+	out, err := renderCiliumNetworkPolicy(opts)
+	if err != nil {
+		t.Fatalf("renderCiliumNetworkPolicy() error = %v", err)
+	}
+	for _, want := range []string{
+		"kind: CiliumNetworkPolicy\n",
+		"    github-ip-ranges/snapshot-date: \"2026-10-14\"\n",
+		"  description: \"GitHub IP ranges as of 2026-10-14\"\n",
+		"  endpointSelector:\n    matchLabels:\n      app: \"runner\"\n",
+		"  egress:\n  - toCIDRSet:\n    - cidr: \"192.30.252.0/22\"\n    - cidr: \"2a0a:a440::/29\"\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderCiliumNetworkPolicy() = %s, should contain %q", out, want)
+		}
+	}
+	if strings.Contains(string(out), "fromCIDRSet") {
+		t.Errorf("renderCiliumNetworkPolicy() = %s, should not contain ingress rules", out)
+	}
+}
+
+func TestRenderCalicoGlobalNetworkSet(t *testing.T) {
+	out, err := renderCalicoGlobalNetworkSet(exportOptions{Areas: testAreas()[:1], Name: "github-hooks"})
+	if err != nil {
+		t.Fatalf("renderCalicoGlobalNetworkSet() error = %v", err)
+	}
+	want := "# GitHub IP ranges generated by gh-check-github-ip-ranges\n" +
+		"apiVersion: projectcalico.org/v3\n" +
+		"kind: GlobalNetworkSet\n" +
+		"metadata:\n" +
+		"  name: github-hooks\n" +
+		"  labels:\n" +
+		"    github-ips: \"github-hooks\"\n" +
+		"spec:\n" +
+		"  nets:\n" +
+		"  - \"192.30.252.0/22\"\n" +
+		"  - \"2a0a:a440::/29\"\n"
+	if string(out) != want {
+		t.Errorf("renderCalicoGlobalNetworkSet() = %q, want %q", out, want)
+	}
+}
+
+func TestApplyCalicoGlobalNetworkSet(t *testing.T) {
+	var gotPath, gotQuery, gotContentType, gotAuth string
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		gotContentType = r.Header.Get("Content-Type")
+		gotAuth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &gotBody)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	t.Setenv("KUBE_TOKEN", "test-token")
+	opts := exportOptions{
+		Areas: testAreas()[:1],
+		Name:  "github-hooks",
+		K8s:   k8sOptions{Server: server.URL + "/"},
+	}
+	if err := applyCalicoGlobalNetworkSet(opts); err != nil {
+		t.Fatalf("applyCalicoGlobalNetworkSet() error = %v", err)
+	}
+
+	if gotPath != "/apis/projectcalico.org/v3/globalnetworksets/github-hooks" {
+		t.Errorf("applyCalicoGlobalNetworkSet() path = %q", gotPath)
+	}
+	if gotQuery != "fieldManager=gh-check-github-ip-ranges&force=true" {
+		t.Errorf("applyCalicoGlobalNetworkSet() query = %q", gotQuery)
+	}
+	if gotContentType != "application/apply-patch+yaml" || gotAuth != "Bearer test-token" {
+		t.Errorf("applyCalicoGlobalNetworkSet() headers = %q, %q", gotContentType, gotAuth)
+	}
+	if gotBody["kind"] != "GlobalNetworkSet" {
+		t.Errorf("applyCalicoGlobalNetworkSet() body = %v", gotBody)
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	opts.K8s.Server = ""
+	if err := applyCalicoGlobalNetworkSet(opts); err == nil {
+		t.Errorf("applyCalicoGlobalNetworkSet() outside a cluster without --k8s-server should fail")
+	}
+}
+
+func TestRenderIstioServiceEntries(t *testing.T) {
+	opts := exportOptions{
+		Areas: testAreas()[:1],
+		Name:  "github",
+		K8s:   k8sOptions{Namespace: "istio-system", Ports: []int{443, 22}},
+	}
+
+	out, err := renderIstioServiceEntries(opts)
+	if err != nil {
+		t.Fatalf("renderIstioServiceEntries() error = %v", err)
+	}
+	for _, want := range []string{
+		"  name: github-ips\n  namespace: istio-system\n",
+		"  addresses:\n  - \"192.30.252.0/22\"\n  - \"2a0a:a440::/29\"\n",
+		"  - number: 443\n    name: tls-443\n    protocol: TLS\n",
+		"  - number: 22\n    name: tcp-22\n    protocol: TCP\n",
+		"  location: MESH_EXTERNAL\n  resolution: NONE\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderIstioServiceEntries() = %s, should contain %q", out, want)
+		}
+	}
+	if strings.Contains(string(out), "---") {
+		t.Errorf("renderIstioServiceEntries() without domains should produce a single entry")
+	}
+
+	opts.Domains = []string{"*.actions.githubusercontent.com"}
+	out, err = renderIstioServiceEntries(opts)
+	if err != nil {
+		t.Fatalf("renderIstioServiceEntries() error = %v", err)
+	}
+	if !strings.Contains(string(out), "---\n") || !strings.Contains(string(out), "  name: github-domains\n") ||
+		!strings.Contains(string(out), "  hosts:\n  - \"*.actions.githubusercontent.com\"\n  ports:\n") {
+		t.Errorf("renderIstioServiceEntries() = %s, should contain a domains entry", out)
+	}
+}
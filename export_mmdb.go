@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerExporter("mmdb", "MaxMind DB file for GeoIP-capable tools (binary)", renderFunc(renderMMDB))
+}
+
+// mmdbMetadataMarker separates the search tree and data section from the
+// metadata at the end of an MMDB file
+const mmdbMetadataMarker = "\xab\xcd\xefMaxMind.com"
+
+// mmdbDataSectionSeparator is the number of zero bytes between the search tree
+// and the data section
+const mmdbDataSectionSeparator = 16
+
+// mmdbRecord is a record of a search tree node: another node, a data section
+// entry, or nothing
+type mmdbRecord struct {
+	node int // Index of the child node, or -1
+	data int // Index of the data entry, or -1
+}
+
+var mmdbEmpty = mmdbRecord{node: -1, data: -1}
+
+// mmdbTree is a binary search tree over 128 bit addresses. IPv4 ranges live in
+// ::/96 as readers expect in IPv6 databases.
+type mmdbTree struct {
+	nodes [][2]mmdbRecord
+}
+
+func newMMDBTree() *mmdbTree {
+	return &mmdbTree{nodes: [][2]mmdbRecord{{mmdbEmpty, mmdbEmpty}}}
+}
+
+// insert points the records covering prefix at data entry data. Networks must
+// be inserted from least to most specific so nested ranges take precedence.
+func (t *mmdbTree) insert(prefix netip.Prefix, data int) {
+	ip := prefix.Addr().As16()
+	ones := prefix.Bits()
+	if prefix.Addr().Is4() {
+		v4 := prefix.Addr().As4()
+		ip = [16]byte{12: v4[0], 13: v4[1], 14: v4[2], 15: v4[3]}
+		ones += 96
+	}
+	if ones == 0 {
+		return
+	}
+
+	node := 0
+	for depth := 0; depth < ones-1; depth++ {
+		bit := ip[depth/8] >> (7 - depth%8) & 1
+		rec := t.nodes[node][bit]
+		if rec.node < 0 {
+			// Split an empty or data record into a node inheriting it on both sides
+			child := len(t.nodes)
+			t.nodes = append(t.nodes, [2]mmdbRecord{rec, rec})
+			t.nodes[node][bit] = mmdbRecord{node: child, data: -1}
+			rec = t.nodes[node][bit]
+		}
+		node = rec.node
+	}
+	bit := ip[(ones-1)/8] >> (7 - (ones-1)%8) & 1
+	t.nodes[node][bit] = mmdbRecord{node: -1, data: data}
+}
+
+// renderMMDB renders the ranges as a MaxMind DB file carrying is_github, area and
+// cidr for each range, for GeoIP-capable tools to look up locally
+func renderMMDB(opts exportOptions) ([]byte, error) {
+	keys := areaKeysByRange(opts.Areas)
+
+	type entry struct {
+		cidr   string
+		prefix netip.Prefix
+	}
+	var entries []entry
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{cidr, prefix.Masked()})
+	}
+	// Depth in the 128 bit tree, where IPv4 ranges start 96 bits down
+	depth := func(p netip.Prefix) int { return p.Bits() + 128 - p.Addr().BitLen() }
+	sort.SliceStable(entries, func(i, j int) bool { return depth(entries[i].prefix) < depth(entries[j].prefix) })
+
+	var data bytes.Buffer
+	offsets := make([]int, len(entries))
+	tree := newMMDBTree()
+	for i, e := range entries {
+		offsets[i] = data.Len()
+		writeMMDBMap(&data, []mmdbField{
+			{"area", strings.Join(keys[e.cidr], ",")},
+			{"cidr", e.cidr},
+			{"is_github", true},
+		})
+		tree.insert(e.prefix, i)
+	}
+
+	nodeCount := len(tree.nodes)
+	recordValue := func(rec mmdbRecord) uint32 {
+		switch {
+		case rec.node >= 0:
+			return uint32(rec.node)
+		case rec.data >= 0:
+			return uint32(nodeCount + mmdbDataSectionSeparator + offsets[rec.data])
+		default:
+			return uint32(nodeCount)
+		}
+	}
+
+	var out bytes.Buffer
+	for _, node := range tree.nodes {
+		binary.Write(&out, binary.BigEndian, recordValue(node[0]))
+		binary.Write(&out, binary.BigEndian, recordValue(node[1]))
+	}
+	out.Write(make([]byte, mmdbDataSectionSeparator))
+	out.Write(data.Bytes())
+	out.WriteString(mmdbMetadataMarker)
+	writeMMDBMap(&out, []mmdbField{
+		{"binary_format_major_version", uint16(2)},
+		{"binary_format_minor_version", uint16(0)},
+		{"build_epoch", uint64(opts.Generated.Unix())},
+		{"database_type", "GitHub-IP-Ranges"},
+		{"description", []mmdbField{{"en", exportHeader}}},
+		{"ip_version", uint16(6)},
+		{"languages", []string{"en"}},
+		{"node_count", uint32(nodeCount)},
+		{"record_size", uint16(32)},
+	})
+	return out.Bytes(), nil
+}
+
+// mmdbField is a key and value of an MMDB map, kept in order
+type mmdbField struct {
+	Key   string
+	Value interface{}
+}
+
+// MMDB data section type numbers
+const (
+	mmdbTypeString  = 2
+	mmdbTypeUint16  = 5
+	mmdbTypeUint32  = 6
+	mmdbTypeMap     = 7
+	mmdbTypeUint64  = 9
+	mmdbTypeArray   = 11
+	mmdbTypeBoolean = 14
+)
+
+// writeMMDBControl writes the control byte(s) for a value of the given type and size
+func writeMMDBControl(buf *bytes.Buffer, typeNum, size int) {
+	var ctrl byte
+	var ext []byte
+	if typeNum <= 7 {
+		ctrl = byte(typeNum) << 5
+	} else {
+		ext = []byte{byte(typeNum - 7)}
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 29+256:
+		ctrl |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 285+65536:
+		ctrl |= 30
+		sizeBytes = []byte{byte((size - 285) >> 8), byte(size - 285)}
+	default:
+		ctrl |= 31
+		size -= 65821
+		sizeBytes = []byte{byte(size >> 16), byte(size >> 8), byte(size)}
+	}
+
+	buf.WriteByte(ctrl)
+	buf.Write(ext)
+	buf.Write(sizeBytes)
+}
+
+// writeMMDBUint writes an unsigned integer using as few bytes as needed
+func writeMMDBUint(buf *bytes.Buffer, typeNum int, v uint64) {
+	var b []byte
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	writeMMDBControl(buf, typeNum, len(b))
+	buf.Write(b)
+}
+
+// writeMMDBValue encodes v in the MMDB data section format
+func writeMMDBValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		writeMMDBControl(buf, mmdbTypeString, len(v))
+		buf.WriteString(v)
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		writeMMDBControl(buf, mmdbTypeBoolean, size)
+	case uint16:
+		writeMMDBUint(buf, mmdbTypeUint16, uint64(v))
+	case uint32:
+		writeMMDBUint(buf, mmdbTypeUint32, uint64(v))
+	case uint64:
+		writeMMDBUint(buf, mmdbTypeUint64, v)
+	case []string:
+		writeMMDBControl(buf, mmdbTypeArray, len(v))
+		for _, s := range v {
+			writeMMDBValue(buf, s)
+		}
+	case []mmdbField:
+		writeMMDBMap(buf, v)
+	default:
+		panic(fmt.Sprintf("unsupported MMDB value type %T", v))
+	}
+}
+
+// writeMMDBMap encodes fields as an MMDB map
+func writeMMDBMap(buf *bytes.Buffer, fields []mmdbField) {
+	writeMMDBControl(buf, mmdbTypeMap, len(fields))
+	for _, f := range fields {
+		writeMMDBValue(buf, f.Key)
+		writeMMDBValue(buf, f.Value)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// decodeMMDBValue decodes the value at offset of an MMDB data section, returning
+// it along with the offset of the next value
+func decodeMMDBValue(t *testing.T, data []byte, offset int) (interface{}, int) {
+	t.Helper()
+	ctrl := data[offset]
+	offset++
+	typeNum := int(ctrl >> 5)
+	if typeNum == 0 {
+		typeNum = int(data[offset]) + 7
+		offset++
+	}
+	size := int(ctrl & 0x1f)
+	switch size {
+	case 29:
+		size = 29 + int(data[offset])
+		offset++
+	case 30:
+		size = 285 + int(data[offset])<<8 | int(data[offset+1])
+		offset += 2
+	}
+
+	switch typeNum {
+	case mmdbTypeString:
+		return string(data[offset : offset+size]), offset + size
+	case mmdbTypeUint16, mmdbTypeUint32, mmdbTypeUint64:
+		var v uint64
+		for _, b := range data[offset : offset+size] {
+			v = v<<8 | uint64(b)
+		}
+		return v, offset + size
+	case mmdbTypeBoolean:
+		return size == 1, offset
+	case mmdbTypeArray:
+		var values []interface{}
+		for i := 0; i < size; i++ {
+			var v interface{}
+			v, offset = decodeMMDBValue(t, data, offset)
+			values = append(values, v)
+		}
+		return values, offset
+	case mmdbTypeMap:
+		m := make(map[string]interface{})
+		for i := 0; i < size; i++ {
+			var k, v interface{}
+			k, offset = decodeMMDBValue(t, data, offset)
+			v, offset = decodeMMDBValue(t, data, offset)
+			m[k.(string)] = v
+		}
+		return m, offset
+	}
+	t.Fatalf("unexpected MMDB type %d at offset %d", typeNum, offset)
+	return nil, 0
+}
+
+// lookupMMDB finds the data for ip in an MMDB file with 32 bit records, the way
+// a reader would
+func lookupMMDB(t *testing.T, db []byte, ip string) interface{} {
+	t.Helper()
+	marker := bytes.LastIndex(db, []byte(mmdbMetadataMarker))
+	if marker < 0 {
+		t.Fatal("metadata marker not found")
+	}
+	metadata, _ := decodeMMDBValue(t, db, marker+len(mmdbMetadataMarker))
+	nodeCount := int(metadata.(map[string]interface{})["node_count"].(uint64))
+	dataSection := db[nodeCount*8+mmdbDataSectionSeparator : marker]
+
+	parsed := netip.MustParseAddr(ip)
+	addr := parsed.As16()
+	if parsed.Is4() {
+		v4 := parsed.As4()
+		addr = [16]byte{12: v4[0], 13: v4[1], 14: v4[2], 15: v4[3]}
+	}
+
+	node := 0
+	for depth := 0; depth < 128 && node < nodeCount; depth++ {
+		bit := addr[depth/8] >> (7 - depth%8) & 1
+		node = int(binary.BigEndian.Uint32(db[node*8+int(bit)*4:]))
+	}
+	if node == nodeCount {
+		return nil
+	}
+	v, _ := decodeMMDBValue(t, dataSection, node-nodeCount-mmdbDataSectionSeparator)
+	return v
+}
+
+func TestRenderMMDB(t *testing.T) {
+	areas := append(testAreas(), Area{Key: "git", Name: "Git", Ranges: []string{"192.30.253.0/24"}})
+	opts := exportOptions{
+		Areas:     areas,
+		Generated: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+	}
+
+	db, err := renderMMDB(opts)
+	if err != nil {
+		t.Fatalf("renderMMDB() error = %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want interface{}
+	}{
+		{"192.30.252.1", map[string]interface{}{"area": "hooks,web", "cidr": "192.30.252.0/22", "is_github": true}},
+		{"192.30.253.1", map[string]interface{}{"area": "git", "cidr": "192.30.253.0/24", "is_github": true}},
+		{"140.82.127.255", map[string]interface{}{"area": "web", "cidr": "140.82.112.0/20", "is_github": true}},
+		{"4.148.1.1", map[string]interface{}{"area": "actions_ipv4", "cidr": "4.148.0.0/16", "is_github": true}},
+		{"2a0a:a441::1", map[string]interface{}{"area": "hooks", "cidr": "2a0a:a440::/29", "is_github": true}},
+		{"8.8.8.8", nil},
+		{"2001:db8::1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := lookupMMDB(t, db, tt.ip); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lookup(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMMDBMetadata(t *testing.T) {
+	db, err := renderMMDB(exportOptions{Areas: testAreas(), Generated: time.Unix(1760443200, 0)})
+	if err != nil {
+		t.Fatalf("renderMMDB() error = %v", err)
+	}
+
+	marker := bytes.LastIndex(db, []byte(mmdbMetadataMarker))
+	metadata, _ := decodeMMDBValue(t, db, marker+len(mmdbMetadataMarker))
+	m := metadata.(map[string]interface{})
+	for key, want := range map[string]interface{}{
+		"binary_format_major_version": uint64(2),
+		"build_epoch":                 uint64(1760443200),
+		"database_type":               "GitHub-IP-Ranges",
+		"ip_version":                  uint64(6),
+		"record_size":                 uint64(32),
+		"languages":                   []interface{}{"en"},
+	} {
+		if !reflect.DeepEqual(m[key], want) {
+			t.Errorf("metadata[%s] = %v, want %v", key, m[key], want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerExporter("zabbix-discovery", "Zabbix low-level discovery data for per-area items (JSON)", renderFunc(renderZabbixDiscovery))
+	registerExporter("zabbix-sender", "zabbix_sender input with the range count of each area", renderFunc(renderZabbixSender))
+}
+
+// renderZabbixDiscovery renders Zabbix low-level discovery data with an entry per
+// area, for item prototypes such as github.area.ranges[{#AREA}]
+func renderZabbixDiscovery(opts exportOptions) ([]byte, error) {
+	entries := []map[string]string{}
+	for _, area := range opts.Areas {
+		entries = append(entries, map[string]string{
+			"{#AREA}":      area.Key,
+			"{#AREA_NAME}": area.Name,
+		})
+	}
+	return marshalExportJSON(entries)
+}
+
+// renderZabbixSender renders zabbix_sender input with the number of ranges of
+// each area and the snapshot time, for the items created by discovery. The host
+// is left as "-" so zabbix_sender takes it from its configuration.
+func renderZabbixSender(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	for _, area := range opts.Areas {
+		fmt.Fprintf(&b, "- github.area.ranges[%s] %d\n", area.Key, len(area.Ranges))
+	}
+	fmt.Fprintf(&b, "- github.snapshot %d\n", opts.Snapshot.Unix())
+	return []byte(b.String()), nil
+}
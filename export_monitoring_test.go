@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderZabbixDiscovery(t *testing.T) {
+	out, err := renderZabbixDiscovery(exportOptions{Areas: testAreas()[1:]})
+	if err != nil {
+		t.Fatalf("renderZabbixDiscovery() error = %v", err)
+	}
+	want := `[
+  {
+    "{#AREA_NAME}": "Web",
+    "{#AREA}": "web"
+  },
+  {
+    "{#AREA_NAME}": "Actions IPv4",
+    "{#AREA}": "actions_ipv4"
+  }
+]
+`
+	if string(out) != want {
+		t.Errorf("renderZabbixDiscovery() =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestRenderZabbixSender(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas(),
+		Snapshot: time.Unix(1760443200, 0),
+	}
+
+	out, err := renderZabbixSender(opts)
+	if err != nil {
+		t.Fatalf("renderZabbixSender() error = %v", err)
+	}
+	want := "- github.area.ranges[hooks] 2\n" +
+		"- github.area.ranges[web] 2\n" +
+		"- github.area.ranges[actions_ipv4] 1\n" +
+		"- github.snapshot 1760443200\n"
+	if string(out) != want {
+		t.Errorf("renderZabbixSender() = %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerExporter("parquet", "Parquet file with a row per area and range (binary)", renderFunc(renderParquet))
+}
+
+// parquetMagic starts and ends every Parquet file
+const parquetMagic = "PAR1"
+
+// Thrift compact protocol field types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// Parquet enum values used by the export
+const (
+	parquetTypeInt32     = 1
+	parquetTypeByteArray = 6
+
+	parquetRequired = 0
+
+	parquetConvertedUTF8 = 0
+	parquetConvertedDate = 6
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecUncompressed = 0
+	parquetDataPage          = 0
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol used by Parquet metadata
+type thriftWriter struct {
+	buf    bytes.Buffer
+	fields []int16 // Last field ID of each open struct
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	w.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) beginStruct() {
+	w.fields = append(w.fields, 0)
+}
+
+func (w *thriftWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.fields = w.fields[:len(w.fields)-1]
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &w.fields[len(w.fields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) binaryValue(s string) {
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) string(id int16, s string) {
+	w.fieldHeader(id, thriftBinary)
+	w.binaryValue(s)
+}
+
+func (w *thriftWriter) beginStructField(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.beginStruct()
+}
+
+// list writes a list field header; the caller then writes size elements
+func (w *thriftWriter) list(id int16, elemType byte, size int) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		w.buf.WriteByte(0xf0 | elemType)
+		w.varint(uint64(size))
+	}
+}
+
+// parquetColumn is a required column of the exported Parquet file
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32
+	logical   int16         // Field of the LogicalType union, e.g. 1 for STRING
+	values    []interface{} // string or int32
+}
+
+// plainValues encodes the column values with the PLAIN encoding
+func (c *parquetColumn) plainValues() []byte {
+	var buf bytes.Buffer
+	for _, v := range c.values {
+		switch v := v.(type) {
+		case string:
+			binary.Write(&buf, binary.LittleEndian, uint32(len(v)))
+			buf.WriteString(v)
+		case int32:
+			binary.Write(&buf, binary.LittleEndian, v)
+		}
+	}
+	return buf.Bytes()
+}
+
+// renderParquet renders the ranges as an uncompressed Parquet file with a row per
+// area and range, for data lake pipelines to join against
+func renderParquet(opts exportOptions) ([]byte, error) {
+	snapshotDays := int32(opts.Snapshot.UTC().Truncate(24*time.Hour).Unix() / 86400)
+
+	columns := []*parquetColumn{
+		{name: "cidr", typ: parquetTypeByteArray, converted: parquetConvertedUTF8, logical: 1},
+		{name: "area", typ: parquetTypeByteArray, converted: parquetConvertedUTF8, logical: 1},
+		{name: "snapshot_date", typ: parquetTypeInt32, converted: parquetConvertedDate, logical: 6},
+	}
+	rows := 0
+	for _, area := range opts.Areas {
+		for _, cidr := range area.Ranges {
+			columns[0].values = append(columns[0].values, cidr)
+			columns[1].values = append(columns[1].values, area.Key)
+			columns[2].values = append(columns[2].values, snapshotDays)
+			rows++
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString(parquetMagic)
+
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(columns))
+	for i, col := range columns {
+		data := col.plainValues()
+
+		var header thriftWriter
+		header.beginStruct()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.beginStructField(5)
+		header.i32(1, int32(len(col.values)))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunks[i] = chunk{int64(out.Len()), int64(header.buf.Len() + len(data))}
+		out.Write(header.buf.Bytes())
+		out.Write(data)
+	}
+
+	var meta thriftWriter
+	meta.beginStruct()
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(columns)+1)
+	meta.beginStruct()
+	meta.string(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.endStruct()
+	for _, col := range columns {
+		meta.beginStruct()
+		meta.i32(1, col.typ)
+		meta.i32(3, parquetRequired)
+		meta.string(4, col.name)
+		meta.i32(6, col.converted)
+		meta.beginStructField(10)
+		meta.beginStructField(col.logical)
+		meta.endStruct()
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(3, int64(rows))
+
+	var total int64
+	for _, c := range chunks {
+		total += c.size
+	}
+	meta.list(4, thriftStruct, 1)
+	meta.beginStruct()
+	meta.list(1, thriftStruct, len(columns))
+	for i, col := range columns {
+		meta.beginStruct()
+		meta.i64(2, chunks[i].offset)
+		meta.beginStructField(3)
+		meta.i32(1, col.typ)
+		meta.list(2, thriftI32, 1)
+		meta.zigzag(parquetEncodingPlain)
+		meta.list(3, thriftBinary, 1)
+		meta.binaryValue(col.name)
+		meta.i32(4, parquetCodecUncompressed)
+		meta.i64(5, int64(len(col.values)))
+		meta.i64(6, chunks[i].size)
+		meta.i64(7, chunks[i].size)
+		meta.i64(9, chunks[i].offset)
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(rows))
+	meta.endStruct()
+
+	meta.list(5, thriftStruct, 1)
+	meta.beginStruct()
+	meta.string(1, "snapshot")
+	meta.string(2, opts.Snapshot.Format(time.RFC3339))
+	meta.endStruct()
+	meta.string(6, "gh-check-github-ip-ranges version "+strings.TrimPrefix(Version, "v"))
+	meta.endStruct()
+
+	out.Write(meta.buf.Bytes())
+	binary.Write(&out, binary.LittleEndian, uint32(meta.buf.Len()))
+	out.WriteString(parquetMagic)
+	return out.Bytes(), nil
+}
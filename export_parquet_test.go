@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// thriftReader decodes Thrift compact protocol structs into maps of field ID to value
+type thriftReader struct {
+	t    *testing.T
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.t.Fatalf("invalid varint at %d", r.pos)
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.varint())
+		s := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		size, elemType := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := []interface{}{}
+		for i := 0; i < size; i++ {
+			list = append(list, r.value(elemType))
+		}
+		return list
+	case thriftStruct:
+		fields := make(map[int16]interface{})
+		var last int16
+		for {
+			header := r.data[r.pos]
+			r.pos++
+			if header == 0 {
+				return fields
+			}
+			id := last + int16(header>>4)
+			if header>>4 == 0 {
+				id = int16(r.zigzag())
+			}
+			fields[id] = r.value(header & 0x0f)
+			last = id
+		}
+	}
+	r.t.Fatalf("unexpected thrift type %d at %d", typ, r.pos)
+	return nil
+}
+
+func TestRenderParquet(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[:2],
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := renderParquet(opts)
+	if err != nil {
+		t.Fatalf("renderParquet() error = %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte(parquetMagic)) || !bytes.HasSuffix(out, []byte(parquetMagic)) {
+		t.Fatal("renderParquet() is missing the PAR1 magic")
+	}
+
+	footerLen := int(binary.LittleEndian.Uint32(out[len(out)-8:]))
+	footer := &thriftReader{t: t, data: out[len(out)-8-footerLen : len(out)-8]}
+	meta := footer.value(thriftStruct).(map[int16]interface{})
+	if footer.pos != footerLen {
+		t.Errorf("footer decoded %d of %d bytes", footer.pos, footerLen)
+	}
+
+	if got := meta[3]; got != int64(4) {
+		t.Errorf("num_rows = %v, want 4", got)
+	}
+	var names []interface{}
+	for _, element := range meta[2].([]interface{}) {
+		names = append(names, element.(map[int16]interface{})[4])
+	}
+	if want := []interface{}{"schema", "cidr", "area", "snapshot_date"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("schema = %v, want %v", names, want)
+	}
+
+	// Read back each column chunk's page and check its values
+	snapshotDays := make([]byte, 4)
+	binary.LittleEndian.PutUint32(snapshotDays, 20740)
+	wantValues := [][]byte{
+		plainStrings("192.30.252.0/22", "2a0a:a440::/29", "192.30.252.0/22", "140.82.112.0/20"),
+		plainStrings("hooks", "hooks", "web", "web"),
+		bytes.Repeat(snapshotDays, 4),
+	}
+	rowGroup := meta[4].([]interface{})[0].(map[int16]interface{})
+	for i, c := range rowGroup[1].([]interface{}) {
+		columnMeta := c.(map[int16]interface{})[3].(map[int16]interface{})
+		page := &thriftReader{t: t, data: out, pos: int(columnMeta[9].(int64))}
+		header := page.value(thriftStruct).(map[int16]interface{})
+		size := int(header[3].(int64))
+		if got := out[page.pos : page.pos+size]; !bytes.Equal(got, wantValues[i]) {
+			t.Errorf("column %d values = %q, want %q", i, got, wantValues[i])
+		}
+		if got := header[5].(map[int16]interface{})[1]; got != int64(4) {
+			t.Errorf("column %d num_values = %v, want 4", i, got)
+		}
+		if got := page.pos + size - int(columnMeta[9].(int64)); int64(got) != columnMeta[7] {
+			t.Errorf("column %d total_compressed_size = %v, want %d", i, columnMeta[7], got)
+		}
+	}
+}
+
+// plainStrings encodes values as PLAIN BYTE_ARRAY data
+func plainStrings(values ...string) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(v)))
+		buf.WriteString(v)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// exportPluginPrefix is the prefix of the executables on PATH providing
+// further export formats, e.g. gh-check-github-ip-ranges-export-acme for the
+// format acme
+const exportPluginPrefix = "gh-check-github-ip-ranges-export-"
+
+// exportPluginVersion is the version of the document plugins read on stdin,
+// increased only by changes that would break them
+const exportPluginVersion = 1
+
+// pluginFormatName matches the format names a plugin can provide, which can't
+// name a path
+var pluginFormatName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// exportPluginInput is the document written to a plugin's stdin
+type exportPluginInput struct {
+	Version    int              `json:"version"`
+	Format     string           `json:"format"`
+	Name       string           `json:"name"`
+	Snapshot   *time.Time       `json:"snapshot,omitempty"`
+	Generated  time.Time        `json:"generated"`
+	SourceHash string           `json:"source_hash,omitempty"`
+	Areas      []areaRangesJSON `json:"areas"`
+	Domains    []string         `json:"domains"`
+	SSHKeys    []string         `json:"ssh_keys"`
+}
+
+// pluginExporter renders an export format by running an external plugin,
+// which reads the ranges as JSON on stdin and writes the export to stdout
+type pluginExporter struct {
+	format string
+	path   string
+}
+
+// lookupExportPlugin returns the exporter of the plugin on PATH providing
+// format, if any
+func lookupExportPlugin(format string) (pluginExporter, bool) {
+	if !pluginFormatName.MatchString(format) {
+		return pluginExporter{}, false
+	}
+	path, err := exec.LookPath(exportPluginPrefix + format)
+	if err != nil {
+		return pluginExporter{}, false
+	}
+	return pluginExporter{format, path}, true
+}
+
+func (p pluginExporter) Render(opts exportOptions) ([]byte, error) {
+	input := exportPluginInput{
+		Version:    exportPluginVersion,
+		Format:     p.format,
+		Name:       opts.Name,
+		Generated:  opts.Generated,
+		SourceHash: opts.SourceHash,
+		Areas:      []areaRangesJSON{},
+		Domains:    append([]string{}, opts.Domains...),
+		SSHKeys:    append([]string{}, opts.SSHKeys...),
+	}
+	if !opts.Snapshot.IsZero() {
+		input.Snapshot = &opts.Snapshot
+	}
+	for _, area := range opts.Areas {
+		input.Areas = append(input.Areas, areaRangesJSON{area.Key, area.Name, append([]string{}, area.Ranges...)})
+	}
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export plugin input: %w", err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(p.path)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("export plugin %s failed: %w", p.path, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// exportPlugins returns the formats provided by plugins on PATH and their
+// paths, skipping those of built-in formats and those shadowed by a plugin
+// earlier on PATH
+func exportPlugins() map[string]string {
+	plugins := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			format, ok := strings.CutPrefix(entry.Name(), exportPluginPrefix)
+			if !ok || !pluginFormatName.MatchString(format) {
+				continue
+			}
+			if _, builtin := exporters[format]; builtin || plugins[format] != "" {
+				continue
+			}
+			if info, err := entry.Info(); err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+				continue
+			}
+			plugins[format] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return plugins
+}
+
+// pluginList lists the formats provided by plugins on PATH, or is empty if
+// there are none
+func pluginList() string {
+	plugins := exportPlugins()
+	if len(plugins) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nPlugins:\n")
+	for _, format := range slices.Sorted(maps.Keys(plugins)) {
+		fmt.Fprintf(&b, "  %s  %s\n", format, plugins[format])
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePlugin writes an export plugin for format running script to dir
+func writePlugin(t *testing.T, dir, format, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, exportPluginPrefix+format)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExportPlugin(t *testing.T) {
+	server := newTestMetaServer(t)
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	dir := t.TempDir()
+	writePlugin(t, dir, "acme", "cat")
+	writePlugin(t, dir, "broken", "echo broken >&2; exit 3")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path := filepath.Join(dir, "export.out")
+	cmd := newExportCommand()
+	cmd.SetArgs([]string{"--format", "acme", "--area", "hooks", "--name", "edge", path})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("export with a plugin error = %v", err)
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	var input exportPluginInput
+	if err := json.Unmarshal(out, &input); err != nil {
+		t.Fatalf("plugin input = %s, not JSON: %v", out, err)
+	}
+	if input.Version != exportPluginVersion || input.Format != "acme" || input.Name != "edge" || input.SourceHash == "" {
+		t.Errorf("plugin input = %+v", input)
+	}
+	if len(input.Areas) != 1 || input.Areas[0].Key != "hooks" || len(input.Areas[0].Ranges) == 0 {
+		t.Errorf("plugin input areas = %+v, want hooks", input.Areas)
+	}
+
+	cmd = newExportCommand()
+	cmd.SetArgs([]string{"--format", "broken"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "export plugin") {
+		t.Errorf("export with a failing plugin error = %v, want the plugin's failure", err)
+	}
+
+	cmd = newExportCommand()
+	cmd.SetArgs([]string{"--format", "acme", "--apply"})
+	if err := cmd.Execute(); errorCategory(err) != errorCategoryUsage {
+		t.Errorf("export with a plugin and --apply error = %v, want a usage error", err)
+	}
+}
+
+func TestExportPlugins(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	acme := writePlugin(t, first, "acme", "cat")
+	writePlugin(t, second, "acme", "cat")
+	writePlugin(t, second, "haproxy", "cat")
+	if err := os.WriteFile(filepath.Join(second, exportPluginPrefix+"plain"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	plugins := exportPlugins()
+	if len(plugins) != 1 || plugins["acme"] != acme {
+		t.Errorf("exportPlugins() = %v, want only acme in %s", plugins, first)
+	}
+	if list := pluginList(); !strings.Contains(list, "  acme  "+acme+"\n") {
+		t.Errorf("pluginList() = %q, should list acme", list)
+	}
+
+	if _, ok := lookupExportPlugin("../acme"); ok {
+		t.Errorf("lookupExportPlugin() found a plugin for a path")
+	}
+	t.Setenv("PATH", t.TempDir())
+	if list := pluginList(); list != "" {
+		t.Errorf("pluginList() without plugins = %q, want empty", list)
+	}
+}
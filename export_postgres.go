@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerExporter("postgres", "PostgreSQL script loading the ranges into a cidr table", renderFunc(renderPostgres))
+}
+
+// renderPostgres renders an SQL script loading the ranges into a PostgreSQL table
+// with a native cidr column, replacing any previously loaded ranges
+func renderPostgres(opts exportOptions) ([]byte, error) {
+	table := identifierName(opts.Name) + "_ip_ranges"
+	snapshotDate := sqlQuote(opts.Snapshot.Format("2006-01-02"))
+
+	var b strings.Builder
+	b.WriteString("-- " + exportHeader + "\n")
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (\n", table)
+	b.WriteString("  cidr cidr NOT NULL,\n")
+	b.WriteString("  area text NOT NULL,\n")
+	b.WriteString("  snapshot_date date NOT NULL,\n")
+	b.WriteString("  PRIMARY KEY (cidr, area)\n")
+	b.WriteString(");\n\n")
+	b.WriteString("-- A GiST index lets joins on the containment operators, e.g.\n")
+	fmt.Fprintf(&b, "-- JOIN %s g ON logs.client_ip <<= g.cidr, use an index scan\n", table)
+	fmt.Fprintf(&b, "CREATE INDEX IF NOT EXISTS %s_cidr_gist ON %s USING gist (cidr inet_ops);\n\n", table, table)
+
+	b.WriteString("BEGIN;\n")
+	fmt.Fprintf(&b, "DELETE FROM %s;\n", table)
+	var rows []string
+	for _, area := range opts.Areas {
+		for _, cidr := range area.Ranges {
+			rows = append(rows, fmt.Sprintf("  (%s, %s, %s)", sqlQuote(cidr), sqlQuote(area.Key), snapshotDate))
+		}
+	}
+	if len(rows) > 0 {
+		fmt.Fprintf(&b, "INSERT INTO %s (cidr, area, snapshot_date) VALUES\n", table)
+		b.WriteString(strings.Join(rows, ",\n") + ";\n")
+	}
+	b.WriteString("COMMIT;\n")
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenderPostgres(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[:1],
+		Name:     "github",
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := renderPostgres(opts)
+	if err != nil {
+		t.Fatalf("renderPostgres() error = %v", err)
+	}
+	want := `-- GitHub IP ranges generated by gh-check-github-ip-ranges
+CREATE TABLE IF NOT EXISTS github_ip_ranges (
+  cidr cidr NOT NULL,
+  area text NOT NULL,
+  snapshot_date date NOT NULL,
+  PRIMARY KEY (cidr, area)
+);
+
+-- A GiST index lets joins on the containment operators, e.g.
+-- JOIN github_ip_ranges g ON logs.client_ip <<= g.cidr, use an index scan
+CREATE INDEX IF NOT EXISTS github_ip_ranges_cidr_gist ON github_ip_ranges USING gist (cidr inet_ops);
+
+BEGIN;
+DELETE FROM github_ip_ranges;
+INSERT INTO github_ip_ranges (cidr, area, snapshot_date) VALUES
+  ('192.30.252.0/22', 'hooks', '2026-10-14'),
+  ('2a0a:a440::/29', 'hooks', '2026-10-14');
+COMMIT;
+`
+	if string(out) != want {
+		t.Errorf("renderPostgres() =\n%s\nwant\n%s", out, want)
+	}
+}
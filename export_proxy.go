@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerExporter("haproxy", "HAProxy ACL file, one range per line", renderFunc(renderHAProxyACL))
+	registerExporter("nginx", "nginx allow/deny snippet", renderFunc(renderNginxAllow))
+	registerExporter("apache", "Apache httpd 2.4 Require ip block", renderFunc(renderApacheRequire))
+	registerExporter("squid", "Squid dst ACL definition", renderFunc(renderSquidACL))
+	registerExporter("envoy-rbac", "Envoy HTTP RBAC filter admitting only the ranges (YAML)", renderFunc(renderEnvoyRBAC))
+}
+
+// exportHeader is the comment added to the top of generated configuration files
+const exportHeader = "GitHub IP ranges generated by gh-check-github-ip-ranges"
+
+// renderHAProxyACL renders the ranges as an HAProxy ACL file, loaded with
+// e.g. "acl github src -f github-ips.lst"
+func renderHAProxyACL(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		b.WriteString(cidr + "\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// renderNginxAllow renders an nginx snippet allowing the ranges and denying all
+// other clients, for inclusion in a location block
+func renderNginxAllow(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		b.WriteString("allow " + cidr + ";\n")
+	}
+	b.WriteString("deny all;\n")
+	return []byte(b.String()), nil
+}
+
+// renderApacheRequire renders an Apache 2.4 RequireAny block admitting the ranges
+func renderApacheRequire(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	b.WriteString("<RequireAny>\n")
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		b.WriteString("    Require ip " + cidr + "\n")
+	}
+	b.WriteString("</RequireAny>\n")
+	return []byte(b.String()), nil
+}
+
+// renderSquidACL renders a Squid dst ACL covering the ranges, for egress proxies
+// permitting access to GitHub only
+func renderSquidACL(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		b.WriteString("acl " + opts.Name + " dst " + cidr + "\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// renderEnvoyRBAC renders an Envoy HTTP RBAC filter whose only policy admits
+// requests from the ranges
+func renderEnvoyRBAC(opts exportOptions) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	b.WriteString("name: envoy.filters.http.rbac\n")
+	b.WriteString("typed_config:\n")
+	b.WriteString("  \"@type\": type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC\n")
+	b.WriteString("  rules:\n")
+	b.WriteString("    action: ALLOW\n")
+	b.WriteString("    policies:\n")
+	fmt.Fprintf(&b, "      %q:\n", opts.Name)
+	b.WriteString("        permissions:\n")
+	b.WriteString("        - any: true\n")
+	b.WriteString("        principals:\n")
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		ip, ones, err := splitCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		b.WriteString("        - remote_ip:\n")
+		fmt.Fprintf(&b, "            address_prefix: %q\n", ip)
+		fmt.Fprintf(&b, "            prefix_len: %d\n", ones)
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHAProxyACL(t *testing.T) {
+	out, err := renderHAProxyACL(exportOptions{Areas: testAreas()[:2]})
+	if err != nil {
+		t.Fatalf("renderHAProxyACL() error = %v", err)
+	}
+	want := "# GitHub IP ranges generated by gh-check-github-ip-ranges\n" +
+		"192.30.252.0/22\n2a0a:a440::/29\n140.82.112.0/20\n"
+	if string(out) != want {
+		t.Errorf("renderHAProxyACL() = %q, want %q", out, want)
+	}
+}
+
+func TestRenderNginxAllow(t *testing.T) {
+	out, err := renderNginxAllow(exportOptions{Areas: testAreas()[:1]})
+	if err != nil {
+		t.Fatalf("renderNginxAllow() error = %v", err)
+	}
+	want := "# GitHub IP ranges generated by gh-check-github-ip-ranges\n" +
+		"allow 192.30.252.0/22;\nallow 2a0a:a440::/29;\ndeny all;\n"
+	if string(out) != want {
+		t.Errorf("renderNginxAllow() = %q, want %q", out, want)
+	}
+}
+
+func TestRenderApacheRequire(t *testing.T) {
+	out, err := renderApacheRequire(exportOptions{Areas: testAreas()[:1]})
+	if err != nil {
+		t.Fatalf("renderApacheRequire() error = %v", err)
+	}
+	want := "# GitHub IP ranges generated by gh-check-github-ip-ranges\n" +
+		"<RequireAny>\n    Require ip 192.30.252.0/22\n    Require ip 2a0a:a440::/29\n</RequireAny>\n"
+	if string(out) != want {
+		t.Errorf("renderApacheRequire() = %q, want %q", out, want)
+	}
+}
+
+func TestRenderSquidACL(t *testing.T) {
+	out, err := renderSquidACL(exportOptions{Areas: testAreas()[:1], Name: "github"})
+	if err != nil {
+		t.Fatalf("renderSquidACL() error = %v", err)
+	}
+	want := "# GitHub IP ranges generated by gh-check-github-ip-ranges\n" +
+		"acl github dst 192.30.252.0/22\nacl github dst 2a0a:a440::/29\n"
+	if string(out) != want {
+		t.Errorf("renderSquidACL() = %q, want %q", out, want)
+	}
+}
+
+func TestRenderEnvoyRBAC(t *testing.T) {
+	out, err := renderEnvoyRBAC(exportOptions{Areas: testAreas()[:1], Name: "github-hooks"})
+	if err != nil {
+		t.Fatalf("renderEnvoyRBAC() error = %v", err)
+	}
+	for _, want := range []string{
+		"    action: ALLOW\n",
+		"      \"github-hooks\":\n",
+		"        - remote_ip:\n            address_prefix: \"192.30.252.0\"\n            prefix_len: 22\n",
+		"            address_prefix: \"2a0a:a440::\"\n            prefix_len: 29\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderEnvoyRBAC() = %s, should contain %q", out, want)
+		}
+	}
+}
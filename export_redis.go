@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerExporter("redis", "redis-cli --pipe script loading the ranges for CIDR lookups", renderFunc(renderRedis))
+}
+
+// writeRedisCommand writes a command in the Redis protocol, as read by redis-cli --pipe
+func writeRedisCommand(b *strings.Builder, args ...string) {
+	fmt.Fprintf(b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// ipv4Interval is the first and last address of an IPv4 range
+type ipv4Interval struct {
+	cidr        string
+	first, last uint32
+}
+
+// outermostIPv4Intervals returns the IPv4 ranges not contained in another range,
+// sorted by address, so that they do not overlap
+func outermostIPv4Intervals(ranges []string) []ipv4Interval {
+	var intervals []ipv4Interval
+	for _, cidr := range ipv4Ranges(ranges) {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || !prefix.Addr().Is4() {
+			continue
+		}
+		ip := prefix.Masked().Addr().As4()
+		first := binary.BigEndian.Uint32(ip[:])
+		last := first | uint32(uint64(1)<<(32-prefix.Bits())-1)
+		intervals = append(intervals, ipv4Interval{cidr, first, last})
+	}
+	sort.SliceStable(intervals, func(i, j int) bool {
+		if intervals[i].first != intervals[j].first {
+			return intervals[i].first < intervals[j].first
+		}
+		return intervals[i].last > intervals[j].last
+	})
+
+	var outermost []ipv4Interval
+	for _, interval := range intervals {
+		if n := len(outermost); n > 0 && interval.last <= outermost[n-1].last {
+			continue
+		}
+		outermost = append(outermost, interval)
+	}
+	return outermost
+}
+
+// renderRedis renders a redis-cli --pipe script atomically replacing the ranges
+// stored under the <name>:ip-ranges prefix:
+//
+//   - <prefix>:v4 is a sorted set of the IPv4 ranges scored by their last address,
+//     so ZRANGEBYSCORE <prefix>:v4 <ip> +inf LIMIT 0 1 finds the candidate range
+//   - <prefix>:areas is a hash of every range to its comma separated area keys
+//   - <prefix>:snapshot holds the snapshot time of the ranges
+func renderRedis(opts exportOptions) ([]byte, error) {
+	prefix := opts.Name + ":ip-ranges"
+	keys := areaKeysByRange(opts.Areas)
+	ranges := uniqueRanges(opts.Areas)
+
+	var b strings.Builder
+	writeRedisCommand(&b, "MULTI")
+	writeRedisCommand(&b, "DEL", prefix+":v4", prefix+":areas", prefix+":snapshot")
+
+	if intervals := outermostIPv4Intervals(ranges); len(intervals) > 0 {
+		args := []string{"ZADD", prefix + ":v4"}
+		for _, interval := range intervals {
+			args = append(args, strconv.FormatUint(uint64(interval.last), 10), interval.cidr)
+		}
+		writeRedisCommand(&b, args...)
+	}
+
+	if len(ranges) > 0 {
+		args := []string{"HSET", prefix + ":areas"}
+		for _, cidr := range ranges {
+			args = append(args, cidr, strings.Join(keys[cidr], ","))
+		}
+		writeRedisCommand(&b, args...)
+	}
+
+	writeRedisCommand(&b, "SET", prefix+":snapshot", opts.Snapshot.Format(time.RFC3339))
+	writeRedisCommand(&b, "EXEC")
+	return []byte(b.String()), nil
+}
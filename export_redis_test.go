@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRenderRedis(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[:1],
+		Name:     "github",
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := renderRedis(opts)
+	if err != nil {
+		t.Fatalf("renderRedis() error = %v", err)
+	}
+	want := "*1\r\n$5\r\nMULTI\r\n" +
+		"*4\r\n$3\r\nDEL\r\n$19\r\ngithub:ip-ranges:v4\r\n$22\r\ngithub:ip-ranges:areas\r\n$25\r\ngithub:ip-ranges:snapshot\r\n" +
+		"*4\r\n$4\r\nZADD\r\n$19\r\ngithub:ip-ranges:v4\r\n$10\r\n3223257087\r\n$15\r\n192.30.252.0/22\r\n" +
+		"*6\r\n$4\r\nHSET\r\n$22\r\ngithub:ip-ranges:areas\r\n$15\r\n192.30.252.0/22\r\n$5\r\nhooks\r\n$14\r\n2a0a:a440::/29\r\n$5\r\nhooks\r\n" +
+		"*3\r\n$3\r\nSET\r\n$25\r\ngithub:ip-ranges:snapshot\r\n$20\r\n2026-10-14T12:00:00Z\r\n" +
+		"*1\r\n$4\r\nEXEC\r\n"
+	if string(out) != want {
+		t.Errorf("renderRedis() =\n%q\nwant\n%q", out, want)
+	}
+}
+
+func TestOutermostIPv4Intervals(t *testing.T) {
+	got := outermostIPv4Intervals([]string{"10.1.0.0/16", "10.0.0.0/8", "10.0.0.0/8", "192.168.0.0/24", "2a0a:a440::/29"})
+	want := []ipv4Interval{
+		{"10.0.0.0/8", 0x0a000000, 0x0affffff},
+		{"192.168.0.0/24", 0xc0a80000, 0xc0a800ff},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outermostIPv4Intervals() = %v, want %v", got, want)
+	}
+}
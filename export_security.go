@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("suricata", "Suricata rules matching traffic from and to each area", renderFunc(renderSuricataRules))
+	registerExporter("snort", "Snort rules matching traffic from and to each area", renderFunc(renderSuricataRules))
+	registerExporter("zeek-intel", "Zeek intelligence framework file", renderFunc(renderZeekIntel))
+	registerExporter("stix", "STIX 2.1 bundle grouping the ranges by area (JSON)", renderFunc(renderSTIXBundle))
+}
+
+// idsOptions contains the settings for the IDS rule export formats
+type idsOptions struct {
+	Action  string
+	SIDBase int
+}
+
+func addIDSFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ids-action", "alert", "Action of the generated IDS rules (alert or pass)")
+	cmd.Flags().Int("ids-sid-base", 9100000, "First signature ID of the generated IDS rules")
+}
+
+func idsOptionsFromFlags(cmd *cobra.Command) idsOptions {
+	var opts idsOptions
+	opts.Action, _ = cmd.Flags().GetString("ids-action")
+	opts.SIDBase, _ = cmd.Flags().GetInt("ids-sid-base")
+	return opts
+}
+
+// renderSuricataRules renders Suricata/Snort rules matching traffic from and to
+// each area's ranges, so sensors can label legitimate GitHub flows by area
+func renderSuricataRules(opts exportOptions) ([]byte, error) {
+	if opts.IDS.Action != "alert" && opts.IDS.Action != "pass" {
+		return nil, fmt.Errorf("invalid IDS rule action %q: must be alert or pass", opts.IDS.Action)
+	}
+
+	snapshotDate := opts.Snapshot.Format("2006-01-02")
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	sid := opts.IDS.SIDBase
+	for _, area := range opts.Areas {
+		if len(area.Ranges) == 0 {
+			continue
+		}
+		addresses := "[" + strings.Join(area.Ranges, ",") + "]"
+		metadata := fmt.Sprintf("metadata:github_area %s, snapshot_date %s;", area.Key, snapshotDate)
+
+		fmt.Fprintf(&b, "%s ip %s any -> $HOME_NET any (msg:\"GITHUB %s traffic from GitHub\"; %s sid:%d; rev:1;)\n",
+			opts.IDS.Action, addresses, area.Name, metadata, sid)
+		fmt.Fprintf(&b, "%s ip $HOME_NET any -> %s any (msg:\"GITHUB %s traffic to GitHub\"; %s sid:%d; rev:1;)\n",
+			opts.IDS.Action, addresses, area.Name, metadata, sid+1)
+		sid += 2
+	}
+	return []byte(b.String()), nil
+}
+
+// renderZeekIntel renders a Zeek intelligence framework file with an indicator per
+// range, naming the areas it belongs to in meta.area
+func renderZeekIntel(opts exportOptions) ([]byte, error) {
+	names := make(map[string][]string)
+	keys := make(map[string][]string)
+	for _, area := range opts.Areas {
+		for _, cidr := range area.Ranges {
+			names[cidr] = append(names[cidr], area.Name)
+			keys[cidr] = append(keys[cidr], area.Key)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("#fields\tindicator\tindicator_type\tmeta.source\tmeta.desc\tmeta.area\n")
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		ip, ones, err := splitCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		indicator, indicatorType := cidr, "Intel::SUBNET"
+		if (ones == 32 && !strings.Contains(ip, ":")) || ones == 128 {
+			indicator, indicatorType = ip, "Intel::ADDR"
+		}
+		fmt.Fprintf(&b, "%s\t%s\tgithub-meta\tGitHub %s\t%s\n",
+			indicator, indicatorType, strings.Join(names[cidr], ", "), strings.Join(keys[cidr], ","))
+	}
+	return []byte(b.String()), nil
+}
+
+// stixNamespace is the UUIDv5 namespace STIX 2.1 defines for deterministic identifiers
+var stixNamespace = [16]byte{0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c, 0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7}
+
+// stixTLPClear is the STIX 2.1 TLP:WHITE (TLP:CLEAR) marking definition
+var stixTLPClear = map[string]interface{}{
+	"type":            "marking-definition",
+	"spec_version":    "2.1",
+	"id":              "marking-definition--613f2e26-407d-48c7-9eca-b8e91df99dc9",
+	"created":         "2017-01-20T00:00:00.000Z",
+	"definition_type": "tlp",
+	"name":            "TLP:WHITE",
+	"definition":      map[string]string{"tlp": "white"},
+}
+
+// uuidV5 returns the name based UUID of name in namespace, as defined by RFC 4122
+func uuidV5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	u := h.Sum(nil)[:16]
+	u[6] = (u[6] & 0x0f) | 0x50
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// stixID returns a deterministic STIX identifier of type for the given contributing properties
+func stixID(objectType string, properties map[string]string) string {
+	data, _ := json.Marshal(properties)
+	return objectType + "--" + uuidV5(stixNamespace, string(data))
+}
+
+// renderSTIXBundle renders a STIX 2.1 bundle with an ipv4-addr or ipv6-addr
+// object per range and a grouping per area referencing its ranges, attributed
+// to GitHub and marked TLP:WHITE
+func renderSTIXBundle(opts exportOptions) ([]byte, error) {
+	timestamp := opts.Snapshot.UTC().Format("2006-01-02T15:04:05.000Z")
+	marking := stixTLPClear["id"].(string)
+
+	identity := map[string]interface{}{
+		"type":                "identity",
+		"spec_version":        "2.1",
+		"id":                  stixID("identity", map[string]string{"name": "GitHub"}),
+		"created":             timestamp,
+		"modified":            timestamp,
+		"name":                "GitHub",
+		"identity_class":      "organization",
+		"object_marking_refs": []string{marking},
+	}
+	objects := []interface{}{stixTLPClear, identity}
+
+	addresses := make(map[string]string)
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		objectType := "ipv4-addr"
+		if strings.Contains(cidr, ":") {
+			objectType = "ipv6-addr"
+		}
+		id := stixID(objectType, map[string]string{"value": cidr})
+		addresses[cidr] = id
+		objects = append(objects, map[string]interface{}{
+			"type":                objectType,
+			"spec_version":        "2.1",
+			"id":                  id,
+			"value":               cidr,
+			"object_marking_refs": []string{marking},
+		})
+	}
+
+	for _, area := range opts.Areas {
+		if len(area.Ranges) == 0 {
+			continue
+		}
+		refs := make([]string, 0, len(area.Ranges))
+		for _, cidr := range area.Ranges {
+			if id, ok := addresses[cidr]; ok {
+				refs = append(refs, id)
+			}
+		}
+		objects = append(objects, map[string]interface{}{
+			"type":                "grouping",
+			"spec_version":        "2.1",
+			"id":                  stixID("grouping", map[string]string{"area": area.Key, "snapshot": timestamp}),
+			"created":             timestamp,
+			"modified":            timestamp,
+			"created_by_ref":      identity["id"],
+			"name":                "GitHub " + area.Name + " IP ranges",
+			"context":             "unspecified",
+			"object_refs":         refs,
+			"object_marking_refs": []string{marking},
+		})
+	}
+
+	return marshalExportJSON(map[string]interface{}{
+		"type":    "bundle",
+		"id":      stixID("bundle", map[string]string{"source": opts.SourceHash, "snapshot": timestamp}),
+		"objects": objects,
+	})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderSuricataRules(t *testing.T) {
+	opts := exportOptions{
+		Areas:    append(testAreas()[:2], Area{Key: "pages", Name: "Pages"}),
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		IDS:      idsOptions{Action: "alert", SIDBase: 9100000},
+	}
+
+	out, err := renderSuricataRules(opts)
+	if err != nil {
+		t.Fatalf("renderSuricataRules() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("renderSuricataRules() = %d lines, want header and 4 rules:\n%s", len(lines), out)
+	}
+
+	want := `alert ip [192.30.252.0/22,2a0a:a440::/29] any -> $HOME_NET any (msg:"GITHUB Hooks traffic from GitHub"; ` +
+		`metadata:github_area hooks, snapshot_date 2026-10-14; sid:9100000; rev:1;)`
+	if lines[1] != want {
+		t.Errorf("renderSuricataRules() first rule = %q, want %q", lines[1], want)
+	}
+	if !strings.Contains(lines[4], "$HOME_NET any -> [192.30.252.0/22,140.82.112.0/20] any") || !strings.Contains(lines[4], "sid:9100003;") {
+		t.Errorf("renderSuricataRules() last rule = %q", lines[4])
+	}
+
+	opts.IDS.Action = "drop"
+	if _, err := renderSuricataRules(opts); err == nil {
+		t.Errorf("renderSuricataRules() with invalid action should fail")
+	}
+}
+
+func TestRenderZeekIntel(t *testing.T) {
+	areas := append(testAreas()[:2], Area{Key: "api", Name: "API", Ranges: []string{"140.82.121.4/32"}})
+
+	out, err := renderZeekIntel(exportOptions{Areas: areas})
+	if err != nil {
+		t.Fatalf("renderZeekIntel() error = %v", err)
+	}
+	want := "#fields\tindicator\tindicator_type\tmeta.source\tmeta.desc\tmeta.area\n" +
+		"192.30.252.0/22\tIntel::SUBNET\tgithub-meta\tGitHub Hooks, Web\thooks,web\n" +
+		"2a0a:a440::/29\tIntel::SUBNET\tgithub-meta\tGitHub Hooks\thooks\n" +
+		"140.82.112.0/20\tIntel::SUBNET\tgithub-meta\tGitHub Web\tweb\n" +
+		"140.82.121.4\tIntel::ADDR\tgithub-meta\tGitHub API\tapi\n"
+	if string(out) != want {
+		t.Errorf("renderZeekIntel() = %q, want %q", out, want)
+	}
+}
+
+func TestUUIDV5(t *testing.T) {
+	// RFC 4122 test vector: the DNS namespace and "www.example.com"
+	dns := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	if got := uuidV5(dns, "www.example.com"); got != "2ed6657d-e927-568b-95e1-2665a8aea6a2" {
+		t.Errorf("uuidV5() = %q, want %q", got, "2ed6657d-e927-568b-95e1-2665a8aea6a2")
+	}
+
+	got := stixID("ipv4-addr", map[string]string{"value": "198.51.100.3"})
+	if !strings.HasPrefix(got, "ipv4-addr--") || got != stixID("ipv4-addr", map[string]string{"value": "198.51.100.3"}) {
+		t.Errorf("stixID() = %q, should be a deterministic ipv4-addr identifier", got)
+	}
+}
+
+func TestRenderSTIXBundle(t *testing.T) {
+	opts := exportOptions{
+		Areas:      testAreas()[:2],
+		Snapshot:   time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		SourceHash: "abc123",
+	}
+
+	out, err := renderSTIXBundle(opts)
+	if err != nil {
+		t.Fatalf("renderSTIXBundle() error = %v", err)
+	}
+
+	var bundle struct {
+		Type    string                   `json:"type"`
+		ID      string                   `json:"id"`
+		Objects []map[string]interface{} `json:"objects"`
+	}
+	if err := json.Unmarshal(out, &bundle); err != nil {
+		t.Fatalf("renderSTIXBundle() produced invalid JSON: %v", err)
+	}
+	if bundle.Type != "bundle" || !strings.HasPrefix(bundle.ID, "bundle--") {
+		t.Errorf("renderSTIXBundle() bundle = %s %s", bundle.Type, bundle.ID)
+	}
+
+	counts := make(map[string]int)
+	for _, object := range bundle.Objects {
+		counts[object["type"].(string)]++
+	}
+	want := map[string]int{"marking-definition": 1, "identity": 1, "ipv4-addr": 2, "ipv6-addr": 1, "grouping": 2}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("renderSTIXBundle() object counts = %v, want %v", counts, want)
+	}
+
+	grouping := bundle.Objects[len(bundle.Objects)-1]
+	if grouping["name"] != "GitHub Web IP ranges" || len(grouping["object_refs"].([]interface{})) != 2 {
+		t.Errorf("renderSTIXBundle() last grouping = %v", grouping)
+	}
+	if grouping["created"] != "2026-10-14T12:00:00.000Z" {
+		t.Errorf("renderSTIXBundle() created = %v", grouping["created"])
+	}
+
+	again, _ := renderSTIXBundle(opts)
+	if string(again) != string(out) {
+		t.Errorf("renderSTIXBundle() should be deterministic")
+	}
+}
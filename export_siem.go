@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("splunk-lookup", "Splunk CSV lookup table", renderFunc(renderSplunkLookup))
+	registerExporter("splunk-transforms", "Splunk transforms.conf stanza for the lookup table", renderFunc(renderSplunkTransforms))
+	registerExporter("elasticsearch-bulk", "Elasticsearch _bulk request indexing a document per range (NDJSON)", renderFunc(renderElasticsearchBulk))
+	registerExporter("elasticsearch-enrich", "Elasticsearch enrich policy and ingest pipeline (Dev Tools console)", renderFunc(renderElasticsearchEnrich))
+}
+
+// elasticsearchOptions contains the settings for the Elasticsearch export formats
+type elasticsearchOptions struct {
+	Field string
+}
+
+func addElasticsearchFlags(cmd *cobra.Command) {
+	cmd.Flags().String("elasticsearch-field", "source.ip", "Event field holding the IP address looked up by the Elasticsearch ingest pipeline")
+}
+
+func elasticsearchOptionsFromFlags(cmd *cobra.Command) elasticsearchOptions {
+	var opts elasticsearchOptions
+	opts.Field, _ = cmd.Flags().GetString("elasticsearch-field")
+	return opts
+}
+
+// areaKeysByRange maps each range to the keys of the areas publishing it
+func areaKeysByRange(areas []Area) map[string][]string {
+	keys := make(map[string][]string)
+	for _, area := range areas {
+		for _, cidr := range area.Ranges {
+			keys[cidr] = append(keys[cidr], area.Key)
+		}
+	}
+	return keys
+}
+
+// renderSplunkLookup renders a CSV lookup table with a row per range, for use
+// with a CIDR match_type lookup definition
+func renderSplunkLookup(opts exportOptions) ([]byte, error) {
+	keys := areaKeysByRange(opts.Areas)
+	snapshotDate := opts.Snapshot.Format("2006-01-02")
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"cidr", "area", "is_github", "snapshot_date"})
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		w.Write([]string{cidr, strings.Join(keys[cidr], ","), "true", snapshotDate})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to encode export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSplunkTransforms renders the transforms.conf stanza defining the lookup
+// produced by renderSplunkLookup with CIDR matching
+func renderSplunkTransforms(opts exportOptions) ([]byte, error) {
+	name := identifierName(opts.Name) + "_ip_ranges"
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	fmt.Fprintf(&b, "[%s]\n", name)
+	fmt.Fprintf(&b, "filename = %s.csv\n", name)
+	b.WriteString("match_type = CIDR(cidr)\n")
+	b.WriteString("max_matches = 1\n")
+	return []byte(b.String()), nil
+}
+
+// elasticsearchIndex returns the name of the index holding the range documents
+func elasticsearchIndex(name string) string {
+	return strings.ToLower(name) + "-ip-ranges"
+}
+
+// elasticsearchRangeDoc is the document indexed for each range
+type elasticsearchRangeDoc struct {
+	CIDR         string   `json:"cidr"`
+	Area         []string `json:"area"`
+	IsGitHub     bool     `json:"is_github"`
+	SnapshotDate string   `json:"snapshot_date"`
+}
+
+// renderElasticsearchBulk renders a _bulk request body indexing a document per
+// range, keyed by the range so reloading replaces existing documents
+func renderElasticsearchBulk(opts exportOptions) ([]byte, error) {
+	keys := areaKeysByRange(opts.Areas)
+	index := elasticsearchIndex(opts.Name)
+	snapshotDate := opts.Snapshot.Format("2006-01-02")
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, cidr := range uniqueRanges(opts.Areas) {
+		action := map[string]map[string]string{"index": {"_index": index, "_id": cidr}}
+		doc := elasticsearchRangeDoc{
+			CIDR:         cidr,
+			Area:         keys[cidr],
+			IsGitHub:     true,
+			SnapshotDate: snapshotDate,
+		}
+		if err := enc.Encode(action); err != nil {
+			return nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode export: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// renderElasticsearchEnrich renders the Kibana Dev Tools requests creating the
+// range index, a range enrich policy over it and an ingest pipeline adding the
+// github.area and github.is_github fields to events. The documents themselves
+// are loaded with the elasticsearch-bulk format before executing the policy.
+func renderElasticsearchEnrich(opts exportOptions) ([]byte, error) {
+	index := elasticsearchIndex(opts.Name)
+	policy := index + "-policy"
+
+	requests := []struct {
+		method, path string
+		body         interface{}
+	}{
+		{"PUT", "/" + index, map[string]interface{}{
+			"mappings": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"cidr":          map[string]string{"type": "ip_range"},
+					"area":          map[string]string{"type": "keyword"},
+					"is_github":     map[string]string{"type": "boolean"},
+					"snapshot_date": map[string]string{"type": "date"},
+				},
+			},
+		}},
+		{"PUT", "/_enrich/policy/" + policy, map[string]interface{}{
+			"range": map[string]interface{}{
+				"indices":       index,
+				"match_field":   "cidr",
+				"enrich_fields": []string{"area", "is_github", "snapshot_date"},
+			},
+		}},
+		{"POST", "/_enrich/policy/" + policy + "/_execute", nil},
+		{"PUT", "/_ingest/pipeline/" + index, map[string]interface{}{
+			"description": "Adds the GitHub functional area of " + opts.Elasticsearch.Field,
+			"processors": []interface{}{
+				map[string]interface{}{
+					"enrich": map[string]interface{}{
+						"policy_name":    policy,
+						"field":          opts.Elasticsearch.Field,
+						"target_field":   "github",
+						"max_matches":    1,
+						"ignore_missing": true,
+					},
+				},
+			},
+		}},
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + exportHeader + "\n")
+	for i, req := range requests {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s %s\n", req.method, req.path)
+		if req.body == nil {
+			continue
+		}
+		body, err := marshalExportJSON(req.body)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(body)
+	}
+	return []byte(b.String()), nil
+}
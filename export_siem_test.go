@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderSplunkLookup(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[:2],
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := renderSplunkLookup(opts)
+	if err != nil {
+		t.Fatalf("renderSplunkLookup() error = %v", err)
+	}
+	want := "cidr,area,is_github,snapshot_date\n" +
+		"192.30.252.0/22,\"hooks,web\",true,2026-10-14\n" +
+		"2a0a:a440::/29,hooks,true,2026-10-14\n" +
+		"140.82.112.0/20,web,true,2026-10-14\n"
+	if string(out) != want {
+		t.Errorf("renderSplunkLookup() = %q, want %q", out, want)
+	}
+}
+
+func TestRenderSplunkTransforms(t *testing.T) {
+	out, err := renderSplunkTransforms(exportOptions{Name: "github"})
+	if err != nil {
+		t.Fatalf("renderSplunkTransforms() error = %v", err)
+	}
+	want := "# GitHub IP ranges generated by gh-check-github-ip-ranges\n" +
+		"[github_ip_ranges]\nfilename = github_ip_ranges.csv\nmatch_type = CIDR(cidr)\nmax_matches = 1\n"
+	if string(out) != want {
+		t.Errorf("renderSplunkTransforms() = %q, want %q", out, want)
+	}
+}
+
+func TestRenderElasticsearchBulk(t *testing.T) {
+	opts := exportOptions{
+		Areas:    testAreas()[:2],
+		Name:     "github",
+		Snapshot: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+	}
+
+	out, err := renderElasticsearchBulk(opts)
+	if err != nil {
+		t.Fatalf("renderElasticsearchBulk() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("renderElasticsearchBulk() returned %d lines, want 6:\n%s", len(lines), out)
+	}
+	if want := `{"index":{"_id":"192.30.252.0/22","_index":"github-ip-ranges"}}`; lines[0] != want {
+		t.Errorf("action = %s, want %s", lines[0], want)
+	}
+	if want := `{"cidr":"192.30.252.0/22","area":["hooks","web"],"is_github":true,"snapshot_date":"2026-10-14"}`; lines[1] != want {
+		t.Errorf("document = %s, want %s", lines[1], want)
+	}
+}
+
+func TestRenderElasticsearchEnrich(t *testing.T) {
+	opts := exportOptions{
+		Areas:         testAreas(),
+		Name:          "github",
+		Elasticsearch: elasticsearchOptions{Field: "client.ip"},
+	}
+
+	out, err := renderElasticsearchEnrich(opts)
+	if err != nil {
+		t.Fatalf("renderElasticsearchEnrich() error = %v", err)
+	}
+	for _, want := range []string{
+		"PUT /github-ip-ranges\n",
+		`"type": "ip_range"`,
+		"PUT /_enrich/policy/github-ip-ranges-policy\n",
+		`"match_field": "cidr"`,
+		"POST /_enrich/policy/github-ip-ranges-policy/_execute\n",
+		"PUT /_ingest/pipeline/github-ip-ranges\n",
+		`"field": "client.ip"`,
+		`"target_field": "github"`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderElasticsearchEnrich() missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	registerExporter("sqlite", "Snapshot added to a SQLite history database (SQL script without a file)", sqliteExporter{})
+}
+
+// sqliteExporter adds the snapshot to a history database given as the output
+// file, or renders the SQL script doing so
+type sqliteExporter struct{}
+
+func (sqliteExporter) Render(opts exportOptions) ([]byte, error) { return renderSQLite(opts) }
+func (sqliteExporter) WriteFile(path string, opts exportOptions) error {
+	return appendSQLiteHistory(path, opts)
+}
+
+// sqliteOptions contains the settings for the sqlite export format
+type sqliteOptions struct {
+	// Retention prunes the older snapshots once the new one is added
+	Retention retentionPolicy
+}
+
+func addSQLiteFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("sqlite-keep", 0, "After adding the snapshot, keep only this many of the latest snapshots, besides those kept by --sqlite-keep-daily (default all)")
+	cmd.Flags().Int("sqlite-keep-daily", 0, "After adding the snapshot, keep only the latest snapshot of each of this many latest days, besides those kept by --sqlite-keep")
+}
+
+func sqliteOptionsFromFlags(cmd *cobra.Command) sqliteOptions {
+	var opts sqliteOptions
+	opts.Retention.Keep, _ = cmd.Flags().GetInt("sqlite-keep")
+	opts.Retention.KeepDaily, _ = cmd.Flags().GetInt("sqlite-keep-daily")
+	return opts
+}
+
+// sqliteCommand is the sqlite3 CLI used to write and query snapshot history
+var sqliteCommand = "sqlite3"
+
+// sqliteSchema creates the snapshot history tables. Snapshot times are stored
+// as RFC 3339 UTC timestamps so they sort chronologically as text.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS snapshots (
+  snapshot_time TEXT PRIMARY KEY,
+  source_hash TEXT NOT NULL,
+  fetched_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ranges (
+  snapshot_time TEXT NOT NULL REFERENCES snapshots (snapshot_time),
+  area TEXT NOT NULL,
+  cidr TEXT NOT NULL,
+  PRIMARY KEY (snapshot_time, area, cidr)
+);
+`
+
+// sqlQuote quotes s as an SQL string literal
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// renderSQLite renders the SQL script adding the snapshot to a history database,
+// then pruning the snapshots the retention policy doesn't keep. Re-running it
+// for a snapshot already stored leaves the database unchanged.
+func renderSQLite(opts exportOptions) ([]byte, error) {
+	if err := opts.SQLite.Retention.validate(); err != nil {
+		return nil, err
+	}
+	snapshot := sqlQuote(opts.Snapshot.UTC().Format(time.RFC3339))
+
+	var b strings.Builder
+	b.WriteString("-- " + exportHeader + "\n")
+	b.WriteString(sqliteSchema)
+	b.WriteString("BEGIN;\n")
+	fmt.Fprintf(&b, "INSERT OR IGNORE INTO snapshots (snapshot_time, source_hash, fetched_at) VALUES (%s, %s, %s);\n",
+		snapshot, sqlQuote(opts.SourceHash), sqlQuote(opts.Generated.UTC().Format(time.RFC3339)))
+	for _, area := range opts.Areas {
+		for _, cidr := range area.Ranges {
+			fmt.Fprintf(&b, "INSERT OR IGNORE INTO ranges (snapshot_time, area, cidr) VALUES (%s, %s, %s);\n",
+				snapshot, sqlQuote(area.Key), sqlQuote(cidr))
+		}
+	}
+	if opts.SQLite.Retention.enabled() {
+		b.WriteString(opts.SQLite.Retention.pruneStatements(opts.Generated))
+	}
+	b.WriteString("COMMIT;\n")
+	return []byte(b.String()), nil
+}
+
+// appendSQLiteHistory adds the snapshot to the history database at path,
+// creating it if needed
+func appendSQLiteHistory(path string, opts exportOptions) error {
+	script, err := renderSQLite(opts)
+	if err != nil {
+		return err
+	}
+	_, err = runSQLite(script, "-bail", path)
+	return err
+}
+
+// runSQLite runs the sqlite3 CLI with args, feeding it input on stdin
+func runSQLite(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(sqliteCommand, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("sqlite3 failed: %s", msg)
+		}
+		return nil, fmt.Errorf("failed to run sqlite3: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSQLite replaces the sqlite3 CLI with a script printing stdout and recording
+// its arguments and input, returning the directory holding the "args" and
+// "stdin" recordings
+func fakeSQLite(t *testing.T, stdout string) string {
+	t.Helper()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "sqlite3")
+	content := "#!/bin/sh\n" +
+		"echo \"$@\" > " + filepath.Join(dir, "args") + "\n" +
+		"cat > " + filepath.Join(dir, "stdin") + "\n" +
+		"printf '%s' '" + stdout + "'\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	original := sqliteCommand
+	sqliteCommand = script
+	t.Cleanup(func() { sqliteCommand = original })
+	return dir
+}
+
+func TestRenderSQLite(t *testing.T) {
+	opts := exportOptions{
+		Areas:      testAreas()[:1],
+		Snapshot:   time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC),
+		Generated:  time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		SourceHash: "abc123",
+	}
+
+	out, err := renderSQLite(opts)
+	if err != nil {
+		t.Fatalf("renderSQLite() error = %v", err)
+	}
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS snapshots (",
+		"CREATE TABLE IF NOT EXISTS ranges (",
+		"INSERT OR IGNORE INTO snapshots (snapshot_time, source_hash, fetched_at) VALUES ('2026-10-01T08:00:00Z', 'abc123', '2026-10-14T12:00:00Z');\n",
+		"INSERT OR IGNORE INTO ranges (snapshot_time, area, cidr) VALUES ('2026-10-01T08:00:00Z', 'hooks', '192.30.252.0/22');\n",
+		"INSERT OR IGNORE INTO ranges (snapshot_time, area, cidr) VALUES ('2026-10-01T08:00:00Z', 'hooks', '2a0a:a440::/29');\n",
+		"COMMIT;\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("renderSQLite() missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderSQLiteRetention(t *testing.T) {
+	opts := exportOptions{
+		Areas:     testAreas()[:1],
+		Snapshot:  time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC),
+		Generated: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		SQLite:    sqliteOptions{Retention: retentionPolicy{Keep: 10, KeepDaily: 90}},
+	}
+	out, err := renderSQLite(opts)
+	if err != nil {
+		t.Fatalf("renderSQLite() error = %v", err)
+	}
+	if want := opts.SQLite.Retention.pruneStatements(opts.Generated) + "COMMIT;\n"; !strings.HasSuffix(string(out), want) {
+		t.Errorf("renderSQLite() = %s, should end with pruning %s", out, want)
+	}
+
+	opts.SQLite.Retention.Keep = -1
+	if _, err := renderSQLite(opts); err == nil {
+		t.Errorf("renderSQLite() with a negative retention count succeeded")
+	}
+}
+
+func TestSQLQuote(t *testing.T) {
+	if got, want := sqlQuote("it's"), "'it''s'"; got != want {
+		t.Errorf("sqlQuote() = %s, want %s", got, want)
+	}
+}
+
+func TestAppendSQLiteHistory(t *testing.T) {
+	dir := fakeSQLite(t, "")
+	opts := exportOptions{Areas: testAreas(), Snapshot: time.Now()}
+
+	if err := appendSQLiteHistory("ranges.db", opts); err != nil {
+		t.Fatalf("appendSQLiteHistory() error = %v", err)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if got := strings.TrimSpace(string(args)); got != "-bail ranges.db" {
+		t.Errorf("sqlite3 args = %q, want %q", got, "-bail ranges.db")
+	}
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin"))
+	want, _ := renderSQLite(opts)
+	if string(stdin) != string(want) {
+		t.Errorf("sqlite3 input = %q, want %q", stdin, want)
+	}
+}
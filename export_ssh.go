@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerExporter("known-hosts", "known_hosts lines for GitHub's SSH host keys", renderFunc(renderKnownHosts))
+}
+
+// knownHostsHosts are the host patterns of GitHub's SSH endpoints, including SSH
+// over the HTTPS port for networks that block port 22
+var knownHostsHosts = []string{"github.com", "[ssh.github.com]:443"}
+
+// renderKnownHosts renders GitHub's published SSH host keys as known_hosts lines,
+// so build agents can be provisioned without trust-on-first-use prompts
+func renderKnownHosts(opts exportOptions) ([]byte, error) {
+	if len(opts.SSHKeys) == 0 {
+		return nil, fmt.Errorf("GitHub meta response has no SSH keys")
+	}
+
+	var b strings.Builder
+	b.WriteString("# GitHub SSH host keys generated by gh-check-github-ip-ranges\n")
+	for _, s := range opts.SSHKeys {
+		key, err := parseSSHKey(s)
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(s)
+		b.WriteString(strings.Join(knownHostsHosts, ",") + " " + key.Type + " " + fields[1] + "\n")
+	}
+	return []byte(b.String()), nil
+}
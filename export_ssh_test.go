@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestRenderKnownHosts(t *testing.T) {
+	out, err := renderKnownHosts(exportOptions{SSHKeys: []string{testSSHKey}})
+	if err != nil {
+		t.Fatalf("renderKnownHosts() error = %v", err)
+	}
+
+	want := "# GitHub SSH host keys generated by gh-check-github-ip-ranges\n" +
+		"github.com,[ssh.github.com]:443 " + testSSHKey + "\n"
+	if string(out) != want {
+		t.Errorf("renderKnownHosts() = %q, want %q", out, want)
+	}
+
+	if _, err := renderKnownHosts(exportOptions{}); err == nil {
+		t.Error("renderKnownHosts() without keys should fail")
+	}
+	if _, err := renderKnownHosts(exportOptions{SSHKeys: []string{"ssh-ed25519"}}); err == nil {
+		t.Error("renderKnownHosts() with an invalid key should fail")
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAreas returns a small set of areas used by the export tests
+func testAreas() []Area {
+	return []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22", "2a0a:a440::/29"}},
+		{Key: "web", Name: "Web", Ranges: []string{"192.30.252.0/22", "140.82.112.0/20"}},
+		{Key: "actions_ipv4", Name: "Actions IPv4", Ranges: []string{"4.148.0.0/16"}},
+	}
+}
+
+// testMetaJSON is a trimmed down /meta response used by the export tests
+const testMetaJSON = `{
+	"hooks": ["192.30.252.0/22", "2a0a:a440::/29"],
+	"web": ["192.30.252.0/22", "140.82.112.0/20"],
+	"api": ["192.30.252.0/22"],
+	"git": ["192.30.252.0/22"],
+	"actions": ["4.148.0.0/16"],
+	"actions_ipv4": ["4.148.0.0/16"],
+	"domains": {
+		"website": ["*.github.com"],
+		"actions": ["*.actions.githubusercontent.com"],
+		"actions_inbound": {"full_domains": ["github.com"], "wildcard_domains": ["*.github.com"]}
+	}
+}`
+
+// newTestMetaServer starts a server responding with testMetaJSON
+func newTestMetaServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(testMetaJSON))
+	}))
+}
+
+func TestSelectAreas(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		wantKeys []string
+		wantErr  bool
+	}{
+		{
+			name:     "No names selects all",
+			names:    nil,
+			wantKeys: []string{"hooks", "web", "actions_ipv4", "actions_macos"},
+		},
+		{
+			name:     "Key names",
+			names:    []string{"web", "hooks"},
+			wantKeys: []string{"web", "hooks"},
+		},
+		{
+			name:     "Display and dashed names",
+			names:    []string{"Actions IPv4", "HOOKS", "actions-ipv4"},
+			wantKeys: []string{"actions_ipv4", "hooks", "actions_ipv4"},
+		},
+		{
+			name:     "Aliases",
+			names:    []string{"webhooks", "macos"},
+			wantKeys: []string{"hooks", "actions_macos"},
+		},
+		{
+			name:    "Unknown area",
+			names:   []string{"nope"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			areas := append(testAreas(), Area{Key: "actions_macos", Name: "Actions macOS"})
+			got, err := selectAreas(areas, tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectAreas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var keys []string
+			for _, area := range got {
+				keys = append(keys, area.Key)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("selectAreas() keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestUniqueRanges(t *testing.T) {
+	got := uniqueRanges(testAreas())
+	want := []string{"192.30.252.0/22", "2a0a:a440::/29", "140.82.112.0/20", "4.148.0.0/16"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueRanges() = %v, want %v", got, want)
+	}
+
+	got = ipv4Ranges(got)
+	want = []string{"192.30.252.0/22", "140.82.112.0/20", "4.148.0.0/16"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ipv4Ranges() = %v, want %v", got, want)
+	}
+}
+
+func TestRelevantDomains(t *testing.T) {
+	groups := map[string][]string{
+		"website": {"*.github.com", "github.com"},
+		"actions": {"*.actions.githubusercontent.com", "github.com"},
+		"copilot": {"*.githubcopilot.com"},
+	}
+	areas := []Area{{Key: "web"}, {Key: "actions"}, {Key: "hooks"}}
+
+	got := relevantDomains(groups, areas)
+	want := []string{"*.github.com", "github.com", "*.actions.githubusercontent.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("relevantDomains() = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultExportName(t *testing.T) {
+	if got := defaultExportName(testAreas(), true); got != "github" {
+		t.Errorf("defaultExportName() = %q, want %q", got, "github")
+	}
+	if got := defaultExportName(testAreas()[1:], false); got != "github-web-actions-ipv4" {
+		t.Errorf("defaultExportName() = %q, want %q", got, "github-web-actions-ipv4")
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	server := newTestMetaServer(t)
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantErr    bool
+		wantOutput string
+	}{
+		{
+			name:       "Azure NSG rule for hooks",
+			args:       []string{"--format", "azure-nsg", "--area", "hooks"},
+			wantOutput: `"name": "github-hooks"`,
+		},
+		{
+			name:       "HAProxy with reload command",
+			args:       []string{"--format", "haproxy", "--reload", "touch reloaded"},
+			wantOutput: "192.30.252.0/22\n",
+		},
+		{
+			name:    "Unknown format",
+			args:    []string{"--format", "nope"},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported --apply",
+			args:    []string{"--format", "haproxy", "--apply"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown area",
+			args:    []string{"--format", "azure-nsg", "--area", "nope"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "export.out")
+			t.Chdir(dir)
+
+			cmd := newExportCommand()
+			cmd.SetArgs(append(tt.args, path))
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("export error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			out, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read export: %v", err)
+			}
+			if !strings.Contains(string(out), tt.wantOutput) {
+				t.Errorf("export output = %s, should contain %s", out, tt.wantOutput)
+			}
+
+			_, err = os.Stat(filepath.Join(dir, "reloaded"))
+			if reload := strings.Contains(strings.Join(tt.args, " "), "--reload"); reload != (err == nil) {
+				t.Errorf("export reload command ran = %v, want %v", err == nil, reload)
+			}
+		})
+	}
+}
+
+func TestExporters(t *testing.T) {
+	// The format specific settings take their flags' defaults
+	cmd := newExportCommand()
+	opts := exportOptions{
+		Areas:     testAreas(),
+		SSHKeys:   []string{testSSHKey},
+		Name:      "github",
+		Generated: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+
+		Azure:      azureOptionsFromFlags(cmd),
+		GCP:        gcpOptionsFromFlags(cmd),
+		Cloudflare: cloudflareOptionsFromFlags(cmd),
+		Fastly:     fastlyOptionsFromFlags(cmd),
+		K8s:        k8sOptionsFromFlags(cmd),
+		RPZ:        rpzOptionsFromFlags(cmd),
+		IDS:        idsOptionsFromFlags(cmd),
+		SQLite:     sqliteOptionsFromFlags(cmd),
+
+		Elasticsearch: elasticsearchOptionsFromFlags(cmd),
+	}
+	for name, e := range exporters {
+		if e.description == "" {
+			t.Errorf("export format %s has no description", name)
+		}
+		if out, err := e.exporter.Render(opts); err != nil || len(out) == 0 {
+			t.Errorf("export format %s Render() = %d bytes, %v", name, len(out), err)
+		}
+	}
+
+	list := exporterList()
+	for _, want := range []string{"  haproxy ", "  azure-nsg ", "(supports --apply)\n"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("exporterList() = %q, should contain %q", list, want)
+		}
+	}
+	if strings.Index(list, "  ansible ") > strings.Index(list, "  zeek-intel ") {
+		t.Errorf("exporterList() = %q, want the formats in name order", list)
+	}
+}
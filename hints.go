@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultAzureServiceTags are the Azure service tags GitHub-hosted runners
+// egress from. GitHub doesn't say which regions it uses, so all of Azure's
+// public cloud is the honest default.
+var defaultAzureServiceTags = []string{"AzureCloud"}
+
+// fastlyPublicIPListURL lists the addresses of Fastly's edge, which serves GitHub Pages sites
+var fastlyPublicIPListURL = "https://api.fastly.com/public-ip-list"
+
+// HintSource is a secondary list of ranges that aren't GitHub-owned but suggest
+// GitHub traffic, consulted for addresses outside GitHub's ranges
+type HintSource struct {
+	Name        string // Source of the ranges, e.g. "Azure service tags"
+	Description string // What a match suggests, e.g. "consistent with a GitHub-hosted Actions runner"
+	Ranges      []HintRange
+}
+
+// HintRange is a range of a hint source, with the tag it is published under if
+// the source has several
+type HintRange struct {
+	Tag   string
+	Range string
+}
+
+// Hint is a heuristic match of an address that isn't GitHub-owned
+type Hint struct {
+	Source      string
+	Description string
+	Tag         string
+	Range       string
+}
+
+// hintRange is a parsed range of a hint source
+type hintRange struct {
+	source *HintSource
+	tag    string
+	cidr   string
+	prefix netip.Prefix
+}
+
+// AddHintSource adds a secondary source consulted for addresses that aren't in
+// GitHub's ranges. Sources are consulted in the order added, and their invalid
+// ranges are skipped.
+func (c *IPChecker) AddHintSource(source HintSource) {
+	for _, r := range source.Ranges {
+		if prefix, err := netip.ParsePrefix(r.Range); err == nil {
+			c.hints = append(c.hints, hintRange{&source, r.Tag, r.Range, prefix.Masked()})
+		}
+	}
+}
+
+// hint returns the first match of ip in the hint sources, or nil
+func (c *IPChecker) hint(ip netip.Addr) *Hint {
+	for _, r := range c.hints {
+		if r.prefix.Contains(ip) {
+			return &Hint{r.source.Name, r.source.Description, r.tag, r.cidr}
+		}
+	}
+	return nil
+}
+
+// formatHint describes a hint for text output, making clear it is a guess
+func formatHint(hint *Hint) string {
+	source := hint.Source
+	if hint.Tag != "" {
+		source += " " + hint.Tag
+	}
+	return fmt.Sprintf("not a GitHub-owned address, but %s (heuristic: %s, %s)",
+		hint.Description, source, hint.Range)
+}
+
+// addHintFlags adds the flags selecting the hint sources
+func addHintFlags(cmd *cobra.Command) {
+	cmd.Flags().String("azure-service-tags", "", "Azure service tags JSON file or URL, used to flag addresses consistent with GitHub-hosted runners")
+	cmd.Flags().StringSlice("azure-service-tag", defaultAzureServiceTags, "Azure service tags whose ranges suggest a GitHub-hosted runner (used with --azure-service-tags)")
+	cmd.Flags().Bool("fastly-pages", false, "Flag addresses in Fastly's public IP list, which may serve GitHub Pages sites")
+	cmd.Flags().String("fastly-ip-list", fastlyPublicIPListURL, "Fastly public IP list file or URL (used with --fastly-pages)")
+}
+
+// applyHintSources loads the hint sources selected by the flags into checker
+func applyHintSources(cmd *cobra.Command, checker *IPChecker) error {
+	if path, _ := cmd.Flags().GetString("azure-service-tags"); path != "" {
+		tags, _ := cmd.Flags().GetStringSlice("azure-service-tag")
+		data, err := readFileOrURL(path)
+		if err != nil {
+			return err
+		}
+		source, err := parseAzureServiceTags(data, tags)
+		if err != nil {
+			return withCategory(errorCategoryInput, fmt.Errorf("%s: %w", path, err))
+		}
+		checker.AddHintSource(source)
+	}
+	if fastly, _ := cmd.Flags().GetBool("fastly-pages"); fastly {
+		path, _ := cmd.Flags().GetString("fastly-ip-list")
+		data, err := readFileOrURL(path)
+		if err != nil {
+			return err
+		}
+		source, err := parseFastlyIPList(data)
+		if err != nil {
+			return withCategory(errorCategoryInput, fmt.Errorf("%s: %w", path, err))
+		}
+		checker.AddHintSource(source)
+	}
+	return nil
+}
+
+// readFileOrURL reads a local file, or fetches an http or https URL
+func readFileOrURL(path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to read %s: %w", path, err))
+		}
+		return data, nil
+	}
+
+	resp, err := http.Get(path)
+	if err != nil {
+		return nil, withCategory(errorCategoryNetwork, fmt.Errorf("failed to fetch %s: %w", path, err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, withCategory(errorCategoryNetwork, fmt.Errorf("%s returned status code %d", path, resp.StatusCode))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, withCategory(errorCategoryNetwork, fmt.Errorf("failed to read %s: %w", path, err))
+	}
+	return data, nil
+}
+
+// azureServiceTags is the weekly Azure service tags file, e.g.
+// ServiceTags_Public_20250602.json
+type azureServiceTags struct {
+	Values []struct {
+		Name       string `json:"name"`
+		Properties struct {
+			AddressPrefixes []string `json:"addressPrefixes"`
+		} `json:"properties"`
+	} `json:"values"`
+}
+
+// parseAzureServiceTags builds the hint source for the ranges of the given tags,
+// matched case-insensitively
+func parseAzureServiceTags(data []byte, tags []string) (HintSource, error) {
+	source := HintSource{
+		Name:        "Azure service tag",
+		Description: "consistent with a GitHub-hosted Actions runner",
+	}
+
+	var file azureServiceTags
+	if err := json.Unmarshal(data, &file); err != nil {
+		return source, fmt.Errorf("failed to decode Azure service tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		found := false
+		for _, value := range file.Values {
+			if !strings.EqualFold(value.Name, tag) {
+				continue
+			}
+			found = true
+			for _, prefix := range value.Properties.AddressPrefixes {
+				source.Ranges = append(source.Ranges, HintRange{value.Name, prefix})
+			}
+		}
+		if !found {
+			return source, fmt.Errorf("unknown Azure service tag %q", tag)
+		}
+	}
+	return source, nil
+}
+
+// parseFastlyIPList builds the hint source for Fastly's public IP list, as
+// returned by https://api.fastly.com/public-ip-list
+func parseFastlyIPList(data []byte) (HintSource, error) {
+	source := HintSource{
+		Name:        "Fastly public IP list",
+		Description: "possibly GitHub Pages via Fastly CDN",
+	}
+
+	var list struct {
+		Addresses     []string `json:"addresses"`
+		IPv6Addresses []string `json:"ipv6_addresses"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return source, fmt.Errorf("failed to decode Fastly public IP list: %w", err)
+	}
+	if len(list.Addresses) == 0 && len(list.IPv6Addresses) == 0 {
+		return source, fmt.Errorf("Fastly public IP list has no addresses")
+	}
+
+	for _, cidr := range append(list.Addresses, list.IPv6Addresses...) {
+		source.Ranges = append(source.Ranges, HintRange{Range: cidr})
+	}
+	return source, nil
+}
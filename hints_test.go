@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const testAzureServiceTags = `{
+  "changeNumber": 340,
+  "cloud": "Public",
+  "values": [
+    {"name": "AzureCloud.eastus", "id": "AzureCloud.eastus", "properties": {"region": "eastus", "addressPrefixes": ["20.42.0.0/17", "2603:1030:210::/47"]}},
+    {"name": "AzureCloud.westus2", "id": "AzureCloud.westus2", "properties": {"region": "westus2", "addressPrefixes": ["4.154.0.0/15"]}},
+    {"name": "Storage", "id": "Storage", "properties": {"addressPrefixes": ["20.38.96.0/19"]}}
+  ]
+}`
+
+func TestParseAzureServiceTags(t *testing.T) {
+	source, err := parseAzureServiceTags([]byte(testAzureServiceTags), []string{"azurecloud.eastus", "AzureCloud.westus2"})
+	if err != nil {
+		t.Fatalf("parseAzureServiceTags() error = %v", err)
+	}
+	want := []HintRange{
+		{"AzureCloud.eastus", "20.42.0.0/17"},
+		{"AzureCloud.eastus", "2603:1030:210::/47"},
+		{"AzureCloud.westus2", "4.154.0.0/15"},
+	}
+	if !reflect.DeepEqual(source.Ranges, want) {
+		t.Errorf("parseAzureServiceTags() ranges = %v, want %v", source.Ranges, want)
+	}
+
+	if _, err := parseAzureServiceTags([]byte(testAzureServiceTags), []string{"AzureCloud"}); err == nil {
+		t.Error("parseAzureServiceTags() with an unknown tag should fail")
+	}
+	if _, err := parseAzureServiceTags([]byte("<html>"), defaultAzureServiceTags); err == nil {
+		t.Error("parseAzureServiceTags() with invalid JSON should fail")
+	}
+}
+
+func TestParseFastlyIPList(t *testing.T) {
+	source, err := parseFastlyIPList([]byte(`{"addresses": ["151.101.0.0/16", "199.232.0.0/16"], "ipv6_addresses": ["2a04:4e40::/32"]}`))
+	if err != nil {
+		t.Fatalf("parseFastlyIPList() error = %v", err)
+	}
+	want := []HintRange{{Range: "151.101.0.0/16"}, {Range: "199.232.0.0/16"}, {Range: "2a04:4e40::/32"}}
+	if !reflect.DeepEqual(source.Ranges, want) {
+		t.Errorf("parseFastlyIPList() ranges = %v, want %v", source.Ranges, want)
+	}
+
+	for _, data := range []string{`{}`, `[]`} {
+		if _, err := parseFastlyIPList([]byte(data)); err == nil {
+			t.Errorf("parseFastlyIPList(%s) should fail", data)
+		}
+	}
+
+	var buf bytes.Buffer
+	result := &CheckResult{Hint: &Hint{source.Name, source.Description, "", "151.101.0.0/16"}}
+	writeResult(&buf, outputText, "151.101.1.195", result)
+	wantText := "IP 151.101.1.195 is not a GitHub-owned address, but possibly GitHub Pages via Fastly CDN (heuristic: Fastly public IP list, 151.101.0.0/16)\n"
+	if buf.String() != wantText {
+		t.Errorf("writeResult() text = %q, want %q", buf.String(), wantText)
+	}
+}
+
+func TestIPChecker_CheckIPHint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	checker := NewIPChecker()
+	checker.AddHintSource(HintSource{
+		Name:        "Azure service tag",
+		Description: "consistent with a GitHub-hosted Actions runner",
+		Ranges:      []HintRange{{"AzureCloud.eastus", "invalid"}, {"AzureCloud.eastus", "20.42.0.0/17"}},
+	})
+
+	tests := []struct {
+		ip   string
+		want *Hint
+	}{
+		{"20.42.1.1", &Hint{"Azure service tag", "consistent with a GitHub-hosted Actions runner", "AzureCloud.eastus", "20.42.0.0/17"}},
+		{"8.8.8.8", nil},
+		{"192.30.252.1", nil},
+	}
+	for _, tt := range tests {
+		result, err := checker.CheckIP(tt.ip)
+		if err != nil {
+			t.Fatalf("CheckIP(%s) error = %v", tt.ip, err)
+		}
+		if !reflect.DeepEqual(result.Hint, tt.want) {
+			t.Errorf("CheckIP(%s) Hint = %+v, want %+v", tt.ip, result.Hint, tt.want)
+		}
+		if tt.want != nil && result.IsGitHubIP {
+			t.Errorf("CheckIP(%s) with a hint should not be GitHub-owned", tt.ip)
+		}
+	}
+}
+
+func TestWriteResultHint(t *testing.T) {
+	result := &CheckResult{Hint: &Hint{"Azure service tag", "consistent with a GitHub-hosted Actions runner", "AzureCloud.eastus", "20.42.0.0/17"}}
+
+	var buf bytes.Buffer
+	writeResult(&buf, outputText, "20.42.1.1", result)
+	want := "IP 20.42.1.1 is not a GitHub-owned address, but consistent with a GitHub-hosted Actions runner (heuristic: Azure service tag AzureCloud.eastus, 20.42.0.0/17)\n"
+	if buf.String() != want {
+		t.Errorf("writeResult() text = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeResult(&buf, outputJSON, "20.42.1.1", result)
+	want = `{"ip":"20.42.1.1","is_github":false,"hint":{"heuristic":true,"source":"Azure service tag",` +
+		`"description":"consistent with a GitHub-hosted Actions runner","tag":"AzureCloud.eastus","range":"20.42.0.0/17"}}` + "\n"
+	if buf.String() != want {
+		t.Errorf("writeResult() JSON = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestApplyHintSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ServiceTags_Public.json":
+			w.Write([]byte(testAzureServiceTags))
+		case "/public-ip-list":
+			w.Write([]byte(`{"addresses": ["151.101.0.0/16"], "ipv6_addresses": ["2a04:4e40::/32"]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "ServiceTags_Public.json")
+	if err := os.WriteFile(path, []byte(testAzureServiceTags), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		source     string
+		tags       []string
+		fastly     string
+		wantErr    bool
+		wantRanges int
+	}{
+		{name: "No source", tags: defaultAzureServiceTags},
+		{name: "File", source: path, tags: []string{"AzureCloud.eastus"}, wantRanges: 2},
+		{name: "URL", source: server.URL + "/ServiceTags_Public.json", tags: []string{"AzureCloud.westus2"}, wantRanges: 1},
+		{name: "Missing file", source: filepath.Join(t.TempDir(), "missing.json"), tags: defaultAzureServiceTags, wantErr: true},
+		{name: "URL not found", source: server.URL + "/missing.json", tags: defaultAzureServiceTags, wantErr: true},
+		{name: "Unknown tag", source: path, tags: []string{"AzureCloud.mars"}, wantErr: true},
+		{name: "Fastly", tags: defaultAzureServiceTags, fastly: server.URL + "/public-ip-list", wantRanges: 2},
+		{name: "Azure and Fastly", source: path, tags: []string{"AzureCloud.westus2"}, fastly: server.URL + "/public-ip-list", wantRanges: 3},
+		{name: "Fastly list not found", tags: defaultAzureServiceTags, fastly: server.URL + "/missing.json", wantErr: true},
+		{name: "Fastly list isn't one", tags: defaultAzureServiceTags, fastly: server.URL + "/ServiceTags_Public.json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().String("azure-service-tags", tt.source, "")
+			cmd.Flags().StringSlice("azure-service-tag", tt.tags, "")
+			cmd.Flags().Bool("fastly-pages", tt.fastly != "", "")
+			cmd.Flags().String("fastly-ip-list", tt.fastly, "")
+
+			checker := NewIPChecker()
+			err := applyHintSources(cmd, checker)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyHintSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(checker.hints) != tt.wantRanges {
+				t.Errorf("applyHintSources() loaded %d ranges, want %d", len(checker.hints), tt.wantRanges)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// historyRow is a range of a stored snapshot as returned by sqlite3 -json
+type historyRow struct {
+	SnapshotTime string `json:"snapshot_time"`
+	SourceHash   string `json:"source_hash"`
+	Area         string `json:"area"`
+	CIDR         string `json:"cidr"`
+}
+
+// parseAsOf parses an --as-of value, either an RFC 3339 timestamp or a date.
+// A date refers to the end of that day in UTC.
+func parseAsOf(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, withCategory(errorCategoryInput, fmt.Errorf("invalid --as-of value %q: must be a date (2006-01-02) or RFC 3339 timestamp", value))
+}
+
+// LoadHistory replaces the checker's ranges with the latest snapshot stored in
+// the SQLite history database at path that was published at or before asOf
+func (c *IPChecker) LoadHistory(path string, asOf time.Time) error {
+	query := fmt.Sprintf(`SELECT r.snapshot_time, s.source_hash, r.area, r.cidr
+FROM ranges r JOIN snapshots s ON s.snapshot_time = r.snapshot_time
+WHERE r.snapshot_time = (SELECT MAX(snapshot_time) FROM snapshots WHERE snapshot_time <= %s)
+ORDER BY r.rowid;`, sqlQuote(asOf.UTC().Format(time.RFC3339)))
+
+	out, err := runSQLite(nil, "-readonly", "-json", path, query)
+	if err != nil {
+		return err
+	}
+
+	var rows []historyRow
+	if len(out) > 0 {
+		if err := json.Unmarshal(out, &rows); err != nil {
+			return fmt.Errorf("failed to decode snapshot history: %w", err)
+		}
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no snapshot in %s at or before %s", path, asOf.Format(time.RFC3339))
+	}
+
+	// The area keys match the /meta field names, so the ranges make up a meta
+	// document with the areas in the order they were stored
+	var meta GitHubMeta
+	for _, row := range rows {
+		meta.setRanges(row.Area, append(meta.Ranges[row.Area], row.CIDR))
+	}
+
+	snapshot, err := time.Parse(time.RFC3339, rows[0].SnapshotTime)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot time %q in history: %w", rows[0].SnapshotTime, err)
+	}
+
+	c.setMeta(&meta)
+	c.snapshot = snapshot
+	c.fetched = time.Time{}
+	c.cached = false
+	c.document = nil
+	c.sourceHash = rows[0].SourceHash
+	return nil
+}
+
+// areaDiff is how the ranges of an area changed between two snapshots
+type areaDiff struct {
+	Key     string
+	Name    string
+	Added   []string
+	Removed []string
+}
+
+// diffAreas returns the areas whose ranges differ between two snapshots, in
+// the order of the newer one followed by areas it no longer publishes
+func diffAreas(old, current []Area) []areaDiff {
+	oldRanges := make(map[string][]string)
+	for _, a := range old {
+		oldRanges[a.Key] = a.Ranges
+	}
+	currentKeys := make(map[string]bool)
+
+	var diffs []areaDiff
+	add := func(key, name string, before, after []string) {
+		d := areaDiff{Key: key, Name: name}
+		d.Added = rangesNotIn(after, before)
+		d.Removed = rangesNotIn(before, after)
+		if len(d.Added) > 0 || len(d.Removed) > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	for _, a := range current {
+		currentKeys[a.Key] = true
+		add(a.Key, a.Name, oldRanges[a.Key], a.Ranges)
+	}
+	for _, a := range old {
+		if !currentKeys[a.Key] {
+			add(a.Key, a.Name, a.Ranges, nil)
+		}
+	}
+	return diffs
+}
+
+// rangesNotIn returns the ranges of a that b doesn't list
+func rangesNotIn(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, cidr := range b {
+		in[cidr] = true
+	}
+	var out []string
+	for _, cidr := range a {
+		if !in[cidr] {
+			out = append(out, cidr)
+		}
+	}
+	return out
+}
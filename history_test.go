@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2026-07-01", time.Date(2026, 7, 1, 23, 59, 59, 0, time.UTC), false},
+		{"2026-07-01T10:00:00Z", time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC), false},
+		{"2026-07-01T12:00:00+02:00", time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC), false},
+		{"last week", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseAsOf(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAsOf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseAsOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	dir := fakeSQLite(t, `[{"snapshot_time":"2026-06-30T08:00:00Z","source_hash":"abc123","area":"hooks","cidr":"192.30.252.0/22"},`+
+		`{"snapshot_time":"2026-06-30T08:00:00Z","source_hash":"abc123","area":"actions_ipv4","cidr":"4.148.0.0/16"}]`)
+
+	checker := NewIPChecker()
+	if err := checker.LoadHistory("ranges.db", time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if !strings.Contains(string(args), "-readonly -json ranges.db") || !strings.Contains(string(args), "<= '2026-07-01T00:00:00Z'") {
+		t.Errorf("sqlite3 args = %q", args)
+	}
+
+	if got := checker.SnapshotTime(); !got.Equal(time.Date(2026, 6, 30, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("SnapshotTime() = %v", got)
+	}
+	if got := checker.SourceHash(); got != "abc123" {
+		t.Errorf("SourceHash() = %q, want abc123", got)
+	}
+
+	result, err := checker.CheckIP("4.148.1.1")
+	if err != nil {
+		t.Fatalf("CheckIP() error = %v", err)
+	}
+	if !result.IsGitHubIP || result.FunctionalArea != "Actions IPv4" {
+		t.Errorf("CheckIP() = %+v, want Actions IPv4 match", result)
+	}
+	if result, _ := checker.CheckIP("140.82.112.1"); result.IsGitHubIP {
+		t.Errorf("CheckIP() matched a range missing from the snapshot: %+v", result)
+	}
+}
+
+func TestLoadHistoryNoSnapshot(t *testing.T) {
+	fakeSQLite(t, "")
+
+	err := NewIPChecker().LoadHistory("ranges.db", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err == nil || !strings.Contains(err.Error(), "no snapshot in ranges.db at or before 2020-01-01T00:00:00Z") {
+		t.Errorf("LoadHistory() error = %v", err)
+	}
+}
+
+func TestDiffAreas(t *testing.T) {
+	old := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22", "185.199.108.0/22"}},
+		{Key: "git", Name: "Git", Ranges: []string{"140.82.112.0/20"}},
+		{Key: "importer", Name: "Importer", Ranges: []string{"52.23.85.212/32"}},
+	}
+	current := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22", "143.55.64.0/20"}},
+		{Key: "git", Name: "Git", Ranges: []string{"140.82.112.0/20"}},
+		{Key: "actions", Name: "Actions", Ranges: []string{"4.148.0.0/16"}},
+	}
+
+	want := []areaDiff{
+		{Key: "hooks", Name: "Hooks", Added: []string{"143.55.64.0/20"}, Removed: []string{"185.199.108.0/22"}},
+		{Key: "actions", Name: "Actions", Added: []string{"4.148.0.0/16"}},
+		{Key: "importer", Name: "Importer", Removed: []string{"52.23.85.212/32"}},
+	}
+	if got := diffAreas(old, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffAreas() = %+v, want %+v", got, want)
+	}
+}
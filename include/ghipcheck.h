@@ -0,0 +1,49 @@
+/*
+ * ghipcheck.h - C interface of libghipcheck, the c-shared build of
+ * gh-check-github-ip-ranges:
+ *
+ *   go build -tags libghipcheck -buildmode=c-shared -o libghipcheck.so .
+ *
+ * Functions taking a meta document accept GitHub's meta JSON as returned by
+ * https://api.github.com/meta, or NULL to use the document the library fetches
+ * from the API on first use and keeps for the life of the process.
+ *
+ * Results are NUL-terminated JSON documents allocated by the library, which the
+ * caller must release with ghipcheck_free. Failures are returned as
+ * {"error": {"category": "...", "message": "..."}}, with the categories of the
+ * command line tool's JSON errors.
+ *
+ * GHIPCHECK_ABI_VERSION changes only when a signature or the meaning of an
+ * argument does. Fields may be added to the JSON documents at any time.
+ */
+#ifndef GHIPCHECK_H
+#define GHIPCHECK_H
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define GHIPCHECK_ABI_VERSION 1
+
+/* Returns the ABI version of the loaded library, to compare with
+ * GHIPCHECK_ABI_VERSION. */
+int ghipcheck_abi_version(void);
+
+/* Checks an IPv4 address, returning the object printed by --output json, e.g.
+ * {"ip": "192.30.252.1", "is_github": true, "area": "Hooks",
+ *  "range": "192.30.252.0/22"}. */
+char *ghipcheck_check(const char *ip, const char *meta);
+
+/* Lists areas and their ranges as [{"key", "name", "ranges"}, ...]. areas is a
+ * comma-separated list of areas, e.g. "hooks,git", or NULL or "" for every area
+ * publishing ranges. */
+char *ghipcheck_list(const char *areas, const char *meta);
+
+/* Releases a result returned by the library. */
+void ghipcheck_free(char *result);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* GHIPCHECK_H */
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// metaInfo summarizes a meta document for the info subcommand
+type metaInfo struct {
+	Snapshot   time.Time  `json:"snapshot"`
+	Fetched    *time.Time `json:"fetched,omitempty"`
+	Cached     bool       `json:"cached,omitempty"` // Whether the document was read from the local cache
+	SourceHash string     `json:"source_hash,omitempty"`
+
+	Areas []areaInfoSummary `json:"areas"`
+	IPv4  uint64            `json:"ipv4_addresses"` // Distinct IPv4 addresses across all areas
+
+	Domains                          map[string]int    `json:"domains"` // Number of domains by service
+	SSHKeyFingerprints               map[string]string `json:"ssh_key_fingerprints"`
+	VerifiablePasswordAuthentication *bool             `json:"verifiable_password_authentication,omitempty"`
+}
+
+// areaInfoSummary is the range counts of a functional area
+type areaInfoSummary struct {
+	Key   string `json:"key"`
+	Name  string `json:"name"`
+	IPv4  int    `json:"ipv4_ranges"`
+	IPv6  int    `json:"ipv6_ranges"`
+	Total uint64 `json:"ipv4_addresses"` // Distinct IPv4 addresses covered by the area
+}
+
+// newInfoCommand creates the info subcommand
+func newInfoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "info",
+		Short: "Summarize GitHub's meta document",
+		Long: `Print a one-screen summary of GitHub's /meta API response: when the ranges
+were published and fetched, the number of ranges and IPv4 addresses of each
+functional area, the published domains, the SSH host key fingerprints and
+whether password authentication is verifiable.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runInfo,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	checker := NewIPChecker()
+	meta, err := checker.Meta()
+	if err != nil {
+		return err
+	}
+	info := newMetaInfo(checker, meta)
+
+	if output == outputJSON {
+		out, err := marshalExportJSON(info)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	writeInfo(os.Stdout, info)
+	return nil
+}
+
+// newMetaInfo summarizes the meta document fetched by checker
+func newMetaInfo(checker *IPChecker, meta *GitHubMeta) metaInfo {
+	info := metaInfo{
+		Snapshot:                         checker.SnapshotTime(),
+		SourceHash:                       checker.SourceHash(),
+		Domains:                          make(map[string]int),
+		SSHKeyFingerprints:               make(map[string]string),
+		VerifiablePasswordAuthentication: meta.VerifiablePasswordAuthentication,
+	}
+	if fetched := checker.FetchTime(); !fetched.IsZero() {
+		info.Fetched = &fetched
+		info.Cached = checker.Cached()
+	}
+
+	var all []string
+	for _, area := range meta.Areas() {
+		v4 := ipv4Ranges(area.Ranges)
+		all = append(all, v4...)
+		info.Areas = append(info.Areas, areaInfoSummary{
+			Key:   area.Key,
+			Name:  area.Name,
+			IPv4:  len(v4),
+			IPv6:  len(ipv6Ranges(area.Ranges)),
+			Total: ipv4AddressCount(v4),
+		})
+	}
+	info.IPv4 = ipv4AddressCount(all)
+
+	for service, domains := range meta.DomainGroups() {
+		info.Domains[service] = len(domains)
+	}
+	for name, fingerprint := range meta.SSHKeyFingerprints {
+		info.SSHKeyFingerprints[name] = normalizeFingerprint(fingerprint)
+	}
+	return info
+}
+
+// ipv4AddressCount returns the number of distinct addresses covered by IPv4 ranges
+func ipv4AddressCount(ranges []string) uint64 {
+	var total uint64
+	for _, iv := range outermostIPv4Intervals(ranges) {
+		total += uint64(iv.last) - uint64(iv.first) + 1
+	}
+	return total
+}
+
+// writeInfo writes the summary as aligned text
+func writeInfo(w io.Writer, info metaInfo) {
+	fmt.Fprintf(w, "Snapshot:  %s\n", info.Snapshot.Format(time.RFC3339))
+	if info.Fetched != nil {
+		cached := ""
+		if info.Cached {
+			cached = fmt.Sprintf(" (cached, %s old)", time.Since(*info.Fetched).Round(time.Second))
+		}
+		fmt.Fprintf(w, "Fetched:   %s%s\n", info.Fetched.Format(time.RFC3339), cached)
+	}
+	if info.SourceHash != "" {
+		fmt.Fprintf(w, "SHA-256:   %s\n", info.SourceHash)
+	}
+	if p := info.VerifiablePasswordAuthentication; p != nil {
+		fmt.Fprintf(w, "Verifiable password authentication: %t\n", *p)
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Area\tIPv4 ranges\tIPv6 ranges\tIPv4 addresses")
+	for _, area := range info.Areas {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", area.Name, area.IPv4, area.IPv6, area.Total)
+	}
+	fmt.Fprintf(tw, "All areas\t\t\t%d\n", info.IPv4)
+	tw.Flush()
+
+	if len(info.Domains) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Domains:")
+		services := make([]string, 0, len(info.Domains))
+		for service := range info.Domains {
+			services = append(services, service)
+		}
+		sort.Strings(services)
+		for _, service := range services {
+			fmt.Fprintf(w, "  %s: %d\n", service, info.Domains[service])
+		}
+	}
+
+	if len(info.SSHKeyFingerprints) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "SSH host key fingerprints:")
+		names := make([]string, 0, len(info.SSHKeyFingerprints))
+		for name := range info.SSHKeyFingerprints {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s: %s\n", strings.TrimPrefix(name, "SHA256_"), info.SSHKeyFingerprints[name])
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetaInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Wed, 14 Oct 2026 12:00:00 GMT")
+		w.Write([]byte(`{
+			"verifiable_password_authentication": false,
+			"ssh_key_fingerprints": {"SHA256_ED25519": "+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"},
+			"hooks": ["192.30.252.0/22", "185.199.108.0/22", "2a0a:a440::/29"],
+			"web": ["192.30.252.0/22", "192.30.253.0/24"],
+			"domains": {"website": ["*.github.com", "github.com"], "copilot": ["*.githubcopilot.com"]}
+		}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	checker := NewIPChecker()
+	meta, err := checker.Meta()
+	if err != nil {
+		t.Fatalf("Meta() error = %v", err)
+	}
+	info := newMetaInfo(checker, meta)
+
+	if info.Fetched == nil {
+		t.Error("newMetaInfo() should include the fetch time")
+	}
+	info.Fetched = nil
+	info.SourceHash = ""
+
+	var buf bytes.Buffer
+	writeInfo(&buf, info)
+	want := "Snapshot:  2026-10-14T12:00:00Z\n" +
+		"Verifiable password authentication: false\n" +
+		"\n" +
+		"Area          IPv4 ranges  IPv6 ranges  IPv4 addresses\n" +
+		"Hooks         2            1            2048\n" +
+		"Web           2            0            1024\n" +
+		"API           0            0            0\n" +
+		"Git           0            0            0\n" +
+		"Packages      0            0            0\n" +
+		"Pages         0            0            0\n" +
+		"Importer      0            0            0\n" +
+		"Actions       0            0            0\n" +
+		"Dependabot    0            0            0\n" +
+		"Actions IPv4  0            0            0\n" +
+		"All areas                               2048\n" +
+		"\n" +
+		"Domains:\n" +
+		"  copilot: 1\n" +
+		"  website: 2\n" +
+		"\n" +
+		"SSH host key fingerprints:\n" +
+		"  ED25519: SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU\n"
+	if buf.String() != want {
+		t.Errorf("writeInfo() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestIPv4AddressCount(t *testing.T) {
+	ranges := []string{"192.30.252.0/22", "192.30.253.0/24", "140.82.112.0/20", "2a0a:a440::/29", "4.148.0.1/32"}
+	if got, want := ipv4AddressCount(ranges), uint64(1024+4096+1); got != want {
+		t.Errorf("ipv4AddressCount() = %d, want %d", got, want)
+	}
+}
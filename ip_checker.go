@@ -1,45 +1,308 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"net"
+	"io"
 	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
 )
 
 var githubMetaURL = "https://api.github.com/meta"
 
+// metaUserAgent and metaAPIVersion are sent with meta requests, since some
+// enterprise proxies and GHES instances filter on them. An empty API version
+// omits the X-GitHub-Api-Version header.
+var (
+	metaUserAgent  = "gh-check-github-ip-ranges/" + Version
+	metaAPIVersion = "2022-11-28"
+)
+
+// metaClient is the HTTP client new checkers fetch the meta document with
+var metaClient = http.DefaultClient
+
 // GitHubMeta represents the response from GitHub's /meta API endpoint
 type GitHubMeta struct {
-	Hooks       []string `json:"hooks"`
-	Web         []string `json:"web"`
-	Api         []string `json:"api"`
-	Git         []string `json:"git"`
-	Packages    []string `json:"packages"`
-	Pages       []string `json:"pages"`
-	Importer    []string `json:"importer"`
-	Actions     []string `json:"actions"`
-	Dependabot  []string `json:"dependabot"`
-	ActionsIPv4 []string `json:"actions_ipv4"`
+	// Ranges maps each published category, e.g. "hooks" or "actions_macos", to
+	// its CIDR ranges. Categories lists the keys in the order of the response.
+	Ranges     map[string][]string
+	Categories []string
+
+	// Domains maps a service to the domains it requires. Most values are lists
+	// of domains, but some are objects grouping several lists.
+	Domains map[string]json.RawMessage
+
+	// SSHKeys are the public host keys of github.com, in authorized_keys format
+	// without a comment, and SSHKeyFingerprints their SHA256 fingerprints by
+	// name, e.g. "SHA256_ED25519"
+	SSHKeys            []string
+	SSHKeyFingerprints map[string]string
+
+	// VerifiablePasswordAuthentication is whether GitHub accepts passwords for
+	// Git over HTTPS, nil if the response doesn't say
+	VerifiablePasswordAuthentication *bool
+}
+
+// UnmarshalJSON decodes every field of the response whose value is a list of
+// CIDR ranges as a category, so categories GitHub adds are checked without a
+// code change. The domains, SSH key and password authentication fields are
+// decoded separately, and other fields are ignored.
+func (m *GitHubMeta) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return fmt.Errorf("meta response is not a JSON object")
+	}
+
+	*m = GitHubMeta{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		switch key {
+		case "domains":
+			if err := json.Unmarshal(raw, &m.Domains); err != nil {
+				return fmt.Errorf("invalid domains: %w", err)
+			}
+			continue
+		case "ssh_keys":
+			if err := json.Unmarshal(raw, &m.SSHKeys); err != nil {
+				return fmt.Errorf("invalid ssh_keys: %w", err)
+			}
+			continue
+		case "ssh_key_fingerprints":
+			if err := json.Unmarshal(raw, &m.SSHKeyFingerprints); err != nil {
+				return fmt.Errorf("invalid ssh_key_fingerprints: %w", err)
+			}
+			continue
+		case "verifiable_password_authentication":
+			if err := json.Unmarshal(raw, &m.VerifiablePasswordAuthentication); err != nil {
+				return fmt.Errorf("invalid verifiable_password_authentication: %w", err)
+			}
+			continue
+		}
+		if ranges, ok := parseRangeList(raw); ok {
+			m.setRanges(key, ranges)
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// parseRangeList decodes raw as a list of CIDR ranges, reporting whether it is
+// one. A list of strings is taken to be ranges if it is empty or any of them is
+// a CIDR, so one malformed entry doesn't drop a whole category.
+func parseRangeList(raw json.RawMessage) ([]string, bool) {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, false
+	}
+	for _, cidr := range list {
+		if _, err := netip.ParsePrefix(cidr); err == nil {
+			return list, true
+		}
+	}
+	return list, len(list) == 0
+}
+
+// setRanges sets the ranges of a category, adding it after the existing ones if it is new
+func (m *GitHubMeta) setRanges(category string, ranges []string) {
+	if m.Ranges == nil {
+		m.Ranges = make(map[string][]string)
+	}
+	if _, ok := m.Ranges[category]; !ok {
+		m.Categories = append(m.Categories, category)
+	}
+	m.Ranges[category] = ranges
+}
+
+// DomainGroups returns the published domains by service, flattening services
+// whose domains are grouped into an object
+func (m *GitHubMeta) DomainGroups() map[string][]string {
+	groups := make(map[string][]string)
+	for service, raw := range m.Domains {
+		var list []string
+		if err := json.Unmarshal(raw, &list); err == nil {
+			groups[service] = list
+			continue
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			continue
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := json.Unmarshal(object[key], &list); err == nil {
+				groups[service] = append(groups[service], list...)
+			}
+		}
+	}
+	return groups
+}
+
+// Area is a functional area of GitHub along with its published CIDR ranges
+type Area struct {
+	Key    string // Field name in the /meta response, e.g. "actions_ipv4"
+	Name   string // Display name, e.g. "Actions IPv4"
+	Ranges []string
+}
+
+// NewArea returns an area publishing prefixes, for exporting ranges held as
+// netip.Prefix. The prefixes are masked to their network address.
+func NewArea(key, name string, prefixes []netip.Prefix) Area {
+	ranges := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		ranges[i] = p.Masked().String()
+	}
+	return Area{key, name, ranges}
+}
+
+// Prefixes returns the area's ranges as prefixes, skipping invalid ones
+func (a Area) Prefixes() []netip.Prefix {
+	return parsePrefixes(a.Ranges)
+}
+
+// areaInfo describes a category GitHub publishes
+type areaInfo struct {
+	Key     string
+	Name    string
+	Aliases []string // Other names accepted by --area
+	Newer   bool     // Only listed when the response includes it
+}
+
+// areaCatalog lists the categories checked first, in this order. The original
+// areas are listed whether or not the response includes them.
+var areaCatalog = []areaInfo{
+	{Key: "hooks", Name: "Hooks", Aliases: []string{"webhooks"}},
+	{Key: "web", Name: "Web"},
+	{Key: "api", Name: "API"},
+	{Key: "git", Name: "Git"},
+	{Key: "packages", Name: "Packages"},
+	{Key: "pages", Name: "Pages", Aliases: []string{"github_pages"}},
+	{Key: "importer", Name: "Importer"},
+	{Key: "actions", Name: "Actions"},
+	{Key: "dependabot", Name: "Dependabot"},
+	{Key: "actions_ipv4", Name: "Actions IPv4"},
+	{Key: "actions_macos", Name: "Actions macOS", Aliases: []string{"macos", "macos_runners"}, Newer: true},
+	{Key: "github_enterprise_importer", Name: "GitHub Enterprise Importer", Aliases: []string{"gei", "enterprise_importer"}, Newer: true},
+	{Key: "codespaces", Name: "Codespaces", Aliases: []string{"codespace"}, Newer: true},
+	{Key: "copilot", Name: "Copilot", Newer: true},
+}
+
+// areaAliases returns the other names --area accepts for a category
+func areaAliases(key string) []string {
+	for _, info := range areaCatalog {
+		if info.Key == key {
+			return info.Aliases
+		}
+	}
+	return nil
+}
+
+// areaNameWords are the words of category keys that aren't simply capitalized in display names
+var areaNameWords = map[string]string{"api": "API", "ipv4": "IPv4", "ipv6": "IPv6", "macos": "macOS", "github": "GitHub"}
+
+// areaDisplayName derives a display name from a category key, e.g. "Actions IPv4"
+// from "actions_ipv4"
+func areaDisplayName(key string) string {
+	words := strings.Split(key, "_")
+	for i, word := range words {
+		if name, ok := areaNameWords[word]; ok {
+			words[i] = name
+		} else if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Areas returns the functional areas of the meta response in check order: the
+// catalogued areas, followed by any other categories in the order of the response
+func (m *GitHubMeta) Areas() []Area {
+	var areas []Area
+	catalogued := make(map[string]bool)
+	for _, info := range areaCatalog {
+		catalogued[info.Key] = true
+		if _, ok := m.Ranges[info.Key]; ok || !info.Newer {
+			areas = append(areas, Area{info.Key, info.Name, m.Ranges[info.Key]})
+		}
+	}
+	for _, key := range m.Categories {
+		if !catalogued[key] {
+			areas = append(areas, Area{key, areaDisplayName(key), m.Ranges[key]})
+		}
+	}
+	return areas
 }
 
 // IPChecker provides functionality to check IP addresses against GitHub's ranges
 type IPChecker struct {
-	meta   *GitHubMeta
-	client *http.Client // Add client field
+	meta     *GitHubMeta
+	ranges   *rangeTrie   // The ranges of meta, parsed once per snapshot
+	client   *http.Client // Add client field
+	snapshot time.Time
+	fetched  time.Time
+	cached   bool // Whether the ranges were read from the meta document cache
+
+	sourceHash string
+	document   []byte // The fetched meta document as served
+
+	hints []hintRange // Secondary ranges consulted for addresses outside GitHub's
+
+	sources []*metaSource // Further meta documents checked alongside GitHub's
+
+	audit *auditLog // Records every check, if set
+
+	allowNonPublic bool // Whether non-public addresses are checked rather than rejected
+
+	metrics Metrics // Receives the measurements of checks and fetches, if set
 }
 
 // CheckResult contains the result of an IP check
 type CheckResult struct {
 	IsGitHubIP     bool
 	FunctionalArea string
+	AreaKey        string // Meta field name of the area, e.g. "actions_ipv4"
+	Range          string
+	Source         string // Meta source publishing the range, if several are checked
+
+	// Also lists the other published ranges containing the IP, most specific first
+	Also []RangeMatch
+
+	// Hint is a heuristic match in a secondary source of an IP that isn't GitHub-owned
+	Hint *Hint
+}
+
+// RangeMatch is a published range of an area
+type RangeMatch struct {
+	FunctionalArea string
+	AreaKey        string
 	Range          string
+	Source         string
 }
 
 // NewIPChecker creates a new IPChecker instance
 func NewIPChecker() *IPChecker {
 	return &IPChecker{
-		client: http.DefaultClient,
+		client: metaClient,
 	}
 }
 
@@ -48,96 +311,291 @@ func (c *IPChecker) setClient(client *http.Client) {
 	c.client = client
 }
 
-// fetchGitHubMeta fetches the IP ranges from GitHub's API
-func (c *IPChecker) fetchGitHubMeta() error {
-	resp, err := c.client.Get(githubMetaURL) // Use injected client
+// fetchMetaDocument fetches the meta document at url with client, returning its
+// body and response headers
+func fetchMetaDocument(client *http.Client, url string) ([]byte, http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to fetch GitHub meta: %w", err)
+		return nil, nil, withCategory(errorCategoryUsage, fmt.Errorf("invalid meta URL: %w", err))
+	}
+	req.Header.Set("User-Agent", metaUserAgent)
+	if metaAPIVersion != "" {
+		req.Header.Set("X-GitHub-Api-Version", metaAPIVersion)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, withCategory(errorCategoryNetwork, fmt.Errorf("failed to fetch GitHub meta: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("GitHub API returned status code %d", resp.StatusCode)
+		return nil, nil, withCategory(errorCategoryAPI, fmt.Errorf("GitHub API returned status code %d", resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, withCategory(errorCategoryNetwork, fmt.Errorf("failed to read GitHub meta response: %w", err))
+	}
+	return body, resp.Header, nil
+}
+
+// fetchGitHubMeta fetches the IP ranges from GitHub's API, or reads them from
+// the cache if its policy allows. A refresh fetches them unless the policy
+// forbids fetching.
+func (c *IPChecker) fetchGitHubMeta(refresh bool) (err error) {
+	start := time.Now()
+	fetched := start.UTC()
+	var entry *metaCacheEntry
+	defer func() { c.recordFetch("", time.Since(start), entry != nil, err) }()
+	if entry, err = metaCache.cached(githubMetaURL, fetched, refresh); err != nil {
+		return err
+	}
+
+	var body []byte
+	header := http.Header{}
+	if entry != nil {
+		body, fetched = entry.Document, entry.Fetched
+		if entry.LastModified != "" {
+			header.Set("Last-Modified", entry.LastModified)
+		}
+	} else if body, header, err = fetchMetaDocument(c.client, githubMetaURL); err != nil {
+		return err
 	}
 
 	var meta GitHubMeta
-	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
-		return fmt.Errorf("failed to decode GitHub meta response: %w", err)
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return withCategory(errorCategoryAPI, fmt.Errorf("failed to decode GitHub meta response: %w", err))
+	}
+	if entry == nil && metaCache != nil {
+		writeMetaCache(githubMetaURL, body, header, fetched)
 	}
 
-	c.meta = &meta
+	c.setMeta(&meta)
+	c.document = body
+	c.sourceHash = fmt.Sprintf("%x", sha256.Sum256(body))
+	c.fetched = fetched
+	c.cached = entry != nil
+	c.snapshot = c.fetched
+	if lastModified, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+		c.snapshot = lastModified.UTC()
+	}
 	return nil
 }
 
-// isBroadcastAddress checks if the IP is a broadcast address
-func isBroadcastAddress(ip net.IP) bool {
-	for i := 0; i < len(ip); i++ {
-		if ip[i] != 255 {
-			return false
+// SnapshotTime returns when the fetched ranges were last modified, falling back
+// to the time they were fetched if GitHub didn't say
+func (c *IPChecker) SnapshotTime() time.Time {
+	return c.snapshot
+}
+
+// FetchTime returns when the ranges were fetched, or the zero time if they were
+// loaded from a snapshot history
+func (c *IPChecker) FetchTime() time.Time {
+	return c.fetched
+}
+
+// Cached reports whether the ranges were read from the local cache rather
+// than fetched, in which case FetchTime is when the cached copy was fetched
+func (c *IPChecker) Cached() bool {
+	return c.cached
+}
+
+// SourceHash returns the SHA-256 of the fetched meta document, which changes
+// whenever GitHub publishes different data
+func (c *IPChecker) SourceHash() string {
+	return c.sourceHash
+}
+
+// Document returns the fetched meta document as GitHub served it, or nil if
+// the ranges weren't fetched
+func (c *IPChecker) Document() []byte {
+	return c.document
+}
+
+// Meta returns GitHub's meta document, fetching it if needed
+func (c *IPChecker) Meta() (*GitHubMeta, error) {
+	if c.meta == nil {
+		if err := c.fetchGitHubMeta(false); err != nil {
+			return nil, fmt.Errorf("failed to fetch GitHub meta: %w", err)
 		}
 	}
-	return true
+	return c.meta, nil
+}
+
+// setMeta replaces the checked meta document, parsing its ranges
+func (c *IPChecker) setMeta(meta *GitHubMeta) {
+	c.meta = meta
+	c.ranges = newRangeTrie(meta.Areas())
+}
+
+// Refresh fetches GitHub's meta document again, replacing the current ranges
+// only if the fetch succeeds
+func (c *IPChecker) Refresh() error {
+	if err := c.fetchGitHubMeta(true); err != nil {
+		return fmt.Errorf("failed to fetch GitHub meta: %w", err)
+	}
+	return nil
+}
+
+// Areas returns the functional areas published by GitHub, fetching them if needed
+func (c *IPChecker) Areas() ([]Area, error) {
+	meta, err := c.Meta()
+	if err != nil {
+		return nil, err
+	}
+	return meta.Areas(), nil
+}
+
+// AllowNonPublic makes CheckIP check private, loopback, carrier-grade NAT and
+// other non-public addresses against the ranges instead of rejecting them, for
+// sources such as GHES instances that publish private ranges
+func (c *IPChecker) AllowNonPublic() {
+	c.allowNonPublic = true
 }
 
 // CheckIP checks if the provided IP address is within GitHub's ranges
 func (c *IPChecker) CheckIP(ipStr string) (*CheckResult, error) {
+	result, err := c.checkIP(ipStr, nil)
+	if auditErr := c.audit.record(c, ipStr, result, err, ""); auditErr != nil {
+		return nil, auditErr
+	}
+	return result, err
+}
+
+// checkIP checks ipStr without recording the check in the audit log, tracing
+// the decision in trace if set
+func (c *IPChecker) checkIP(ipStr string, trace *explanation) (*CheckResult, error) {
+	start := time.Now()
+	result, err := c.evaluate(ipStr, trace)
+	c.recordCheck(result, time.Since(start), err)
+	return result, err
+}
+
+// evaluate checks ipStr against the ranges, tracing the decision in trace if set
+func (c *IPChecker) evaluate(ipStr string, trace *explanation) (*CheckResult, error) {
+	ip, matches, err := c.match(ipStr, trace)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return &CheckResult{IsGitHubIP: false, Hint: c.hint(ip)}, nil
+	}
+
+	result := &CheckResult{
+		IsGitHubIP:     true,
+		FunctionalArea: matches[0].FunctionalArea,
+		AreaKey:        matches[0].AreaKey,
+		Range:          matches[0].Range,
+		Source:         matches[0].Source,
+	}
+	result.Also = append(result.Also, matches[1:]...)
+	return result, nil
+}
+
+// match validates ipStr and returns the published ranges containing it, most
+// specific first, tracing the steps in trace if set
+func (c *IPChecker) match(ipStr string, trace *explanation) (netip.Addr, []RangeMatch, error) {
 	// Parse and validate the IP address
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return nil, fmt.Errorf("invalid IP address format")
+	ip, err := netip.ParseAddr(ipStr)
+	if err != nil || ip.Zone() != "" {
+		trace.step("parse", false, "not an IP address")
+		return netip.Addr{}, nil, withCategory(errorCategoryInput, fmt.Errorf("invalid IP address format"))
 	}
+	trace.step("parse", true, "parsed as "+ip.String())
 
-	// Ensure it's an IPv4 address
-	ip = ip.To4()
-	if ip == nil {
-		return nil, fmt.Errorf("only IPv4 addresses are supported")
+	// Ensure it's an IPv4 address. Log pipelines and dual-stack sockets report
+	// IPv4 peers in IPv4-mapped form, e.g. ::ffff:140.82.121.3, so those are
+	// checked as the IPv4 address.
+	if ip.Is4In6() {
+		ip = ip.Unmap()
+		trace.step("unmap", true, "IPv4-mapped address, checked as "+ip.String())
+	}
+	if !ip.Is4() {
+		trace.step("family", false, "IPv6 address")
+		return netip.Addr{}, nil, withCategory(errorCategoryInput, fmt.Errorf("only IPv4 addresses are supported"))
+	}
+	trace.step("family", true, "IPv4 address")
+	if trace != nil {
+		trace.Normalized = ip.String()
 	}
 
 	// Check if it's a public IP address
-	if ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() || isBroadcastAddress(ip) {
-		return nil, fmt.Errorf("IP address must be a public, routable address")
+	if r, ok := classifyNonPublic(ip); ok {
+		detail := fmt.Sprintf("%s address (%s, %s)", r.Kind, r.Prefix, r.Rule)
+		if !c.allowNonPublic {
+			trace.step("public", false, detail)
+			return netip.Addr{}, nil, withCategory(errorCategoryInput, nonPublicError{r})
+		}
+		trace.step("public", true, detail+", allowed by --allow-non-public")
+	} else {
+		trace.step("public", true, "not in a special-purpose range")
 	}
 
 	// Fetch GitHub meta if not already cached
-	if c.meta == nil {
-		if err := c.fetchGitHubMeta(); err != nil {
-			return nil, fmt.Errorf("failed to fetch GitHub meta: %w", err)
+	if _, err := c.Meta(); err != nil {
+		trace.step("ranges", false, err.Error())
+		return netip.Addr{}, nil, err
+	}
+
+	// Several areas often publish the same or nested ranges, so the most specific
+	// range is the answer, with ties going to the area listed first
+	matches := c.ranges.match(ip)
+	if len(c.sources) > 0 {
+		if matches, err = c.matchSources(matches, ip); err != nil {
+			return netip.Addr{}, nil, err
 		}
 	}
+	return ip, matches, nil
+}
 
-	// Check each range category
-	ranges := []struct {
-		name   string
-		ranges []string
-	}{
-		{"Hooks", c.meta.Hooks},
-		{"Web", c.meta.Web},
-		{"API", c.meta.Api},
-		{"Git", c.meta.Git},
-		{"Packages", c.meta.Packages},
-		{"Pages", c.meta.Pages},
-		{"Importer", c.meta.Importer},
-		{"Actions", c.meta.Actions},
-		{"Dependabot", c.meta.Dependabot},
-		{"Actions IPv4", c.meta.ActionsIPv4},
-	}
-
-	for _, category := range ranges {
-		for _, cidr := range category.ranges {
-			_, ipNet, err := net.ParseCIDR(cidr)
-			if err != nil {
-				continue
-			}
+// Match is a published range containing a checked address
+type Match struct {
+	Area      string // Display name of the functional area
+	AreaKey   string // Meta field name of the area, e.g. "actions_ipv4"
+	CIDR      string
+	Prefix    netip.Prefix
+	PrefixLen int
+	Source    string // Meta source publishing the range, if several are checked
+}
 
-			if ipNet.Contains(ip) {
-				return &CheckResult{
-					IsGitHubIP:     true,
-					FunctionalArea: category.name,
-					Range:          cidr,
-				}, nil
-			}
-		}
+// newMatch returns the Match of a range containing an address
+func newMatch(m RangeMatch) Match {
+	prefix, _ := parsePrefix(m.Range)
+	return Match{Area: m.FunctionalArea, AreaKey: m.AreaKey, CIDR: m.Range, Prefix: prefix, PrefixLen: prefix.Bits(), Source: m.Source}
+}
+
+// CheckAll returns every published range containing the address, most
+// specific first with ties in area order, for callers that need the complete
+// classification rather than the single answer of CheckIP. An address that
+// isn't GitHub-owned has no matches.
+func (c *IPChecker) CheckAll(ipStr string) ([]Match, error) {
+	_, matches, err := c.match(ipStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	all := make([]Match, len(matches))
+	for i, m := range matches {
+		all[i] = newMatch(m)
 	}
+	return all, nil
+}
 
-	return &CheckResult{IsGitHubIP: false}, nil
+// Contains returns the most specific published range containing addr, with
+// ties going to the area listed first. Unlike CheckIP it takes addresses of
+// either family without validating them, checking IPv4-mapped ones as IPv4,
+// and reports false if the ranges can't be fetched; Meta returns the error.
+func (c *IPChecker) Contains(addr netip.Addr) (Match, bool) {
+	if !addr.IsValid() || c.load() != nil {
+		return Match{}, false
+	}
+	addr = addr.Unmap()
+	matches := c.ranges.match(addr)
+	if len(c.sources) > 0 {
+		matches, _ = c.matchSources(matches, addr)
+	}
+	if len(matches) == 0 {
+		return Match{}, false
+	}
+	return newMatch(matches[0]), true
 }
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestIPChecker_CheckIP(t *testing.T) {
@@ -91,6 +96,7 @@ func TestIPChecker_CheckIP(t *testing.T) {
 			want: &CheckResult{
 				IsGitHubIP:     true,
 				FunctionalArea: "Hooks",
+				AreaKey:        "hooks",
 				Range:          "192.30.252.0/22",
 			},
 		},
@@ -119,7 +125,7 @@ func TestIPChecker_CheckIP(t *testing.T) {
 			mockServer: successServer,
 			client:     nil,
 			wantErr:    true,
-			wantErrMsg: "IP address must be a public, routable address",
+			wantErrMsg: "IP address is a private address (192.168.0.0/16, RFC 1918), not a public one",
 			want:       nil,
 		},
 		{
@@ -131,13 +137,44 @@ func TestIPChecker_CheckIP(t *testing.T) {
 			wantErrMsg: "only IPv4 addresses are supported",
 			want:       nil,
 		},
+		{
+			name:       "IPv4-mapped IPv6 address",
+			ip:         "::ffff:192.30.252.1",
+			mockServer: successServer,
+			client:     nil,
+			wantErr:    false,
+			want: &CheckResult{
+				IsGitHubIP:     true,
+				FunctionalArea: "Hooks",
+				AreaKey:        "hooks",
+				Range:          "192.30.252.0/22",
+			},
+		},
+		{
+			name:       "IPv4-mapped private address",
+			ip:         "::ffff:10.0.0.1",
+			mockServer: successServer,
+			client:     nil,
+			wantErr:    true,
+			wantErrMsg: "IP address is a private address (10.0.0.0/8, RFC 1918), not a public one",
+			want:       nil,
+		},
+		{
+			name:       "Carrier-grade NAT address",
+			ip:         "100.100.1.1",
+			mockServer: successServer,
+			client:     nil,
+			wantErr:    true,
+			wantErrMsg: "IP address is a carrier-grade NAT address (100.64.0.0/10, RFC 6598), not a public one",
+			want:       nil,
+		},
 		{
 			name:       "Broadcast address",
 			ip:         "255.255.255.255",
 			mockServer: successServer,
 			client:     nil,
 			wantErr:    true,
-			wantErrMsg: "IP address must be a public, routable address",
+			wantErrMsg: "IP address is a broadcast address (255.255.255.255/32, RFC 919), not a public one",
 			want:       nil,
 		},
 		{
@@ -174,6 +211,7 @@ func TestIPChecker_CheckIP(t *testing.T) {
 			want: &CheckResult{
 				IsGitHubIP:     true,
 				FunctionalArea: "API",
+				AreaKey:        "api",
 				Range:          "192.30.252.0/22",
 			},
 		},
@@ -186,6 +224,7 @@ func TestIPChecker_CheckIP(t *testing.T) {
 			want: &CheckResult{
 				IsGitHubIP:     true,
 				FunctionalArea: "Git",
+				AreaKey:        "git",
 				Range:          "192.30.252.0/22",
 			},
 		},
@@ -229,6 +268,9 @@ func TestIPChecker_CheckIP(t *testing.T) {
 				if got.FunctionalArea != tt.want.FunctionalArea {
 					t.Errorf("CheckIP() FunctionalArea = %v, want %v", got.FunctionalArea, tt.want.FunctionalArea)
 				}
+				if got.AreaKey != tt.want.AreaKey {
+					t.Errorf("CheckIP() AreaKey = %v, want %v", got.AreaKey, tt.want.AreaKey)
+				}
 				if got.Range != tt.want.Range {
 					t.Errorf("CheckIP() Range = %v, want %v", got.Range, tt.want.Range)
 				}
@@ -243,3 +285,293 @@ type failingTransport struct{}
 func (t *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
 	return nil, fmt.Errorf("failed to fetch GitHub meta")
 }
+
+func TestGitHubMeta_UnmarshalJSON(t *testing.T) {
+	var meta GitHubMeta
+	err := json.Unmarshal([]byte(`{
+		"verifiable_password_authentication": false,
+		"ssh_key_fingerprints": {"SHA256_ED25519": "+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"},
+		"ssh_keys": ["ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"],
+		"hooks": ["192.30.252.0/22"],
+		"copilot": ["20.85.130.105/32"],
+		"actions_macos": ["13.105.49.0/24", "not-a-range"],
+		"codespaces": [],
+		"models_ipv4": ["20.85.130.0/24"],
+		"domains": {"website": ["github.com"]}
+	}`), &meta)
+	if err != nil {
+		t.Fatalf("failed to decode meta: %v", err)
+	}
+
+	if want := []string{"hooks", "copilot", "actions_macos", "codespaces", "models_ipv4"}; !reflect.DeepEqual(meta.Categories, want) {
+		t.Errorf("Categories = %q, want %q", meta.Categories, want)
+	}
+	if want := []string{"13.105.49.0/24", "not-a-range"}; !reflect.DeepEqual(meta.Ranges["actions_macos"], want) {
+		t.Errorf("Ranges[actions_macos] = %q, want %q", meta.Ranges["actions_macos"], want)
+	}
+	if _, ok := meta.Domains["website"]; !ok {
+		t.Errorf("Domains = %v, want the website domains", meta.Domains)
+	}
+
+	// Catalogued areas come first in check order, leaving out newer ones that
+	// aren't published, then other categories in response order
+	var keys, names []string
+	for _, area := range meta.Areas() {
+		keys = append(keys, area.Key)
+		names = append(names, area.Name)
+	}
+	wantKeys := []string{"hooks", "web", "api", "git", "packages", "pages", "importer", "actions", "dependabot", "actions_ipv4", "actions_macos", "codespaces", "copilot", "models_ipv4"}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("Areas() keys = %q, want %q", keys, wantKeys)
+	}
+	if got := names[len(names)-4:]; !reflect.DeepEqual(got, []string{"Actions macOS", "Codespaces", "Copilot", "Models IPv4"}) {
+		t.Errorf("Areas() names = %q", got)
+	}
+
+	if err := json.Unmarshal([]byte(`["192.30.252.0/22"]`), &meta); err == nil {
+		t.Error("decoding a non-object meta response should fail")
+	}
+}
+
+func TestIPChecker_CheckIPNewCategory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "copilot": ["20.85.130.105/32"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	result, err := NewIPChecker().CheckIP("20.85.130.105")
+	if err != nil {
+		t.Fatalf("CheckIP() error = %v", err)
+	}
+	if !result.IsGitHubIP || result.AreaKey != "copilot" || result.FunctionalArea != "Copilot" {
+		t.Errorf("CheckIP() = %+v, want a match in the copilot category", result)
+	}
+}
+
+func TestIPChecker_CheckIPMostSpecific(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["192.30.0.0/16"], "api": ["192.30.252.0/22"], "copilot": ["192.30.252.0/24"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	result, err := NewIPChecker().CheckIP("192.30.252.1")
+	if err != nil {
+		t.Fatalf("CheckIP() error = %v", err)
+	}
+	if result.AreaKey != "copilot" || result.Range != "192.30.252.0/24" {
+		t.Errorf("CheckIP() = %+v, want the copilot /24 as the primary match", result)
+	}
+	want := []RangeMatch{
+		{"Hooks", "hooks", "192.30.252.0/22", ""},
+		{"API", "api", "192.30.252.0/22", ""},
+		{"Web", "web", "192.30.0.0/16", ""},
+	}
+	if !reflect.DeepEqual(result.Also, want) {
+		t.Errorf("CheckIP() Also = %+v, want %+v", result.Also, want)
+	}
+}
+
+func TestIPChecker_AllowNonPublic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["10.0.0.0/8", "100.64.0.0/10"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	for _, ip := range []string{"10.1.1.1", "100.64.0.1"} {
+		if _, err := NewIPChecker().CheckIP(ip); err == nil || errorCategory(err) != errorCategoryInput {
+			t.Errorf("CheckIP(%s) error = %v, want an %s error", ip, err, errorCategoryInput)
+		}
+	}
+
+	checker := NewIPChecker()
+	checker.AllowNonPublic()
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"10.1.1.1", "10.0.0.0/8"},
+		{"100.64.0.1", "100.64.0.0/10"},
+		{"192.168.1.1", ""},
+		{"127.0.0.1", ""},
+	}
+	for _, tt := range tests {
+		result, err := checker.CheckIP(tt.ip)
+		if err != nil {
+			t.Errorf("CheckIP(%s) allowing non-public addresses error = %v", tt.ip, err)
+			continue
+		}
+		if result.IsGitHubIP != (tt.want != "") || result.Range != tt.want {
+			t.Errorf("CheckIP(%s) allowing non-public addresses = %+v, want range %q", tt.ip, result, tt.want)
+		}
+	}
+	if _, err := checker.CheckIP("2001:db8::1"); err == nil {
+		t.Error("CheckIP() of an IPv6 address allowing non-public addresses should fail")
+	}
+}
+
+func TestIPChecker_CheckAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["192.30.0.0/16"], "copilot": ["192.30.252.0/24"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name    string
+		ip      string
+		want    []Match
+		wantErr bool
+	}{
+		{
+			name: "every containing range",
+			ip:   "192.30.252.1",
+			want: []Match{
+				{Area: "Copilot", AreaKey: "copilot", CIDR: "192.30.252.0/24", Prefix: netip.MustParsePrefix("192.30.252.0/24"), PrefixLen: 24},
+				{Area: "Hooks", AreaKey: "hooks", CIDR: "192.30.252.0/22", Prefix: netip.MustParsePrefix("192.30.252.0/22"), PrefixLen: 22},
+				{Area: "Web", AreaKey: "web", CIDR: "192.30.0.0/16", Prefix: netip.MustParsePrefix("192.30.0.0/16"), PrefixLen: 16},
+			},
+		},
+		{
+			name: "single range",
+			ip:   "192.30.1.1",
+			want: []Match{{Area: "Web", AreaKey: "web", CIDR: "192.30.0.0/16", Prefix: netip.MustParsePrefix("192.30.0.0/16"), PrefixLen: 16}},
+		},
+		{name: "not GitHub", ip: "8.8.8.8", want: []Match{}},
+		{name: "private", ip: "10.0.0.1", wantErr: true},
+	}
+
+	checker := NewIPChecker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checker.CheckAll(tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckAll() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPChecker_Contains(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22", "2a0a:a440::/29"], "web": ["192.30.0.0/16"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	checker := NewIPChecker()
+	tests := []struct {
+		addr   netip.Addr
+		want   string
+		wantOK bool
+	}{
+		{netip.MustParseAddr("192.30.252.1"), "192.30.252.0/22", true},
+		{netip.MustParseAddr("192.30.1.1"), "192.30.0.0/16", true},
+		{netip.MustParseAddr("2a0a:a440::1"), "2a0a:a440::/29", true},
+		{netip.MustParseAddr("::ffff:192.30.252.1"), "192.30.252.0/22", true},
+		{netip.MustParseAddr("8.8.8.8"), "", false},
+		{netip.Addr{}, "", false},
+	}
+	for _, tt := range tests {
+		m, ok := checker.Contains(tt.addr)
+		if ok != tt.wantOK || m.CIDR != tt.want {
+			t.Errorf("Contains(%v) = %+v, %t, want %s, %t", tt.addr, m, ok, tt.want, tt.wantOK)
+		}
+		if ok && m.Prefix != netip.MustParsePrefix(tt.want) {
+			t.Errorf("Contains(%v) Prefix = %v, want %s", tt.addr, m.Prefix, tt.want)
+		}
+	}
+
+	githubMetaURL = "http://127.0.0.1:0"
+	if _, ok := NewIPChecker().Contains(netip.MustParseAddr("192.30.252.1")); ok {
+		t.Error("Contains() without ranges should report false")
+	}
+}
+
+func TestNewArea(t *testing.T) {
+	prefixes := []netip.Prefix{netip.MustParsePrefix("192.30.252.7/22"), netip.MustParsePrefix("2a0a:a440::/29")}
+	area := NewArea("hooks", "Hooks", prefixes)
+	want := Area{"hooks", "Hooks", []string{"192.30.252.0/22", "2a0a:a440::/29"}}
+	if !reflect.DeepEqual(area, want) {
+		t.Errorf("NewArea() = %+v, want %+v", area, want)
+	}
+	if got := area.Prefixes(); !reflect.DeepEqual(got, []netip.Prefix{netip.MustParsePrefix("192.30.252.0/22"), prefixes[1]}) {
+		t.Errorf("Prefixes() = %v", got)
+	}
+
+	// Areas of prefixes export like those of a meta document
+	out, err := renderHAProxyACL(exportOptions{Areas: []Area{area}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(out), "\n192.30.252.0/22\n2a0a:a440::/29\n") {
+		t.Errorf("renderHAProxyACL() = %q, want the prefixes", out)
+	}
+}
+
+func TestGitHubMeta_DomainGroups(t *testing.T) {
+	var meta GitHubMeta
+	err := json.Unmarshal([]byte(`{"domains": {
+		"website": ["*.github.com", "github.com"],
+		"actions_inbound": {"wildcard_domains": ["*.githubusercontent.com"], "full_domains": ["github.com"]},
+		"artifact_attestations": {"trust_domain": "", "services": ["*.actions.githubusercontent.com"]}
+	}}`), &meta)
+	if err != nil {
+		t.Fatalf("failed to decode meta: %v", err)
+	}
+
+	got := meta.DomainGroups()
+	want := map[string][]string{
+		"website":               {"*.github.com", "github.com"},
+		"actions_inbound":       {"github.com", "*.githubusercontent.com"},
+		"artifact_attestations": {"*.actions.githubusercontent.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DomainGroups() = %v, want %v", got, want)
+	}
+}
+
+func TestIPChecker_SourceHash(t *testing.T) {
+	body := `{"hooks": ["192.30.252.0/22"]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Wed, 14 Oct 2026 12:00:00 GMT")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	checker := NewIPChecker()
+	if _, err := checker.Meta(); err != nil {
+		t.Fatalf("Meta() error = %v", err)
+	}
+
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte(body))); checker.SourceHash() != want {
+		t.Errorf("SourceHash() = %q, want %q", checker.SourceHash(), want)
+	}
+	if want := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC); !checker.SnapshotTime().Equal(want) {
+		t.Errorf("SnapshotTime() = %v, want %v", checker.SnapshotTime(), want)
+	}
+}
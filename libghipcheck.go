@@ -0,0 +1,100 @@
+//go:build libghipcheck && cgo
+
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// The C library, built with
+//
+//	go build -tags libghipcheck -buildmode=c-shared -o libghipcheck.so .
+//
+// Its ABI is plain C strings and ints: every function taking a meta document
+// accepts GitHub's meta JSON, or NULL to use the document fetched from the API
+// on first use. Results are JSON documents allocated with malloc, which the
+// caller releases with ghipcheck_free. The ABI version only changes when a
+// function's signature or the meaning of its arguments does; new fields may be
+// added to the JSON documents at any time.
+
+// ghipcheckABIVersion is the version returned by ghipcheck_abi_version
+const ghipcheckABIVersion = 1
+
+var (
+	libCheckerMu sync.Mutex
+	libChecker   *IPChecker // Checker for calls without a meta document
+)
+
+// libMetaChecker returns a checker for meta, or the shared fetching checker if
+// meta is NULL
+func libMetaChecker(meta *C.char) (*IPChecker, error) {
+	if meta != nil {
+		return newMetaChecker([]byte(C.GoString(meta)))
+	}
+	libCheckerMu.Lock()
+	defer libCheckerMu.Unlock()
+	if libChecker == nil {
+		checker := NewIPChecker()
+		if _, err := checker.Meta(); err != nil {
+			return nil, err
+		}
+		libChecker = checker
+	}
+	return libChecker, nil
+}
+
+// libResult returns out, or err as {"error": {"category", "message"}}, as a C
+// string
+func libResult(out []byte, err error) *C.char {
+	if err != nil {
+		out, _ = json.Marshal(map[string]any{"error": map[string]string{
+			"category": errorCategory(err),
+			"message":  err.Error(),
+		}})
+	}
+	return C.CString(string(out))
+}
+
+//export ghipcheck_abi_version
+func ghipcheck_abi_version() C.int {
+	return ghipcheckABIVersion
+}
+
+//export ghipcheck_check
+func ghipcheck_check(ip, meta *C.char) *C.char {
+	if ip == nil {
+		return libResult(nil, withCategory(errorCategoryUsage, fmt.Errorf("ip must not be NULL")))
+	}
+	checker, err := libMetaChecker(meta)
+	if err != nil {
+		return libResult(nil, err)
+	}
+	return libResult(checkIPJSON(checker, C.GoString(ip)))
+}
+
+//export ghipcheck_list
+func ghipcheck_list(areas, meta *C.char) *C.char {
+	var names []string
+	if areas != nil && C.GoString(areas) != "" {
+		names = strings.Split(C.GoString(areas), ",")
+	}
+	checker, err := libMetaChecker(meta)
+	if err != nil {
+		return libResult(nil, err)
+	}
+	return libResult(listRangesJSON(checker, names))
+}
+
+//export ghipcheck_free
+func ghipcheck_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -13,58 +15,284 @@ const Version = "v1.0.0"
 // For testing purposes
 var osExit = os.Exit
 
-func main() {
+// notGitHubError reports addresses that aren't GitHub-owned. It is printed without
+// the "Error:" prefix and exits with --exit-code-not-github.
+type notGitHubError string
+
+func (e notGitHubError) Error() string {
+	return string(e)
+}
+
+// exitStatusError exits with the given status without printing anything, for
+// output formats that report errors themselves
+type exitStatusError int
+
+func (e exitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// runCLI runs the command line interface, which is the program except in the
+// WebAssembly build
+func runCLI() {
 	cmd := &cobra.Command{
-		Use:   "gh-check-github-ip-ranges <ip-address>",
+		Use:   "gh-check-github-ip-ranges <ip-address>...",
 		Short: "Check if an IP address is within GitHub's published IP ranges",
 		Long: `Check if a given IP address is within GitHub's published IP ranges.
 The ranges are fetched from GitHub's /meta API endpoint. Only IPv4 addresses
-are supported at this time.`,
-		Version:       Version,
-		Args:          cobra.ExactArgs(1),
-		RunE:          runCommand,
-		SilenceUsage:  true,
-		SilenceErrors: true,
+are supported at this time.
+
+Several addresses can be checked at once by passing them as arguments or
+listing them in a file with --input. Each address then gets its own result,
+and the exit code reflects all of them.`,
+		Version:           Version,
+		Args:              usageArgs(rootArgs),
+		PersistentPreRunE: preRun,
+		RunE:              runCommand,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
 	}
 
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withCategory(errorCategoryUsage, err)
+	})
+	cmd.PersistentFlags().Int("exit-code-not-github", 1, "Exit code when an address is not GitHub-owned")
+	cmd.PersistentFlags().Int("exit-code-error", 2, "Exit code for invalid input and other errors")
+	addRequestFlags(cmd)
+	addCacheFlags(cmd)
 	cmd.Flags().BoolP("silent", "s", false, "Silent mode - only use exit codes")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text, json, cef, leef, actions, nagios or checkmk)")
+	cmd.Flags().Bool("porcelain", false, "Print only github:<area> or not-github, a format that stays stable across releases")
+	cmd.Flags().Duration("nagios-max-age", 0, "Report WARNING when the ranges snapshot is older than this, e.g. 720h (nagios and checkmk output)")
+	cmd.Flags().String("summary", os.Getenv("GITHUB_STEP_SUMMARY"), "Append a markdown table of the results to this GitHub Actions job summary file")
+	cmd.Flags().String("as-of", "", "Check against the ranges published at this date or time, read from --history")
+	cmd.Flags().String("history", "", "SQLite snapshot history database written by 'export --format sqlite'")
+	cmd.Flags().StringP("input", "i", "", "Also check the addresses listed in this file, one per line (\"-\" for stdin)")
+	cmd.Flags().Bool("fail-fast", false, "Stop checking at the first address that isn't GitHub-owned or can't be checked")
+	cmd.Flags().Bool("unique", false, "Check each distinct address once, reporting how many times it occurs")
+	cmd.Flags().Bool("sort", false, "Check the addresses in address order")
+	cmd.Flags().Bool("traceroute", false, "Trace the route to an address that isn't GitHub-owned and report the networks it crosses (requires root or CAP_NET_RAW)")
+	cmd.Flags().Int("traceroute-max-hops", 30, "Maximum number of hops traced with --traceroute")
+	cmd.Flags().Duration("watch", 0, "Check the address again against freshly fetched ranges at this interval, e.g. 1h, and exit when its status or area changes")
+	cmd.Flags().String("schedule", "", `Watch the address like --watch, but check it again at the times of this cron expression, e.g. "*/30 * * * *" or @hourly`)
+	cmd.Flags().String("watch-exec", "", "With --watch or --schedule, run this shell command on every change instead of exiting")
+	cmd.Flags().StringArray("watch-hook", nil, "With --watch or --schedule, run this shell command whenever GitHub's ranges change, passing a summary of the changes in its environment and the changes as JSON on stdin (repeatable)")
+	cmd.Flags().StringArray("notify", nil, "With --watch or --schedule, tell the notifier plugin gh-check-github-ip-ranges-notify-<name> of every change (repeatable)")
+	cmd.Flags().Duration("watch-hook-timeout", time.Minute, "Kill a --watch-hook command or --notify plugin that runs longer than this")
+	cmd.Flags().StringArray("source", nil, "Also check against the meta document at this URL or file, labeling its matches, as name=location (repeatable)")
+	cmd.Flags().String("audit-log", "", "Append a JSON line recording every check to this file")
+	cmd.Flags().Bool("explain", false, "Print the decision trace of the check: validation steps, snapshot, prefixes evaluated and every candidate match")
+	cmd.Flags().Bool("allow-non-public", false, "Check private, loopback, carrier-grade NAT and other non-public addresses instead of rejecting them")
+	cmd.Flags().Bool("report", false, "After checking several addresses, print totals per verdict and area, even in silent mode")
+	addHintFlags(cmd)
+	cmd.AddCommand(newExportCommand())
+	cmd.AddCommand(newScanCommand())
+	cmd.AddCommand(newCheckDomainCommand())
+	cmd.AddCommand(newVerifySSHKeyCommand())
+	cmd.AddCommand(newInfoCommand())
+	cmd.AddCommand(newSelfCommand())
+	cmd.AddCommand(newDoctorCommand())
+	cmd.AddCommand(newProbeCommand())
+	cmd.AddCommand(newAuditCommand())
+	cmd.AddCommand(newAllowlistCommand())
+	cmd.AddCommand(newAggregateCommand())
+	cmd.AddCommand(newSubtractCommand())
+	cmd.AddCommand(newStatsCommand())
+	cmd.AddCommand(newOverlapCommand())
+	cmd.AddCommand(newCoverageCommand())
+	cmd.AddCommand(newOrgAllowListCommand())
+	cmd.AddCommand(newPolicyCommand())
+	cmd.AddCommand(newPruneCommand())
+	cmd.AddCommand(newTUICommand())
+	cmd.AddCommand(newBenchCommand())
+	cmd.AddCommand(newServeCommand())
 
 	if err := cmd.Execute(); err != nil {
+		var status exitStatusError
+		if errors.As(err, &status) {
+			osExit(int(status))
+			return
+		}
+
+		var notGitHub notGitHubError
+		code := exitCodeFlag(cmd, "exit-code-error", 2)
+		if errors.As(err, &notGitHub) {
+			code = exitCodeFlag(cmd, "exit-code-not-github", 1)
+		}
+
 		if !cmd.Flags().Changed("silent") {
-			if err.Error() == "the provided IP address is not a GitHub-owned address" {
+			output, _ := cmd.Flags().GetString("output")
+			switch {
+			case output == outputJSON:
+				writeJSONError(os.Stderr, err, code)
+			case errors.As(err, &notGitHub):
 				fmt.Fprintf(os.Stderr, "%v\n", err)
-			} else {
+			default:
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			}
 		}
 
-		// Determine exit code based on error type
-		switch {
-		case err.Error() == "the provided IP address is not a GitHub-owned address":
-			osExit(1)
-		default:
-			osExit(2)
-		}
+		osExit(code)
 	}
 }
 
 func runCommand(cmd *cobra.Command, args []string) error {
-	ipAddress := args[0]
 	silent, _ := cmd.Flags().GetBool("silent")
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		output = outputText
+	}
+	if porcelain, _ := cmd.Flags().GetBool("porcelain"); porcelain {
+		if cmd.Flags().Changed("output") && output != outputPorcelain {
+			return withCategory(errorCategoryUsage, fmt.Errorf("--porcelain can't be combined with --output %s", output))
+		}
+		output = outputPorcelain
+	}
+	if err := validateOutputFormat(output); err != nil {
+		return err
+	}
+
+	inputs := args
+	input, _ := cmd.Flags().GetString("input")
+	if input != "" {
+		lines, err := readBatchInput(input)
+		if err != nil {
+			return err
+		}
+		inputs = append(append([]string{}, args...), lines...)
+	}
 
 	checker := NewIPChecker()
-	result, err := checker.CheckIP(ipAddress)
+	if !silent {
+		defer writeCacheNote(os.Stderr, checker)
+	}
+	if allow, _ := cmd.Flags().GetBool("allow-non-public"); allow {
+		checker.AllowNonPublic()
+	}
+	if err := applyAuditLog(cmd, checker); err != nil {
+		return err
+	}
+	defer checker.audit.Close()
+	if err := applyHintSources(cmd, checker); err != nil {
+		return err
+	}
+	if err := applySources(cmd, checker); err != nil {
+		return err
+	}
+	explain, _ := cmd.Flags().GetBool("explain")
+	if explain && (input != "" || len(inputs) > 1 || cmd.Flags().Changed("watch") || cmd.Flags().Changed("schedule")) {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--explain checks a single address, without --input or --watch"))
+	}
+	if cmd.Flags().Changed("watch") || cmd.Flags().Changed("schedule") || cmd.Flags().Changed("watch-exec") || cmd.Flags().Changed("watch-hook") || cmd.Flags().Changed("notify") {
+		if err := validateWatch(cmd, output, inputs); err != nil {
+			return err
+		}
+		return runWatch(cmd, checker, output, inputs[0])
+	}
+	if input != "" || len(inputs) > 1 {
+		return runBatch(cmd, checker, output, inputs)
+	}
+
+	ipAddress := inputs[0]
+	err := applyAsOf(cmd, checker)
+	if explain && err == nil {
+		return runExplain(checker, output, silent, ipAddress)
+	}
+	var result *CheckResult
+	if err == nil {
+		result, err = checker.CheckIP(ipAddress)
+	}
+	if output == outputNagios || output == outputCheckmk {
+		maxAge, _ := cmd.Flags().GetDuration("nagios-max-age")
+		return reportMonitoring(os.Stdout, output, ipAddress, result, err, checker.SnapshotTime(), maxAge)
+	}
 	if err != nil {
 		return err
 	}
 
+	var trace *tracerouteReport
+	if traceroute, _ := cmd.Flags().GetBool("traceroute"); traceroute && !silent && !result.IsGitHubIP {
+		if output != outputText && output != outputJSON {
+			return withCategory(errorCategoryUsage, fmt.Errorf("--traceroute requires text or json output"))
+		}
+		maxHops, _ := cmd.Flags().GetInt("traceroute-max-hops")
+		trace = traceRoute(ipAddress, maxHops)
+	}
+
+	switch {
+	case silent:
+	case trace != nil:
+		writeTracedResult(os.Stdout, output, ipAddress, result, trace)
+	default:
+		writeResult(os.Stdout, output, ipAddress, result)
+	}
+
+	if summary, _ := cmd.Flags().GetString("summary"); summary != "" {
+		if err := appendSummary(summary, []checkedIP{{IP: ipAddress, Result: result}}); err != nil {
+			return err
+		}
+	}
+
 	if !result.IsGitHubIP {
-		return fmt.Errorf("the provided IP address is not a GitHub-owned address")
+		return notGitHubError("the provided IP address is not a GitHub-owned address")
 	}
+	return nil
+}
 
-	if !silent {
-		fmt.Printf("IP %s belongs to GitHub's %s range (%s)\n",
-			ipAddress, result.FunctionalArea, result.Range)
+// usageArgs tags the errors of an argument validator as usage errors
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return withCategory(errorCategoryUsage, err)
+		}
+		return nil
+	}
+}
+
+// preRun validates and applies the flags shared by all commands
+func preRun(cmd *cobra.Command, args []string) error {
+	if err := validateExitCodes(cmd, args); err != nil {
+		return err
+	}
+	if err := applyRequestFlags(cmd); err != nil {
+		return err
+	}
+	return applyCacheFlags(cmd)
+}
+
+// validateExitCodes checks the exit code flags are valid process exit statuses
+func validateExitCodes(cmd *cobra.Command, args []string) error {
+	for _, name := range []string{"exit-code-not-github", "exit-code-error"} {
+		code, _ := cmd.Flags().GetInt(name)
+		if code < 0 || code > 255 {
+			return withCategory(errorCategoryUsage, fmt.Errorf("--%s must be between 0 and 255", name))
+		}
 	}
 	return nil
 }
+
+// exitCodeFlag returns the exit code set by the named flag, or def if it is invalid
+func exitCodeFlag(cmd *cobra.Command, name string, def int) int {
+	code, err := cmd.PersistentFlags().GetInt(name)
+	if err != nil || code < 0 || code > 255 {
+		return def
+	}
+	return code
+}
+
+// applyAsOf replaces the checker's ranges with those stored in --history if
+// --as-of is set
+func applyAsOf(cmd *cobra.Command, checker *IPChecker) error {
+	asOf, _ := cmd.Flags().GetString("as-of")
+	if asOf == "" {
+		return nil
+	}
+	history, _ := cmd.Flags().GetString("history")
+	if history == "" {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--as-of requires --history"))
+	}
+	t, err := parseAsOf(asOf)
+	if err != nil {
+		return err
+	}
+	return checker.LoadHistory(history, t)
+}
@@ -10,6 +10,18 @@ import (
 	"github.com/spf13/cobra"
 )
 
+func TestMain(m *testing.M) {
+	// Keep the command line tests' meta documents out of the user's cache
+	dir, err := os.MkdirTemp("", "gh-check-github-ip-ranges-cache")
+	if err != nil {
+		panic(err)
+	}
+	metaCacheDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestRunCommand(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -32,7 +44,7 @@ func TestRunCommand(t *testing.T) {
 			args:       []string{"192.30.252.1"},
 			silent:     false,
 			wantError:  false,
-			wantStdout: "IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)\n",
+			wantStdout: "IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22), also in Web, API, Git, Packages, Pages, Importer, Actions, Dependabot, Actions IPv4 (192.30.252.0/22)\n",
 			wantStderr: "",
 		},
 		{
@@ -177,6 +189,9 @@ func TestMainFunction(t *testing.T) {
 	// Override githubMetaURL for testing
 	githubMetaURL = server.URL
 
+	// Don't write to the job summary when the tests run in GitHub Actions
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+
 	tests := []struct {
 		name     string
 		args     []string
@@ -226,6 +241,20 @@ func TestMainFunction(t *testing.T) {
 			wantErr:  true,
 			silent:   false,
 		},
+		{
+			name:     "Explained non-GitHub IP",
+			args:     []string{"gh-check-github-ip-ranges", "--explain", "8.8.8.8"},
+			wantCode: 1,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Explaining several addresses",
+			args:     []string{"gh-check-github-ip-ranges", "--explain", "192.30.252.1", "8.8.8.8"},
+			wantCode: 2,
+			wantErr:  true,
+			silent:   false,
+		},
 		{
 			name:     "No arguments",
 			args:     []string{"gh-check-github-ip-ranges"},
@@ -247,6 +276,111 @@ func TestMainFunction(t *testing.T) {
 			wantErr:  true,
 			silent:   true,
 		},
+		{
+			name:     "CEF output for non-GitHub IP",
+			args:     []string{"gh-check-github-ip-ranges", "8.8.8.8", "--output", "cef"},
+			wantCode: 1,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Nagios output for GitHub IP",
+			args:     []string{"gh-check-github-ip-ranges", "192.30.252.1", "--output", "nagios"},
+			wantCode: 0,
+			wantErr:  false,
+			silent:   false,
+		},
+		{
+			name:     "Nagios output for non-GitHub IP",
+			args:     []string{"gh-check-github-ip-ranges", "8.8.8.8", "--output", "nagios"},
+			wantCode: 2,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Nagios output for invalid IP",
+			args:     []string{"gh-check-github-ip-ranges", "invalid-ip", "--output", "nagios"},
+			wantCode: 3,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Custom not-GitHub exit code",
+			args:     []string{"gh-check-github-ip-ranges", "8.8.8.8", "--exit-code-not-github", "10"},
+			wantCode: 10,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Custom error exit code",
+			args:     []string{"gh-check-github-ip-ranges", "invalid-ip", "--exit-code-error", "20"},
+			wantCode: 20,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Custom exit code leaves GitHub IPs successful",
+			args:     []string{"gh-check-github-ip-ranges", "192.30.252.1", "--exit-code-not-github", "0"},
+			wantCode: 0,
+			wantErr:  false,
+			silent:   false,
+		},
+		{
+			name:     "Out of range exit code",
+			args:     []string{"gh-check-github-ip-ranges", "8.8.8.8", "--exit-code-not-github", "300"},
+			wantCode: 2,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Porcelain output",
+			args:     []string{"gh-check-github-ip-ranges", "8.8.8.8", "--porcelain"},
+			wantCode: 1,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Porcelain with another output format",
+			args:     []string{"gh-check-github-ip-ranges", "192.30.252.1", "--porcelain", "--output", "json"},
+			wantCode: 2,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Unsupported output format",
+			args:     []string{"gh-check-github-ip-ranges", "192.30.252.1", "--output", "xml"},
+			wantCode: 2,
+			wantErr:  true,
+			silent:   false,
+		},
+		{
+			name:     "Batch of GitHub IPs",
+			args:     []string{"gh-check-github-ip-ranges", "192.30.252.1", "192.30.252.2"},
+			wantCode: 0,
+			wantErr:  false,
+			silent:   false,
+		},
+		{
+			name:     "Batch with non-GitHub IP",
+			args:     []string{"gh-check-github-ip-ranges", "192.30.252.1", "8.8.8.8", "-s"},
+			wantCode: 1,
+			wantErr:  true,
+			silent:   true,
+		},
+		{
+			name:     "Batch with invalid IP",
+			args:     []string{"gh-check-github-ip-ranges", "8.8.8.8", "invalid-ip", "--fail-fast", "-s"},
+			wantCode: 1,
+			wantErr:  true,
+			silent:   true,
+		},
+		{
+			name:     "Missing input file",
+			args:     []string{"gh-check-github-ip-ranges", "--input", "does-not-exist.txt"},
+			wantCode: 2,
+			wantErr:  true,
+			silent:   false,
+		},
 		{
 			name:     "Version flag",
 			args:     []string{"gh-check-github-ip-ranges", "--version"},
@@ -317,3 +451,79 @@ func TestMainFunction(t *testing.T) {
 		})
 	}
 }
+
+func TestMainJSONErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldArgs := os.Args
+	oldURL := githubMetaURL
+	oldOsExit := osExit
+	defer func() {
+		os.Args = oldArgs
+		githubMetaURL = oldURL
+		osExit = oldOsExit
+	}()
+	githubMetaURL = server.URL
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStderr string
+	}{
+		{
+			name:       "Not GitHub",
+			args:       []string{"8.8.8.8"},
+			wantStderr: `{"code":1,"category":"not_github","message":"the provided IP address is not a GitHub-owned address"}` + "\n",
+		},
+		{
+			name:       "Invalid input",
+			args:       []string{"invalid-ip"},
+			wantStderr: `{"code":2,"category":"invalid_input","message":"invalid IP address format"}` + "\n",
+		},
+		{
+			name:       "Usage",
+			args:       []string{},
+			wantStderr: `{"code":2,"category":"usage","message":"requires at least 1 arg(s), only received 0"}` + "\n",
+		},
+		{
+			name:       "Custom exit code",
+			args:       []string{"invalid-ip", "--exit-code-error", "9"},
+			wantStderr: `{"code":9,"category":"invalid_input","message":"invalid IP address format"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldStdout := os.Stdout
+			oldStderr := os.Stderr
+			_, wOut, _ := os.Pipe()
+			rErr, wErr, _ := os.Pipe()
+			os.Stdout = wOut
+			os.Stderr = wErr
+
+			os.Args = append([]string{"gh-check-github-ip-ranges", "--output", "json"}, tt.args...)
+			exitCode := make(chan int, 1)
+			osExit = func(code int) { exitCode <- code }
+			go func() {
+				main()
+				exitCode <- 0
+			}()
+			<-exitCode
+
+			wOut.Close()
+			wErr.Close()
+			os.Stdout = oldStdout
+			os.Stderr = oldStderr
+
+			var bufErr bytes.Buffer
+			bufErr.ReadFrom(rErr)
+			if bufErr.String() != tt.wantStderr {
+				t.Errorf("main() stderr = %q, want %q", bufErr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
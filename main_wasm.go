@@ -0,0 +1,34 @@
+//go:build js && wasm
+
+package main
+
+import "syscall/js"
+
+// main registers globalThis.ghipcheck.checkIP(ip, metaJSON) and keeps the
+// module running to serve calls. A call returns {result} with the JSON check
+// output, or {error: {category, message}}; wasm/ghipcheck.mjs wraps it.
+func main() {
+	js.Global().Set("ghipcheck", js.ValueOf(map[string]any{
+		"checkIP": js.FuncOf(func(this js.Value, args []js.Value) any {
+			if len(args) != 2 || args[0].Type() != js.TypeString || args[1].Type() != js.TypeString {
+				return map[string]any{"error": map[string]any{
+					"category": errorCategoryUsage,
+					"message":  "checkIP expects an IP address and a meta JSON document as strings",
+				}}
+			}
+			checker, err := newMetaChecker([]byte(args[1].String()))
+			var out []byte
+			if err == nil {
+				out, err = checkIPJSON(checker, args[0].String())
+			}
+			if err != nil {
+				return map[string]any{"error": map[string]any{
+					"category": errorCategory(err),
+					"message":  err.Error(),
+				}}
+			}
+			return map[string]any{"result": string(out)}
+		}),
+	}))
+	select {}
+}
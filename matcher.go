@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// matchLinear returns the published ranges containing addr, most specific first
+// with ties going to the area listed first. It parses and tests every range in
+// turn, the reference the trie is checked and benchmarked against.
+func matchLinear(areas []Area, addr netip.Addr) []RangeMatch {
+	type match struct {
+		RangeMatch
+		bits int
+	}
+	var matches []match
+	for _, area := range areas {
+		for _, cidr := range area.Ranges {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				continue
+			}
+
+			if prefix.Contains(addr) {
+				matches = append(matches, match{RangeMatch{FunctionalArea: area.Name, AreaKey: area.Key, Range: cidr}, prefix.Bits()})
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].bits > matches[j].bits })
+	result := make([]RangeMatch, len(matches))
+	for i, m := range matches {
+		result[i] = m.RangeMatch
+	}
+	return result
+}
+
+// rangeTrie is a binary trie of the published ranges, for matching many
+// addresses against ranges parsed once
+type rangeTrie struct {
+	v4, v6 *trieNode
+}
+
+// trieNode is a prefix of the trie, with the ranges of that exact prefix
+type trieNode struct {
+	children [2]*trieNode
+	ranges   []RangeMatch
+}
+
+// newRangeTrie builds the trie of the areas' ranges, skipping invalid ones
+func newRangeTrie(areas []Area) *rangeTrie {
+	t := &rangeTrie{v4: &trieNode{}, v6: &trieNode{}}
+	for _, area := range areas {
+		for _, cidr := range area.Ranges {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				continue
+			}
+			node, bytes := t.root(prefix.Addr())
+			for i := range prefix.Bits() {
+				bit := bytes[i/8] >> (7 - i%8) & 1
+				if node.children[bit] == nil {
+					node.children[bit] = &trieNode{}
+				}
+				node = node.children[bit]
+			}
+			node.ranges = append(node.ranges, RangeMatch{FunctionalArea: area.Name, AreaKey: area.Key, Range: cidr})
+		}
+	}
+	return t
+}
+
+// root returns the root of addr's family and the bytes of addr, of which the
+// first 4 of an IPv4 address are its bytes
+func (t *rangeTrie) root(addr netip.Addr) (*trieNode, [16]byte) {
+	if addr.Is4() {
+		v4 := addr.As4()
+		return t.v4, [16]byte{v4[0], v4[1], v4[2], v4[3]}
+	}
+	return t.v6, addr.As16()
+}
+
+// match returns the ranges containing addr in the same order as matchLinear
+func (t *rangeTrie) match(addr netip.Addr) []RangeMatch {
+	if !addr.IsValid() {
+		return nil
+	}
+	node, bytes := t.root(addr)
+	bits := addr.BitLen()
+
+	// Walk down to the most specific prefix, then list the ranges deepest first
+	var path []*trieNode
+	for i := 0; node != nil; i++ {
+		if len(node.ranges) > 0 {
+			path = append(path, node)
+		}
+		if i == bits {
+			break
+		}
+		node = node.children[bytes[i/8]>>(7-i%8)&1]
+	}
+
+	var matches []RangeMatch
+	for i := len(path) - 1; i >= 0; i-- {
+		matches = append(matches, path[i].ranges...)
+	}
+	return matches
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestRangeTrie(t *testing.T) {
+	areas := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22", "2a0a:a440::/29", "invalid"}},
+		{Key: "web", Name: "Web", Ranges: []string{"192.30.252.0/22", "140.82.112.0/20"}},
+		{Key: "git", Name: "Git", Ranges: []string{"140.82.121.3/32", "0.0.0.0/0"}},
+	}
+	trie := newRangeTrie(areas)
+
+	tests := []struct {
+		ip   string
+		want []RangeMatch
+	}{
+		{"192.30.252.1", []RangeMatch{{"Hooks", "hooks", "192.30.252.0/22", ""}, {"Web", "web", "192.30.252.0/22", ""}, {"Git", "git", "0.0.0.0/0", ""}}},
+		{"140.82.121.3", []RangeMatch{{"Git", "git", "140.82.121.3/32", ""}, {"Web", "web", "140.82.112.0/20", ""}, {"Git", "git", "0.0.0.0/0", ""}}},
+		{"8.8.8.8", []RangeMatch{{"Git", "git", "0.0.0.0/0", ""}}},
+		{"2a0a:a440::1", []RangeMatch{{"Hooks", "hooks", "2a0a:a440::/29", ""}}},
+		{"2001:db8::1", nil},
+	}
+	for _, tt := range tests {
+		ip := netip.MustParseAddr(tt.ip)
+		if got := trie.match(ip); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rangeTrie.match(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+		if got := matchLinear(areas, ip); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("matchLinear(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+// benchAreas returns areas publishing about as many ranges as GitHub does, and
+// addresses in and out of them
+func benchAreas() ([]Area, []string) {
+	var areas []Area
+	for a := range 8 {
+		area := Area{Key: fmt.Sprintf("area%d", a), Name: fmt.Sprintf("Area %d", a)}
+		for r := range 500 {
+			area.Ranges = append(area.Ranges, fmt.Sprintf("%d.%d.%d.0/24", 20+a, r/256, r%256))
+		}
+		area.Ranges = append(area.Ranges, fmt.Sprintf("2a0a:a4%02x::/32", a))
+		areas = append(areas, area)
+	}
+	addrs := append(sampleAddresses(uniqueRanges(areas), 256), benchMissAddresses...)
+	return areas, addrs
+}
+
+// BenchmarkCheckIP checks addresses as batch mode does, one after another
+// against the same checker
+func BenchmarkCheckIP(b *testing.B) {
+	areas, addrs := benchAreas()
+	checker := NewIPChecker()
+	meta := &GitHubMeta{Ranges: make(map[string][]string)}
+	for _, area := range areas {
+		meta.setRanges(area.Key, area.Ranges)
+	}
+	checker.setMeta(meta)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		checker.CheckIP(addrs[i%len(addrs)])
+	}
+}
+
+// benchAddrs parses the addresses of benchAreas
+func benchAddrs(addrs []string) []netip.Addr {
+	var parsed []netip.Addr
+	for _, addr := range addrs {
+		parsed = append(parsed, netip.MustParseAddr(addr))
+	}
+	return parsed
+}
+
+func BenchmarkMatchLinear(b *testing.B) {
+	areas, addrs := benchAreas()
+	parsed := benchAddrs(addrs)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		matchLinear(areas, parsed[i%len(parsed)])
+	}
+}
+
+func BenchmarkRangeTrie(b *testing.B) {
+	areas, addrs := benchAreas()
+	trie := newRangeTrie(areas)
+	parsed := benchAddrs(addrs)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		trie.match(parsed[i%len(parsed)])
+	}
+}
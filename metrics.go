@@ -0,0 +1,94 @@
+package main
+
+import (
+	"expvar"
+	"time"
+)
+
+// Metrics receives the measurements of an IPChecker, so a host application can
+// bind them to Prometheus, OpenTelemetry metrics, expvar or its own counters.
+// Its methods are called synchronously, concurrently by CheckMany's workers,
+// so they must be quick and safe for concurrent use.
+type Metrics interface {
+	// Check is called after each check by CheckIP, CheckMany or an explained
+	// check, with the key of the area of the answer, empty if the address isn't
+	// GitHub-owned or couldn't be checked
+	Check(areaKey string, duration time.Duration, err error)
+
+	// Fetch is called after each load of a meta document, with the name of its
+	// source, empty for GitHub's, and whether it was read from the local cache
+	// instead of fetched
+	Fetch(source string, duration time.Duration, cached bool, err error)
+}
+
+// SetMetrics makes the checker report its checks and fetches to m, or to
+// nothing if m is nil
+func (c *IPChecker) SetMetrics(m Metrics) {
+	c.metrics = m
+}
+
+// recordCheck reports a check to the checker's metrics, if set
+func (c *IPChecker) recordCheck(result *CheckResult, duration time.Duration, err error) {
+	if c.metrics == nil {
+		return
+	}
+	var areaKey string
+	if err == nil && result.IsGitHubIP {
+		areaKey = result.AreaKey
+	}
+	c.metrics.Check(areaKey, duration, err)
+}
+
+// recordFetch reports a load of a meta document to the checker's metrics, if set
+func (c *IPChecker) recordFetch(source string, duration time.Duration, cached bool, err error) {
+	if c.metrics != nil {
+		c.metrics.Fetch(source, duration, cached, err)
+	}
+}
+
+// ExpvarMetrics publishes a checker's metrics with expvar, as a map of counters:
+// checks, check_errors, check_seconds, matches (per area key), not_github,
+// fetches, fetch_errors, fetch_seconds and cache_hits. The durations are
+// totals, to be divided by the counts.
+type ExpvarMetrics struct {
+	vars *expvar.Map
+}
+
+// NewExpvarMetrics publishes the map of counters under name. Like
+// expvar.Publish, it panics if name is already published.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{vars: expvar.NewMap(name)}
+	// Publish every counter from the start, so dashboards see zeros
+	for _, key := range []string{"checks", "check_errors", "not_github", "fetches", "fetch_errors", "cache_hits"} {
+		m.vars.Add(key, 0)
+	}
+	for _, key := range []string{"check_seconds", "fetch_seconds"} {
+		m.vars.AddFloat(key, 0)
+	}
+	m.vars.Set("matches", new(expvar.Map))
+	return m
+}
+
+func (m *ExpvarMetrics) Check(areaKey string, duration time.Duration, err error) {
+	m.vars.Add("checks", 1)
+	m.vars.AddFloat("check_seconds", duration.Seconds())
+	switch {
+	case err != nil:
+		m.vars.Add("check_errors", 1)
+	case areaKey == "":
+		m.vars.Add("not_github", 1)
+	default:
+		m.vars.Get("matches").(*expvar.Map).Add(areaKey, 1)
+	}
+}
+
+func (m *ExpvarMetrics) Fetch(source string, duration time.Duration, cached bool, err error) {
+	m.vars.Add("fetches", 1)
+	m.vars.AddFloat("fetch_seconds", duration.Seconds())
+	if err != nil {
+		m.vars.Add("fetch_errors", 1)
+	}
+	if cached {
+		m.vars.Add("cache_hits", 1)
+	}
+}
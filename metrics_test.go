@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedMetrics records the measurements reported to it
+type recordedMetrics struct {
+	mu      sync.Mutex
+	checks  []string // Area keys, "error" for failed checks
+	fetches []string // Sources, suffixed with " cached" or " error"
+}
+
+func (m *recordedMetrics) Check(areaKey string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		areaKey = "error"
+	}
+	m.checks = append(m.checks, areaKey)
+}
+
+func (m *recordedMetrics) Fetch(source string, duration time.Duration, cached bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case err != nil:
+		source += " error"
+	case cached:
+		source += " cached"
+	}
+	m.fetches = append(m.fetches, source)
+}
+
+func TestIPChecker_SetMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+	if err := useCacheFlags(t, "--max-age", "1h"); err != nil {
+		t.Fatal(err)
+	}
+
+	source := filepath.Join(t.TempDir(), "ghes.json")
+	if err := os.WriteFile(source, []byte(`{"web": ["185.199.108.0/22"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &recordedMetrics{}
+	checker := NewIPChecker()
+	checker.SetMetrics(m)
+	checker.AddSource("ghes", source)
+	for _, ip := range []string{"192.30.252.1", "8.8.8.8", "not-an-ip", "185.199.108.1"} {
+		checker.CheckIP(ip)
+	}
+	checker.Refresh()
+
+	// A new checker reads the cached document
+	cached := NewIPChecker()
+	cached.SetMetrics(m)
+	cached.CheckIP("140.82.112.1")
+
+	wantChecks := []string{"hooks", "", "error", "web", "web"}
+	wantFetches := []string{"", "ghes", "", " cached"}
+	if len(m.checks) != len(wantChecks) || len(m.fetches) != len(wantFetches) {
+		t.Fatalf("metrics recorded checks %q and fetches %q, want %q and %q", m.checks, m.fetches, wantChecks, wantFetches)
+	}
+	for i := range wantChecks {
+		if m.checks[i] != wantChecks[i] {
+			t.Errorf("check %d recorded %q, want %q", i, m.checks[i], wantChecks[i])
+		}
+	}
+	for i := range wantFetches {
+		if m.fetches[i] != wantFetches[i] {
+			t.Errorf("fetch %d recorded %q, want %q", i, m.fetches[i], wantFetches[i])
+		}
+	}
+
+	githubMetaURL = "http://127.0.0.1:0"
+	checker.Refresh()
+	if got := m.fetches[len(m.fetches)-1]; got != " error" {
+		t.Errorf("failed fetch recorded %q, want an error", got)
+	}
+}
+
+func TestExpvarMetrics(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	m := NewExpvarMetrics("test_ghipcheck")
+	m.Check("hooks", time.Millisecond, nil)
+	m.Check("hooks", time.Millisecond, nil)
+	m.Check("", time.Millisecond, nil)
+	m.Check("", time.Millisecond, errRefused)
+	m.Fetch("", time.Second, true, nil)
+	m.Fetch("", time.Second, false, errRefused)
+
+	var got struct {
+		Checks       int            `json:"checks"`
+		CheckErrors  int            `json:"check_errors"`
+		CheckSeconds float64        `json:"check_seconds"`
+		NotGitHub    int            `json:"not_github"`
+		Matches      map[string]int `json:"matches"`
+		Fetches      int            `json:"fetches"`
+		FetchErrors  int            `json:"fetch_errors"`
+		FetchSeconds float64        `json:"fetch_seconds"`
+		CacheHits    int            `json:"cache_hits"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("test_ghipcheck").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Checks != 4 || got.CheckErrors != 1 || got.NotGitHub != 1 || got.Matches["hooks"] != 2 || math.Abs(got.CheckSeconds-0.004) > 1e-9 {
+		t.Errorf("expvar check metrics = %+v", got)
+	}
+	if got.Fetches != 2 || got.FetchErrors != 1 || got.CacheHits != 1 || got.FetchSeconds != 2 {
+		t.Errorf("expvar fetch metrics = %+v", got)
+	}
+}
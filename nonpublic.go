@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// nonPublicRange is a special-purpose IPv4 range whose addresses can't be
+// GitHub's public addresses
+type nonPublicRange struct {
+	Prefix netip.Prefix
+	Kind   string // Classification of its addresses, e.g. "private"
+	Rule   string // Standard reserving the range
+}
+
+// nonPublicRanges are the special-purpose ranges rejected by CheckIP, most
+// specific first within each kind
+var nonPublicRanges = []nonPublicRange{
+	{netip.MustParsePrefix("0.0.0.0/32"), "unspecified", "RFC 1122"},
+	{netip.MustParsePrefix("10.0.0.0/8"), "private", "RFC 1918"},
+	{netip.MustParsePrefix("172.16.0.0/12"), "private", "RFC 1918"},
+	{netip.MustParsePrefix("192.168.0.0/16"), "private", "RFC 1918"},
+	{netip.MustParsePrefix("100.64.0.0/10"), "carrier-grade NAT", "RFC 6598"},
+	{netip.MustParsePrefix("127.0.0.0/8"), "loopback", "RFC 1122"},
+	{netip.MustParsePrefix("169.254.0.0/16"), "link-local", "RFC 3927"},
+	{netip.MustParsePrefix("192.0.2.0/24"), "documentation", "RFC 5737"},
+	{netip.MustParsePrefix("198.51.100.0/24"), "documentation", "RFC 5737"},
+	{netip.MustParsePrefix("203.0.113.0/24"), "documentation", "RFC 5737"},
+	{netip.MustParsePrefix("224.0.0.0/4"), "multicast", "RFC 5771"},
+	{netip.MustParsePrefix("255.255.255.255/32"), "broadcast", "RFC 919"},
+}
+
+// classifyNonPublic returns the special-purpose range containing ip, if any
+func classifyNonPublic(ip netip.Addr) (nonPublicRange, bool) {
+	for _, r := range nonPublicRanges {
+		if r.Prefix.Contains(ip) {
+			return r, true
+		}
+	}
+	return nonPublicRange{}, false
+}
+
+// nonPublicError reports an address rejected for being in a special-purpose
+// range, naming the range and the standard reserving it
+type nonPublicError struct {
+	Range nonPublicRange
+}
+
+func (e nonPublicError) Error() string {
+	return fmt.Sprintf("IP address is %s %s address (%s, %s), not a public one",
+		article(e.Range.Kind), e.Range.Kind, e.Range.Prefix, e.Range.Rule)
+}
+
+// article returns the indefinite article of word
+func article(word string) string {
+	switch word[0] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return "an"
+	}
+	return "a"
+}
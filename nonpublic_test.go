@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestClassifyNonPublic(t *testing.T) {
+	tests := []struct {
+		ip       string
+		wantKind string
+		wantRule string
+	}{
+		{"0.0.0.0", "unspecified", "RFC 1122"},
+		{"10.1.2.3", "private", "RFC 1918"},
+		{"172.31.255.255", "private", "RFC 1918"},
+		{"192.168.1.1", "private", "RFC 1918"},
+		{"100.127.255.255", "carrier-grade NAT", "RFC 6598"},
+		{"127.0.0.1", "loopback", "RFC 1122"},
+		{"169.254.169.254", "link-local", "RFC 3927"},
+		{"198.51.100.7", "documentation", "RFC 5737"},
+		{"239.255.255.250", "multicast", "RFC 5771"},
+		{"255.255.255.255", "broadcast", "RFC 919"},
+		{"172.32.0.1", "", ""},
+		{"100.128.0.1", "", ""},
+		{"140.82.121.3", "", ""},
+	}
+	for _, tt := range tests {
+		r, ok := classifyNonPublic(netip.MustParseAddr(tt.ip))
+		if ok != (tt.wantKind != "") || r.Kind != tt.wantKind || r.Rule != tt.wantRule {
+			t.Errorf("classifyNonPublic(%s) = %+v, %t, want %s (%s)", tt.ip, r, ok, tt.wantKind, tt.wantRule)
+		}
+	}
+}
+
+func TestNonPublicError(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"0.0.0.0", "IP address is an unspecified address (0.0.0.0/32, RFC 1122), not a public one"},
+		{"169.254.1.1", "IP address is a link-local address (169.254.0.0/16, RFC 3927), not a public one"},
+		{"203.0.113.5", "IP address is a documentation address (203.0.113.0/24, RFC 5737), not a public one"},
+	}
+	for _, tt := range tests {
+		r, _ := classifyNonPublic(netip.MustParseAddr(tt.ip))
+		if got := (nonPublicError{r}).Error(); got != tt.want {
+			t.Errorf("nonPublicError of %s = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
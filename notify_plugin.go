@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// notifyPluginPrefix is the prefix of the executables on PATH notifying of the
+// changes seen while watching, e.g. gh-check-github-ip-ranges-notify-slack for
+// --notify slack
+const notifyPluginPrefix = "gh-check-github-ip-ranges-notify-"
+
+// notifyPluginVersion is the version of the document notifiers read on stdin,
+// increased only by changes that would break them
+const notifyPluginVersion = 1
+
+// The events notifiers are told of
+const (
+	notifyAddressChanged = "address_changed" // The watched address's status or area changed
+	notifyRangesChanged  = "ranges_changed"  // GitHub's ranges changed between two fetches
+)
+
+// notifyPluginInput is the document written to a notifier's stdin, with the
+// address or ranges field of its event set
+type notifyPluginInput struct {
+	Version  int              `json:"version"`
+	Notifier string           `json:"notifier"`
+	Event    string           `json:"event"`
+	Address  *watchEventJSON  `json:"address,omitempty"`
+	Ranges   *rangeChangeJSON `json:"ranges,omitempty"`
+}
+
+// notifyPlugin is a notifier plugin found on PATH
+type notifyPlugin struct {
+	name string
+	path string
+}
+
+// lookupNotifyPlugins returns the plugins on PATH of the --notify names
+func lookupNotifyPlugins(names []string) ([]notifyPlugin, error) {
+	var plugins []notifyPlugin
+	for _, name := range names {
+		if !pluginFormatName.MatchString(name) {
+			return nil, withCategory(errorCategoryUsage, fmt.Errorf("invalid --notify name %q", name))
+		}
+		path, err := exec.LookPath(notifyPluginPrefix + name)
+		if err != nil {
+			return nil, withCategory(errorCategoryUsage, fmt.Errorf("no notifier %s%s on PATH", notifyPluginPrefix, name))
+		}
+		plugins = append(plugins, notifyPlugin{name, path})
+	}
+	return plugins, nil
+}
+
+// runNotifyPlugins runs each notifier in turn with input on its stdin, and
+// returns the errors of those that failed or didn't finish within timeout
+func runNotifyPlugins(plugins []notifyPlugin, timeout time.Duration, input notifyPluginInput) []error {
+	var errs []error
+	for _, p := range plugins {
+		if err := p.notify(timeout, input); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// notify runs the notifier with input on its stdin, killing it after timeout
+func (p notifyPlugin) notify(timeout time.Duration, input notifyPluginInput) error {
+	input.Version, input.Notifier = notifyPluginVersion, p.name
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to encode notifier input: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, p.path)
+	c.Stdin = bytes.NewReader(stdin)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.WaitDelay = time.Second
+	err = c.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("notifier %s timed out after %s", p.path, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("notifier %s failed: %w", p.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// writeNotifyPlugin writes a notifier plugin called name running script to dir
+func writeNotifyPlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, notifyPluginPrefix+name), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLookupNotifyPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writeNotifyPlugin(t, dir, "chat", "cat")
+	t.Setenv("PATH", dir)
+
+	plugins, err := lookupNotifyPlugins([]string{"chat"})
+	if err != nil || len(plugins) != 1 || plugins[0].path != filepath.Join(dir, notifyPluginPrefix+"chat") {
+		t.Errorf("lookupNotifyPlugins(chat) = %+v, %v", plugins, err)
+	}
+	for _, name := range []string{"pager", "../chat"} {
+		if _, err := lookupNotifyPlugins([]string{name}); err == nil || errorCategory(err) != errorCategoryUsage {
+			t.Errorf("lookupNotifyPlugins(%s) error = %v, want a usage error", name, err)
+		}
+	}
+}
+
+func TestRunWatch_Notify(t *testing.T) {
+	// The ranges change on the second fetch, and the address leaves Hooks on the third
+	responses := []string{
+		`{"hooks": ["192.30.252.0/22"]}`,
+		`{"hooks": ["192.30.252.0/22", "185.199.108.0/22"]}`,
+		`{"hooks": ["185.199.108.0/22"]}`,
+	}
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responses[min(fetches, len(responses)-1)]))
+		fetches++
+	}))
+	defer server.Close()
+
+	oldURL, oldSleep, oldNow, oldLimit := githubMetaURL, watchSleep, watchNow, watchLimit
+	githubMetaURL = server.URL
+	watchSleep = func(time.Duration) {}
+	watchNow = func() time.Time { return time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC) }
+	watchLimit = 3
+	defer func() { githubMetaURL, watchSleep, watchNow, watchLimit = oldURL, oldSleep, oldNow, oldLimit }()
+
+	dir := t.TempDir()
+	received := filepath.Join(dir, "received")
+	writeNotifyPlugin(t, dir, "chat", "cat >> "+received+"; echo >> "+received)
+	writeNotifyPlugin(t, dir, "broken", "exit 3")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Duration("watch", time.Hour, "")
+	cmd.Flags().Bool("silent", true, "")
+	cmd.Flags().String("watch-exec", "", "")
+	cmd.Flags().StringArray("watch-hook", nil, "")
+	cmd.Flags().StringArray("notify", nil, "")
+	cmd.Flags().Duration("watch-hook-timeout", time.Second, "")
+	cmd.Flags().Set("notify", "chat")
+	cmd.Flags().Set("notify", "broken")
+
+	oldStderr := os.Stderr
+	rErr, wErr, _ := os.Pipe()
+	os.Stderr = wErr
+	err := runWatch(cmd, NewIPChecker(), outputText, "192.30.252.1")
+	wErr.Close()
+	os.Stderr = oldStderr
+
+	var stderr bytes.Buffer
+	stderr.ReadFrom(rErr)
+	if _, ok := err.(notGitHubError); !ok {
+		t.Fatalf("runWatch() error = %v, want notGitHubError", err)
+	}
+	if !strings.Contains(stderr.String(), "broken failed: exit status 3") {
+		t.Errorf("runWatch() stderr = %q, should report the failing notifier", stderr.String())
+	}
+
+	data, _ := os.ReadFile(received)
+	var events []notifyPluginInput
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var input notifyPluginInput
+		if err := json.Unmarshal([]byte(line), &input); err != nil {
+			t.Fatalf("notifier input = %s, not JSON: %v", line, err)
+		}
+		events = append(events, input)
+	}
+	// The third fetch changes both the ranges and the address
+	wantEvents := []string{notifyRangesChanged, notifyRangesChanged, notifyAddressChanged}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("notifier received %d events, want %d: %s", len(events), len(wantEvents), data)
+	}
+	for i, e := range events {
+		if e.Version != notifyPluginVersion || e.Notifier != "chat" || e.Event != wantEvents[i] {
+			t.Errorf("notifier event %d = %+v, want %s", i, e, wantEvents[i])
+		}
+	}
+	if r := events[0].Ranges; r == nil || len(r.Changes) != 1 || r.Changes[0].Added[0] != "185.199.108.0/22" {
+		t.Errorf("ranges_changed event = %+v, want 185.199.108.0/22 added to Hooks", r)
+	}
+	if a := events[2].Address; a == nil || !a.Previous.IsGitHub || a.Current.IsGitHub {
+		t.Errorf("address_changed event = %+v, want the address leaving Hooks", a)
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var githubGraphQLURL = "https://api.github.com/graphql"
+
+// defaultAllowListLabel prefixes the names of the allow list entries the sync
+// command manages, so it never touches entries added by hand
+const defaultAllowListLabel = "gh-check-github-ip-ranges"
+
+// ipAllowListEntry is an entry of an organization's or enterprise's IP allow list
+type ipAllowListEntry struct {
+	ID       string `json:"id"`
+	Value    string `json:"allowListValue"`
+	Name     string `json:"name"`
+	IsActive bool   `json:"isActive"`
+}
+
+// allowListChange is a change the sync command makes to an IP allow list
+type allowListChange struct {
+	ID    string `json:"id,omitempty"` // Entry to update or delete
+	Value string `json:"value"`
+	Name  string `json:"name"`
+}
+
+// allowListPlan is the set of changes that brings an IP allow list in line with
+// the published ranges
+type allowListPlan struct {
+	Create []allowListChange `json:"create"`
+	Update []allowListChange `json:"update"`
+	Delete []allowListChange `json:"delete"`
+}
+
+// empty reports whether the plan changes nothing
+func (p allowListPlan) empty() bool {
+	return len(p.Create) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// allowListOwner is the organization or enterprise owning an IP allow list
+type allowListOwner struct {
+	Org        string
+	Enterprise string
+}
+
+func (o allowListOwner) String() string {
+	if o.Enterprise != "" {
+		return "enterprise " + o.Enterprise
+	}
+	return "organization " + o.Org
+}
+
+// newOrgAllowListCommand creates the org-allowlist subcommand
+func newOrgAllowListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "org-allowlist",
+		Short: "Manage an organization's or enterprise's IP allow list",
+		Long: `Manage the IP allow list of a GitHub organization or enterprise through the
+GraphQL API, so that members and apps restricted by the allow list still admit
+GitHub's own services.
+
+The API token is taken from GH_TOKEN, GITHUB_TOKEN or the gh CLI's current login,
+and needs the admin:org scope, or admin:enterprise for an enterprise.`,
+		Args: usageArgs(cobra.NoArgs),
+	}
+
+	cmd.PersistentFlags().String("org", "", "Organization owning the allow list")
+	cmd.PersistentFlags().String("enterprise", "", "Enterprise slug owning the allow list")
+	cmd.PersistentFlags().StringSliceP("area", "a", nil, "Functional areas to admit, e.g. hooks,actions (default all)")
+	cmd.PersistentFlags().String("label", defaultAllowListLabel, "Prefix of the names of the entries managed by this command")
+
+	cmd.AddCommand(newOrgAllowListSyncCommand())
+	cmd.AddCommand(newOrgAllowListAuditCommand())
+
+	return cmd
+}
+
+// newOrgAllowListSyncCommand creates the org-allowlist sync subcommand
+func newOrgAllowListSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Add GitHub's ranges to the IP allow list",
+		Long: `Add an entry to the IP allow list for each published range of the selected
+areas that it doesn't list yet, and update the managed entries whose name or
+state is out of date. Managed entries are named "<label>: GitHub <areas>";
+entries with other names are never changed. With --prune, managed entries of
+the selected areas for ranges that are no longer published by any area are
+deleted.
+
+With --dry-run, the changes are printed without being made.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runOrgAllowListSync,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Print the changes without making them")
+	cmd.Flags().Bool("prune", false, "Delete managed entries of the selected areas for ranges that are no longer published")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runOrgAllowListSync(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	prune, _ := cmd.Flags().GetBool("prune")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	owner, label, published, areas, err := orgAllowListOptions(cmd)
+	if err != nil {
+		return err
+	}
+	token, err := githubToken()
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+
+	ownerID, entries, err := fetchIPAllowList(token, owner)
+	if err != nil {
+		return err
+	}
+	plan := planAllowListSync(entries, published, areas, label, prune)
+
+	if !dryRun {
+		if err := applyAllowListPlan(token, ownerID, plan); err != nil {
+			return err
+		}
+	}
+
+	if output == outputJSON {
+		out, err := marshalExportJSON(plan)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(out)
+		return nil
+	}
+	writeAllowListPlan(os.Stdout, plan, owner, dryRun)
+	return nil
+}
+
+// orgAllowListOptions reads the owner, label and areas shared by the org-allowlist
+// subcommands, returning every published area and those selected by --area
+func orgAllowListOptions(cmd *cobra.Command) (allowListOwner, string, []Area, []Area, error) {
+	var owner allowListOwner
+	owner.Org, _ = cmd.Flags().GetString("org")
+	owner.Enterprise, _ = cmd.Flags().GetString("enterprise")
+	label, _ := cmd.Flags().GetString("label")
+	areaNames, _ := cmd.Flags().GetStringSlice("area")
+
+	if (owner.Org == "") == (owner.Enterprise == "") {
+		return owner, "", nil, nil, withCategory(errorCategoryUsage, fmt.Errorf("exactly one of --org and --enterprise is required"))
+	}
+	if strings.TrimSpace(label) == "" {
+		return owner, "", nil, nil, withCategory(errorCategoryUsage, fmt.Errorf("--label must not be empty"))
+	}
+
+	meta, err := NewIPChecker().Meta()
+	if err != nil {
+		return owner, "", nil, nil, err
+	}
+	published := meta.Areas()
+	areas, err := selectAreas(published, areaNames)
+	if err != nil {
+		return owner, "", nil, nil, withCategory(errorCategoryUsage, err)
+	}
+	return owner, label, published, areas, nil
+}
+
+// githubToken returns a GitHub API token, taken from GH_TOKEN, GITHUB_TOKEN or
+// the gh CLI's current login
+var githubToken = func() (string, error) {
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token, nil
+		}
+	}
+
+	out, err := exec.Command("gh", "auth", "token").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get GitHub token (set GH_TOKEN or run 'gh auth login'): %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// managedEntryName returns the name of the managed entry for a range published
+// for the given areas
+func managedEntryName(label string, areaNames []string) string {
+	return label + ": GitHub " + strings.Join(areaNames, ", ")
+}
+
+// isManagedEntry reports whether an entry was created by the sync command
+func isManagedEntry(entry ipAllowListEntry, label string) bool {
+	return strings.HasPrefix(entry.Name, label+":")
+}
+
+// planAllowListSync computes the changes that make the allow list admit the
+// ranges of areas, selected from the published ones. Entries that aren't
+// managed are left alone, even if inactive, since they belong to whoever added
+// them. Pruning only deletes the managed entries of the selected areas, and
+// only for ranges no published area has, so syncing one area doesn't delete
+// the entries of the others.
+func planAllowListSync(entries []ipAllowListEntry, published, areas []Area, label string, prune bool) allowListPlan {
+	plan := allowListPlan{Create: []allowListChange{}, Update: []allowListChange{}, Delete: []allowListChange{}}
+
+	names := make(map[string][]string)
+	for _, area := range areas {
+		for _, cidr := range area.Ranges {
+			names[cidr] = append(names[cidr], area.Name)
+		}
+	}
+
+	listed := make(map[string][]ipAllowListEntry)
+	for _, entry := range entries {
+		listed[entry.Value] = append(listed[entry.Value], entry)
+	}
+
+	for _, cidr := range uniqueRanges(areas) {
+		name := managedEntryName(label, names[cidr])
+		existing := listed[cidr]
+		if len(existing) == 0 {
+			plan.Create = append(plan.Create, allowListChange{Value: cidr, Name: name})
+			continue
+		}
+		for _, entry := range existing {
+			if isManagedEntry(entry, label) && (entry.Name != name || !entry.IsActive) {
+				plan.Update = append(plan.Update, allowListChange{ID: entry.ID, Value: cidr, Name: name})
+			}
+		}
+	}
+
+	if prune {
+		publishedRanges := make(map[string]bool)
+		for _, cidr := range uniqueRanges(published) {
+			publishedRanges[cidr] = true
+		}
+		for _, entry := range entries {
+			if !publishedRanges[entry.Value] && isManagedEntry(entry, label) && managesSelectedArea(entry, label, published, areas) {
+				plan.Delete = append(plan.Delete, allowListChange{ID: entry.ID, Value: entry.Value, Name: entry.Name})
+			}
+		}
+	}
+	return plan
+}
+
+// managesSelectedArea reports whether a managed entry is named after one of the
+// selected areas. With every area selected, entries of areas that are no longer
+// published at all, or whose names were edited, are also included.
+func managesSelectedArea(entry ipAllowListEntry, label string, published, areas []Area) bool {
+	if len(areas) == len(published) {
+		return true
+	}
+	entryAreas := strings.Split(strings.TrimPrefix(entry.Name, label+": GitHub "), ", ")
+	for _, area := range areas {
+		if slices.Contains(entryAreas, area.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipAllowListFields selects the fields of ipAllowListEntry in GraphQL queries
+const ipAllowListFields = `ipAllowListEntries(first: 100, after: $cursor) {
+      nodes { id allowListValue name isActive }
+      pageInfo { hasNextPage endCursor }
+    }`
+
+// ipAllowListPage is a page of IP allow list entries
+type ipAllowListPage struct {
+	Nodes    []ipAllowListEntry `json:"nodes"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+// fetchIPAllowList returns the node ID of the owner and all of its IP allow list
+// entries
+func fetchIPAllowList(token string, owner allowListOwner) (string, []ipAllowListEntry, error) {
+	query := `query($login: String!, $cursor: String) {
+  organization(login: $login) {
+    id
+    ` + ipAllowListFields + `
+  }
+}`
+	login := owner.Org
+	if owner.Enterprise != "" {
+		query = `query($login: String!, $cursor: String) {
+  enterprise(slug: $login) {
+    id
+    ownerInfo {
+      ` + ipAllowListFields + `
+    }
+  }
+}`
+		login = owner.Enterprise
+	}
+
+	var ownerID string
+	var entries []ipAllowListEntry
+	var cursor interface{}
+	for {
+		var data struct {
+			Organization *struct {
+				ID      string          `json:"id"`
+				Entries ipAllowListPage `json:"ipAllowListEntries"`
+			} `json:"organization"`
+			Enterprise *struct {
+				ID        string `json:"id"`
+				OwnerInfo struct {
+					Entries ipAllowListPage `json:"ipAllowListEntries"`
+				} `json:"ownerInfo"`
+			} `json:"enterprise"`
+		}
+		if err := githubGraphQL(token, query, map[string]interface{}{"login": login, "cursor": cursor}, &data); err != nil {
+			return "", nil, err
+		}
+
+		var page ipAllowListPage
+		switch {
+		case data.Organization != nil:
+			ownerID, page = data.Organization.ID, data.Organization.Entries
+		case data.Enterprise != nil:
+			ownerID, page = data.Enterprise.ID, data.Enterprise.OwnerInfo.Entries
+		default:
+			return "", nil, withCategory(errorCategoryAPI, fmt.Errorf("%s not found", owner))
+		}
+
+		entries = append(entries, page.Nodes...)
+		if !page.PageInfo.HasNextPage {
+			return ownerID, entries, nil
+		}
+		cursor = page.PageInfo.EndCursor
+	}
+}
+
+// applyAllowListPlan makes the planned changes to the allow list of ownerID
+func applyAllowListPlan(token, ownerID string, plan allowListPlan) error {
+	for _, c := range plan.Create {
+		mutation := `mutation($ownerId: ID!, $value: String!, $name: String!) {
+  createIpAllowListEntry(input: {ownerId: $ownerId, allowListValue: $value, name: $name, isActive: true}) {
+    ipAllowListEntry { id }
+  }
+}`
+		vars := map[string]interface{}{"ownerId": ownerID, "value": c.Value, "name": c.Name}
+		if err := githubGraphQL(token, mutation, vars, nil); err != nil {
+			return fmt.Errorf("failed to add %s: %w", c.Value, err)
+		}
+	}
+	for _, c := range plan.Update {
+		mutation := `mutation($id: ID!, $value: String!, $name: String!) {
+  updateIpAllowListEntry(input: {ipAllowListEntryId: $id, allowListValue: $value, name: $name, isActive: true}) {
+    ipAllowListEntry { id }
+  }
+}`
+		vars := map[string]interface{}{"id": c.ID, "value": c.Value, "name": c.Name}
+		if err := githubGraphQL(token, mutation, vars, nil); err != nil {
+			return fmt.Errorf("failed to update %s: %w", c.Value, err)
+		}
+	}
+	for _, c := range plan.Delete {
+		mutation := `mutation($id: ID!) {
+  deleteIpAllowListEntry(input: {ipAllowListEntryId: $id}) {
+    clientMutationId
+  }
+}`
+		if err := githubGraphQL(token, mutation, map[string]interface{}{"id": c.ID}, nil); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", c.Value, err)
+		}
+	}
+	return nil
+}
+
+// githubGraphQL sends a query to GitHub's GraphQL API, decoding the data into out if set
+func githubGraphQL(token, query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode GraphQL request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, githubGraphQLURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create GraphQL request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return withCategory(errorCategoryNetwork, fmt.Errorf("failed to call GitHub GraphQL API: %w", err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return withCategory(errorCategoryNetwork, fmt.Errorf("failed to read GraphQL response: %w", err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return withCategory(errorCategoryAPI, fmt.Errorf("GitHub GraphQL API returned status code %d", resp.StatusCode))
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return withCategory(errorCategoryAPI, fmt.Errorf("failed to decode GraphQL response: %w", err))
+	}
+	if len(envelope.Errors) > 0 {
+		return withCategory(errorCategoryAPI, fmt.Errorf("GitHub GraphQL API error: %s", envelope.Errors[0].Message))
+	}
+	if out != nil {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return withCategory(errorCategoryAPI, fmt.Errorf("failed to decode GraphQL response: %w", err))
+		}
+	}
+	return nil
+}
+
+// writeAllowListPlan writes the changes as "+", "~" and "-" lines followed by a summary
+func writeAllowListPlan(w io.Writer, plan allowListPlan, owner allowListOwner, dryRun bool) {
+	for _, c := range plan.Create {
+		fmt.Fprintf(w, "+ %-20s %s\n", c.Value, c.Name)
+	}
+	for _, c := range plan.Update {
+		fmt.Fprintf(w, "~ %-20s %s\n", c.Value, c.Name)
+	}
+	for _, c := range plan.Delete {
+		fmt.Fprintf(w, "- %-20s %s\n", c.Value, c.Name)
+	}
+
+	switch {
+	case plan.empty():
+		fmt.Fprintf(w, "The IP allow list of %s is up to date\n", owner)
+	case dryRun:
+		fmt.Fprintf(w, "Would add %d, update %d and delete %d entries of the IP allow list of %s\n",
+			len(plan.Create), len(plan.Update), len(plan.Delete), owner)
+	default:
+		fmt.Fprintf(w, "Added %d, updated %d and deleted %d entries of the IP allow list of %s\n",
+			len(plan.Create), len(plan.Update), len(plan.Delete), owner)
+	}
+}
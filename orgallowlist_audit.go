@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// orgAllowListAudit is the result of auditing an IP allow list against the
+// published ranges
+type orgAllowListAudit struct {
+	Missing []auditMissing     `json:"missing"` // Published ranges the active entries don't fully admit
+	Stale   []ipAllowListEntry `json:"stale"`   // Managed entries of the areas for ranges that are no longer published
+	Changes allowListPlan      `json:"changes"` // What sync --prune would change
+}
+
+// ok reports whether the allow list admits every published range and has no stale
+// managed entries
+func (a orgAllowListAudit) ok() bool {
+	return len(a.Missing) == 0 && len(a.Stale) == 0
+}
+
+// newOrgAllowListAuditCommand creates the org-allowlist audit subcommand
+func newOrgAllowListAuditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Compare the IP allow list with GitHub's ranges",
+		Long: `Fetch the entries of the IP allow list and report the published ranges of the
+selected areas that its active entries don't fully admit and the managed entries
+for ranges that are no longer published, followed by the changes that
+"org-allowlist sync --prune" would make, for review before running it.
+
+Exits with status 1 if a range is missing or a managed entry is stale.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runOrgAllowListAudit,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runOrgAllowListAudit(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	owner, label, published, areas, err := orgAllowListOptions(cmd)
+	if err != nil {
+		return err
+	}
+	token, err := githubToken()
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+
+	_, entries, err := fetchIPAllowList(token, owner)
+	if err != nil {
+		return err
+	}
+	audit := auditOrgAllowList(entries, published, areas, label)
+
+	if output == outputJSON {
+		out, err := marshalExportJSON(audit)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(out)
+	} else {
+		writeOrgAllowListAudit(os.Stdout, audit, owner, areas)
+	}
+
+	if !audit.ok() {
+		return notGitHubError(fmt.Sprintf("the IP allow list of %s is missing %d published ranges and has %d stale managed entries",
+			owner, len(audit.Missing), len(audit.Stale)))
+	}
+	return nil
+}
+
+// auditOrgAllowList compares the allow list entries with the ranges of areas,
+// selected from the published ones.
+// Inactive entries admit nothing, and entries that aren't valid CIDRs, which
+// the API doesn't prevent, are ignored.
+func auditOrgAllowList(entries []ipAllowListEntry, published, areas []Area, label string) orgAllowListAudit {
+	var active []netip.Prefix
+	for _, entry := range entries {
+		if p, err := parsePrefix(entry.Value); err == nil && entry.IsActive {
+			active = append(active, p)
+		}
+	}
+
+	audit := orgAllowListAudit{
+		Missing: missingRanges(newPrefixSet(active), areas),
+		Stale:   []ipAllowListEntry{},
+		Changes: planAllowListSync(entries, published, areas, label, true),
+	}
+	for _, c := range audit.Changes.Delete {
+		for _, entry := range entries {
+			if entry.ID == c.ID {
+				audit.Stale = append(audit.Stale, entry)
+			}
+		}
+	}
+	return audit
+}
+
+// writeOrgAllowListAudit writes the audit findings and the pending changes as text
+func writeOrgAllowListAudit(w io.Writer, audit orgAllowListAudit, owner allowListOwner, areas []Area) {
+	if audit.ok() && audit.Changes.empty() {
+		fmt.Fprintf(w, "The IP allow list of %s admits the published ranges and is up to date\n", owner)
+		return
+	}
+
+	writeMissingRanges(w, audit.Missing, areas)
+	if len(audit.Stale) > 0 {
+		fmt.Fprintf(w, "Stale managed entries (%d):\n", len(audit.Stale))
+		for _, entry := range audit.Stale {
+			fmt.Fprintf(w, "  %-20s %s\n", entry.Value, entry.Name)
+		}
+	}
+	if !audit.Changes.empty() {
+		fmt.Fprintln(w, "Changes org-allowlist sync --prune would make:")
+		writeAllowListPlan(w, audit.Changes, owner, true)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestAuditOrgAllowList(t *testing.T) {
+	areas := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22", "140.82.112.0/20"}},
+		{Key: "git", Name: "Git", Ranges: []string{"140.82.112.0/20", "143.55.64.0/20"}},
+	}
+	entries := []ipAllowListEntry{
+		{ID: "1", Value: "192.30.252.0/22", Name: "gh-check-github-ip-ranges: GitHub Hooks", IsActive: true},
+		{ID: "2", Value: "140.82.112.0/21", Name: "Office", IsActive: true},
+		{ID: "3", Value: "143.55.64.0/20", Name: "gh-check-github-ip-ranges: GitHub Git", IsActive: false},
+		{ID: "4", Value: "185.199.108.0/22", Name: "gh-check-github-ip-ranges: GitHub Pages", IsActive: true},
+	}
+
+	audit := auditOrgAllowList(entries, areas, areas, defaultAllowListLabel)
+	wantMissing := []auditMissing{
+		{Range: "140.82.112.0/20", Areas: []string{"hooks", "git"}, Partial: true},
+		{Range: "143.55.64.0/20", Areas: []string{"git"}},
+	}
+	if !reflect.DeepEqual(audit.Missing, wantMissing) {
+		t.Errorf("auditOrgAllowList() Missing = %+v, want %+v", audit.Missing, wantMissing)
+	}
+	if want := entries[3:]; !reflect.DeepEqual(audit.Stale, want) {
+		t.Errorf("auditOrgAllowList() Stale = %+v, want %+v", audit.Stale, want)
+	}
+
+	var buf bytes.Buffer
+	writeOrgAllowListAudit(&buf, audit, allowListOwner{Org: "octo-org"}, areas)
+	want := `Missing published ranges (2):
+  140.82.112.0/20      Hooks, Git (partially allowed)
+  143.55.64.0/20       Git
+Stale managed entries (1):
+  185.199.108.0/22     gh-check-github-ip-ranges: GitHub Pages
+Changes org-allowlist sync --prune would make:
++ 140.82.112.0/20      gh-check-github-ip-ranges: GitHub Hooks, Git
+~ 143.55.64.0/20       gh-check-github-ip-ranges: GitHub Git
+- 185.199.108.0/22     gh-check-github-ip-ranges: GitHub Pages
+Would add 1, update 1 and delete 1 entries of the IP allow list of organization octo-org
+`
+	if buf.String() != want {
+		t.Errorf("writeOrgAllowListAudit() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunOrgAllowListAudit(t *testing.T) {
+	tests := []struct {
+		name          string
+		meta          string
+		org           string
+		wantErr       bool
+		wantNotGitHub bool
+	}{
+		{name: "Up to date", meta: `{"hooks": ["192.30.252.0/22"], "pages": ["185.199.108.0/22"]}`, org: "octo-org"},
+		{name: "Missing range", meta: `{"hooks": ["192.30.252.0/22"], "pages": ["185.199.108.0/22"], "git": ["140.82.112.0/20"]}`, org: "octo-org", wantErr: true, wantNotGitHub: true},
+		{name: "Stale entry", meta: `{"hooks": ["192.30.252.0/22"]}`, org: "octo-org", wantErr: true, wantNotGitHub: true},
+		{name: "Unknown organization", meta: `{"hooks": ["192.30.252.0/22"]}`, org: "nope", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.meta))
+			}))
+			defer metaServer.Close()
+
+			oldURL := githubMetaURL
+			githubMetaURL = metaServer.URL
+			defer func() { githubMetaURL = oldURL }()
+
+			var mutations []string
+			fakeGraphQL(t, &mutations)
+
+			cmd := &cobra.Command{}
+			cmd.Flags().String("org", tt.org, "")
+			cmd.Flags().String("enterprise", "", "")
+			cmd.Flags().StringSlice("area", nil, "")
+			cmd.Flags().String("label", defaultAllowListLabel, "")
+			cmd.Flags().StringP("output", "o", outputJSON, "")
+
+			err := runOrgAllowListAudit(cmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runOrgAllowListAudit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(notGitHubError); ok != tt.wantNotGitHub {
+				t.Errorf("runOrgAllowListAudit() error = %v, want notGitHubError %v", err, tt.wantNotGitHub)
+			}
+			if len(mutations) > 0 {
+				t.Errorf("runOrgAllowListAudit() sent mutations %v", mutations)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPlanAllowListSync(t *testing.T) {
+	areas := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22", "140.82.112.0/20"}},
+		{Key: "web", Name: "Web", Ranges: []string{"140.82.112.0/20"}},
+	}
+	entries := []ipAllowListEntry{
+		{ID: "1", Value: "140.82.112.0/20", Name: "gh-check-github-ip-ranges: GitHub Hooks", IsActive: true},
+		{ID: "2", Value: "185.199.108.0/22", Name: "gh-check-github-ip-ranges: GitHub Pages", IsActive: true},
+		{ID: "3", Value: "203.0.113.0/24", Name: "Office VPN", IsActive: true},
+	}
+
+	tests := []struct {
+		name  string
+		prune bool
+		want  allowListPlan
+	}{
+		{
+			name: "Without prune",
+			want: allowListPlan{
+				Create: []allowListChange{{Value: "192.30.252.0/22", Name: "gh-check-github-ip-ranges: GitHub Hooks"}},
+				Update: []allowListChange{{ID: "1", Value: "140.82.112.0/20", Name: "gh-check-github-ip-ranges: GitHub Hooks, Web"}},
+				Delete: []allowListChange{},
+			},
+		},
+		{
+			name:  "With prune",
+			prune: true,
+			want: allowListPlan{
+				Create: []allowListChange{{Value: "192.30.252.0/22", Name: "gh-check-github-ip-ranges: GitHub Hooks"}},
+				Update: []allowListChange{{ID: "1", Value: "140.82.112.0/20", Name: "gh-check-github-ip-ranges: GitHub Hooks, Web"}},
+				Delete: []allowListChange{{ID: "2", Value: "185.199.108.0/22", Name: "gh-check-github-ip-ranges: GitHub Pages"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planAllowListSync(entries, areas, areas, defaultAllowListLabel, tt.prune)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planAllowListSync() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// An unmanaged entry for a published range is left alone, even if inactive
+	unmanaged := []ipAllowListEntry{{ID: "4", Value: "192.30.252.0/22", Name: "Webhooks", IsActive: false}}
+	got := planAllowListSync(unmanaged, areas, areas[:1], defaultAllowListLabel, true)
+	want := []allowListChange{{Value: "140.82.112.0/20", Name: "gh-check-github-ip-ranges: GitHub Hooks"}}
+	if !reflect.DeepEqual(got.Create, want) || len(got.Update) != 0 || len(got.Delete) != 0 {
+		t.Errorf("planAllowListSync() with an unmanaged entry = %+v", got)
+	}
+
+	// Pruning some areas keeps the entries of the others, and of ranges another
+	// area still publishes
+	published := append(areas, Area{Key: "pages", Name: "Pages", Ranges: []string{"185.199.108.0/22"}})
+	selective := []ipAllowListEntry{
+		{ID: "1", Value: "192.30.252.0/22", Name: "gh-check-github-ip-ranges: GitHub Hooks", IsActive: true},
+		{ID: "2", Value: "140.82.112.0/20", Name: "gh-check-github-ip-ranges: GitHub Web", IsActive: true},
+		{ID: "3", Value: "185.199.108.0/22", Name: "gh-check-github-ip-ranges: GitHub Pages", IsActive: true},
+		{ID: "4", Value: "185.199.112.0/22", Name: "gh-check-github-ip-ranges: GitHub Pages", IsActive: true},
+		{ID: "5", Value: "192.30.254.0/24", Name: "gh-check-github-ip-ranges: GitHub Hooks, Pages", IsActive: true},
+	}
+	got = planAllowListSync(selective, published, areas[:1], defaultAllowListLabel, true)
+	wantDelete := []allowListChange{{ID: "5", Value: "192.30.254.0/24", Name: "gh-check-github-ip-ranges: GitHub Hooks, Pages"}}
+	if !reflect.DeepEqual(got.Delete, wantDelete) {
+		t.Errorf("planAllowListSync() of one area deletes %+v, want %+v", got.Delete, wantDelete)
+	}
+	got = planAllowListSync(selective, published, published, defaultAllowListLabel, true)
+	wantDelete = []allowListChange{
+		{ID: "4", Value: "185.199.112.0/22", Name: "gh-check-github-ip-ranges: GitHub Pages"},
+		{ID: "5", Value: "192.30.254.0/24", Name: "gh-check-github-ip-ranges: GitHub Hooks, Pages"},
+	}
+	if !reflect.DeepEqual(got.Delete, wantDelete) {
+		t.Errorf("planAllowListSync() of every area deletes %+v, want %+v", got.Delete, wantDelete)
+	}
+}
+
+func TestWriteAllowListPlan(t *testing.T) {
+	plan := allowListPlan{
+		Create: []allowListChange{{Value: "192.30.252.0/22", Name: "gh-check-github-ip-ranges: GitHub Hooks"}},
+		Delete: []allowListChange{{ID: "2", Value: "185.199.108.0/22", Name: "gh-check-github-ip-ranges: GitHub Pages"}},
+	}
+
+	var buf bytes.Buffer
+	writeAllowListPlan(&buf, plan, allowListOwner{Org: "octo-org"}, true)
+	want := "+ 192.30.252.0/22      gh-check-github-ip-ranges: GitHub Hooks\n" +
+		"- 185.199.108.0/22     gh-check-github-ip-ranges: GitHub Pages\n" +
+		"Would add 1, update 0 and delete 1 entries of the IP allow list of organization octo-org\n"
+	if buf.String() != want {
+		t.Errorf("writeAllowListPlan() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeAllowListPlan(&buf, allowListPlan{}, allowListOwner{Enterprise: "octo-corp"}, false)
+	if want := "The IP allow list of enterprise octo-corp is up to date\n"; buf.String() != want {
+		t.Errorf("writeAllowListPlan() = %q, want %q", buf.String(), want)
+	}
+}
+
+// fakeGraphQL serves an organization's IP allow list over two pages and records
+// the mutations it receives
+func fakeGraphQL(t *testing.T, mutations *[]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch {
+		case strings.HasPrefix(req.Query, "mutation"):
+			_, body, _ := strings.Cut(req.Query, "{")
+			name, _, _ := strings.Cut(strings.TrimSpace(body), "(")
+			*mutations = append(*mutations, name)
+			w.Write([]byte(`{"data": {}}`))
+		case req.Variables["login"] != "octo-org":
+			w.Write([]byte(`{"data": {"organization": null}, "errors": [{"message": "Could not resolve to an Organization"}]}`))
+		case req.Variables["cursor"] == nil:
+			w.Write([]byte(`{"data": {"organization": {"id": "O_1", "ipAllowListEntries": {
+				"nodes": [{"id": "1", "allowListValue": "192.30.252.0/22", "name": "gh-check-github-ip-ranges: GitHub Hooks", "isActive": true}],
+				"pageInfo": {"hasNextPage": true, "endCursor": "c1"}}}}}`))
+		default:
+			w.Write([]byte(`{"data": {"organization": {"id": "O_1", "ipAllowListEntries": {
+				"nodes": [{"id": "2", "allowListValue": "185.199.108.0/22", "name": "gh-check-github-ip-ranges: GitHub Pages", "isActive": true}],
+				"pageInfo": {"hasNextPage": false, "endCursor": "c2"}}}}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	oldURL, oldToken := githubGraphQLURL, githubToken
+	githubGraphQLURL = server.URL
+	githubToken = func() (string, error) { return "test-token", nil }
+	t.Cleanup(func() { githubGraphQLURL, githubToken = oldURL, oldToken })
+}
+
+func TestRunOrgAllowListSync(t *testing.T) {
+	metaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`))
+	}))
+	defer metaServer.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = metaServer.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name          string
+		org           string
+		enterprise    string
+		dryRun        bool
+		prune         bool
+		wantErr       bool
+		wantMutations []string
+	}{
+		{name: "Dry run", org: "octo-org", dryRun: true, prune: true},
+		{name: "Sync", org: "octo-org", wantMutations: []string{"createIpAllowListEntry"}},
+		{
+			name:          "Sync with prune",
+			org:           "octo-org",
+			prune:         true,
+			wantMutations: []string{"createIpAllowListEntry", "deleteIpAllowListEntry"},
+		},
+		{name: "Unknown organization", org: "nope", wantErr: true},
+		{name: "No owner", wantErr: true},
+		{name: "Both owners", org: "octo-org", enterprise: "octo-corp", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutations []string
+			fakeGraphQL(t, &mutations)
+
+			cmd := &cobra.Command{}
+			cmd.Flags().String("org", tt.org, "")
+			cmd.Flags().String("enterprise", tt.enterprise, "")
+			cmd.Flags().StringSlice("area", nil, "")
+			cmd.Flags().String("label", defaultAllowListLabel, "")
+			cmd.Flags().Bool("dry-run", tt.dryRun, "")
+			cmd.Flags().Bool("prune", tt.prune, "")
+			cmd.Flags().StringP("output", "o", outputJSON, "")
+
+			err := runOrgAllowListSync(cmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runOrgAllowListSync() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(mutations, tt.wantMutations) {
+				t.Errorf("runOrgAllowListSync() mutations = %v, want %v", mutations, tt.wantMutations)
+			}
+		})
+	}
+}
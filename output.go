@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Output formats for check results
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputCEF  = "cef"
+	outputLEEF = "leef"
+
+	outputActions = "actions"
+	outputNagios  = "nagios"
+	outputCheckmk = "checkmk"
+
+	outputPorcelain = "porcelain"
+)
+
+// validateOutputFormat returns an error for unsupported output formats
+func validateOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON, outputCEF, outputLEEF, outputActions, outputNagios, outputCheckmk, outputPorcelain:
+		return nil
+	default:
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", format))
+	}
+}
+
+// writeResult writes the result of checking ip in the given output format. Text
+// output only describes GitHub-owned addresses and heuristic hints, since the
+// error returned for other addresses already explains the verdict.
+func writeResult(w io.Writer, format, ip string, result *CheckResult) {
+	switch format {
+	case outputJSON:
+		json.NewEncoder(w).Encode(newResultJSON(ip, result))
+	case outputCEF:
+		fmt.Fprintln(w, formatCEF(ip, result))
+	case outputLEEF:
+		fmt.Fprintln(w, formatLEEF(ip, result))
+	case outputActions:
+		fmt.Fprintln(w, formatActionsCommand(ip, result))
+	case outputPorcelain:
+		fmt.Fprintln(w, formatPorcelain(result))
+	default:
+		switch {
+		case result.IsGitHubIP:
+			fmt.Fprintf(w, "IP %s belongs to GitHub's %s range (%s)%s%s\n",
+				ip, result.FunctionalArea, result.Range, formatSource(result.Source), formatAlsoMatches(result.Also))
+		case result.Hint != nil:
+			fmt.Fprintf(w, "IP %s is %s\n", ip, formatHint(result.Hint))
+		}
+	}
+}
+
+// formatSource formats the meta source of a match as a suffix, if several
+// sources are checked
+func formatSource(source string) string {
+	if source == "" {
+		return ""
+	}
+	return " [" + source + "]"
+}
+
+// formatAlsoMatches formats the other ranges containing an address as a suffix
+// of its text result, grouping areas that publish the same range
+func formatAlsoMatches(also []RangeMatch) string {
+	if len(also) == 0 {
+		return ""
+	}
+	type group struct{ Range, Source string }
+	var groups []group
+	names := make(map[group][]string)
+	for _, m := range also {
+		g := group{m.Range, m.Source}
+		if _, ok := names[g]; !ok {
+			groups = append(groups, g)
+		}
+		names[g] = append(names[g], m.FunctionalArea)
+	}
+	formatted := make([]string, len(groups))
+	for i, g := range groups {
+		formatted[i] = fmt.Sprintf("%s (%s)%s", strings.Join(names[g], ", "), g.Range, formatSource(g.Source))
+	}
+	return ", also in " + strings.Join(formatted, "; ")
+}
+
+// formatPorcelain formats a result as a single stable token, github:<area key>
+// or not-github, for scripts that shouldn't depend on the text wording
+func formatPorcelain(result *CheckResult) string {
+	if result.IsGitHubIP {
+		return "github:" + result.AreaKey
+	}
+	return "not-github"
+}
+
+// resultJSON is the JSON output of a check result
+type resultJSON struct {
+	IP       string     `json:"ip"`
+	IsGitHub bool       `json:"is_github"`
+	Area     string     `json:"area,omitempty"`
+	Range    string     `json:"range,omitempty"`
+	Source   string     `json:"source,omitempty"` // Meta source of the range, if several are checked
+	Also     []alsoJSON `json:"also,omitempty"`   // Other ranges containing the IP, most specific first
+	Hint     *hintJSON  `json:"hint,omitempty"`   // Heuristic match of an IP that isn't GitHub-owned
+	Error    string     `json:"error,omitempty"`  // Why a batch input couldn't be checked
+	Count    int        `json:"count,omitempty"`  // Occurrences of a batch input with --unique
+
+	Traceroute *tracerouteReport `json:"traceroute,omitempty"`
+}
+
+// alsoJSON is another range containing a checked IP
+type alsoJSON struct {
+	Area   string `json:"area"`
+	Range  string `json:"range"`
+	Source string `json:"source,omitempty"`
+}
+
+// hintJSON is a heuristic match of a checked IP in a secondary source
+type hintJSON struct {
+	Heuristic   bool   `json:"heuristic"` // Always true, so consumers can't mistake it for a verdict
+	Source      string `json:"source"`
+	Description string `json:"description"`
+	Tag         string `json:"tag"`
+	Range       string `json:"range"`
+}
+
+func newResultJSON(ip string, result *CheckResult) resultJSON {
+	out := resultJSON{
+		IP:       ip,
+		IsGitHub: result.IsGitHubIP,
+		Area:     result.FunctionalArea,
+		Range:    result.Range,
+		Source:   result.Source,
+	}
+	for _, m := range result.Also {
+		out.Also = append(out.Also, alsoJSON{m.FunctionalArea, m.Range, m.Source})
+	}
+	if h := result.Hint; h != nil {
+		out.Hint = &hintJSON{true, h.Source, h.Description, h.Tag, h.Range}
+	}
+	return out
+}
+
+// errorJSON is the JSON output of an error, written to stderr with --output json
+type errorJSON struct {
+	Code     int    `json:"code"`
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+// writeJSONError writes err as a JSON object along with the exit code it causes
+func writeJSONError(w io.Writer, err error, code int) {
+	json.NewEncoder(w).Encode(errorJSON{
+		Code:     code,
+		Category: errorCategory(err),
+		Message:  err.Error(),
+	})
+}
+
+// resultEvent returns the event ID, name and verdict describing a result
+func resultEvent(result *CheckResult) (id, name, verdict string) {
+	if result.IsGitHubIP {
+		return "github-ip", "GitHub IP address", "github"
+	}
+	return "non-github-ip", "Non-GitHub IP address", "not-github"
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+	leefValueEscaper    = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+	actionsDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	actionsPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// formatCEF formats a result as an ArcSight Common Event Format record
+func formatCEF(ip string, result *CheckResult) string {
+	id, name, verdict := resultEvent(result)
+	severity := 1
+	if !result.IsGitHubIP {
+		severity = 3
+	}
+
+	header := []string{"CEF:0", "gclhub", "gh-check-github-ip-ranges", Version, id, name, fmt.Sprint(severity)}
+	for i := range header[1:] {
+		header[i+1] = cefHeaderEscaper.Replace(header[i+1])
+	}
+
+	extension := []string{
+		"src=" + cefExtensionEscaper.Replace(ip),
+		"outcome=" + verdict,
+	}
+	if result.IsGitHubIP {
+		extension = append(extension,
+			"cs1Label=area", "cs1="+cefExtensionEscaper.Replace(result.FunctionalArea),
+			"cs2Label=range", "cs2="+cefExtensionEscaper.Replace(result.Range))
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(extension, " ")
+}
+
+// formatLEEF formats a result as an IBM QRadar LEEF 1.0 record
+func formatLEEF(ip string, result *CheckResult) string {
+	id, _, verdict := resultEvent(result)
+
+	attributes := []string{
+		"src=" + leefValueEscaper.Replace(ip),
+		"verdict=" + verdict,
+	}
+	if result.IsGitHubIP {
+		attributes = append(attributes,
+			"area="+leefValueEscaper.Replace(result.FunctionalArea),
+			"range="+leefValueEscaper.Replace(result.Range))
+	}
+	return "LEEF:1.0|gclhub|gh-check-github-ip-ranges|" + Version + "|" + id + "|" + strings.Join(attributes, "\t")
+}
+
+// formatActionsCommand formats a result as a GitHub Actions workflow command, a
+// notice for GitHub-owned addresses and an error otherwise, so the result shows
+// up as an annotation on the workflow run
+func formatActionsCommand(ip string, result *CheckResult) string {
+	if result.IsGitHubIP {
+		message := fmt.Sprintf("IP %s belongs to GitHub's %s range (%s)", ip, result.FunctionalArea, result.Range)
+		return "::notice title=" + actionsPropertyEscaper.Replace("GitHub IP "+ip) + "::" + actionsDataEscaper.Replace(message)
+	}
+	message := fmt.Sprintf("IP %s is not a GitHub-owned address", ip)
+	return "::error title=" + actionsPropertyEscaper.Replace("Not a GitHub IP "+ip) + "::" + actionsDataEscaper.Replace(message)
+}
+
+// checkedIP is an address along with the result of checking it, or the error
+// that prevented checking it
+type checkedIP struct {
+	IP     string
+	Result *CheckResult
+	Err    error
+	Count  int // Occurrences of the address in the input, with --unique
+}
+
+var markdownCellEscaper = strings.NewReplacer("|", `\|`, "\n", " ", "\r", " ")
+
+// writeSummary writes the results as a markdown table for a GitHub Actions job summary
+func writeSummary(w io.Writer, results []checkedIP) {
+	fmt.Fprintln(w, "### GitHub IP range check")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| IP | Verdict | Area | Range |")
+	fmt.Fprintln(w, "|----|---------|------|-------|")
+	for _, r := range results {
+		verdict, area, cidr := ":x: Not GitHub", "", ""
+		switch {
+		case r.Err != nil:
+			verdict = ":warning: " + markdownCellEscaper.Replace(r.Err.Error())
+		case r.Result.IsGitHubIP:
+			verdict, area, cidr = ":white_check_mark: GitHub", r.Result.FunctionalArea, "`"+r.Result.Range+"`"
+		}
+		fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n",
+			markdownCellEscaper.Replace(r.IP), verdict, markdownCellEscaper.Replace(area), cidr)
+	}
+	fmt.Fprintln(w)
+}
+
+// appendSummary appends the results table to the job summary file at path
+func appendSummary(path string, results []checkedIP) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job summary: %w", err)
+	}
+	writeSummary(f, results)
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write job summary: %w", err)
+	}
+	return nil
+}
+
+// Nagios plugin states, which are also the plugin's exit status and the
+// Checkmk local check states
+const (
+	nagiosOK       = 0
+	nagiosWarning  = 1
+	nagiosCritical = 2
+	nagiosUnknown  = 3
+)
+
+var nagiosStateNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// monitorStatus is the outcome of a check as reported to monitoring systems
+type monitorStatus struct {
+	State     int
+	Message   string
+	Age       time.Duration // Age of the ranges snapshot
+	Unmatched int
+}
+
+// checkStatus returns the monitoring state of checking ip. Non-GitHub addresses
+// are CRITICAL, errors UNKNOWN, and GitHub addresses checked against a snapshot
+// older than a non-zero maxAge WARNING.
+func checkStatus(ip string, result *CheckResult, checkErr error, snapshot time.Time, maxAge time.Duration) monitorStatus {
+	if checkErr != nil {
+		return monitorStatus{State: nagiosUnknown, Message: checkErr.Error()}
+	}
+
+	status := monitorStatus{
+		State:   nagiosOK,
+		Message: fmt.Sprintf("IP %s belongs to GitHub's %s range (%s)%s", ip, result.FunctionalArea, result.Range, formatAlsoMatches(result.Also)),
+		Age:     time.Since(snapshot).Truncate(time.Second),
+	}
+	switch {
+	case !result.IsGitHubIP:
+		status.State, status.Unmatched = nagiosCritical, 1
+		status.Message = fmt.Sprintf("IP %s is not a GitHub-owned address", ip)
+	case maxAge > 0 && status.Age > maxAge:
+		status.State = nagiosWarning
+		status.Message += fmt.Sprintf(", but the ranges snapshot is %s old", status.Age)
+	}
+	return status
+}
+
+// formatNagios formats a check status as a Nagios plugin status line with
+// snapshot age and unmatched address perfdata
+func formatNagios(status monitorStatus, maxAge time.Duration) string {
+	line := fmt.Sprintf("GITHUB-IP %s - %s", nagiosStateNames[status.State], status.Message)
+	if status.State == nagiosUnknown {
+		return line
+	}
+
+	warn := ""
+	if maxAge > 0 {
+		warn = fmt.Sprint(int64(maxAge.Seconds()))
+	}
+	return fmt.Sprintf("%s | snapshot_age=%ds;%s;;0 unmatched=%d;;1;0", line, int64(status.Age.Seconds()), warn, status.Unmatched)
+}
+
+// formatCheckmk formats a check status as a Checkmk local check line
+func formatCheckmk(ip string, status monitorStatus, maxAge time.Duration) string {
+	service := fmt.Sprintf("%q", "GitHub IP "+ip)
+	if status.State == nagiosUnknown {
+		return fmt.Sprintf("%d %s - %s", status.State, service, status.Message)
+	}
+
+	age := fmt.Sprintf("snapshot_age=%d", int64(status.Age.Seconds()))
+	if maxAge > 0 {
+		age += fmt.Sprintf(";%d", int64(maxAge.Seconds()))
+	}
+	return fmt.Sprintf("%d %s %s|unmatched=%d;;1 %s", status.State, service, age, status.Unmatched, status.Message)
+}
+
+// reportMonitoring prints the Nagios or Checkmk status line for a check and
+// returns an error exiting with the plugin state, if it isn't OK
+func reportMonitoring(w io.Writer, format, ip string, result *CheckResult, checkErr error, snapshot time.Time, maxAge time.Duration) error {
+	status := checkStatus(ip, result, checkErr, snapshot, maxAge)
+	if format == outputCheckmk {
+		fmt.Fprintln(w, formatCheckmk(ip, status, maxAge))
+	} else {
+		fmt.Fprintln(w, formatNagios(status, maxAge))
+	}
+	if status.State != nagiosOK {
+		return exitStatusError(status.State)
+	}
+	return nil
+}
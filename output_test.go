@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteResult(t *testing.T) {
+	github := &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", AreaKey: "hooks", Range: "192.30.252.0/22"}
+	notGitHub := &CheckResult{IsGitHubIP: false}
+	nested := &CheckResult{IsGitHubIP: true, FunctionalArea: "Copilot", AreaKey: "copilot", Range: "192.30.252.0/24",
+		Also: []RangeMatch{{"Hooks", "hooks", "192.30.252.0/22", ""}, {"Web", "web", "192.30.252.0/22", ""}, {"API", "api", "192.30.0.0/16", ""}}}
+	merged := &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", AreaKey: "hooks", Range: "192.30.252.0/22", Source: "ghes",
+		Also: []RangeMatch{{"Web", "web", "192.30.252.0/22", "ghes"}, {"Web", "web", "192.30.252.0/22", "github.com"}}}
+
+	tests := []struct {
+		name   string
+		format string
+		ip     string
+		result *CheckResult
+		want   string
+	}{
+		{
+			name:   "Text GitHub IP",
+			format: outputText,
+			ip:     "192.30.252.1",
+			result: github,
+			want:   "IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)\n",
+		},
+		{
+			name:   "Text GitHub IP in nested ranges",
+			format: outputText,
+			ip:     "192.30.252.1",
+			result: nested,
+			want:   "IP 192.30.252.1 belongs to GitHub's Copilot range (192.30.252.0/24), also in Hooks, Web (192.30.252.0/22); API (192.30.0.0/16)\n",
+		},
+		{
+			name:   "Text non-GitHub IP",
+			format: outputText,
+			ip:     "8.8.8.8",
+			result: notGitHub,
+			want:   "",
+		},
+		{
+			name:   "Porcelain GitHub IP",
+			format: outputPorcelain,
+			ip:     "192.30.252.1",
+			result: github,
+			want:   "github:hooks\n",
+		},
+		{
+			name:   "Porcelain non-GitHub IP",
+			format: outputPorcelain,
+			ip:     "8.8.8.8",
+			result: notGitHub,
+			want:   "not-github\n",
+		},
+		{
+			name:   "JSON GitHub IP",
+			format: outputJSON,
+			ip:     "192.30.252.1",
+			result: github,
+			want:   `{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22"}` + "\n",
+		},
+		{
+			name:   "JSON GitHub IP in nested ranges",
+			format: outputJSON,
+			ip:     "192.30.252.1",
+			result: nested,
+			want: `{"ip":"192.30.252.1","is_github":true,"area":"Copilot","range":"192.30.252.0/24","also":[` +
+				`{"area":"Hooks","range":"192.30.252.0/22"},{"area":"Web","range":"192.30.252.0/22"},{"area":"API","range":"192.30.0.0/16"}]}` + "\n",
+		},
+		{
+			name:   "Text GitHub IP in ranges of several sources",
+			format: outputText,
+			ip:     "192.30.252.1",
+			result: merged,
+			want:   "IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22) [ghes], also in Web (192.30.252.0/22) [ghes]; Web (192.30.252.0/22) [github.com]\n",
+		},
+		{
+			name:   "JSON GitHub IP in ranges of several sources",
+			format: outputJSON,
+			ip:     "192.30.252.1",
+			result: merged,
+			want: `{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22","source":"ghes","also":[` +
+				`{"area":"Web","range":"192.30.252.0/22","source":"ghes"},{"area":"Web","range":"192.30.252.0/22","source":"github.com"}]}` + "\n",
+		},
+		{
+			name:   "JSON non-GitHub IP",
+			format: outputJSON,
+			ip:     "8.8.8.8",
+			result: notGitHub,
+			want:   `{"ip":"8.8.8.8","is_github":false}` + "\n",
+		},
+		{
+			name:   "CEF GitHub IP",
+			format: outputCEF,
+			ip:     "192.30.252.1",
+			result: github,
+			want: "CEF:0|gclhub|gh-check-github-ip-ranges|" + Version + "|github-ip|GitHub IP address|1|" +
+				"src=192.30.252.1 outcome=github cs1Label=area cs1=Hooks cs2Label=range cs2=192.30.252.0/22\n",
+		},
+		{
+			name:   "CEF non-GitHub IP",
+			format: outputCEF,
+			ip:     "8.8.8.8",
+			result: notGitHub,
+			want:   "CEF:0|gclhub|gh-check-github-ip-ranges|" + Version + "|non-github-ip|Non-GitHub IP address|3|src=8.8.8.8 outcome=not-github\n",
+		},
+		{
+			name:   "CEF escapes extension values",
+			format: outputCEF,
+			ip:     "192.30.252.1",
+			result: &CheckResult{IsGitHubIP: true, FunctionalArea: `a=b\c`, Range: "192.30.252.0/22"},
+			want: "CEF:0|gclhub|gh-check-github-ip-ranges|" + Version + "|github-ip|GitHub IP address|1|" +
+				`src=192.30.252.1 outcome=github cs1Label=area cs1=a\=b\\c cs2Label=range cs2=192.30.252.0/22` + "\n",
+		},
+		{
+			name:   "LEEF GitHub IP",
+			format: outputLEEF,
+			ip:     "192.30.252.1",
+			result: github,
+			want:   "LEEF:1.0|gclhub|gh-check-github-ip-ranges|" + Version + "|github-ip|src=192.30.252.1\tverdict=github\tarea=Hooks\trange=192.30.252.0/22\n",
+		},
+		{
+			name:   "LEEF non-GitHub IP",
+			format: outputLEEF,
+			ip:     "8.8.8.8",
+			result: notGitHub,
+			want:   "LEEF:1.0|gclhub|gh-check-github-ip-ranges|" + Version + "|non-github-ip|src=8.8.8.8\tverdict=not-github\n",
+		},
+		{
+			name:   "Actions GitHub IP",
+			format: outputActions,
+			ip:     "192.30.252.1",
+			result: github,
+			want:   "::notice title=GitHub IP 192.30.252.1::IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)\n",
+		},
+		{
+			name:   "Actions non-GitHub IP",
+			format: outputActions,
+			ip:     "8.8.8.8",
+			result: notGitHub,
+			want:   "::error title=Not a GitHub IP 8.8.8.8::IP 8.8.8.8 is not a GitHub-owned address\n",
+		},
+		{
+			name:   "Actions escapes messages",
+			format: outputActions,
+			ip:     "192.30.252.1",
+			result: &CheckResult{IsGitHubIP: true, FunctionalArea: "100%\nHooks", Range: "192.30.252.0/22"},
+			want:   "::notice title=GitHub IP 192.30.252.1::IP 192.30.252.1 belongs to GitHub's 100%25%0AHooks range (192.30.252.0/22)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeResult(&buf, tt.format, tt.ip, tt.result)
+			if buf.String() != tt.want {
+				t.Errorf("writeResult() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	for _, format := range []string{outputText, outputJSON, outputCEF, outputLEEF, outputActions, outputNagios, outputCheckmk, outputPorcelain} {
+		if err := validateOutputFormat(format); err != nil {
+			t.Errorf("validateOutputFormat(%q) error = %v", format, err)
+		}
+	}
+	if err := validateOutputFormat("xml"); err == nil {
+		t.Errorf("validateOutputFormat(%q) should fail", "xml")
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	var buf bytes.Buffer
+	writeSummary(&buf, []checkedIP{
+		{IP: "192.30.252.1", Result: &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", Range: "192.30.252.0/22"}},
+		{IP: "8.8.8.8", Result: &CheckResult{IsGitHubIP: false}},
+		{IP: "10.0.0.1", Err: fmt.Errorf("IP address must be a public, routable address")},
+	})
+
+	want := "### GitHub IP range check\n\n" +
+		"| IP | Verdict | Area | Range |\n" +
+		"|----|---------|------|-------|\n" +
+		"| `192.30.252.1` | :white_check_mark: GitHub | Hooks | `192.30.252.0/22` |\n" +
+		"| `8.8.8.8` | :x: Not GitHub |  |  |\n" +
+		"| `10.0.0.1` | :warning: IP address must be a public, routable address |  |  |\n\n"
+	if buf.String() != want {
+		t.Errorf("writeSummary() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestAppendSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	if err := os.WriteFile(path, []byte("# Build\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := []checkedIP{{IP: "8.8.8.8", Result: &CheckResult{IsGitHubIP: false}}}
+	if err := appendSummary(path, results); err != nil {
+		t.Fatalf("appendSummary() error = %v", err)
+	}
+
+	var want bytes.Buffer
+	want.WriteString("# Build\n\n")
+	writeSummary(&want, results)
+	got, _ := os.ReadFile(path)
+	if string(got) != want.String() {
+		t.Errorf("summary file = %q, want %q", got, want.String())
+	}
+}
+
+func TestMonitoringOutput(t *testing.T) {
+	github := &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", Range: "192.30.252.0/22"}
+	snapshot := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name        string
+		result      *CheckResult
+		err         error
+		maxAge      time.Duration
+		wantState   int
+		wantNagios  string
+		wantCheckmk string
+	}{
+		{
+			name:        "GitHub IP",
+			result:      github,
+			wantState:   nagiosOK,
+			wantNagios:  "GITHUB-IP OK - IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22) | snapshot_age=7200s;;;0 unmatched=0;;1;0",
+			wantCheckmk: `0 "GitHub IP 192.30.252.1" snapshot_age=7200|unmatched=0;;1 IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)`,
+		},
+		{
+			name:        "GitHub IP with other matches",
+			result:      &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", Range: "192.30.252.0/22", Also: []RangeMatch{{FunctionalArea: "Web", Range: "192.30.252.0/22"}}},
+			wantState:   nagiosOK,
+			wantNagios:  "GITHUB-IP OK - IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22), also in Web (192.30.252.0/22) | snapshot_age=7200s;;;0 unmatched=0;;1;0",
+			wantCheckmk: `0 "GitHub IP 192.30.252.1" snapshot_age=7200|unmatched=0;;1 IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22), also in Web (192.30.252.0/22)`,
+		},
+		{
+			name:        "Non-GitHub IP",
+			result:      &CheckResult{IsGitHubIP: false},
+			wantState:   nagiosCritical,
+			wantNagios:  "GITHUB-IP CRITICAL - IP 192.30.252.1 is not a GitHub-owned address | snapshot_age=7200s;;;0 unmatched=1;;1;0",
+			wantCheckmk: `2 "GitHub IP 192.30.252.1" snapshot_age=7200|unmatched=1;;1 IP 192.30.252.1 is not a GitHub-owned address`,
+		},
+		{
+			name:        "Stale snapshot",
+			result:      github,
+			maxAge:      time.Hour,
+			wantState:   nagiosWarning,
+			wantNagios:  "GITHUB-IP WARNING - IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22), but the ranges snapshot is 2h0m0s old | snapshot_age=7200s;3600;;0 unmatched=0;;1;0",
+			wantCheckmk: `1 "GitHub IP 192.30.252.1" snapshot_age=7200;3600|unmatched=0;;1 IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22), but the ranges snapshot is 2h0m0s old`,
+		},
+		{
+			name:        "Fresh snapshot",
+			result:      github,
+			maxAge:      3 * time.Hour,
+			wantState:   nagiosOK,
+			wantNagios:  "GITHUB-IP OK - IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22) | snapshot_age=7200s;10800;;0 unmatched=0;;1;0",
+			wantCheckmk: `0 "GitHub IP 192.30.252.1" snapshot_age=7200;10800|unmatched=0;;1 IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)`,
+		},
+		{
+			name:        "Error",
+			err:         errors.New("invalid IP address format"),
+			wantState:   nagiosUnknown,
+			wantNagios:  "GITHUB-IP UNKNOWN - invalid IP address format",
+			wantCheckmk: `3 "GitHub IP 192.30.252.1" - invalid IP address format`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := checkStatus("192.30.252.1", tt.result, tt.err, snapshot, tt.maxAge)
+			if status.State != tt.wantState {
+				t.Errorf("checkStatus() state = %d, want %d", status.State, tt.wantState)
+			}
+			if got := formatNagios(status, tt.maxAge); got != tt.wantNagios {
+				t.Errorf("formatNagios() = %q, want %q", got, tt.wantNagios)
+			}
+			if got := formatCheckmk("192.30.252.1", status, tt.maxAge); got != tt.wantCheckmk {
+				t.Errorf("formatCheckmk() = %q, want %q", got, tt.wantCheckmk)
+			}
+		})
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	var buf bytes.Buffer
+	writeJSONError(&buf, withCategory(errorCategoryInput, errors.New("invalid IP address format")), 2)
+
+	want := `{"code":2,"category":"invalid_input","message":"invalid IP address format"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("writeJSONError() = %q, want %q", buf.String(), want)
+	}
+}
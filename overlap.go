@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// overlapReport is the output of the overlap subcommand
+type overlapReport struct {
+	Family    string                        `json:"family"`
+	Areas     []string                      `json:"areas"`           // Keys of the areas with addresses in the family
+	Overlap   map[string]map[string]float64 `json:"overlap_percent"` // Share of the row area's addresses also in the column area
+	Relations []overlapRelation             `json:"relations"`
+}
+
+// overlapRelation describes two areas that share addresses
+type overlapRelation struct {
+	A        string  `json:"a"`
+	B        string  `json:"b"`
+	Relation string  `json:"relation"`  // "identical", "subset" when A is contained in B, or "overlap"
+	APercent float64 `json:"a_percent"` // Share of A's addresses also in B
+	BPercent float64 `json:"b_percent"` // Share of B's addresses also in A
+}
+
+// newOverlapCommand creates the overlap subcommand
+func newOverlapCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "overlap",
+		Short: "Show which functional areas share address space",
+		Long: `Print a matrix of the share of each functional area's addresses that are also
+in every other area, followed by the relations between areas that share
+addresses: identical, contained in another area, or partially overlapping.
+
+This answers questions like "can I allow only Hooks without also allowing
+Web?": if Hooks is contained in Web, any rule allowing Web already allows Hooks.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runOverlap,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringSliceP("area", "a", nil, "Functional areas to compare, e.g. hooks,web (default all)")
+	cmd.Flags().String("family", "ipv4", "Address family to compare (ipv4 or ipv6)")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runOverlap(cmd *cobra.Command, args []string) error {
+	areaNames, _ := cmd.Flags().GetStringSlice("area")
+	family, _ := cmd.Flags().GetString("family")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+	if family != "ipv4" && family != "ipv6" {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported address family %q", family))
+	}
+
+	meta, err := NewIPChecker().Meta()
+	if err != nil {
+		return err
+	}
+	areas, err := selectAreas(meta.Areas(), areaNames)
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+
+	report := newOverlapReport(areas, family == "ipv4")
+
+	if output == outputJSON {
+		out, err := marshalExportJSON(report)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	writeOverlap(os.Stdout, report, areas)
+	return nil
+}
+
+// newOverlapReport compares the IPv4 or IPv6 address space of each pair of areas.
+// Areas without addresses in the family are left out.
+func newOverlapReport(areas []Area, ipv4 bool) overlapReport {
+	report := overlapReport{
+		Family:    "ipv6",
+		Areas:     []string{},
+		Overlap:   make(map[string]map[string]float64),
+		Relations: []overlapRelation{},
+	}
+	if ipv4 {
+		report.Family = "ipv4"
+	}
+
+	var sets []rangeSet
+	for _, area := range areas {
+		set := newPrefixSet(parsePrefixes(area.Ranges)).family(ipv4)
+		if len(set) == 0 {
+			continue
+		}
+		report.Areas = append(report.Areas, area.Key)
+		sets = append(sets, set)
+	}
+
+	for i, a := range report.Areas {
+		report.Overlap[a] = make(map[string]float64)
+		for j, b := range report.Areas {
+			report.Overlap[a][b] = percentOf(sets[i].intersectionSize(sets[j]), sets[i].size())
+		}
+	}
+
+	for i, a := range report.Areas {
+		for _, b := range report.Areas[i+1:] {
+			r := overlapRelation{A: a, B: b, Relation: "overlap", APercent: report.Overlap[a][b], BPercent: report.Overlap[b][a]}
+			switch {
+			case r.APercent == 0 && r.BPercent == 0:
+				continue
+			case r.APercent == 100 && r.BPercent == 100:
+				r.Relation = "identical"
+			case r.APercent == 100:
+				r.Relation = "subset"
+			case r.BPercent == 100:
+				r = overlapRelation{A: b, B: a, Relation: "subset", APercent: r.BPercent, BPercent: r.APercent}
+			}
+			report.Relations = append(report.Relations, r)
+		}
+	}
+	return report
+}
+
+// writeOverlap writes the overlap matrix and the relations between areas as text
+func writeOverlap(w io.Writer, report overlapReport, areas []Area) {
+	names := make(map[string]string)
+	for _, area := range areas {
+		names[area.Key] = area.Name
+	}
+	if len(report.Areas) == 0 {
+		fmt.Fprintf(w, "No %s ranges to compare\n", report.Family)
+		return
+	}
+
+	fmt.Fprintf(w, "Share of each row's %s addresses also in the column's area:\n\n", report.Family)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\t"+strings.Join(report.Areas, "\t"))
+	for _, a := range report.Areas {
+		cells := []string{names[a]}
+		for _, b := range report.Areas {
+			if a == b {
+				cells = append(cells, "-")
+			} else {
+				cells = append(cells, fmt.Sprintf("%.1f%%", report.Overlap[a][b]))
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+
+	if len(report.Relations) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	for _, r := range report.Relations {
+		switch r.Relation {
+		case "identical":
+			fmt.Fprintf(w, "%s and %s are identical\n", names[r.A], names[r.B])
+		case "subset":
+			fmt.Fprintf(w, "%s is contained in %s (%.1f%% of %s)\n", names[r.A], names[r.B], r.BPercent, names[r.B])
+		default:
+			fmt.Fprintf(w, "%s and %s overlap (%.1f%% of %s, %.1f%% of %s)\n", names[r.A], names[r.B], r.APercent, names[r.A], r.BPercent, names[r.B])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestNewOverlapReport(t *testing.T) {
+	areas := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22"}},
+		{Key: "web", Name: "Web", Ranges: []string{"192.30.252.0/22", "140.82.112.0/20"}},
+		{Key: "api", Name: "API", Ranges: []string{"192.30.252.0/22", "140.82.112.0/20", "2a0a:a440::/29"}},
+		{Key: "git", Name: "Git", Ranges: []string{"140.82.112.0/21", "20.201.28.0/22"}},
+		{Key: "pages", Name: "Pages", Ranges: []string{"185.199.108.0/22"}},
+	}
+
+	report := newOverlapReport(areas, true)
+	wantRelations := []overlapRelation{
+		{A: "hooks", B: "web", Relation: "subset", APercent: 100, BPercent: 20},
+		{A: "hooks", B: "api", Relation: "subset", APercent: 100, BPercent: 20},
+		{A: "web", B: "api", Relation: "identical", APercent: 100, BPercent: 100},
+		{A: "web", B: "git", Relation: "overlap", APercent: 40, BPercent: 66.6},
+		{A: "api", B: "git", Relation: "overlap", APercent: 40, BPercent: 66.6},
+	}
+	if !reflect.DeepEqual(report.Relations, wantRelations) {
+		t.Errorf("newOverlapReport() relations = %+v, want %+v", report.Relations, wantRelations)
+	}
+	if got := report.Overlap["git"]["web"]; got != 66.6 {
+		t.Errorf("newOverlapReport() git in web = %v, want 66.6", got)
+	}
+	if got := report.Overlap["pages"]["hooks"]; got != 0 {
+		t.Errorf("newOverlapReport() pages in hooks = %v, want 0", got)
+	}
+
+	var buf bytes.Buffer
+	writeOverlap(&buf, report, areas)
+	want := `Share of each row's ipv4 addresses also in the column's area:
+
+       hooks  web     api     git    pages
+Hooks  -      100.0%  100.0%  0.0%   0.0%
+Web    20.0%  -       100.0%  40.0%  0.0%
+API    20.0%  100.0%  -       40.0%  0.0%
+Git    0.0%   66.6%   66.6%   -      0.0%
+Pages  0.0%   0.0%    0.0%    0.0%   -
+
+Hooks is contained in Web (20.0% of Web)
+Hooks is contained in API (20.0% of API)
+Web and API are identical
+Web and Git overlap (40.0% of Web, 66.6% of Git)
+API and Git overlap (40.0% of API, 66.6% of Git)
+`
+	if buf.String() != want {
+		t.Errorf("writeOverlap() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	report = newOverlapReport(areas, false)
+	if !reflect.DeepEqual(report.Areas, []string{"api"}) || len(report.Relations) != 0 {
+		t.Errorf("newOverlapReport() for IPv6 = %+v", report)
+	}
+}
+
+func TestRunOverlap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name    string
+		family  string
+		areas   []string
+		wantErr bool
+	}{
+		{name: "IPv4", family: "ipv4"},
+		{name: "IPv6 without ranges", family: "ipv6"},
+		{name: "Selected areas", family: "ipv4", areas: []string{"hooks", "web"}},
+		{name: "Unknown family", family: "ipv5", wantErr: true},
+		{name: "Unknown area", family: "ipv4", areas: []string{"nope"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().StringSlice("area", tt.areas, "")
+			cmd.Flags().String("family", tt.family, "")
+			cmd.Flags().StringP("output", "o", outputJSON, "")
+
+			err := runOverlap(cmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runOverlap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && errorCategory(err) != errorCategoryUsage {
+				t.Errorf("runOverlap() error category = %q, want usage", errorCategory(err))
+			}
+		})
+	}
+}
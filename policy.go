@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Policy document formats
+const (
+	policyMarkdown = "markdown"
+	policyCSV      = "csv"
+)
+
+// policyUseCase is a scenario whose network requirements the policy command
+// documents
+type policyUseCase struct {
+	Key         string
+	Name        string
+	Description string
+	Services    []string // Keys of the domains section whose domains are required
+	Areas       []string // Keys of the areas whose ranges are required
+	Ports       []policyPort
+}
+
+// policyPort is a port a use case needs open
+type policyPort struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Purpose  string `json:"purpose"`
+}
+
+// policyUseCases are the use cases the policy command knows, in the order listed
+// in its help
+var policyUseCases = []policyUseCase{
+	{
+		Key:         "self-hosted-runners",
+		Name:        "Self-hosted runners",
+		Description: "Outbound connections from self-hosted Actions runners to GitHub",
+		Services:    []string{"actions_inbound", "website"},
+		Areas:       []string{"web", "api", "git", "packages"},
+		Ports: []policyPort{
+			{443, "tcp", "HTTPS to GitHub, the Actions service and package registries"},
+			{22, "tcp", "Git over SSH, if workflows clone with SSH"},
+		},
+	},
+	{
+		Key:         "dependabot",
+		Name:        "Dependabot",
+		Description: "Connections from Dependabot to private registries it updates dependencies from",
+		Services:    []string{"website"},
+		Areas:       []string{"dependabot", "actions"},
+		Ports: []policyPort{
+			{443, "tcp", "HTTPS from Dependabot to private registries"},
+		},
+	},
+	{
+		Key:         "copilot",
+		Name:        "Copilot",
+		Description: "Outbound connections from editors and the CLI to GitHub Copilot",
+		Services:    []string{"copilot", "website"},
+		Areas:       []string{"copilot", "api", "web"},
+		Ports: []policyPort{
+			{443, "tcp", "HTTPS to GitHub and the Copilot service"},
+		},
+	},
+}
+
+// policyUseCaseKeys returns the keys of the known use cases
+func policyUseCaseKeys() []string {
+	keys := make([]string, len(policyUseCases))
+	for i, u := range policyUseCases {
+		keys[i] = u.Key
+	}
+	return keys
+}
+
+// findPolicyUseCase returns the use case with the given key
+func findPolicyUseCase(key string) (policyUseCase, error) {
+	for _, u := range policyUseCases {
+		if u.Key == key {
+			return u, nil
+		}
+	}
+	return policyUseCase{}, fmt.Errorf("unknown use case %q, must be one of %s", key, strings.Join(policyUseCaseKeys(), ", "))
+}
+
+// policyDocument is the network requirements of a use case
+type policyDocument struct {
+	UseCase     string         `json:"use_case"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Generated   time.Time      `json:"generated"`
+	Snapshot    time.Time      `json:"snapshot"` // When GitHub last changed the ranges and domains
+	Ports       []policyPort   `json:"ports"`
+	Domains     []policyDomain `json:"domains"`
+	Ranges      []policyRange  `json:"ranges"`
+	Missing     []string       `json:"missing_sources,omitempty"` // Services and areas the meta document doesn't publish
+}
+
+// policyDomain is a required domain, with the services publishing it
+type policyDomain struct {
+	Domain   string   `json:"domain"`
+	Services []string `json:"services"`
+}
+
+// policyRange is a required range, with the areas publishing it
+type policyRange struct {
+	Range string   `json:"range"`
+	Areas []string `json:"areas"`
+}
+
+// newPolicyCommand creates the policy subcommand
+func newPolicyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Generate the egress requirements of a use case",
+		Long: `Generate a document listing everything a firewall must allow for a use case:
+the ports, the domains GitHub publishes for it and the IP ranges of the areas
+it uses, with where each entry comes from. This is the document to hand to a
+firewall team.
+
+Use cases: ` + strings.Join(policyUseCaseKeys(), ", ") + `.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runPolicy,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("use-case", "u", "", "Use case to document ("+strings.Join(policyUseCaseKeys(), ", ")+")")
+	cmd.Flags().StringP("output", "o", policyMarkdown, "Output format (markdown, json or csv)")
+	cmd.MarkFlagRequired("use-case")
+
+	return cmd
+}
+
+func runPolicy(cmd *cobra.Command, args []string) error {
+	key, _ := cmd.Flags().GetString("use-case")
+	output, _ := cmd.Flags().GetString("output")
+	if output != policyMarkdown && output != outputJSON && output != policyCSV {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+	useCase, err := findPolicyUseCase(key)
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+
+	checker := NewIPChecker()
+	meta, err := checker.Meta()
+	if err != nil {
+		return err
+	}
+	doc := newPolicyDocument(useCase, meta, checker.SnapshotTime(), time.Now().UTC().Truncate(time.Second))
+
+	switch output {
+	case outputJSON:
+		out, err := marshalExportJSON(doc)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	case policyCSV:
+		out, err := renderPolicyCSV(doc)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	writePolicyMarkdown(os.Stdout, doc)
+	return nil
+}
+
+// newPolicyDocument collects the requirements of a use case from the meta document
+func newPolicyDocument(useCase policyUseCase, meta *GitHubMeta, snapshot, generated time.Time) policyDocument {
+	doc := policyDocument{
+		UseCase:     useCase.Key,
+		Name:        useCase.Name,
+		Description: useCase.Description,
+		Generated:   generated,
+		Snapshot:    snapshot,
+		Ports:       useCase.Ports,
+		Domains:     []policyDomain{},
+		Ranges:      []policyRange{},
+	}
+
+	groups := meta.DomainGroups()
+	services := make(map[string][]string)
+	for _, service := range useCase.Services {
+		domains, ok := groups[service]
+		if !ok {
+			doc.Missing = append(doc.Missing, "domains."+service)
+			continue
+		}
+		for _, domain := range domains {
+			if !slices.Contains(services[domain], service) {
+				services[domain] = append(services[domain], service)
+			}
+		}
+	}
+	for domain, s := range services {
+		doc.Domains = append(doc.Domains, policyDomain{domain, s})
+	}
+	sort.Slice(doc.Domains, func(i, j int) bool { return doc.Domains[i].Domain < doc.Domains[j].Domain })
+
+	var areas []Area
+	for _, key := range useCase.Areas {
+		if _, ok := meta.Ranges[key]; !ok {
+			doc.Missing = append(doc.Missing, key)
+			continue
+		}
+		areas = append(areas, Area{key, areaDisplayName(key), meta.Ranges[key]})
+	}
+	keys := areaKeysByRange(areas)
+	for _, cidr := range uniqueRanges(areas) {
+		doc.Ranges = append(doc.Ranges, policyRange{cidr, keys[cidr]})
+	}
+	return doc
+}
+
+// formatPorts formats the ports of a document, e.g. "443/tcp, 22/tcp"
+func formatPorts(ports []policyPort) string {
+	formatted := make([]string, len(ports))
+	for i, p := range ports {
+		formatted[i] = fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// writePolicyMarkdown writes the document as markdown
+func writePolicyMarkdown(w io.Writer, doc policyDocument) {
+	fmt.Fprintf(w, "# Network requirements: %s\n\n", doc.Name)
+	fmt.Fprintf(w, "%s.\n\n", doc.Description)
+	fmt.Fprintf(w, "Generated %s from GitHub's meta API (snapshot %s).\n\n",
+		doc.Generated.Format(time.RFC3339), doc.Snapshot.Format(time.RFC3339))
+
+	fmt.Fprintln(w, "## Ports")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Port | Protocol | Purpose |")
+	fmt.Fprintln(w, "| --- | --- | --- |")
+	for _, p := range doc.Ports {
+		fmt.Fprintf(w, "| %d | %s | %s |\n", p.Port, strings.ToUpper(p.Protocol), p.Purpose)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "## Domains (%d)\n\n", len(doc.Domains))
+	if len(doc.Domains) > 0 {
+		fmt.Fprintln(w, "| Domain | Source |")
+		fmt.Fprintln(w, "| --- | --- |")
+		for _, d := range doc.Domains {
+			fmt.Fprintf(w, "| `%s` | %s |\n", d.Domain, strings.Join(d.Services, ", "))
+		}
+	} else {
+		fmt.Fprintln(w, "GitHub publishes no domains for this use case.")
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "## IP ranges (%d)\n\n", len(doc.Ranges))
+	if len(doc.Ranges) > 0 {
+		fmt.Fprintln(w, "| Range | Areas |")
+		fmt.Fprintln(w, "| --- | --- |")
+		for _, r := range doc.Ranges {
+			fmt.Fprintf(w, "| %s | %s |\n", r.Range, strings.Join(r.Areas, ", "))
+		}
+	} else {
+		fmt.Fprintln(w, "GitHub publishes no IP ranges for this use case.")
+	}
+
+	if len(doc.Missing) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Not published in this snapshot: %s.\n", strings.Join(doc.Missing, ", "))
+	}
+}
+
+// renderPolicyCSV renders the document as CSV with a row per domain and range,
+// each listing the ports to allow
+func renderPolicyCSV(doc policyDocument) ([]byte, error) {
+	ports := formatPorts(doc.Ports)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"type", "value", "ports", "source"})
+	for _, d := range doc.Domains {
+		w.Write([]string{"domain", d.Domain, ports, strings.Join(d.Services, ",")})
+	}
+	for _, r := range doc.Ranges {
+		w.Write([]string{"cidr", r.Range, ports, strings.Join(r.Areas, ",")})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to encode policy: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const testPolicyMeta = `{
+	"web": ["192.30.252.0/22", "140.82.112.0/20"],
+	"api": ["192.30.252.0/22"],
+	"git": ["140.82.112.0/20"],
+	"packages": ["140.82.121.33/32"],
+	"domains": {
+		"website": ["*.github.com", "github.com"],
+		"actions_inbound": {"full_domains": ["github.com", "api.github.com"], "wildcard_domains": ["*.actions.githubusercontent.com"]}
+	}
+}`
+
+func TestNewPolicyDocument(t *testing.T) {
+	var meta GitHubMeta
+	if err := json.Unmarshal([]byte(testPolicyMeta), &meta); err != nil {
+		t.Fatal(err)
+	}
+	useCase, err := findPolicyUseCase("self-hosted-runners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	generated := time.Date(2025, 6, 3, 12, 0, 0, 0, time.UTC)
+
+	doc := newPolicyDocument(useCase, &meta, snapshot, generated)
+	wantDomains := []policyDomain{
+		{"*.actions.githubusercontent.com", []string{"actions_inbound"}},
+		{"*.github.com", []string{"website"}},
+		{"api.github.com", []string{"actions_inbound"}},
+		{"github.com", []string{"actions_inbound", "website"}},
+	}
+	if !reflect.DeepEqual(doc.Domains, wantDomains) {
+		t.Errorf("newPolicyDocument() domains = %v, want %v", doc.Domains, wantDomains)
+	}
+	wantRanges := []policyRange{
+		{"192.30.252.0/22", []string{"web", "api"}},
+		{"140.82.112.0/20", []string{"web", "git"}},
+		{"140.82.121.33/32", []string{"packages"}},
+	}
+	if !reflect.DeepEqual(doc.Ranges, wantRanges) {
+		t.Errorf("newPolicyDocument() ranges = %v, want %v", doc.Ranges, wantRanges)
+	}
+	if len(doc.Missing) != 0 {
+		t.Errorf("newPolicyDocument() missing = %v, want none", doc.Missing)
+	}
+
+	copilot, _ := findPolicyUseCase("copilot")
+	doc = newPolicyDocument(copilot, &meta, snapshot, generated)
+	if want := []string{"domains.copilot", "copilot"}; !reflect.DeepEqual(doc.Missing, want) {
+		t.Errorf("newPolicyDocument() missing = %v, want %v", doc.Missing, want)
+	}
+
+	if _, err := findPolicyUseCase("codespaces"); err == nil {
+		t.Error("findPolicyUseCase() with an unknown use case should fail")
+	}
+}
+
+func TestWritePolicy(t *testing.T) {
+	doc := policyDocument{
+		UseCase:     "dependabot",
+		Name:        "Dependabot",
+		Description: "Connections from Dependabot to private registries it updates dependencies from",
+		Generated:   time.Date(2025, 6, 3, 12, 0, 0, 0, time.UTC),
+		Snapshot:    time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+		Ports:       []policyPort{{443, "tcp", "HTTPS"}, {22, "tcp", "SSH"}},
+		Domains:     []policyDomain{{"github.com", []string{"website"}}},
+		Ranges:      []policyRange{{"192.30.252.0/22", []string{"dependabot", "actions"}}},
+		Missing:     []string{"domains.copilot"},
+	}
+
+	var buf bytes.Buffer
+	writePolicyMarkdown(&buf, doc)
+	want := "# Network requirements: Dependabot\n\n" +
+		"Connections from Dependabot to private registries it updates dependencies from.\n\n" +
+		"Generated 2025-06-03T12:00:00Z from GitHub's meta API (snapshot 2025-06-02T00:00:00Z).\n\n" +
+		"## Ports\n\n| Port | Protocol | Purpose |\n| --- | --- | --- |\n| 443 | TCP | HTTPS |\n| 22 | TCP | SSH |\n\n" +
+		"## Domains (1)\n\n| Domain | Source |\n| --- | --- |\n| `github.com` | website |\n\n" +
+		"## IP ranges (1)\n\n| Range | Areas |\n| --- | --- |\n| 192.30.252.0/22 | dependabot, actions |\n\n" +
+		"Not published in this snapshot: domains.copilot.\n"
+	if buf.String() != want {
+		t.Errorf("writePolicyMarkdown() = %q, want %q", buf.String(), want)
+	}
+
+	out, err := renderPolicyCSV(doc)
+	if err != nil {
+		t.Fatalf("renderPolicyCSV() error = %v", err)
+	}
+	wantCSV := "type,value,ports,source\n" +
+		"domain,github.com,\"443/tcp, 22/tcp\",website\n" +
+		"cidr,192.30.252.0/22,\"443/tcp, 22/tcp\",\"dependabot,actions\"\n"
+	if string(out) != wantCSV {
+		t.Errorf("renderPolicyCSV() = %q, want %q", out, wantCSV)
+	}
+}
+
+func TestRunPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testPolicyMeta))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name    string
+		useCase string
+		output  string
+		wantErr bool
+	}{
+		{name: "Markdown", useCase: "self-hosted-runners", output: policyMarkdown},
+		{name: "JSON", useCase: "dependabot", output: outputJSON},
+		{name: "CSV", useCase: "copilot", output: policyCSV},
+		{name: "Unknown use case", useCase: "codespaces", output: policyMarkdown, wantErr: true},
+		{name: "Unsupported output", useCase: "copilot", output: "yaml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().String("use-case", tt.useCase, "")
+			cmd.Flags().String("output", tt.output, "")
+
+			err := runPolicy(cmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && errorCategory(err) != errorCategoryUsage {
+				t.Errorf("runPolicy() error category = %s, want %s", errorCategory(err), errorCategoryUsage)
+			}
+		})
+	}
+}
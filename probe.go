@@ -0,0 +1,407 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// probeResult is the outcome of connecting to one address or hostname of an area
+type probeResult struct {
+	Endpoint string        `json:"endpoint"`
+	Source   string        `json:"source"` // "hostname" or "sample"
+	Attempts int           `json:"attempts"`
+	Failed   int           `json:"failed"`
+	Error    string        `json:"error,omitempty"` // First error, if any attempt failed
+	Latency  *probeLatency `json:"latency,omitempty"`
+}
+
+// ok reports whether every attempt succeeded
+func (r probeResult) ok() bool {
+	return r.Failed == 0
+}
+
+// probeOptions configures how endpoints are probed
+type probeOptions struct {
+	TLS       bool
+	FirstByte bool
+	Attempts  int
+	Timeout   time.Duration
+}
+
+// probeTiming is the duration of each phase of a probe attempt
+type probeTiming struct {
+	DNS, Connect, TLS, FirstByte time.Duration
+}
+
+// probeLatency summarizes the phase durations of the successful attempts of a probe
+type probeLatency struct {
+	DNS       *latencySummary `json:"dns,omitempty"`
+	Connect   latencySummary  `json:"connect"`
+	TLS       *latencySummary `json:"tls,omitempty"`
+	FirstByte *latencySummary `json:"first_byte,omitempty"`
+}
+
+// latencySummary is the distribution of a phase's duration in milliseconds
+type latencySummary struct {
+	Min float64 `json:"min_ms"`
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// newProbeCommand creates the probe subcommand
+func newProbeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "probe",
+		Short: "Test TCP or TLS connections to the ranges of a functional area",
+		Long: `Connect to the canonical hostnames of a functional area, such as github.com for
+git, and to addresses sampled from its published IPv4 ranges on the given port,
+e.g. to validate that a new firewall rule opened the right paths. With --tls, a
+TLS handshake is performed after connecting. Certificates are verified for
+hostnames only, since sampled addresses have no name to verify them against.
+
+Each probe is timed: the DNS lookup of hostnames, the TCP connect, the TLS
+handshake and, with --first-byte, the time until the server's first byte. With
+--tls, an HTTP HEAD request is sent for the first byte; otherwise the server is
+expected to speak first, as SSH servers do. With --attempts, each endpoint is
+probed repeatedly and the timings of successful attempts are summarized as
+percentiles.
+
+Exits with status 2 if any attempt fails.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runProbe,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("area", "a", "", "Functional area to probe, e.g. git")
+	cmd.Flags().Int("port", 443, "Port to connect to")
+	cmd.Flags().Bool("tls", false, "Perform a TLS handshake after connecting")
+	cmd.Flags().Bool("first-byte", false, "Measure the time until the server's first byte")
+	cmd.Flags().Int("attempts", 1, "Number of times to probe each endpoint")
+	cmd.Flags().Int("samples", 3, "Number of addresses to sample from the area's ranges (0 for hostnames only)")
+	cmd.Flags().Duration("timeout", 5*time.Second, "Timeout for each connection")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+	cmd.MarkFlagRequired("area")
+
+	return cmd
+}
+
+func runProbe(cmd *cobra.Command, args []string) error {
+	areaName, _ := cmd.Flags().GetString("area")
+	port, _ := cmd.Flags().GetInt("port")
+	samples, _ := cmd.Flags().GetInt("samples")
+	var opts probeOptions
+	opts.TLS, _ = cmd.Flags().GetBool("tls")
+	opts.FirstByte, _ = cmd.Flags().GetBool("first-byte")
+	opts.Attempts, _ = cmd.Flags().GetInt("attempts")
+	opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+	if port < 1 || port > 65535 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("invalid port %d", port))
+	}
+	if samples < 0 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--samples must not be negative"))
+	}
+	if opts.Attempts < 1 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--attempts must be at least 1"))
+	}
+
+	meta, err := NewIPChecker().Meta()
+	if err != nil {
+		return err
+	}
+	areas, err := selectAreas(meta.Areas(), []string{areaName})
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+	area := areas[0]
+
+	var endpoints []probeResult
+	for _, host := range areaHostnames(area.Key) {
+		endpoints = append(endpoints, probeResult{Endpoint: net.JoinHostPort(host, strconv.Itoa(port)), Source: "hostname"})
+	}
+	for _, addr := range sampleAddresses(area.Ranges, samples) {
+		endpoints = append(endpoints, probeResult{Endpoint: net.JoinHostPort(addr, strconv.Itoa(port)), Source: "sample"})
+	}
+	if len(endpoints) == 0 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("%s has no hostnames or IPv4 ranges to probe", area.Name))
+	}
+
+	results := probeEndpoints(endpoints, opts)
+
+	switch output {
+	case outputJSON:
+		out, err := marshalExportJSON(results)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(out)
+	default:
+		writeProbe(os.Stdout, results, opts)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.ok() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return withCategory(errorCategoryNetwork, fmt.Errorf("%d of %d %s endpoints on port %d failed", failed, len(results), area.Name, port))
+	}
+	return nil
+}
+
+// areaHostnames returns the canonical hostnames of an area, taken from the
+// doctor targets
+func areaHostnames(key string) []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, target := range doctorTargets {
+		if target.Area == key && !seen[target.Host] {
+			seen[target.Host] = true
+			hosts = append(hosts, target.Host)
+		}
+	}
+	return hosts
+}
+
+// sampleAddresses picks up to n IPv4 addresses from ranges, one per range spread
+// evenly across them. The first host address of each range is used, or the network
+// address itself for /31 and /32 ranges.
+func sampleAddresses(ranges []string, n int) []string {
+	v4 := ipv4Ranges(ranges)
+	if n > len(v4) {
+		n = len(v4)
+	}
+
+	var addrs []string
+	for i := 0; i < n; i++ {
+		prefix, err := netip.ParsePrefix(v4[i*len(v4)/n])
+		if err != nil {
+			continue
+		}
+		addr := prefix.Masked().Addr()
+		if prefix.Bits() < 31 {
+			addr = addr.Next()
+		}
+		addrs = append(addrs, addr.String())
+	}
+	return addrs
+}
+
+// probeEndpoints probes the endpoints in parallel, returning the results in
+// endpoint order. The attempts of each endpoint are made one after another.
+func probeEndpoints(endpoints []probeResult, opts probeOptions) []probeResult {
+	results := make([]probeResult, len(endpoints))
+	var wg sync.WaitGroup
+	for i, r := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeEndpoint(r, opts)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// probeEndpoint probes r.Endpoint opts.Attempts times and summarizes the timings
+// of the successful attempts
+func probeEndpoint(r probeResult, opts probeOptions) probeResult {
+	var timings []probeTiming
+	for i := 0; i < opts.Attempts; i++ {
+		r.Attempts++
+		timing, err := probeAttempt(r, opts)
+		if err != nil {
+			r.Failed++
+			if r.Error == "" {
+				r.Error = err.Error()
+			}
+			continue
+		}
+		timings = append(timings, timing)
+	}
+	if len(timings) == 0 {
+		return r
+	}
+
+	phase := func(d func(probeTiming) time.Duration) latencySummary {
+		durations := make([]time.Duration, len(timings))
+		for i, t := range timings {
+			durations[i] = d(t)
+		}
+		return summarizeLatency(durations)
+	}
+	r.Latency = &probeLatency{Connect: phase(func(t probeTiming) time.Duration { return t.Connect })}
+	if r.Source == "hostname" {
+		dns := phase(func(t probeTiming) time.Duration { return t.DNS })
+		r.Latency.DNS = &dns
+	}
+	if opts.TLS {
+		handshake := phase(func(t probeTiming) time.Duration { return t.TLS })
+		r.Latency.TLS = &handshake
+	}
+	if opts.FirstByte {
+		firstByte := phase(func(t probeTiming) time.Duration { return t.FirstByte })
+		r.Latency.FirstByte = &firstByte
+	}
+	return r
+}
+
+// probeAttempt resolves r.Endpoint if it is a hostname, connects to it and
+// optionally performs a TLS handshake and waits for the first byte, timing each
+// phase
+func probeAttempt(r probeResult, opts probeOptions) (probeTiming, error) {
+	var timing probeTiming
+	host, port, _ := net.SplitHostPort(r.Endpoint)
+	address := r.Endpoint
+
+	if r.Source == "hostname" {
+		start := time.Now()
+		ips, err := lookupIP(host)
+		if err != nil {
+			return timing, fmt.Errorf("lookup failed: %w", err)
+		}
+		if len(ips) == 0 {
+			return timing, fmt.Errorf("lookup failed: no addresses for %s", host)
+		}
+		timing.DNS = time.Since(start)
+
+		ip := ips[0]
+		for _, candidate := range ips {
+			if candidate.Is4() {
+				ip = candidate
+				break
+			}
+		}
+		address = net.JoinHostPort(ip.String(), port)
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, opts.Timeout)
+	if err != nil {
+		return timing, fmt.Errorf("connect failed: %w", err)
+	}
+	defer conn.Close()
+	timing.Connect = time.Since(start)
+	conn.SetDeadline(time.Now().Add(opts.Timeout))
+
+	if opts.TLS {
+		start = time.Now()
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: r.Source == "sample",
+		})
+		if err := tlsConn.Handshake(); err != nil {
+			return timing, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		timing.TLS = time.Since(start)
+		conn = tlsConn
+	}
+
+	if opts.FirstByte {
+		start = time.Now()
+		if opts.TLS {
+			if _, err := fmt.Fprintf(conn, "HEAD / HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n\r\n", host); err != nil {
+				return timing, fmt.Errorf("first byte failed: %w", err)
+			}
+		}
+		if _, err := conn.Read(make([]byte, 1)); err != nil {
+			return timing, fmt.Errorf("first byte failed: %w", err)
+		}
+		timing.FirstByte = time.Since(start)
+	}
+	return timing, nil
+}
+
+// summarizeLatency returns the distribution of durations, using nearest-rank
+// percentiles
+func summarizeLatency(durations []time.Duration) latencySummary {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		return milliseconds(sorted[max(rank, 1)-1])
+	}
+	return latencySummary{
+		Min: milliseconds(sorted[0]),
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// milliseconds converts d to milliseconds, rounded to microseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+}
+
+// writeProbe writes the probe results as a table, with the median and 90th
+// percentile of each phase when endpoints were probed more than once
+func writeProbe(w io.Writer, results []probeResult, opts probeOptions) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "Endpoint\tSource\tResult\tDNS\tConnect"
+	if opts.TLS {
+		header += "\tTLS"
+	}
+	if opts.FirstByte {
+		header += "\tFirst byte"
+	}
+	fmt.Fprintln(tw, header)
+
+	for _, r := range results {
+		result := "connected"
+		switch {
+		case r.Failed > 0 && r.Attempts > 1:
+			result = fmt.Sprintf("%d of %d failed, %s", r.Failed, r.Attempts, r.Error)
+		case r.Failed > 0:
+			result = r.Error
+		case opts.TLS:
+			result = "TLS handshake ok"
+		}
+
+		line := r.Endpoint + "\t" + r.Source + "\t" + result
+		if r.Latency != nil {
+			line += "\t" + formatLatency(r.Latency.DNS, opts.Attempts) + "\t" + formatLatency(&r.Latency.Connect, opts.Attempts)
+			if opts.TLS {
+				line += "\t" + formatLatency(r.Latency.TLS, opts.Attempts)
+			}
+			if opts.FirstByte {
+				line += "\t" + formatLatency(r.Latency.FirstByte, opts.Attempts)
+			}
+		}
+		fmt.Fprintln(tw, line)
+	}
+	tw.Flush()
+}
+
+// formatLatency formats a phase's duration, or its median and 90th percentile
+// over several attempts
+func formatLatency(s *latencySummary, attempts int) string {
+	if s == nil {
+		return "-"
+	}
+	if attempts == 1 {
+		return fmt.Sprintf("%.1fms", s.P50)
+	}
+	return fmt.Sprintf("%.1f/%.1fms", s.P50, s.P90)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSampleAddresses(t *testing.T) {
+	ranges := []string{"192.30.252.0/22", "2606:50c0::/32", "185.199.108.0/22", "140.82.112.0/20", "20.201.28.151/32"}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{0, nil},
+		{1, []string{"192.30.252.1"}},
+		{2, []string{"192.30.252.1", "140.82.112.1"}},
+		{10, []string{"192.30.252.1", "185.199.108.1", "140.82.112.1", "20.201.28.151"}},
+	}
+	for _, tt := range tests {
+		if got := sampleAddresses(ranges, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sampleAddresses(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAreaHostnames(t *testing.T) {
+	want := []string{"github.com", "ssh.github.com"}
+	if got := areaHostnames("git"); !reflect.DeepEqual(got, want) {
+		t.Errorf("areaHostnames(git) = %v, want %v", got, want)
+	}
+	if got := areaHostnames("hooks"); got != nil {
+		t.Errorf("areaHostnames(hooks) = %v, want none", got)
+	}
+}
+
+// listenTCP starts a server accepting connections on 127.0.0.1, writing greeting
+// to each before closing it
+func listenTCP(t *testing.T, greeting string) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(greeting))
+			conn.Close()
+		}
+	}()
+	return listener
+}
+
+func TestProbeEndpoints(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	plain := listenTCP(t, "")
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	tlsAddr := tlsServer.Listener.Addr().String()
+	opts := probeOptions{TLS: true, FirstByte: true, Attempts: 3, Timeout: time.Second}
+	results := probeEndpoints([]probeResult{
+		{Endpoint: tlsAddr, Source: "sample"},
+		{Endpoint: plain.Addr().String(), Source: "sample"},
+		{Endpoint: closed.Addr().String(), Source: "sample"},
+		{Endpoint: strings.Replace(tlsAddr, "127.0.0.1", "localhost", 1), Source: "hostname"},
+	}, opts)
+
+	wantErr := []string{"", "TLS handshake failed: ", "connect failed: ", "TLS handshake failed: "}
+	for i, r := range results {
+		if r.Attempts != 3 || r.ok() != (wantErr[i] == "") || !strings.HasPrefix(r.Error, wantErr[i]) {
+			t.Errorf("probe of %s = %+v, want error %q", r.Endpoint, r, wantErr[i])
+		}
+	}
+	if l := results[0].Latency; l == nil || l.TLS == nil || l.FirstByte == nil || l.DNS != nil {
+		t.Errorf("probe of %s latency = %+v, want connect, TLS and first byte timings", results[0].Endpoint, l)
+	}
+	if results[2].Latency != nil {
+		t.Errorf("failed probe of %s has latency %+v", results[2].Endpoint, results[2].Latency)
+	}
+
+	var buf bytes.Buffer
+	writeProbe(&buf, results, opts)
+	for _, want := range []string{"TLS handshake ok", "3 of 3 failed, TLS handshake failed: ", "3 of 3 failed, connect failed: ", "First byte"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeProbe() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestProbeFirstByte(t *testing.T) {
+	banner := listenTCP(t, "SSH-2.0-babeld\r\n")
+	silent := listenTCP(t, "")
+
+	opts := probeOptions{FirstByte: true, Attempts: 1, Timeout: time.Second}
+	if _, err := probeAttempt(probeResult{Endpoint: banner.Addr().String(), Source: "sample"}, opts); err != nil {
+		t.Errorf("probeAttempt() of a server sending a banner error = %v", err)
+	}
+	if _, err := probeAttempt(probeResult{Endpoint: silent.Addr().String(), Source: "sample"}, opts); err == nil || !strings.HasPrefix(err.Error(), "first byte failed: ") {
+		t.Errorf("probeAttempt() of a silent server error = %v, want first byte failure", err)
+	}
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	var durations []time.Duration
+	for i := 10; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	want := latencySummary{Min: 1, P50: 5, P90: 9, P99: 10, Max: 10}
+	if got := summarizeLatency(durations); got != want {
+		t.Errorf("summarizeLatency() = %+v, want %+v", got, want)
+	}
+	if durations[0] != 10*time.Millisecond {
+		t.Error("summarizeLatency() modified its argument")
+	}
+
+	want = latencySummary{Min: 1.5, P50: 1.5, P90: 1.5, P99: 1.5, Max: 1.5}
+	if got := summarizeLatency([]time.Duration{1500 * time.Microsecond}); got != want {
+		t.Errorf("summarizeLatency() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRunProbe(t *testing.T) {
+	listener := listenTCP(t, "")
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"git": ["127.0.0.1/32"], "hooks": ["127.0.0.2/32"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	oldTargets := doctorTargets
+	doctorTargets = []doctorTarget{{"localhost", 0, "git"}}
+	defer func() { doctorTargets = oldTargets }()
+
+	tests := []struct {
+		name         string
+		area         string
+		port         string
+		useTLS       bool
+		attempts     int
+		wantCategory string
+	}{
+		{name: "Open port", area: "git", port: port, attempts: 1},
+		{name: "Repeated attempts", area: "git", port: port, attempts: 5},
+		{name: "TLS handshake fails", area: "git", port: port, useTLS: true, attempts: 1, wantCategory: errorCategoryNetwork},
+		{name: "Unknown area", area: "nope", port: port, attempts: 1, wantCategory: errorCategoryUsage},
+		{name: "Invalid port", area: "git", port: "0", attempts: 1, wantCategory: errorCategoryUsage},
+		{name: "No attempts", area: "git", port: port, wantCategory: errorCategoryUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := strconv.Atoi(tt.port)
+			cmd := &cobra.Command{}
+			cmd.Flags().String("area", tt.area, "")
+			cmd.Flags().Int("port", p, "")
+			cmd.Flags().Bool("tls", tt.useTLS, "")
+			cmd.Flags().Bool("first-byte", false, "")
+			cmd.Flags().Int("attempts", tt.attempts, "")
+			cmd.Flags().Int("samples", 3, "")
+			cmd.Flags().Duration("timeout", time.Second, "")
+			cmd.Flags().StringP("output", "o", outputJSON, "")
+
+			err := runProbe(cmd, nil)
+			if (err != nil) != (tt.wantCategory != "") {
+				t.Fatalf("runProbe() error = %v, want category %q", err, tt.wantCategory)
+			}
+			if err != nil && errorCategory(err) != tt.wantCategory {
+				t.Errorf("runProbe() error category = %q, want %q", errorCategory(err), tt.wantCategory)
+			}
+		})
+	}
+}
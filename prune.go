@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// retentionPolicy decides which snapshots of a history database are kept: the
+// Keep latest ones, and the latest one of each of the KeepDaily latest days.
+// A zero policy keeps every snapshot.
+type retentionPolicy struct {
+	Keep      int
+	KeepDaily int
+}
+
+// enabled reports whether the policy prunes any snapshots
+func (p retentionPolicy) enabled() bool {
+	return p.Keep > 0 || p.KeepDaily > 0
+}
+
+// validate checks the policy's counts
+func (p retentionPolicy) validate() error {
+	if p.Keep < 0 || p.KeepDaily < 0 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("snapshot retention counts can't be negative"))
+	}
+	return nil
+}
+
+// pruneCondition returns the SQL condition selecting the snapshots the policy
+// doesn't keep, as of now. Days are UTC days, as are the stored snapshot times.
+// The latest snapshot is always kept, even if GitHub hasn't changed the ranges
+// for longer than KeepDaily days.
+func (p retentionPolicy) pruneCondition(now time.Time) string {
+	cond := fmt.Sprintf("snapshot_time NOT IN (SELECT snapshot_time FROM snapshots ORDER BY snapshot_time DESC LIMIT %d)", max(p.Keep, 1))
+	if p.KeepDaily > 0 {
+		since := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-p.KeepDaily)
+		cond += fmt.Sprintf(`
+  AND snapshot_time NOT IN (SELECT MAX(snapshot_time) FROM snapshots WHERE snapshot_time >= %s GROUP BY substr(snapshot_time, 1, 10))`,
+			sqlQuote(since.Format(time.RFC3339)))
+	}
+	return cond
+}
+
+// pruneStatements returns the SQL statements deleting the snapshots the policy
+// doesn't keep, and their ranges
+func (p retentionPolicy) pruneStatements(now time.Time) string {
+	cond := p.pruneCondition(now)
+	return fmt.Sprintf("DELETE FROM ranges WHERE snapshot_time IN (SELECT snapshot_time FROM snapshots WHERE %s);\n"+
+		"DELETE FROM snapshots WHERE %s;\n", cond, cond)
+}
+
+// prunedSnapshot is a snapshot selected for pruning as returned by sqlite3 -json
+type prunedSnapshot struct {
+	SnapshotTime string `json:"snapshot_time"`
+	SourceHash   string `json:"source_hash"`
+}
+
+// pruneHistory deletes the snapshots the policy doesn't keep from the history
+// database at path and returns them, or only returns them if dryRun is set
+func pruneHistory(path string, policy retentionPolicy, now time.Time, dryRun bool) ([]prunedSnapshot, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to open history database: %w", err))
+	}
+
+	script := fmt.Sprintf("SELECT snapshot_time, source_hash FROM snapshots WHERE %s ORDER BY snapshot_time;\n", policy.pruneCondition(now))
+	args := []string{"-readonly", "-json", path}
+	if !dryRun {
+		// Reclaim the space of the deleted rows, so the file shrinks too
+		script += "BEGIN;\n" + policy.pruneStatements(now) + "COMMIT;\nVACUUM;\n"
+		args = []string{"-bail", "-json", path}
+	}
+	out, err := runSQLite([]byte(script), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []prunedSnapshot
+	if len(strings.TrimSpace(string(out))) > 0 {
+		if err := json.Unmarshal(out, &pruned); err != nil {
+			return nil, fmt.Errorf("failed to decode pruned snapshots: %w", err)
+		}
+	}
+	return pruned, nil
+}
+
+// newPruneCommand creates the prune subcommand
+func newPruneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old snapshots from a SQLite history database",
+		Long: `Delete the snapshots of a SQLite history database written by 'export --format sqlite'
+that the retention policy doesn't keep: the --keep latest snapshots, and the latest
+snapshot of each of the --keep-daily latest days (UTC). The latest snapshot is always
+kept.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runPrune,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().String("history", "", "SQLite snapshot history database written by 'export --format sqlite'")
+	cmd.Flags().Int("keep", 0, "Keep this many of the latest snapshots")
+	cmd.Flags().Int("keep-daily", 0, "Keep the latest snapshot of each of this many latest days, e.g. 90")
+	cmd.Flags().Bool("dry-run", false, "List the snapshots that would be deleted without deleting them")
+
+	return cmd
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	history, _ := cmd.Flags().GetString("history")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	var policy retentionPolicy
+	policy.Keep, _ = cmd.Flags().GetInt("keep")
+	policy.KeepDaily, _ = cmd.Flags().GetInt("keep-daily")
+
+	if history == "" {
+		return withCategory(errorCategoryUsage, fmt.Errorf(`required flag(s) "history" not set`))
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	if !policy.enabled() {
+		return withCategory(errorCategoryUsage, fmt.Errorf("prune requires --keep or --keep-daily"))
+	}
+
+	pruned, err := pruneHistory(history, policy, time.Now(), dryRun)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(pruned) == 0:
+		fmt.Printf("No snapshots to prune in %s\n", history)
+		return nil
+	case dryRun:
+		fmt.Printf("Would prune %d snapshots from %s:\n", len(pruned), history)
+	default:
+		fmt.Printf("Pruned %d snapshots from %s:\n", len(pruned), history)
+	}
+	for _, s := range pruned {
+		fmt.Printf("  %s  %s\n", s.SnapshotTime, s.SourceHash)
+	}
+	return nil
+}
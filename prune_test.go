@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+
+	cond := retentionPolicy{Keep: 5}.pruneCondition(now)
+	if !strings.Contains(cond, "DESC LIMIT 5)") || strings.Contains(cond, "GROUP BY") {
+		t.Errorf("pruneCondition() of --keep 5 = %q", cond)
+	}
+	// The latest snapshot is kept even by --keep-daily alone
+	cond = retentionPolicy{KeepDaily: 3}.pruneCondition(now)
+	if !strings.Contains(cond, "DESC LIMIT 1)") || !strings.Contains(cond, "snapshot_time >= '2026-07-08T00:00:00Z' GROUP BY substr(snapshot_time, 1, 10)") {
+		t.Errorf("pruneCondition() of --keep-daily 3 = %q", cond)
+	}
+
+	statements := retentionPolicy{Keep: 5}.pruneStatements(now)
+	if ranges, snapshots := strings.Index(statements, "DELETE FROM ranges "), strings.Index(statements, "DELETE FROM snapshots "); ranges < 0 || snapshots < ranges {
+		t.Errorf("pruneStatements() = %q, want the ranges deleted before their snapshots", statements)
+	}
+
+	if (retentionPolicy{}).enabled() {
+		t.Errorf("enabled() of a zero policy = true")
+	}
+	if err := (retentionPolicy{Keep: -1}).validate(); errorCategory(err) != errorCategoryUsage {
+		t.Errorf("validate() of a negative count error = %v, want a usage error", err)
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	dir := fakeSQLite(t, `[{"snapshot_time":"2026-06-01T00:00:00Z","source_hash":"abc123"}]`)
+	path := filepath.Join(dir, "ranges.db")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+
+	pruned, err := pruneHistory(path, retentionPolicy{Keep: 2}, now, true)
+	if err != nil {
+		t.Fatalf("pruneHistory() error = %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].SnapshotTime != "2026-06-01T00:00:00Z" || pruned[0].SourceHash != "abc123" {
+		t.Errorf("pruneHistory() = %+v", pruned)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin"))
+	if !strings.HasPrefix(string(args), "-readonly -json ") || strings.Contains(string(stdin), "DELETE") {
+		t.Errorf("pruneHistory() with dryRun ran sqlite3 %q with %q", args, stdin)
+	}
+
+	if _, err := pruneHistory(path, retentionPolicy{Keep: 2}, now, false); err != nil {
+		t.Fatalf("pruneHistory() error = %v", err)
+	}
+	args, _ = os.ReadFile(filepath.Join(dir, "args"))
+	stdin, _ = os.ReadFile(filepath.Join(dir, "stdin"))
+	if !strings.HasPrefix(string(args), "-bail -json ") || !strings.Contains(string(stdin), "DELETE FROM snapshots WHERE ") || !strings.HasSuffix(string(stdin), "COMMIT;\nVACUUM;\n") {
+		t.Errorf("pruneHistory() ran sqlite3 %q with %q", args, stdin)
+	}
+
+	if _, err := pruneHistory(filepath.Join(dir, "missing.db"), retentionPolicy{Keep: 2}, now, false); errorCategory(err) != errorCategoryInput {
+		t.Errorf("pruneHistory() of a missing database error = %v, want an input error", err)
+	}
+}
+
+func TestPruneCommand(t *testing.T) {
+	dir := fakeSQLite(t, "")
+	path := filepath.Join(dir, "ranges.db")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "Keep", args: []string{"--history", path, "--keep", "30", "--keep-daily", "90"}},
+		{name: "No history", args: []string{"--keep", "30"}, wantErr: true},
+		{name: "No policy", args: []string{"--history", path}, wantErr: true},
+		{name: "Negative count", args: []string{"--history", path, "--keep", "-1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newPruneCommand()
+			cmd.SetArgs(tt.args)
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("prune error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && errorCategory(err) != errorCategoryUsage {
+				t.Errorf("prune error category = %s, want %s", errorCategory(err), errorCategoryUsage)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errRateLimited is returned instead of answering a client that made more
+// requests than --rate-limit allows
+var errRateLimited = errors.New("too many requests")
+
+// rateLimiter is a token bucket per client: each holds up to burst tokens,
+// refilled at rate per second, and a request takes one. Its methods allow
+// every request on a nil limiter.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // Tokens added to a bucket per second
+	burst   int
+	tokens  map[string]bool         // SHA-256 of the --rate-limit-tokens clients are told apart by
+	buckets map[string]*tokenBucket // By client, as returned by key
+	swept   time.Time               // When full buckets were last dropped
+	allowed uint64
+	limited uint64
+}
+
+// tokenBucket is the bucket of a client, as of when its tokens were updated
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimitJSON is the state of the rate limiter reported by /healthz
+type rateLimitJSON struct {
+	Rate    float64 `json:"rate"`
+	Burst   int     `json:"burst"`
+	Clients int     `json:"clients"`
+	Allowed uint64  `json:"allowed"`
+	Limited uint64  `json:"limited"`
+}
+
+// newRateLimiter returns a limiter of rate requests per second per client,
+// with bursts of up to burst requests, or nil if rate is 0. Clients sending
+// one of tokens as a bearer token get a bucket per token.
+func newRateLimiter(rate float64, burst int, tokens []string) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	l := &rateLimiter{rate: rate, burst: burst, tokens: make(map[string]bool), buckets: make(map[string]*tokenBucket)}
+	for _, token := range tokens {
+		l.tokens[hashToken(token)] = true
+	}
+	return l
+}
+
+// readRateLimitTokens reads the --rate-limit-tokens file, a token per line.
+// Blank lines and lines starting with # are skipped.
+func readRateLimitTokens(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to read --rate-limit-tokens: %w", err))
+	}
+	var tokens []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			tokens = append(tokens, line)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, withCategory(errorCategoryInput, fmt.Errorf("no tokens in --rate-limit-tokens file %s", path))
+	}
+	return tokens, nil
+}
+
+// allow takes a token from the bucket of the client making r as of now, or
+// returns how long until the bucket has one
+func (l *rateLimiter) allow(r *http.Request, now time.Time) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	key := l.key(r)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.burst), updated: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = min(float64(l.burst), b.tokens+elapsed.Seconds()*l.rate)
+		b.updated = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		l.allowed++
+		return 0, true
+	}
+	l.limited++
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+}
+
+// sweep drops the buckets that have refilled, which are as good as new, so
+// clients that went away don't keep their buckets. It runs at most once per
+// refill time, and at least a minute apart.
+func (l *rateLimiter) sweep(now time.Time) {
+	refill := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+	if now.Sub(l.swept) < max(refill, time.Minute) {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// stats returns the state of the limiter, or nil if there is no limiter
+func (l *rateLimiter) stats() *rateLimitJSON {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return &rateLimitJSON{Rate: l.rate, Burst: l.burst, Clients: len(l.buckets), Allowed: l.allowed, Limited: l.limited}
+}
+
+// key returns the client a request is counted against: its bearer token if
+// it is one of the --rate-limit-tokens, so clients behind the same NAT or
+// proxy are told apart, and otherwise its address. Any other token is ignored,
+// so a client can't get a fresh bucket by making one up.
+func (l *rateLimiter) key(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+		if hash := hashToken(token); l.tokens[hash] {
+			return "token:" + hash
+		}
+	}
+	return "ip:" + serveCaller(r)
+}
+
+// hashToken returns the hex SHA-256 of a token, so the limiter's keys have the
+// same length however long the tokens clients send are
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
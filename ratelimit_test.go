@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	l := newRateLimiter(2, 3, nil)
+	client := func(addr string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/v1/check", nil)
+		r.RemoteAddr = addr + ":1234"
+		return r
+	}
+	a, b, c := client("192.0.2.1"), client("192.0.2.2"), client("192.0.2.3")
+
+	// A burst, then a token every half a second
+	for i := range 3 {
+		if _, ok := l.allow(a, now); !ok {
+			t.Fatalf("allow() #%d of a burst = false", i+1)
+		}
+	}
+	if wait, ok := l.allow(a, now); ok || wait != 500*time.Millisecond {
+		t.Errorf("allow() beyond the burst = %s, %v, want 500ms, false", wait, ok)
+	}
+	if _, ok := l.allow(b, now); !ok {
+		t.Error("allow() of another client = false, want its own bucket")
+	}
+	if _, ok := l.allow(a, now.Add(500*time.Millisecond)); !ok {
+		t.Error("allow() after a refill = false")
+	}
+	if stats := l.stats(); stats.Clients != 2 || stats.Allowed != 5 || stats.Limited != 1 {
+		t.Errorf("stats() = %+v, want 2 clients, 5 allowed and 1 limited", stats)
+	}
+
+	// Refilled buckets are dropped
+	l.allow(c, now.Add(2*time.Minute))
+	if stats := l.stats(); stats.Clients != 1 {
+		t.Errorf("stats() after a sweep = %+v, want 1 client", stats)
+	}
+
+	var none *rateLimiter
+	if _, ok := none.allow(a, now); !ok || none.stats() != nil {
+		t.Error("nil limiter limits requests")
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	s := newTestServer(t, true, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	s.limiter = newRateLimiter(1, 2, []string{"s3cret"})
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	get := func(path, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, path := range []string{"/v1/check?ip=192.30.252.1", "/meta"} {
+		if resp := get(path, ""); resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+	resp := get("/v1/check?ip=192.30.252.1", "")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("GET /v1/check beyond the burst status = %d, Retry-After %q, want 429 after 1", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if resp := get("/v1/check?ip=192.30.252.1", "s3cret"); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /v1/check with a listed token status = %d, want 200 from its own bucket", resp.StatusCode)
+	}
+	// Made-up tokens count against the address
+	for _, token := range []string{"rnd1", "rnd2"} {
+		if resp := get("/v1/check?ip=192.30.252.1", token); resp.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("GET /v1/check with unlisted token %q status = %d, want 429", token, resp.StatusCode)
+		}
+	}
+
+	// Health checks aren't limited, and report the limiter
+	hr, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hr.Body.Close()
+	var health healthJSON
+	json.NewDecoder(hr.Body).Decode(&health)
+	if hr.StatusCode != http.StatusOK || health.RateLimit == nil || health.RateLimit.Clients != 2 || health.RateLimit.Limited != 3 {
+		t.Errorf("GET /healthz = %d, %+v, want the rate limit", hr.StatusCode, health.RateLimit)
+	}
+}
+
+func TestReadRateLimitTokens(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokens")
+	os.WriteFile(path, []byte("# dashboards\ns3cret\n\n  other  \n"), 0o600)
+	tokens, err := readRateLimitTokens(path)
+	if err != nil || !slices.Equal(tokens, []string{"s3cret", "other"}) {
+		t.Errorf("readRateLimitTokens() = %q, %v", tokens, err)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	os.WriteFile(empty, []byte("# none yet\n"), 0o600)
+	for _, path := range []string{empty, filepath.Join(dir, "missing")} {
+		if _, err := readRateLimitTokens(path); err == nil || errorCategory(err) != errorCategoryInput {
+			t.Errorf("readRateLimitTokens(%s) error = %v, want an input error", path, err)
+		}
+	}
+}
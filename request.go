@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// addRequestFlags adds the flags configuring how the meta document is fetched,
+// shared by all commands
+func addRequestFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("meta-url", githubMetaURL, "URL of the meta document, e.g. of a GHES instance or a mirror run with serve --mirror")
+	cmd.PersistentFlags().String("user-agent", metaUserAgent, "User-Agent header sent with meta requests")
+	cmd.PersistentFlags().String("api-version", metaAPIVersion, "X-GitHub-Api-Version header sent with meta requests (empty to omit it)")
+	cmd.PersistentFlags().String("client-cert", "", "PEM client certificate presented when fetching the meta document, for mutual-TLS gateways")
+	cmd.PersistentFlags().String("client-key", "", "PEM private key of --client-cert (default --client-cert, for files holding both)")
+	cmd.PersistentFlags().String("ca-bundle", "", "PEM file of additional root certificates trusted when fetching the meta document, e.g. of an inspection proxy")
+	cmd.PersistentFlags().StringSlice("pin-sha256", nil, "Base64 SHA-256 of a public key (SPKI) the meta server's certificate chain must contain")
+}
+
+// applyRequestFlags configures meta requests from the flags
+func applyRequestFlags(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("meta-url") {
+		metaURL, _ := cmd.Flags().GetString("meta-url")
+		if u, err := url.Parse(metaURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return withCategory(errorCategoryUsage, fmt.Errorf("invalid --meta-url %q: must be an http(s) URL", metaURL))
+		}
+		githubMetaURL = metaURL
+	}
+	if cmd.Flags().Changed("user-agent") {
+		userAgent, _ := cmd.Flags().GetString("user-agent")
+		// GitHub's API rejects requests without a User-Agent
+		if userAgent == "" {
+			return withCategory(errorCategoryUsage, fmt.Errorf("--user-agent must not be empty"))
+		}
+		metaUserAgent = userAgent
+	}
+	if cmd.Flags().Changed("api-version") {
+		metaAPIVersion, _ = cmd.Flags().GetString("api-version")
+	}
+
+	config, err := metaTLSConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		metaClient = &http.Client{Transport: transport}
+	}
+	return nil
+}
+
+// metaTLSConfig returns the TLS settings of meta requests selected by the
+// flags, or nil for the defaults
+func metaTLSConfig(cmd *cobra.Command) (*tls.Config, error) {
+	cert, _ := cmd.Flags().GetString("client-cert")
+	key, _ := cmd.Flags().GetString("client-key")
+	bundle, _ := cmd.Flags().GetString("ca-bundle")
+	pins, _ := cmd.Flags().GetStringSlice("pin-sha256")
+	if cert == "" && key == "" && bundle == "" && len(pins) == 0 {
+		return nil, nil
+	}
+	config := &tls.Config{}
+
+	if cert == "" && key != "" {
+		return nil, withCategory(errorCategoryUsage, fmt.Errorf("--client-key requires --client-cert"))
+	}
+	if cert != "" {
+		if key == "" {
+			key = cert
+		}
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to load client certificate: %w", err))
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	if bundle != "" {
+		// The bundle adds to the system roots, so only the proxy's traffic
+		// depends on it
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		data, err := os.ReadFile(bundle)
+		if err != nil {
+			return nil, withCategory(errorCategoryInput, fmt.Errorf("failed to read CA bundle: %w", err))
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, withCategory(errorCategoryInput, fmt.Errorf("no certificates found in CA bundle %s", bundle))
+		}
+		config.RootCAs = pool
+	}
+
+	if len(pins) > 0 {
+		hashes := make(map[string]bool)
+		for _, pin := range pins {
+			if hash, err := base64.StdEncoding.DecodeString(pin); err != nil || len(hash) != sha256.Size {
+				return nil, withCategory(errorCategoryUsage, fmt.Errorf("invalid --pin-sha256 %q: must be a base64 SHA-256 hash", pin))
+			}
+			hashes[pin] = true
+		}
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, hashes)
+		}
+	}
+	return config, nil
+}
+
+// verifyPins checks that the verified certificate chain of a connection holds a
+// public key whose SPKI hash is pinned
+func verifyPins(cs tls.ConnectionState, pins map[string]bool) error {
+	certs := cs.PeerCertificates
+	for _, chain := range cs.VerifiedChains {
+		certs = append(certs, chain...)
+	}
+	for _, cert := range certs {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		if pins[base64.StdEncoding.EncodeToString(hash[:])] {
+			return nil
+		}
+	}
+	return fmt.Errorf("the certificate of %s matches no --pin-sha256, the connection may be intercepted", cs.ServerName)
+}
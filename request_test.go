@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyRequestFlags(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		wantErr        bool
+		wantUserAgent  string
+		wantAPIVersion []string
+	}{
+		{name: "Defaults", wantUserAgent: "gh-check-github-ip-ranges/" + Version, wantAPIVersion: []string{"2022-11-28"}},
+		{name: "Custom", args: []string{"--user-agent", "acme-egress-audit/2.0", "--api-version", "2026-03-10"},
+			wantUserAgent: "acme-egress-audit/2.0", wantAPIVersion: []string{"2026-03-10"}},
+		{name: "No API version", args: []string{"--api-version", ""}, wantUserAgent: "gh-check-github-ip-ranges/" + Version},
+		{name: "Empty User-Agent", args: []string{"--user-agent", ""}, wantErr: true},
+		{name: "Meta URL without scheme", args: []string{"--meta-url", "mirror.internal/meta"}, wantErr: true},
+		{name: "Meta URL not HTTP", args: []string{"--meta-url", "file:///srv/meta.json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+			}))
+			defer server.Close()
+
+			oldURL, oldUserAgent, oldAPIVersion := githubMetaURL, metaUserAgent, metaAPIVersion
+			githubMetaURL = server.URL
+			defer func() { githubMetaURL, metaUserAgent, metaAPIVersion = oldURL, oldUserAgent, oldAPIVersion }()
+
+			cmd := &cobra.Command{}
+			addRequestFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := applyRequestFlags(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyRequestFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if errorCategory(err) != errorCategoryUsage {
+					t.Errorf("applyRequestFlags() error category = %s, want %s", errorCategory(err), errorCategoryUsage)
+				}
+				return
+			}
+
+			if _, err := NewIPChecker().Meta(); err != nil {
+				t.Fatalf("Meta() error = %v", err)
+			}
+			if got := header.Get("User-Agent"); got != tt.wantUserAgent {
+				t.Errorf("User-Agent = %q, want %q", got, tt.wantUserAgent)
+			}
+			if got := header.Values("X-GitHub-Api-Version"); len(got) != len(tt.wantAPIVersion) || (len(got) > 0 && got[0] != tt.wantAPIVersion[0]) {
+				t.Errorf("X-GitHub-Api-Version = %q, want %q", got, tt.wantAPIVersion)
+			}
+		})
+	}
+}
+
+// testCertificate issues a certificate for name signed by parent, or
+// self-signed if parent is nil
+func testCertificate(t *testing.T, name string, isCA bool, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	signer, signerKey := template, any(key)
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeCertificate writes the certificate and, if key is set, its private key
+// as PEM to path
+func writeCertificate(t *testing.T, path string, cert tls.Certificate, key bool) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	if key {
+		der, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})...)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetaClientCertificate(t *testing.T) {
+	ca := testCertificate(t, "Test CA", true, nil)
+	client := testCertificate(t, "egress-audit", false, &ca)
+	other := testCertificate(t, "Other CA", true, nil)
+
+	dir := t.TempDir()
+	writeCertificate(t, filepath.Join(dir, "client.pem"), client, true)
+	writeCertificate(t, filepath.Join(dir, "client.crt"), client, false)
+	writeCertificate(t, filepath.Join(dir, "other.pem"), other, true)
+	os.WriteFile(filepath.Join(dir, "client.key"), []byte("not a key"), 0600)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantErr    bool
+		wantFetch  bool
+		wantConfig bool
+	}{
+		{name: "Certificate and key in one file", args: []string{"--client-cert", filepath.Join(dir, "client.pem")}, wantFetch: true, wantConfig: true},
+		{name: "Separate key", args: []string{"--client-cert", filepath.Join(dir, "client.crt"), "--client-key", filepath.Join(dir, "client.pem")}, wantFetch: true, wantConfig: true},
+		{name: "Untrusted certificate", args: []string{"--client-cert", filepath.Join(dir, "other.pem")}, wantConfig: true},
+		{name: "No certificate"},
+		{name: "Invalid key", args: []string{"--client-cert", filepath.Join(dir, "client.crt"), "--client-key", filepath.Join(dir, "client.key")}, wantErr: true},
+		{name: "Key without certificate", args: []string{"--client-key", filepath.Join(dir, "client.pem")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldURL, oldClient := githubMetaURL, metaClient
+			githubMetaURL = server.URL
+			// The test server's certificate isn't trusted by default
+			metaClient = server.Client()
+			defer func() { githubMetaURL, metaClient = oldURL, oldClient }()
+
+			cmd := &cobra.Command{}
+			addRequestFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := applyRequestFlags(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyRequestFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if changed := metaClient != server.Client(); changed != tt.wantConfig {
+				t.Fatalf("applyRequestFlags() replaced the client = %v, want %v", changed, tt.wantConfig)
+			}
+			if tt.wantConfig {
+				metaClient.Transport.(*http.Transport).TLSClientConfig.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+			}
+
+			_, err = NewIPChecker().Meta()
+			if (err == nil) != tt.wantFetch {
+				t.Errorf("Meta() error = %v, want success %v", err, tt.wantFetch)
+			}
+		})
+	}
+}
+
+func TestMetaCABundleAndPins(t *testing.T) {
+	ca := testCertificate(t, "Inspection proxy CA", true, nil)
+	serverCert := testCertificate(t, "api.github.com", false, &ca)
+
+	dir := t.TempDir()
+	writeCertificate(t, filepath.Join(dir, "ca.pem"), ca, false)
+	os.WriteFile(filepath.Join(dir, "empty.pem"), []byte("no certificates here"), 0600)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	pin := func(cert tls.Certificate) string {
+		hash := sha256.Sum256(cert.Leaf.RawSubjectPublicKeyInfo)
+		return base64.StdEncoding.EncodeToString(hash[:])
+	}
+	otherPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+	bundle := filepath.Join(dir, "ca.pem")
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantErr   string
+		wantFetch bool
+	}{
+		{name: "Untrusted proxy"},
+		{name: "CA bundle", args: []string{"--ca-bundle", bundle}, wantFetch: true},
+		{name: "Leaf pin", args: []string{"--ca-bundle", bundle, "--pin-sha256", pin(serverCert)}, wantFetch: true},
+		{name: "CA pin", args: []string{"--ca-bundle", bundle, "--pin-sha256", otherPin + "," + pin(ca)}, wantFetch: true},
+		{name: "Pin mismatch", args: []string{"--ca-bundle", bundle, "--pin-sha256", otherPin}},
+		{name: "Invalid pin", args: []string{"--pin-sha256", "c2hvcnQ="}, wantErr: errorCategoryUsage},
+		{name: "Empty bundle", args: []string{"--ca-bundle", filepath.Join(dir, "empty.pem")}, wantErr: errorCategoryInput},
+		{name: "Missing bundle", args: []string{"--ca-bundle", filepath.Join(dir, "missing.pem")}, wantErr: errorCategoryInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldURL, oldClient := githubMetaURL, metaClient
+			githubMetaURL = server.URL
+			defer func() { githubMetaURL, metaClient = oldURL, oldClient }()
+
+			cmd := &cobra.Command{}
+			addRequestFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := applyRequestFlags(cmd)
+			if tt.wantErr != "" {
+				if err == nil || errorCategory(err) != tt.wantErr {
+					t.Fatalf("applyRequestFlags() error = %v, want category %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyRequestFlags() error = %v", err)
+			}
+
+			_, err = NewIPChecker().Meta()
+			if (err == nil) != tt.wantFetch {
+				t.Errorf("Meta() error = %v, want success %v", err, tt.wantFetch)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+)
+
+// resultCache is an LRU of the checks of one snapshot, so a flood of checks of
+// the same few addresses isn't looked up and encoded again each time. Its
+// methods do nothing on a nil cache.
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	version string                   // Source hash of the snapshot the cached results were checked against
+	order   *list.List               // Of *resultCacheItem, most recently used first
+	items   map[string]*list.Element // By address as given
+	hits    uint64
+	misses  uint64
+}
+
+// resultCacheItem is a cached check of an address
+type resultCacheItem struct {
+	ip    string
+	check cachedCheck
+}
+
+// cachedCheck is the result of a check and its encoded response
+type cachedCheck struct {
+	out    []byte
+	result *CheckResult
+}
+
+// resultCacheJSON is the state of the result cache reported by /healthz
+type resultCacheJSON struct {
+	Size    int    `json:"size"`
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// newResultCache returns a cache of up to size checks, or nil if size is 0
+func newResultCache(size int) *resultCache {
+	if size <= 0 {
+		return nil
+	}
+	return &resultCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the cached check of ip against snapshot version
+func (c *resultCache) get(version, ip string) (cachedCheck, bool) {
+	if c == nil {
+		return cachedCheck{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setVersion(version)
+	e, ok := c.items[ip]
+	if !ok {
+		c.misses++
+		return cachedCheck{}, false
+	}
+	c.hits++
+	c.order.MoveToFront(e)
+	return e.Value.(*resultCacheItem).check, true
+}
+
+// add caches the check of ip against snapshot version, evicting the least
+// recently used check if the cache is full
+func (c *resultCache) add(version, ip string, check cachedCheck) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A check that raced a refresh has a result of the previous snapshot
+	if version != c.version {
+		return
+	}
+	if e, ok := c.items[ip]; ok {
+		e.Value.(*resultCacheItem).check = check
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[ip] = c.order.PushFront(&resultCacheItem{ip, check})
+	if c.order.Len() > c.size {
+		oldest := c.order.Remove(c.order.Back()).(*resultCacheItem)
+		delete(c.items, oldest.ip)
+	}
+}
+
+// setVersion empties the cache if version is a different snapshot than its
+// results were checked against
+func (c *resultCache) setVersion(version string) {
+	if version == c.version {
+		return
+	}
+	c.version = version
+	c.order.Init()
+	clear(c.items)
+}
+
+// invalidate empties the cache if version is a different snapshot
+func (c *resultCache) invalidate(version string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setVersion(version)
+}
+
+// stats returns the state of the cache, or nil if there is no cache
+func (c *resultCache) stats() *resultCacheJSON {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &resultCacheJSON{Size: c.size, Entries: c.order.Len(), Hits: c.hits, Misses: c.misses}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResultCache(t *testing.T) {
+	c := newResultCache(2)
+	if _, ok := c.get("v1", "192.30.252.1"); ok {
+		t.Fatal("get() of an empty cache should miss")
+	}
+	c.add("v1", "192.30.252.1", cachedCheck{out: []byte("a")})
+	c.add("v1", "140.82.112.1", cachedCheck{out: []byte("b")})
+
+	// Using the first makes the second the least recently used
+	if check, ok := c.get("v1", "192.30.252.1"); !ok || string(check.out) != "a" {
+		t.Errorf("get() = %q, %t, want a", check.out, ok)
+	}
+	c.add("v1", "8.8.8.8", cachedCheck{out: []byte("c")})
+	if _, ok := c.get("v1", "140.82.112.1"); ok {
+		t.Error("get() of the evicted address should miss")
+	}
+	if check, ok := c.get("v1", "8.8.8.8"); !ok || string(check.out) != "c" {
+		t.Errorf("get() = %q, %t, want c", check.out, ok)
+	}
+
+	// A result of the previous snapshot, checked while refreshing, is dropped
+	c.invalidate("v2")
+	c.add("v1", "192.30.252.1", cachedCheck{out: []byte("a")})
+	if _, ok := c.get("v2", "192.30.252.1"); ok {
+		t.Error("get() after the snapshot changed should miss")
+	}
+	c.add("v2", "192.30.252.1", cachedCheck{out: []byte("d")})
+	c.invalidate("v2")
+	if check, ok := c.get("v2", "192.30.252.1"); !ok || string(check.out) != "d" {
+		t.Errorf("get() after a refresh to the same snapshot = %q, %t, want d", check.out, ok)
+	}
+
+	want := &resultCacheJSON{Size: 2, Entries: 1, Hits: 3, Misses: 3}
+	if got := c.stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stats() = %+v, want %+v", got, want)
+	}
+
+	var disabled *resultCache = newResultCache(0)
+	disabled.add("v1", "192.30.252.1", cachedCheck{out: []byte("a")})
+	if _, ok := disabled.get("v1", "192.30.252.1"); ok || disabled.stats() != nil {
+		t.Error("a disabled cache should cache nothing")
+	}
+}
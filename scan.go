@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats for scan findings
+const (
+	scanOutputText  = "text"
+	scanOutputSARIF = "sarif"
+)
+
+// sarifRuleID identifies non-GitHub address findings in SARIF output
+const sarifRuleID = "non-github-ip"
+
+// ipv4Candidate matches runs of digits and dots that may be an IPv4 address
+var ipv4Candidate = regexp.MustCompile(`[0-9][0-9.]*[0-9]`)
+
+// scanFinding is a non-GitHub address found in a scanned file
+type scanFinding struct {
+	Path   string
+	Line   int
+	Column int // 1-based byte offset of the address in the line
+	IP     string
+}
+
+// newScanCommand creates the scan subcommand
+func newScanCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan <file>...",
+		Short: "Report IP addresses in files that aren't GitHub-owned",
+		Long: `Scan log or configuration files for public IPv4 addresses and report each
+occurrence of an address outside GitHub's published ranges. Use "-" to read
+from stdin. Exits with status 1 if any non-GitHub address is found.`,
+		Args:         cobra.MinimumNArgs(1),
+		RunE:         runScan,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("output", "o", scanOutputText, "Output format (text or sarif)")
+
+	return cmd
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output != scanOutputText && output != scanOutputSARIF {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+
+	checker := NewIPChecker()
+	if _, err := checker.Meta(); err != nil {
+		return err
+	}
+
+	var findings []scanFinding
+	for _, path := range args {
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", path, err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		found, err := scanReader(checker, path, r)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, found...)
+	}
+
+	switch output {
+	case scanOutputSARIF:
+		if err := writeSARIF(os.Stdout, findings); err != nil {
+			return err
+		}
+	default:
+		for _, f := range findings {
+			fmt.Printf("%s:%d:%d: IP %s is not a GitHub-owned address\n", f.Path, f.Line, f.Column, f.IP)
+		}
+	}
+
+	if len(findings) > 0 {
+		return notGitHubError(fmt.Sprintf("found %d non-GitHub IP addresses", len(findings)))
+	}
+	return nil
+}
+
+// scanReader returns every occurrence of a public, non-GitHub IPv4 address in r.
+// Addresses that can't be checked, such as private ones, are skipped.
+func scanReader(checker *IPChecker, path string, r io.Reader) ([]scanFinding, error) {
+	verdicts := make(map[string]bool)
+	var findings []scanFinding
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, loc := range ipv4Candidate.FindAllStringIndex(text, -1) {
+			candidate := text[loc[0]:loc[1]]
+			if _, err := netip.ParseAddr(candidate); strings.Count(candidate, ".") != 3 || err != nil {
+				continue
+			}
+
+			isGitHub, seen := verdicts[candidate]
+			if !seen {
+				result, err := checker.CheckIP(candidate)
+				// Only unsupported addresses fail here, the ranges are already fetched
+				isGitHub = err != nil || result.IsGitHubIP
+				verdicts[candidate] = isGitHub
+			}
+			if !isGitHub {
+				findings = append(findings, scanFinding{path, line, loc[0] + 1, candidate})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return findings, nil
+}
+
+// SARIF 2.1.0 log structure, limited to the properties used by the scan output
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      sarifMessage       `json:"fullDescription"`
+	Help                 sarifMessage       `json:"help"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// writeSARIF writes the findings as a SARIF 2.1.0 log for GitHub code scanning
+func writeSARIF(w io.Writer, findings []scanFinding) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "gh-check-github-ip-ranges",
+			Version:        Version,
+			InformationURI: "https://github.com/gclhub/gh-check-github-ip-ranges",
+			Rules: []sarifRule{{
+				ID:                   sarifRuleID,
+				Name:                 "NonGitHubIPAddress",
+				ShortDescription:     sarifMessage{"IP address outside GitHub's published ranges"},
+				FullDescription:      sarifMessage{"The file references a public IP address that is not within any of the ranges GitHub publishes through its /meta API."},
+				Help:                 sarifMessage{"Check whether the address is expected. If it is meant to be a GitHub address, update it from https://api.github.com/meta."},
+				DefaultConfiguration: sarifConfiguration{Level: "warning"},
+			}},
+		}},
+		Results: []sarifResult{},
+	}
+
+	for _, f := range findings {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  sarifRuleID,
+			Level:   "warning",
+			Message: sarifMessage{fmt.Sprintf("IP %s is not a GitHub-owned address", f.IP)},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.Path)},
+				Region: sarifRegion{
+					StartLine:   f.Line,
+					StartColumn: f.Column,
+					EndColumn:   f.Column + len(f.IP),
+				},
+			}}},
+		})
+	}
+
+	out, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SARIF: %w", err)
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestScanReader(t *testing.T) {
+	server := newTestMetaServer(t)
+	defer server.Close()
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	input := `allow 192.30.252.10;
+allow 8.8.8.8; # resolver
+upstream 10.0.0.1 1.1.1.1,8.8.8.8
+version 1.2.3.4.5 and 999.1.1.1
+`
+	got, err := scanReader(NewIPChecker(), "nginx.conf", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("scanReader() error = %v", err)
+	}
+
+	want := []scanFinding{
+		{"nginx.conf", 2, 7, "8.8.8.8"},
+		{"nginx.conf", 3, 19, "1.1.1.1"},
+		{"nginx.conf", 3, 27, "8.8.8.8"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanReader() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, []scanFinding{{"conf/nginx.conf", 2, 7, "8.8.8.8"}}); err != nil {
+		t.Fatalf("writeSARIF() error = %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("writeSARIF() produced invalid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("writeSARIF() = %s", buf.String())
+	}
+
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "gh-check-github-ip-ranges" || len(run.Tool.Driver.Rules) != 1 || run.Tool.Driver.Rules[0].ID != sarifRuleID {
+		t.Errorf("tool = %+v", run.Tool)
+	}
+
+	want := sarifResult{
+		RuleID:  sarifRuleID,
+		Level:   "warning",
+		Message: sarifMessage{"IP 8.8.8.8 is not a GitHub-owned address"},
+		Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: "conf/nginx.conf"},
+			Region:           sarifRegion{StartLine: 2, StartColumn: 7, EndColumn: 14},
+		}}},
+	}
+	if len(run.Results) != 1 || !reflect.DeepEqual(run.Results[0], want) {
+		t.Errorf("results = %+v, want %+v", run.Results, want)
+	}
+}
+
+func TestWriteSARIFNoFindings(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, nil); err != nil {
+		t.Fatalf("writeSARIF() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"results": []`) {
+		t.Errorf("writeSARIF() should list an empty results array:\n%s", buf.String())
+	}
+}
+
+func TestRunScan(t *testing.T) {
+	server := newTestMetaServer(t)
+	defer server.Close()
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.log")
+	dirty := filepath.Join(dir, "dirty.log")
+	os.WriteFile(clean, []byte("from 192.30.252.1\n"), 0644)
+	os.WriteFile(dirty, []byte("from 8.8.8.8\n"), 0644)
+
+	tests := []struct {
+		name    string
+		args    []string
+		output  string
+		wantErr string
+	}{
+		{name: "No findings", args: []string{clean}},
+		{name: "Findings", args: []string{clean, dirty}, wantErr: "found 1 non-GitHub IP addresses"},
+		{name: "SARIF findings", args: []string{dirty}, output: "sarif", wantErr: "found 1 non-GitHub IP addresses"},
+		{name: "Unsupported output", args: []string{clean}, output: "xml", wantErr: `unsupported output format "xml"`},
+		{name: "Missing file", args: []string{filepath.Join(dir, "missing.log")}, wantErr: "failed to open"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().String("output", scanOutputText, "")
+			if tt.output != "" {
+				cmd.Flags().Set("output", tt.output)
+			}
+
+			oldStdout := os.Stdout
+			_, w, _ := os.Pipe()
+			os.Stdout = w
+			err := runScan(cmd, tt.args)
+			w.Close()
+			os.Stdout = oldStdout
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("runScan() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runScan() error = %v, want %q", err, tt.wantErr)
+			}
+			var notGitHub notGitHubError
+			if strings.HasPrefix(tt.wantErr, "found") && !errors.As(err, &notGitHub) {
+				t.Errorf("runScan() error = %v, want a notGitHubError", err)
+			}
+		})
+	}
+}
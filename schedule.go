@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// For testing purposes
+var (
+	scheduleSleep = time.Sleep
+	scheduleNow   = time.Now
+	// scheduleLimit stops after this many scheduled runs; 0 runs forever
+	scheduleLimit = 0
+)
+
+// scheduleMacros are the shorthands accepted in place of the five fields of a
+// cron expression
+var scheduleMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// schedule is a parsed cron expression, each field the set of values it
+// matches as a bit mask
+type schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// Whether the day of the month or week is restricted, i.e. doesn't start
+	// with *: cron matches a day if either restricted field matches it
+	domRestricted, dowRestricted bool
+}
+
+// scheduleField is the range of values of a cron field
+type scheduleField struct {
+	name     string
+	min, max int
+}
+
+var scheduleFields = []scheduleField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseSchedule parses a cron expression of five fields (minute, hour, day of
+// month, month and day of week), each a list of values, ranges such as 1-5 and
+// steps such as */30, or one of the macros such as @daily. Times are local.
+func parseSchedule(spec string) (schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if macro, ok := scheduleMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(scheduleFields) {
+		return schedule{}, fmt.Errorf("invalid schedule %q: want 5 fields (minute hour day-of-month month day-of-week)", spec)
+	}
+
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := parseScheduleField(field, scheduleFields[i])
+		if err != nil {
+			return schedule{}, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		masks[i] = mask
+	}
+	// Both 0 and 7 are Sunday
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+	return schedule{
+		minute:        masks[0],
+		hour:          masks[1],
+		dom:           masks[2],
+		month:         masks[3],
+		dow:           masks[4],
+		domRestricted: !strings.HasPrefix(fields[2], "*"),
+		dowRestricted: !strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseScheduleField parses a field of a cron expression into the bit mask of
+// the values it matches
+func parseScheduleField(field string, f scheduleField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		span, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepStr, f.name)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if span != "*" {
+			first, last, isRange := strings.Cut(span, "-")
+			var err error
+			if lo, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s field", first, f.name)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s field", last, f.name)
+				}
+			} else if hasStep {
+				// 5/15 means from 5 to the end in steps of 15
+				hi = f.max
+			}
+			if lo < f.min || hi > f.max || lo > hi {
+				return 0, fmt.Errorf("%s field %q is outside %d-%d", f.name, span, f.min, f.max)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+// dayMatches reports whether the schedule runs on the day of t
+func (s schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// next returns the first time after t the schedule runs, or the zero time if
+// it never does, e.g. on the 30th of February
+func (s schedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Every schedule that runs at all does so within a leap year cycle
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// validateSchedule parses the --schedule flag, checking it runs at all
+func validateSchedule(spec string) (schedule, error) {
+	s, err := parseSchedule(spec)
+	if err != nil {
+		return schedule{}, withCategory(errorCategoryUsage, err)
+	}
+	if s.next(scheduleNow()).IsZero() {
+		return schedule{}, withCategory(errorCategoryUsage, fmt.Errorf("schedule %q never runs", spec))
+	}
+	return s, nil
+}
+
+// runScheduled runs job at every time of the schedule, until the process is
+// stopped. A failed run is reported as a warning, so a long-running instance
+// carries on at the next scheduled time.
+func runScheduled(s schedule, name string, job func() error) error {
+	for runs := 0; scheduleLimit == 0 || runs < scheduleLimit; runs++ {
+		now := scheduleNow()
+		next := s.next(now)
+		scheduleSleep(next.Sub(now))
+		if err := job(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: scheduled %s at %s failed: %v\n", name, next.Format(time.RFC3339), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2026, 7, 1, 8, 10, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 7, 1, 8, 11, 0, 0, time.UTC)},
+		{"*/30 * * * *", time.Date(2026, 7, 1, 8, 30, 0, 0, time.UTC)},
+		{"5,10 * * * *", time.Date(2026, 7, 1, 9, 5, 0, 0, time.UTC)},
+		{"15/20 * * * *", time.Date(2026, 7, 1, 8, 15, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 7, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 7, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 7, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// A restricted day of the month or of the week matches
+		{"0 0 15 * 5", time.Date(2026, 7, 3, 0, 0, 0, 0, time.UTC)},
+		// A day field starting with * isn't restricted, even with a step, so
+		// both day fields must match
+		{"*/2 * * * 1", time.Date(2026, 7, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */2 * 1", time.Date(2026, 7, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := parseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("parseSchedule() error = %v", err)
+			}
+			if got := s.next(from); !got.Equal(tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@often", "0 0 31 4 *"} {
+		if _, err := validateSchedule(spec); err == nil || errorCategory(err) != errorCategoryUsage {
+			t.Errorf("validateSchedule(%q) error = %v, want a usage error", spec, err)
+		}
+	}
+	if _, err := validateSchedule("*/30 * * * *"); err != nil {
+		t.Errorf("validateSchedule() error = %v", err)
+	}
+}
+
+func TestExportSchedule(t *testing.T) {
+	server := newTestMetaServer(t)
+	defer server.Close()
+
+	now := time.Date(2026, 7, 1, 8, 10, 0, 0, time.UTC)
+	var slept []time.Duration
+	oldURL, oldSleep, oldNow, oldLimit := githubMetaURL, scheduleSleep, scheduleNow, scheduleLimit
+	githubMetaURL = server.URL
+	scheduleSleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+	scheduleNow = func() time.Time { return now }
+	scheduleLimit = 3
+	defer func() { githubMetaURL, scheduleSleep, scheduleNow, scheduleLimit = oldURL, oldSleep, oldNow, oldLimit }()
+
+	dir := t.TempDir()
+	path, runs := filepath.Join(dir, "export.out"), filepath.Join(dir, "runs")
+	cmd := newExportCommand()
+	cmd.SetArgs([]string{"--format", "haproxy", "--schedule", "*/30 * * * *", "--reload", "echo run >> " + runs, path})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("export --schedule error = %v", err)
+	}
+	if want := []time.Duration{20 * time.Minute, 30 * time.Minute, 30 * time.Minute}; len(slept) != len(want) || slept[0] != want[0] || slept[1] != want[1] || slept[2] != want[2] {
+		t.Errorf("export --schedule slept %v, want %v", slept, want)
+	}
+	if got, _ := os.ReadFile(runs); string(got) != strings.Repeat("run\n", 3) {
+		t.Errorf("export --schedule reloads = %q, want 3", got)
+	}
+
+	// A failed run doesn't stop the schedule
+	githubMetaURL = "http://127.0.0.1:0"
+	oldStderr := os.Stderr
+	rErr, wErr, _ := os.Pipe()
+	os.Stderr = wErr
+	cmd = newExportCommand()
+	cmd.SetArgs([]string{"--format", "haproxy", "--schedule", "@hourly", path})
+	err := cmd.Execute()
+	wErr.Close()
+	os.Stderr = oldStderr
+
+	var stderr bytes.Buffer
+	stderr.ReadFrom(rErr)
+	if err != nil {
+		t.Errorf("export --schedule with failing runs error = %v", err)
+	}
+	if n := strings.Count(stderr.String(), "Warning: scheduled export at "); n != 3 {
+		t.Errorf("export --schedule stderr = %q, want 3 warnings", stderr.String())
+	}
+}
@@ -0,0 +1,617 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// For testing purposes
+var serveNow = time.Now
+
+// openAPISpec is the OpenAPI specification of the server's API
+//
+//go:embed api/openapi.json
+var openAPISpec []byte
+
+// The timeouts of the server's connections, so slow or idle clients can't hold
+// them open indefinitely. /debug/pprof/profile only records CPU profiles
+// shorter than the write timeout.
+const (
+	serveReadHeaderTimeout = 10 * time.Second
+	serveReadTimeout       = time.Minute
+	serveWriteTimeout      = 2 * time.Minute
+	serveIdleTimeout       = 2 * time.Minute
+)
+
+// errStaleRanges is returned instead of answering from ranges fetched longer
+// than --max-stale ago
+var errStaleRanges = errors.New("the ranges are stale")
+
+// server answers checks over HTTP from a checker it refreshes periodically
+type server struct {
+	mu       sync.RWMutex
+	checker  *IPChecker // Replaced whole on refresh, so requests see one snapshot
+	schedule *schedule  // Refresh at these times instead of every interval, if set
+	mirror   bool       // Serve the meta document at /meta
+	results  *resultCache
+	breaker  *breaker
+	limiter  *rateLimiter
+	cors     *corsPolicy // Origins whose pages may call the API, if set
+	audit    *auditLog
+	maxStale time.Duration // Oldest ranges answered from, if set
+	batchMax int           // Most addresses checked by a POST /v1/check
+	metrics  Metrics       // Receives the measurements of each checker, if set
+
+	debug      bool   // Serve /debug/vars and /debug/pprof/
+	debugToken string // Bearer token required by the debug endpoints, if set
+
+	certs *certLoader       // Certificate served over TLS, if set
+	acme  *autocert.Manager // Obtains the certificates served over TLS instead, if set
+}
+
+// healthJSON is the response of /healthz
+type healthJSON struct {
+	Status     string    `json:"status"` // ok, degraded while refreshes fail, or stale beyond --max-stale
+	Snapshot   time.Time `json:"snapshot"`
+	Fetched    time.Time `json:"fetched"`
+	SourceHash string    `json:"source_hash"`
+
+	Breaker     breakerJSON      `json:"breaker"`
+	ResultCache *resultCacheJSON `json:"result_cache,omitempty"`
+	RateLimit   *rateLimitJSON   `json:"rate_limit,omitempty"`
+}
+
+// newServeCommand creates the serve subcommand
+func newServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve address checks over HTTP",
+		Long: `Fetch GitHub's meta document and answer checks over HTTP, fetching it again
+every --refresh interval, or at the times of the --schedule cron expression.
+A failed refresh keeps the previous ranges and is
+retried every minute. After --breaker-threshold failures in a row the circuit
+breaker opens, and refreshes are only attempted every --breaker-probe interval
+until one succeeds. Checks are answered from the previous ranges meanwhile,
+unless they were fetched longer than --max-stale ago.
+
+Endpoints:
+  GET /v1/check?ip=ADDR   The JSON check result of ADDR
+  POST /v1/check          The JSON check results of a JSON array of up to --batch-max addresses
+  GET /healthz            The snapshot being served
+  GET /meta               With --mirror, the meta document as GitHub served it
+  GET /openapi.json       The OpenAPI specification of these endpoints
+  GET /debug/vars         With --debug, expvar counters of checks, fetches and the breaker
+  GET /debug/pprof/       With --debug, pprof profiles
+
+With --mirror, machines that can't reach api.github.com, such as those in an
+air-gapped enclave, can run this tool with --meta-url pointing at the mirror's
+/meta. The mirror sets ETag and Last-Modified, and answers conditional requests
+with 304 Not Modified.
+
+With --rate-limit, each client, told apart by its address, or by its bearer
+token if it is one of --rate-limit-tokens, may make that many requests per
+second to /v1/check and /meta, in bursts of up to --rate-burst. Requests beyond
+are answered with 429 Too Many Requests and a Retry-After header.
+
+With --cors-origin, pages of those origins may call the endpoints from the
+browser, sending the --cors-header request headers.
+
+With --tls-cert, the endpoints are served over HTTPS only. With --acme-host,
+they are too, the certificate being obtained from Let's Encrypt when the first
+client connects and renewed automatically; the CA has to reach the server on
+port 443.
+
+SIGHUP refreshes the ranges right away, reopens the audit log, e.g. after
+logrotate moved it, and loads the --tls-cert certificate again. SIGINT and
+SIGTERM stop accepting connections and wait up to --shutdown-timeout for the
+requests in flight to complete.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runServe,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().String("listen", "localhost:8080", "Address to listen on")
+	cmd.Flags().Bool("mirror", false, "Also serve the meta document at /meta, for other copies of this tool to use with --meta-url")
+	cmd.Flags().Duration("refresh", time.Hour, "How often to fetch the meta document again")
+	cmd.Flags().String("schedule", "", `Fetch the meta document again at the times of this cron expression instead, e.g. "*/30 * * * *" or @hourly`)
+	cmd.Flags().String("audit-log", "", "Append a JSON line recording every check, with the client's address, to this file")
+	cmd.Flags().Int("batch-max", 100, "Most addresses a POST /v1/check may check at once")
+	cmd.Flags().Int("result-cache-size", 10000, "Cache the results of up to this many addresses until the ranges change (0 to disable)")
+	cmd.Flags().Int("breaker-threshold", 3, "Failed refreshes in a row that open the circuit breaker")
+	cmd.Flags().Duration("breaker-probe", 10*time.Minute, "How often a refresh is attempted while the circuit breaker is open")
+	cmd.Flags().Duration("max-stale", 7*24*time.Hour, "Stop answering checks from ranges fetched longer ago than this (0 for no limit)")
+	cmd.Flags().Float64("rate-limit", 0, "Requests per second each client may make to /v1/check and /meta (0 for no limit)")
+	cmd.Flags().Int("rate-burst", 20, "Requests a client may make at once beyond --rate-limit")
+	cmd.Flags().String("rate-limit-tokens", "", "File of bearer tokens, one per line, whose clients get a --rate-limit each instead of one per address")
+	cmd.Flags().StringSlice("cors-origin", nil, "Let pages of this origin, e.g. https://dashboard.example.com, https://*.example.com or *, call the API from the browser; repeat or comma-separate for several")
+	cmd.Flags().StringSlice("cors-header", nil, "Request header the --cors-origin pages may send, e.g. Authorization; repeat or comma-separate for several")
+	cmd.Flags().Duration("cors-max-age", 10*time.Minute, "How long browsers may cache the answer to a preflight request")
+	cmd.Flags().String("tls-cert", "", "Serve HTTPS with this PEM certificate chain")
+	cmd.Flags().String("tls-key", "", "PEM private key of --tls-cert (default read from the --tls-cert file)")
+	cmd.Flags().StringSlice("acme-host", nil, "Serve HTTPS with a Let's Encrypt certificate of this hostname, obtained and renewed automatically; repeat or comma-separate for several")
+	cmd.Flags().String("acme-cache", defaultACMECacheDir(), "Directory keeping the --acme-host certificates and account key across restarts")
+	cmd.Flags().Duration("shutdown-timeout", 30*time.Second, "On SIGINT or SIGTERM, how long to wait for the requests in flight before exiting")
+	cmd.Flags().Bool("debug", false, "Also serve expvar counters at /debug/vars and pprof profiles at /debug/pprof/")
+	cmd.Flags().String("debug-token", "", "Bearer token required by the --debug endpoints (default $GH_IP_DEBUG_TOKEN)")
+
+	return cmd
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	mirror, _ := cmd.Flags().GetBool("mirror")
+	refresh, _ := cmd.Flags().GetDuration("refresh")
+	cacheSize, _ := cmd.Flags().GetInt("result-cache-size")
+	batchMax, _ := cmd.Flags().GetInt("batch-max")
+	threshold, _ := cmd.Flags().GetInt("breaker-threshold")
+	probe, _ := cmd.Flags().GetDuration("breaker-probe")
+	maxStale, _ := cmd.Flags().GetDuration("max-stale")
+	drain, _ := cmd.Flags().GetDuration("shutdown-timeout")
+	rate, _ := cmd.Flags().GetFloat64("rate-limit")
+	burst, _ := cmd.Flags().GetInt("rate-burst")
+	tokensFile, _ := cmd.Flags().GetString("rate-limit-tokens")
+	spec, _ := cmd.Flags().GetString("schedule")
+	switch {
+	case spec != "" && cmd.Flags().Changed("refresh"):
+		return withCategory(errorCategoryUsage, fmt.Errorf("--refresh and --schedule can't be combined"))
+	case refresh < minWatchInterval:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--refresh interval must be at least %s", minWatchInterval))
+	case probe < minWatchInterval:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--breaker-probe interval must be at least %s", minWatchInterval))
+	case threshold < 1:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--breaker-threshold must be at least 1"))
+	case batchMax < 1:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--batch-max must be at least 1"))
+	case cacheSize < 0:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--result-cache-size must not be negative"))
+	case maxStale < 0:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--max-stale must not be negative"))
+	case drain < 0:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--shutdown-timeout must not be negative"))
+	case rate < 0:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--rate-limit must not be negative"))
+	case burst < 1:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--rate-burst must be at least 1"))
+	case tokensFile != "" && rate == 0:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--rate-limit-tokens requires --rate-limit"))
+	}
+
+	var tokens []string
+	if tokensFile != "" {
+		var err error
+		if tokens, err = readRateLimitTokens(tokensFile); err != nil {
+			return err
+		}
+	}
+	s := &server{
+		mirror:   mirror,
+		results:  newResultCache(cacheSize),
+		breaker:  newBreaker(threshold, probe),
+		limiter:  newRateLimiter(rate, burst, tokens),
+		maxStale: maxStale,
+		batchMax: batchMax,
+	}
+	if spec != "" {
+		schedule, err := validateSchedule(spec)
+		if err != nil {
+			return err
+		}
+		s.schedule = &schedule
+	}
+	origins, _ := cmd.Flags().GetStringSlice("cors-origin")
+	headers, _ := cmd.Flags().GetStringSlice("cors-header")
+	maxAge, _ := cmd.Flags().GetDuration("cors-max-age")
+	cors, err := newCORSPolicy(origins, headers, maxAge)
+	if err != nil {
+		return err
+	}
+	s.cors = cors
+	s.debug, _ = cmd.Flags().GetBool("debug")
+	s.debugToken, _ = cmd.Flags().GetString("debug-token")
+	if !cmd.Flags().Changed("debug-token") {
+		// Not the flag's default, which serve --help would print
+		s.debugToken = os.Getenv("GH_IP_DEBUG_TOKEN")
+	}
+	if s.debug {
+		publishServeVars(s)
+		if s.debugToken == "" {
+			fmt.Fprintln(os.Stderr, "Warning: the --debug endpoints are served without --debug-token, to anyone who can connect")
+		}
+	}
+	if path, _ := cmd.Flags().GetString("audit-log"); path != "" {
+		audit, err := openAuditLog(path)
+		if err != nil {
+			return err
+		}
+		defer audit.Close()
+		s.audit = audit
+	}
+	certFile, _ := cmd.Flags().GetString("tls-cert")
+	keyFile, _ := cmd.Flags().GetString("tls-key")
+	if certFile == "" && keyFile != "" {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--tls-key requires --tls-cert"))
+	}
+	acmeHosts, _ := cmd.Flags().GetStringSlice("acme-host")
+	acmeCache, _ := cmd.Flags().GetString("acme-cache")
+	switch {
+	case certFile != "" && len(acmeHosts) > 0:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--tls-cert and --acme-host can't be used together"))
+	case len(acmeHosts) > 0 && acmeCache == "":
+		return withCategory(errorCategoryUsage, fmt.Errorf("--acme-host requires --acme-cache"))
+	}
+	scheme := "http"
+	if certFile != "" {
+		certs, err := newCertLoader(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		s.certs, scheme = certs, "https"
+	}
+	if len(acmeHosts) > 0 {
+		s.acme, scheme = newACMEManager(acmeHosts, acmeCache), "https"
+	}
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return withCategory(errorCategoryUsage, fmt.Errorf("failed to listen on %s: %w", listen, err))
+	}
+	fmt.Fprintf(os.Stderr, "Serving checks on %s://%s\n", scheme, listener.Addr())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, append([]os.Signal{os.Interrupt, syscall.SIGTERM}, reloadSignals...)...)
+	defer signal.Stop(signals)
+	return s.serve(listener, s.handler(), refresh, signals, drain)
+}
+
+// serve answers requests on listener with handler, over TLS if a certificate
+// or ACME is set, refreshing the ranges every interval, until a shutdown signal
+// arrives on signals. The requests in flight are then given drain to complete.
+func (s *server) serve(listener net.Listener, handler http.Handler, interval time.Duration, signals <-chan os.Signal, drain time.Duration) error {
+	srv := newHTTPServer(handler)
+	served := make(chan error, 1)
+	if config := s.tlsConfig(); config != nil {
+		srv.TLSConfig = config
+		go func() { served <- srv.ServeTLS(listener, "", "") }()
+	} else {
+		go func() { served <- srv.Serve(listener) }()
+	}
+
+	reload := make(chan struct{}, 1)
+	go s.refreshLoop(interval, reload)
+
+	for {
+		select {
+		case err := <-served:
+			return err
+		case sig := <-signals:
+			if slices.Contains(reloadSignals, sig) {
+				fmt.Fprintf(os.Stderr, "Received %s, refreshing the ranges and reopening the audit log\n", sig)
+				if err := s.audit.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v, still writing to the previous audit log\n", err)
+				}
+				if err := s.certs.reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v, still serving the previous certificate\n", err)
+				}
+				select {
+				case reload <- struct{}{}:
+				default: // A refresh is already pending
+				}
+				continue
+			}
+
+			fmt.Fprintf(os.Stderr, "Received %s, waiting up to %s for the requests in flight\n", sig, drain)
+			ctx, cancel := context.WithTimeout(context.Background(), drain)
+			defer cancel()
+			if err := srv.Shutdown(ctx); err != nil {
+				return fmt.Errorf("failed to complete the requests in flight: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// newHTTPServer returns the HTTP server of handler, with the connection timeouts
+func newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: serveReadHeaderTimeout,
+		ReadTimeout:       serveReadTimeout,
+		WriteTimeout:      serveWriteTimeout,
+		IdleTimeout:       serveIdleTimeout,
+	}
+}
+
+// refreshLoop refreshes the ranges every interval, or at the times of the
+// schedule, as the breaker allows, or right away when reload receives
+func (s *server) refreshLoop(interval time.Duration, reload <-chan struct{}) {
+	wait := s.untilRefresh(interval)
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-reload:
+			timer.Stop()
+		}
+		err := s.refresh()
+		wait = s.breaker.record(err, serveNow(), s.untilRefresh(interval))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v, serving the previous ranges; next attempt in %s\n", err, wait)
+		}
+	}
+}
+
+// untilRefresh returns how long until the next regular refresh: interval, or
+// until the next time of the schedule
+func (s *server) untilRefresh(interval time.Duration) time.Duration {
+	if s.schedule == nil {
+		return interval
+	}
+	now := serveNow()
+	return s.schedule.next(now).Sub(now)
+}
+
+// refresh fetches the meta document into a new checker, replacing the served
+// one only if the fetch succeeds. Cached results are dropped if the ranges
+// changed.
+func (s *server) refresh() error {
+	checker := NewIPChecker()
+	checker.SetMetrics(s.metrics)
+	if err := checker.Refresh(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.checker = checker
+	s.mu.Unlock()
+	s.results.invalidate(checker.SourceHash())
+	return nil
+}
+
+// current returns the checker of the served snapshot
+func (s *server) current() *IPChecker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checker
+}
+
+// fresh returns the checker of the served snapshot, or errStaleRanges if it
+// was fetched longer than --max-stale ago
+func (s *server) fresh() (*IPChecker, error) {
+	checker := s.current()
+	if age := serveNow().Sub(checker.FetchTime()); s.maxStale > 0 && age > s.maxStale {
+		return nil, withCategory(errorCategoryAPI, fmt.Errorf("%w: last fetched %s ago, more than --max-stale %s", errStaleRanges, age.Round(time.Second), s.maxStale))
+	}
+	return checker, nil
+}
+
+// handler routes the server's endpoints
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/check", s.limit(s.handleCheck))
+	mux.HandleFunc("POST /v1/check", s.limit(s.handleBatch))
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPISpec)
+	})
+	if s.mirror {
+		mux.HandleFunc("GET /meta", s.limit(s.handleMeta))
+	}
+	if !s.debug {
+		return s.cors.handler(mux)
+	}
+
+	// Pages of other origins can't call the debug endpoints
+	root := http.NewServeMux()
+	root.Handle("/debug/", debugHandler(s.debugToken))
+	root.Handle("/", s.cors.handler(mux))
+	return root
+}
+
+// limit answers requests beyond the client's --rate-limit with 429 instead of
+// passing them to h
+func (s *server) limit(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wait, ok := s.limiter.allow(r, serveNow())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+			writeServeError(w, withCategory(errorCategoryUsage, fmt.Errorf("%w: more than %g per second, retry in %s", errRateLimited, s.limiter.rate, wait.Round(time.Millisecond))))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	ip := r.URL.Query().Get("ip")
+	if ip == "" {
+		writeServeError(w, withCategory(errorCategoryUsage, fmt.Errorf("missing ip parameter")))
+		return
+	}
+	checker, err := s.fresh()
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+
+	var checkErr error
+	check, ok := s.results.get(checker.SourceHash(), ip)
+	if !ok {
+		var result *CheckResult
+		if result, checkErr = checker.CheckIP(ip); checkErr == nil {
+			check = newCachedCheck(ip, result)
+			s.results.add(checker.SourceHash(), ip, check)
+		}
+	}
+	if err := s.audit.record(checker, ip, check.result, checkErr, serveCaller(r)); err != nil {
+		writeServeError(w, err)
+		return
+	}
+	if checkErr != nil {
+		writeServeError(w, checkErr)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(check.out)
+}
+
+// handleBatch checks a JSON array of addresses against one snapshot, answering
+// a JSON array of their results in the same order. An address that can't be
+// checked gets a result with an error message, as in batch mode.
+func (s *server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var ips []string
+	// Generously more than batchMax addresses take, so a huge body fails early
+	body := http.MaxBytesReader(w, r.Body, int64(s.batchMax)*64+1024)
+	if err := json.NewDecoder(body).Decode(&ips); err != nil {
+		writeServeError(w, withCategory(errorCategoryInput, fmt.Errorf("invalid batch, want a JSON array of addresses: %w", err)))
+		return
+	}
+	if len(ips) > s.batchMax {
+		writeServeError(w, withCategory(errorCategoryUsage, fmt.Errorf("batch of %d addresses is more than --batch-max %d", len(ips), s.batchMax)))
+		return
+	}
+	checker, err := s.fresh()
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+
+	// Only the addresses not in the result cache are checked
+	checks := make([]cachedCheck, len(ips))
+	errs := make([]error, len(ips))
+	var misses []string
+	var missed []int
+	for i, ip := range ips {
+		var ok bool
+		if checks[i], ok = s.results.get(checker.SourceHash(), ip); !ok {
+			misses = append(misses, ip)
+			missed = append(missed, i)
+		}
+	}
+	results, err := checker.CheckMany(r.Context(), misses)
+	if results == nil && err != nil {
+		writeServeError(w, err)
+		return
+	}
+	for j, result := range results {
+		i := missed[j]
+		if errs[i] = result.Err; errs[i] == nil {
+			checks[i] = newCachedCheck(ips[i], result.Result)
+			s.results.add(checker.SourceHash(), ips[i], checks[i])
+		}
+	}
+
+	out := []byte{'['}
+	for i, ip := range ips {
+		if err := s.audit.record(checker, ip, checks[i].result, errs[i], serveCaller(r)); err != nil {
+			writeServeError(w, err)
+			return
+		}
+		if i > 0 {
+			out = append(out, ',')
+		}
+		if errs[i] != nil {
+			item, _ := json.Marshal(resultJSON{IP: ip, Error: errs[i].Error()})
+			out = append(out, item...)
+		} else {
+			out = append(out, bytes.TrimSuffix(checks[i].out, []byte{'\n'})...)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(out, ']', '\n'))
+}
+
+// newCachedCheck encodes the response to a check of ip
+func newCachedCheck(ip string, result *CheckResult) cachedCheck {
+	out, _ := json.Marshal(newResultJSON(ip, result))
+	return cachedCheck{out: append(out, '\n'), result: result}
+}
+
+// serveCaller returns the address of the client making a request
+func serveCaller(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// handleHealth reports the served snapshot and the breaker's state, failing
+// once the snapshot is too stale to answer from
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	checker := s.current()
+	health := healthJSON{
+		Status:     "ok",
+		Snapshot:   checker.SnapshotTime(),
+		Fetched:    checker.FetchTime(),
+		SourceHash: checker.SourceHash(),
+
+		Breaker:     s.breaker.stats(),
+		ResultCache: s.results.stats(),
+		RateLimit:   s.limiter.stats(),
+	}
+	status := http.StatusOK
+	if health.Breaker.Failures > 0 {
+		health.Status = "degraded"
+	}
+	if _, err := s.fresh(); err != nil {
+		health.Status, status = "stale", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(health)
+}
+
+// handleMeta serves the meta document, leaving conditional and range requests
+// to http.ServeContent
+func (s *server) handleMeta(w http.ResponseWriter, r *http.Request) {
+	checker, err := s.fresh()
+	if err != nil {
+		writeServeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("ETag", `"`+checker.SourceHash()+`"`)
+	http.ServeContent(w, r, "meta.json", checker.SnapshotTime(), bytes.NewReader(checker.Document()))
+}
+
+// writeServeError writes err as {"error": {"category", "message"}}, with a
+// status reflecting whether the request or GitHub's API was at fault
+func writeServeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errStaleRanges):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, errRateLimited):
+		status = http.StatusTooManyRequests
+	case errorCategory(err) == errorCategoryInput, errorCategory(err) == errorCategoryUsage:
+		status = http.StatusBadRequest
+	case errorCategory(err) == errorCategoryNetwork, errorCategory(err) == errorCategoryAPI:
+		status = http.StatusBadGateway
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{
+		"category": errorCategory(err),
+		"message":  err.Error(),
+	}})
+}
@@ -0,0 +1,12 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals make serve refresh the ranges, reopen its audit log and load
+// its TLS certificate again
+var reloadSignals = []os.Signal{syscall.SIGHUP}
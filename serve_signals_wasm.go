@@ -0,0 +1,9 @@
+//go:build js && wasm
+
+package main
+
+import "os"
+
+// reloadSignals make serve refresh the ranges, reopen its audit log and load
+// its TLS certificate again. There is no SIGHUP on this platform.
+var reloadSignals []os.Signal
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gclhub/gh-check-github-ip-ranges/client"
+)
+
+// newTestServer returns a server of a meta document with the given
+// Last-Modified header
+func newTestServer(t *testing.T, mirror bool, meta, lastModified string) *server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(meta))
+	}))
+	defer upstream.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = upstream.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	s := &server{mirror: mirror, results: newResultCache(10), breaker: newBreaker(3, 10*time.Minute)}
+	if err := s.refresh(); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	return s
+}
+
+func TestServer_Check(t *testing.T) {
+	s := newTestServer(t, false, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantBody     string
+		wantCategory string
+	}{
+		{name: "GitHub", path: "/v1/check?ip=192.30.252.1", wantStatus: http.StatusOK,
+			wantBody: `{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22"}`},
+		{name: "Not GitHub", path: "/v1/check?ip=8.8.8.8", wantStatus: http.StatusOK,
+			wantBody: `{"ip":"8.8.8.8","is_github":false}`},
+		{name: "Invalid", path: "/v1/check?ip=10.0.0.1", wantStatus: http.StatusBadRequest, wantCategory: errorCategoryInput},
+		{name: "Missing", path: "/v1/check", wantStatus: http.StatusBadRequest, wantCategory: errorCategoryUsage},
+		{name: "No mirror", path: "/meta", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(string(body)) != tt.wantBody {
+				t.Errorf("GET %s = %s, want %s", tt.path, body, tt.wantBody)
+			}
+			if tt.wantCategory != "" {
+				var out struct {
+					Error struct{ Category string } `json:"error"`
+				}
+				if err := json.Unmarshal(body, &out); err != nil || out.Error.Category != tt.wantCategory {
+					t.Errorf("GET %s = %s, want category %s", tt.path, body, tt.wantCategory)
+				}
+			}
+		})
+	}
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var health healthJSON
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != "ok" || health.Snapshot.Format(http.TimeFormat) != "Mon, 02 Jun 2025 00:00:00 GMT" || health.SourceHash == "" {
+		t.Errorf("GET /healthz = %+v", health)
+	}
+	// The two valid checks were cached, the invalid ones not
+	if c := health.ResultCache; c == nil || c.Entries != 2 || c.Misses != 3 {
+		t.Errorf("GET /healthz result cache = %+v, want 2 entries", c)
+	}
+}
+
+func TestServer_CheckCached(t *testing.T) {
+	meta := `{"hooks": ["192.30.252.0/22"]}`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(meta))
+	}))
+	defer upstream.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = upstream.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	s := &server{results: newResultCache(10), breaker: newBreaker(3, 10*time.Minute)}
+	if err := s.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	check := func() string {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/v1/check?ip=140.82.112.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return strings.TrimSpace(string(body))
+	}
+
+	want := `{"ip":"140.82.112.1","is_github":false}`
+	for range 2 {
+		if got := check(); got != want {
+			t.Fatalf("GET /v1/check = %s, want %s", got, want)
+		}
+	}
+	if stats := s.results.stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("result cache = %+v, want 1 hit and 1 miss", stats)
+	}
+
+	// New ranges invalidate the cached results
+	meta = `{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`
+	if err := s.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	want = `{"ip":"140.82.112.1","is_github":true,"area":"Git","range":"140.82.112.0/20"}`
+	if got := check(); got != want {
+		t.Errorf("GET /v1/check after a refresh = %s, want %s", got, want)
+	}
+}
+
+func TestServer_Batch(t *testing.T) {
+	s := newTestServer(t, false, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	s.batchMax = 3
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	// One address is cached already
+	http.Get(ts.URL + "/v1/check?ip=8.8.8.8")
+
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantBody     string
+		wantCategory string
+	}{
+		{name: "Batch", body: `["192.30.252.1", "8.8.8.8", "10.0.0.1"]`, wantStatus: http.StatusOK,
+			wantBody: `[{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22"},` +
+				`{"ip":"8.8.8.8","is_github":false},` +
+				`{"ip":"10.0.0.1","is_github":false,"error":"IP address is a private address (10.0.0.0/8, RFC 1918), not a public one"}]`},
+		{name: "Empty", body: `[]`, wantStatus: http.StatusOK, wantBody: `[]`},
+		{name: "Too many", body: `["1.1.1.1", "1.1.1.2", "1.1.1.3", "1.1.1.4"]`, wantStatus: http.StatusBadRequest, wantCategory: errorCategoryUsage},
+		{name: "Not an array", body: `{"ip": "192.30.252.1"}`, wantStatus: http.StatusBadRequest, wantCategory: errorCategoryInput},
+		{name: "Too large", body: `["` + strings.Repeat("1", 2000) + `"]`, wantStatus: http.StatusBadRequest, wantCategory: errorCategoryInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+"/v1/check", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("POST /v1/check status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(string(body)) != tt.wantBody {
+				t.Errorf("POST /v1/check = %s, want %s", body, tt.wantBody)
+			}
+			if tt.wantCategory != "" {
+				var errBody struct {
+					Error struct {
+						Category string `json:"category"`
+					} `json:"error"`
+				}
+				if json.Unmarshal(body, &errBody); errBody.Error.Category != tt.wantCategory {
+					t.Errorf("POST /v1/check error = %s, want category %s", body, tt.wantCategory)
+				}
+			}
+		})
+	}
+	if stats := s.results.stats(); stats.Hits != 1 || stats.Entries != 2 {
+		t.Errorf("result cache = %+v, want 1 hit and 2 entries", stats)
+	}
+}
+
+func TestServer_Mirror(t *testing.T) {
+	const meta = `{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`
+	const lastModified = "Mon, 02 Jun 2025 00:00:00 GMT"
+	s := newTestServer(t, true, meta, lastModified)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != meta {
+		t.Fatalf("GET /meta = %d %s, want the upstream document", resp.StatusCode, body)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag != `"`+s.current().SourceHash()+`"` {
+		t.Errorf("GET /meta ETag = %s, want the document's hash", etag)
+	}
+	if got := resp.Header.Get("Last-Modified"); got != lastModified {
+		t.Errorf("GET /meta Last-Modified = %s, want %s", got, lastModified)
+	}
+
+	conditions := map[string]string{"If-None-Match": etag, "If-Modified-Since": lastModified}
+	for header, value := range conditions {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/meta", nil)
+		req.Header.Set(header, value)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotModified {
+			t.Errorf("GET /meta with %s status = %d, want %d", header, resp.StatusCode, http.StatusNotModified)
+		}
+	}
+
+	// A copy of the tool pointed at the mirror checks against the same ranges
+	oldURL := githubMetaURL
+	githubMetaURL = ts.URL + "/meta"
+	defer func() { githubMetaURL = oldURL }()
+	checker := NewIPChecker()
+	result, err := checker.CheckIP("140.82.112.1")
+	if err != nil {
+		t.Fatalf("CheckIP() through the mirror error = %v", err)
+	}
+	if result.AreaKey != "git" || checker.SourceHash() != s.current().SourceHash() ||
+		checker.SnapshotTime().Format(http.TimeFormat) != lastModified {
+		t.Errorf("CheckIP() through the mirror = %+v, snapshot %s", result, checker.SnapshotTime())
+	}
+}
+
+func TestServer_Stale(t *testing.T) {
+	s := newTestServer(t, true, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	s.maxStale = 24 * time.Hour
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	oldNow := serveNow
+	defer func() { serveNow = oldNow }()
+	fetched := s.current().FetchTime()
+
+	tests := []struct {
+		name       string
+		age        time.Duration
+		failures   int
+		wantHealth string
+		wantStatus int
+	}{
+		{name: "Fresh", age: time.Hour, wantHealth: "ok", wantStatus: http.StatusOK},
+		{name: "Failing", age: 2 * time.Hour, failures: 1, wantHealth: "degraded", wantStatus: http.StatusOK},
+		{name: "Breaker open", age: 12 * time.Hour, failures: 3, wantHealth: "degraded", wantStatus: http.StatusOK},
+		{name: "Stale", age: 25 * time.Hour, failures: 3, wantHealth: "stale", wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := fetched.Add(tt.age)
+			serveNow = func() time.Time { return now }
+			s.breaker = newBreaker(3, 10*time.Minute)
+			for range tt.failures {
+				s.breaker.record(errStaleRanges, now, time.Hour)
+			}
+
+			for _, path := range []string{"/v1/check?ip=192.30.252.1", "/meta", "/healthz"} {
+				resp, err := http.Get(ts.URL + path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, tt.wantStatus)
+				}
+				if path != "/healthz" {
+					continue
+				}
+				var health healthJSON
+				if err := json.Unmarshal(body, &health); err != nil {
+					t.Fatal(err)
+				}
+				if health.Status != tt.wantHealth || health.Breaker.Failures != tt.failures {
+					t.Errorf("GET /healthz = %s, want status %s", body, tt.wantHealth)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_UntilRefresh(t *testing.T) {
+	oldNow := serveNow
+	defer func() { serveNow = oldNow }()
+	serveNow = func() time.Time { return time.Date(2026, 7, 1, 8, 10, 0, 0, time.Local) }
+
+	s := &server{}
+	if got := s.untilRefresh(time.Hour); got != time.Hour {
+		t.Errorf("untilRefresh() without a schedule = %s, want 1h0m0s", got)
+	}
+	schedule, err := parseSchedule("*/30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.schedule = &schedule
+	if got := s.untilRefresh(time.Hour); got != 20*time.Minute {
+		t.Errorf("untilRefresh() with --schedule = %s, want 20m0s", got)
+	}
+}
+
+func TestNewHTTPServer(t *testing.T) {
+	srv := newHTTPServer(http.NotFoundHandler())
+	if srv.ReadHeaderTimeout <= 0 || srv.ReadTimeout <= 0 || srv.WriteTimeout <= 0 || srv.IdleTimeout <= 0 {
+		t.Errorf("newHTTPServer() timeouts = %s, %s, %s, %s, want all set", srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+	// A 30 second CPU profile, pprof's default, has to fit in a response
+	if srv.WriteTimeout <= 30*time.Second {
+		t.Errorf("newHTTPServer() write timeout = %s, too short for a CPU profile", srv.WriteTimeout)
+	}
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	s := newTestServer(t, true, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var spec struct {
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatalf("GET /openapi.json isn't JSON: %v", err)
+	}
+
+	// Every documented path is served
+	for path := range spec.Paths {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+			t.Errorf("GET %s status = %d, want the documented endpoint", path, resp.StatusCode)
+		}
+	}
+
+	// The schemas document the fields of the responses; resultJSON's other
+	// fields are only used by --unique and traceroute output
+	types := map[string]struct {
+		typ  reflect.Type
+		skip []string
+	}{
+		"CheckResult": {reflect.TypeFor[resultJSON](), []string{"count", "traceroute"}},
+		"AlsoMatch":   {reflect.TypeFor[alsoJSON](), nil},
+		"Hint":        {reflect.TypeFor[hintJSON](), nil},
+		"Health":      {reflect.TypeFor[healthJSON](), nil},
+		"Breaker":     {reflect.TypeFor[breakerJSON](), nil},
+		"ResultCache": {reflect.TypeFor[resultCacheJSON](), nil},
+		"RateLimit":   {reflect.TypeFor[rateLimitJSON](), nil},
+	}
+	for name, tt := range types {
+		var got, want []string
+		for i := range tt.typ.NumField() {
+			field, _, _ := strings.Cut(tt.typ.Field(i).Tag.Get("json"), ",")
+			if !slices.Contains(tt.skip, field) {
+				got = append(got, field)
+			}
+		}
+		for property := range spec.Components.Schemas[name].Properties {
+			want = append(want, property)
+		}
+		sort.Strings(got)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s fields = %v, want the properties of schema %s %v", tt.typ, got, name, want)
+		}
+	}
+}
+
+func TestServer_Client(t *testing.T) {
+	s := newTestServer(t, true, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	ctx := context.Background()
+	c := client.New(ts.URL)
+	result, err := c.Check(ctx, "192.30.252.1")
+	if err != nil || !result.IsGitHub || result.Area != "Hooks" {
+		t.Errorf("Check() = %+v, %v, want a Hooks address", result, err)
+	}
+	var apiErr *client.Error
+	if _, err := c.Check(ctx, "10.0.0.1"); !errors.As(err, &apiErr) || apiErr.Category != client.CategoryInput {
+		t.Errorf("Check() of a private address error = %v, want an %s error", err, client.CategoryInput)
+	}
+	s.batchMax = 10
+	results, err := c.CheckBatch(ctx, []string{"192.30.252.1", "10.0.0.1"})
+	if err != nil || len(results) != 2 || !results[0].IsGitHub || results[1].Error == "" {
+		t.Errorf("CheckBatch() = %+v, %v, want a Hooks address and an error", results, err)
+	}
+	health, err := c.Health(ctx)
+	if err != nil || health.Status != "ok" || health.SourceHash != s.current().SourceHash() {
+		t.Errorf("Health() = %+v, %v", health, err)
+	}
+	meta, err := c.Meta(ctx)
+	if err != nil || string(meta) != `{"hooks": ["192.30.252.0/22"]}` {
+		t.Errorf("Meta() = %s, %v", meta, err)
+	}
+}
+
+func TestServer_Signals(t *testing.T) {
+	var fetches atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer upstream.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = upstream.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	s := &server{breaker: newBreaker(3, 10*time.Minute), audit: log}
+	if err := s.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Health checks are held until released, to be in flight during shutdown
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			<-release
+		}
+		s.handler().ServeHTTP(w, r)
+	})
+	signals := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() { served <- s.serve(listener, handler, time.Hour, signals, 5*time.Second) }()
+	check := func() {
+		t.Helper()
+		resp, err := http.Get("http://" + listener.Addr().String() + "/v1/check?ip=192.30.252.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// SIGHUP refreshes the ranges and reopens the rotated audit log
+	check()
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	signals <- reloadSignals[0]
+	for deadline := time.Now().Add(5 * time.Second); fetches.Load() < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("SIGHUP didn't refresh the ranges")
+		}
+	}
+	check()
+	if rotated, current := readAuditLog(t, path+".1"), readAuditLog(t, path); len(rotated) != 1 || len(current) != 1 {
+		t.Errorf("audit logs have %d and %d records after SIGHUP, want 1 each", len(rotated), len(current))
+	}
+
+	// SIGTERM waits for a request in flight
+	inFlight := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/healthz")
+		if err != nil {
+			resp = nil
+		}
+		inFlight <- resp
+	}()
+	time.Sleep(50 * time.Millisecond)
+	signals <- syscall.SIGTERM
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-served:
+		t.Fatalf("serve() returned %v with a request in flight", err)
+	default:
+	}
+	close(release)
+	if resp := <-inFlight; resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("request in flight during shutdown = %v, want 200", resp)
+	} else {
+		resp.Body.Close()
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve() after SIGTERM error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("serve() didn't return after SIGTERM")
+	}
+}
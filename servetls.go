@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certLoader holds serve's --tls-cert certificate, loaded again on SIGHUP so a
+// renewed certificate is served without a restart
+type certLoader struct {
+	certFile, keyFile string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// newCertLoader loads the certificate and key, which may be in the same PEM file
+func newCertLoader(certFile, keyFile string) (*certLoader, error) {
+	if keyFile == "" {
+		keyFile = certFile
+	}
+	l := &certLoader{certFile: certFile, keyFile: keyFile}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// reload loads the certificate files again, keeping the current certificate
+// if they can't be loaded. It does nothing on a nil loader.
+func (l *certLoader) reload() error {
+	if l == nil {
+		return nil
+	}
+	pair, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return withCategory(errorCategoryInput, fmt.Errorf("failed to load TLS certificate: %w", err))
+	}
+	l.mu.Lock()
+	l.cert = &pair
+	l.mu.Unlock()
+	return nil
+}
+
+// config returns the TLS settings serving the loaded certificate
+func (l *certLoader) config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			l.mu.RLock()
+			defer l.mu.RUnlock()
+			return l.cert, nil
+		},
+	}
+}
+
+// newACMEManager returns the manager obtaining the certificates of hosts from
+// Let's Encrypt and renewing them before they expire, keeping them and the
+// account key in cacheDir
+func newACMEManager(hosts []string, cacheDir string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+}
+
+// tlsConfig returns the TLS settings of the server, or nil to serve plain HTTP.
+// With ACME, the CA's TLS-ALPN-01 challenges are answered on the same listener.
+func (s *server) tlsConfig() *tls.Config {
+	switch {
+	case s.acme != nil:
+		config := s.acme.TLSConfig()
+		config.MinVersion = tls.VersionTLS12
+		return config
+	case s.certs != nil:
+		return s.certs.config()
+	}
+	return nil
+}
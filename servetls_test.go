@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServer_TLS(t *testing.T) {
+	s := newTestServer(t, false, `{"hooks": ["192.30.252.0/22"]}`, "Mon, 02 Jun 2025 00:00:00 GMT")
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	first, renewed := testCertificate(t, "localhost", false, nil), testCertificate(t, "localhost", false, nil)
+	writeCertificate(t, certFile, first, true)
+
+	if _, err := newCertLoader(filepath.Join(dir, "missing.pem"), ""); errorCategory(err) != errorCategoryInput {
+		t.Errorf("newCertLoader() of a missing file error = %v, want an input error", err)
+	}
+	certs, err := newCertLoader(certFile, "")
+	if err != nil {
+		t.Fatalf("newCertLoader() error = %v", err)
+	}
+	s.certs = certs
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() { served <- s.serve(listener, s.handler(), time.Hour, signals, time.Second) }()
+	defer func() {
+		signals <- syscall.SIGTERM
+		<-served
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(first.Leaf)
+	pool.AddCert(renewed.Leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	servedCert := func() *x509.Certificate {
+		t.Helper()
+		client.CloseIdleConnections()
+		resp, err := client.Get("https://" + listener.Addr().String() + "/healthz")
+		if err != nil {
+			t.Fatalf("GET /healthz over TLS error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET /healthz over TLS status = %d, want 200", resp.StatusCode)
+		}
+		return resp.TLS.PeerCertificates[0]
+	}
+	if got := servedCert(); !got.Equal(first.Leaf) {
+		t.Errorf("served certificate %v, want the first", got.SerialNumber)
+	}
+
+	// Plain HTTP isn't answered
+	if resp, err := http.Get("http://" + listener.Addr().String() + "/healthz"); err == nil && resp.StatusCode == http.StatusOK {
+		t.Errorf("GET /healthz over plain HTTP succeeded")
+	}
+
+	// SIGHUP loads the renewed certificate; a broken one keeps the current
+	writeCertificate(t, certFile, renewed, true)
+	signals <- reloadSignals[0]
+	for deadline := time.Now().Add(5 * time.Second); !servedCert().Equal(renewed.Leaf); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("SIGHUP didn't load the renewed certificate")
+		}
+	}
+	if err := os.WriteFile(certFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := certs.reload(); err == nil {
+		t.Errorf("reload() of a broken certificate succeeded")
+	}
+	if got := servedCert(); !got.Equal(renewed.Leaf) {
+		t.Errorf("served certificate %v after a failed reload, want the renewed one", got.SerialNumber)
+	}
+}
+
+func TestServer_ACME(t *testing.T) {
+	s := &server{acme: newACMEManager([]string{"ip.example.com"}, t.TempDir())}
+	config := s.tlsConfig()
+	if config == nil || config.MinVersion != tls.VersionTLS12 || !slices.Contains(config.NextProtos, "acme-tls/1") {
+		t.Fatalf("tlsConfig() with ACME = %+v, want TLS 1.2 answering TLS-ALPN-01 challenges", config)
+	}
+	// Other hostnames are refused before the CA is asked
+	if _, err := config.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"}); err == nil {
+		t.Error("GetCertificate() of another hostname error = nil")
+	}
+
+	if config := (&server{}).tlsConfig(); config != nil {
+		t.Errorf("tlsConfig() without a certificate = %+v, want nil", config)
+	}
+
+	for _, args := range [][]string{
+		{"--acme-host", "ip.example.com", "--tls-cert", "cert.pem"},
+		{"--acme-host", "ip.example.com", "--acme-cache", ""},
+	} {
+		cmd := newServeCommand()
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); errorCategory(err) != errorCategoryUsage {
+			t.Errorf("serve %q error = %v, want a usage error", args, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// metaSource is a further meta document checked alongside GitHub's, such as
+// that of a GHES instance or a local snapshot
+type metaSource struct {
+	name     string
+	location string // http(s) URL or file path
+	areas    []Area // Loaded on first use
+	ranges   *rangeTrie
+}
+
+// AddSource adds a meta document, fetched from an http(s) URL or read from a
+// file, that CheckIP consults along with GitHub's. Matches are then labeled
+// with the name of the source publishing them, and GitHub's with the host of
+// its meta URL.
+func (c *IPChecker) AddSource(name, location string) {
+	c.sources = append(c.sources, &metaSource{name: name, location: location})
+}
+
+// primarySourceName labels the matches of GitHub's meta document when further
+// sources are checked
+func primarySourceName() string {
+	if u, err := url.Parse(githubMetaURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return githubMetaURL
+}
+
+// matchSources labels the matches of GitHub's meta document and adds those of
+// the further sources, ordered most specific first with ties going to the
+// source added first
+func (c *IPChecker) matchSources(matches []RangeMatch, addr netip.Addr) ([]RangeMatch, error) {
+	primary := primarySourceName()
+	for i := range matches {
+		matches[i].Source = primary
+	}
+	for _, source := range c.sources {
+		if source.areas == nil {
+			if err := c.loadSource(source); err != nil {
+				return nil, err
+			}
+		}
+		for _, m := range source.ranges.match(addr) {
+			m.Source = source.name
+			matches = append(matches, m)
+		}
+	}
+
+	bits := func(m RangeMatch) int {
+		prefix, _ := netip.ParsePrefix(m.Range)
+		return prefix.Bits()
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return bits(matches[i]) > bits(matches[j]) })
+	return matches, nil
+}
+
+// loadSource reads a source's meta document, reporting the load to the
+// checker's metrics
+func (c *IPChecker) loadSource(s *metaSource) error {
+	start := time.Now()
+	err := s.load()
+	c.recordFetch(s.name, time.Since(start), false, err)
+	return err
+}
+
+// load reads the source's meta document
+func (s *metaSource) load() error {
+	var body []byte
+	var err error
+	if strings.HasPrefix(s.location, "http://") || strings.HasPrefix(s.location, "https://") {
+		body, _, err = fetchMetaDocument(metaClient, s.location)
+	} else if body, err = os.ReadFile(s.location); err != nil {
+		err = withCategory(errorCategoryInput, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load meta source %s: %w", s.name, err)
+	}
+
+	checker, err := newMetaChecker(body)
+	if err != nil {
+		return fmt.Errorf("failed to load meta source %s: %w", s.name, err)
+	}
+	s.areas, s.ranges = checker.meta.Areas(), checker.ranges
+	return nil
+}
+
+// applySources adds the --source meta documents to checker
+func applySources(cmd *cobra.Command, checker *IPChecker) error {
+	values, _ := cmd.Flags().GetStringArray("source")
+	seen := map[string]bool{primarySourceName(): true}
+	for _, value := range values {
+		name, location, ok := strings.Cut(value, "=")
+		if !ok || name == "" || location == "" {
+			return withCategory(errorCategoryUsage, fmt.Errorf("invalid --source %q: must be name=url or name=file", value))
+		}
+		if seen[name] {
+			return withCategory(errorCategoryUsage, fmt.Errorf("duplicate --source name %q", name))
+		}
+		seen[name] = true
+		checker.AddSource(name, location)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestIPChecker_CheckIPSources(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"web": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`))
+	}))
+	defer primary.Close()
+	ghes := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"git": ["203.0.113.0/24", "192.30.252.0/22"], "web": ["192.30.252.0/24"]}`))
+	}))
+	defer ghes.Close()
+	snapshot := filepath.Join(t.TempDir(), "meta.json")
+	if err := os.WriteFile(snapshot, []byte(`{"api": ["140.82.112.0/20", "185.199.108.0/22"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldURL := githubMetaURL
+	githubMetaURL = primary.URL
+	defer func() { githubMetaURL = oldURL }()
+	host := strings.TrimPrefix(primary.URL, "http://")
+
+	checker := NewIPChecker()
+	checker.AddSource("ghes", ghes.URL)
+	checker.AddSource("snapshot", snapshot)
+
+	tests := []struct {
+		ip   string
+		want *CheckResult
+	}{
+		{
+			ip: "192.30.252.1",
+			want: &CheckResult{IsGitHubIP: true, FunctionalArea: "Web", AreaKey: "web", Range: "192.30.252.0/24", Source: "ghes",
+				Also: []RangeMatch{
+					{FunctionalArea: "Web", AreaKey: "web", Range: "192.30.252.0/22", Source: host},
+					{FunctionalArea: "Git", AreaKey: "git", Range: "192.30.252.0/22", Source: "ghes"},
+				}},
+		},
+		{
+			ip: "140.82.112.1",
+			want: &CheckResult{IsGitHubIP: true, FunctionalArea: "Git", AreaKey: "git", Range: "140.82.112.0/20", Source: host,
+				Also: []RangeMatch{{FunctionalArea: "API", AreaKey: "api", Range: "140.82.112.0/20", Source: "snapshot"}}},
+		},
+		{
+			ip:   "185.199.108.7",
+			want: &CheckResult{IsGitHubIP: true, FunctionalArea: "API", AreaKey: "api", Range: "185.199.108.0/22", Source: "snapshot"},
+		},
+		{ip: "8.8.8.8", want: &CheckResult{IsGitHubIP: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			got, err := checker.CheckIP(tt.ip)
+			if err != nil {
+				t.Fatalf("CheckIP() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckIP() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	result, _ := checker.CheckIP("192.30.252.1")
+	var buf bytes.Buffer
+	writeResult(&buf, outputText, "192.30.252.1", result)
+	want := "IP 192.30.252.1 belongs to GitHub's Web range (192.30.252.0/24) [ghes]" +
+		", also in Web (192.30.252.0/22) [" + host + "]; Git (192.30.252.0/22) [ghes]\n"
+	if buf.String() != want {
+		t.Errorf("writeResult() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeResult(&buf, outputJSON, "192.30.252.1", result)
+	var out resultJSON
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("writeResult() JSON error = %v", err)
+	}
+	if out.Source != "ghes" || len(out.Also) != 2 || out.Also[0].Source != host {
+		t.Errorf("writeResult() JSON = %s, want sources ghes and %s", buf.String(), host)
+	}
+}
+
+func TestIPChecker_CheckIPSourceErrors(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"web": ["192.30.252.0/22"]}`))
+	}))
+	defer primary.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = primary.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	invalid := filepath.Join(t.TempDir(), "meta.json")
+	if err := os.WriteFile(invalid, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		location string
+	}{
+		{name: "Missing file", location: filepath.Join(t.TempDir(), "missing.json")},
+		{name: "Invalid document", location: invalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewIPChecker()
+			checker.AddSource("snapshot", tt.location)
+			_, err := checker.CheckIP("192.30.252.1")
+			if err == nil {
+				t.Fatal("CheckIP() with an unloadable source should fail")
+			}
+			if errorCategory(err) != errorCategoryInput {
+				t.Errorf("CheckIP() error category = %s, want %s", errorCategory(err), errorCategoryInput)
+			}
+			if !strings.Contains(err.Error(), "meta source snapshot") {
+				t.Errorf("CheckIP() error = %v, want the source named", err)
+			}
+		})
+	}
+}
+
+func TestApplySources(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "None"},
+		{name: "Several", values: []string{"ghes=https://ghes.example.com/api/v3/meta", "snapshot=meta.json"}, want: []string{"ghes", "snapshot"}},
+		{name: "Equals in location", values: []string{"ghes=https://ghes.example.com/meta?a=b"}, want: []string{"ghes"}},
+		{name: "Missing location", values: []string{"ghes="}, wantErr: true},
+		{name: "Missing name", values: []string{"meta.json"}, wantErr: true},
+		{name: "Duplicate", values: []string{"a=one.json", "a=two.json"}, wantErr: true},
+		{name: "Primary name", values: []string{"api.github.com=meta.json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().StringArray("source", tt.values, "")
+			checker := NewIPChecker()
+
+			err := applySources(cmd, checker)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applySources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if errorCategory(err) != errorCategoryUsage {
+					t.Errorf("applySources() error category = %s, want %s", errorCategory(err), errorCategoryUsage)
+				}
+				return
+			}
+			var names []string
+			for _, s := range checker.sources {
+				names = append(names, s.name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("applySources() sources = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
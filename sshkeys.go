@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// sshKeyscanCommand is the ssh-keyscan executable used by verify-ssh-key --scan
+var sshKeyscanCommand = "ssh-keyscan"
+
+// sshKey is a public SSH host key
+type sshKey struct {
+	Type string // e.g. "ssh-ed25519"
+	Blob []byte
+}
+
+// parseSSHKey parses a key in authorized_keys format, "<type> <base64> [comment]"
+func parseSSHKey(s string) (sshKey, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return sshKey{}, fmt.Errorf("invalid SSH key %q", s)
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return sshKey{}, fmt.Errorf("invalid SSH key %q: %w", s, err)
+	}
+	return sshKey{fields[0], blob}, nil
+}
+
+// Fingerprint returns the key's SHA256 fingerprint as printed by ssh-keygen -l
+func (k sshKey) Fingerprint() string {
+	sum := sha256.Sum256(k.Blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint adds the SHA256: prefix to a bare fingerprint and drops
+// base64 padding, so fingerprints copied from different tools compare equal
+func normalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimRight(strings.TrimSpace(fingerprint), "=")
+	if !strings.HasPrefix(fingerprint, "SHA256:") {
+		fingerprint = "SHA256:" + fingerprint
+	}
+	return fingerprint
+}
+
+// PublishedSSHKeys returns the key types of GitHub's published SSH host keys by
+// fingerprint, from both the ssh_keys and ssh_key_fingerprints fields
+func (m *GitHubMeta) PublishedSSHKeys() map[string]string {
+	keys := make(map[string]string)
+	for name, fingerprint := range m.SSHKeyFingerprints {
+		keys[normalizeFingerprint(fingerprint)] = strings.ToLower(strings.TrimPrefix(name, "SHA256_"))
+	}
+	for _, s := range m.SSHKeys {
+		if key, err := parseSSHKey(s); err == nil {
+			keys[key.Fingerprint()] = key.Type
+		}
+	}
+	return keys
+}
+
+// newVerifySSHKeyCommand creates the verify-ssh-key subcommand
+func newVerifySSHKeyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-ssh-key [fingerprint]",
+		Short: "Verify SSH host key fingerprints against GitHub's published keys",
+		Long: `Verify an SSH host key fingerprint, such as SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU,
+against the host keys GitHub publishes through its /meta API endpoint. With
+--scan, the keys served by --host are fetched with ssh-keyscan and verified
+instead, to detect a man-in-the-middle or stale known_hosts entries. Exits with
+status 1 if any key doesn't match.`,
+		Args:         usageArgs(cobra.MaximumNArgs(1)),
+		RunE:         runVerifySSHKey,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().BoolP("silent", "s", false, "Silent mode - only use exit codes")
+	cmd.Flags().Bool("scan", false, "Fetch the host keys to verify with ssh-keyscan")
+	cmd.Flags().String("host", "github.com", "Host to scan with --scan")
+	cmd.Flags().Int("port", 22, "SSH port to scan with --scan, e.g. 443 for ssh.github.com")
+
+	return cmd
+}
+
+func runVerifySSHKey(cmd *cobra.Command, args []string) error {
+	silent, _ := cmd.Flags().GetBool("silent")
+	scan, _ := cmd.Flags().GetBool("scan")
+	if scan == (len(args) == 1) {
+		return withCategory(errorCategoryUsage, fmt.Errorf("provide either a fingerprint or --scan"))
+	}
+
+	checker := NewIPChecker()
+	meta, err := checker.Meta()
+	if err != nil {
+		return err
+	}
+	published := meta.PublishedSSHKeys()
+	if len(published) == 0 {
+		return withCategory(errorCategoryAPI, fmt.Errorf("GitHub meta response has no SSH keys"))
+	}
+
+	if !scan {
+		fingerprint := normalizeFingerprint(args[0])
+		keyType, ok := published[fingerprint]
+		if !silent && ok {
+			fmt.Printf("SSH key %s (%s) matches GitHub's published host keys\n", fingerprint, keyType)
+		}
+		if !ok {
+			return notGitHubError("the provided fingerprint does not match any GitHub SSH host key")
+		}
+		return nil
+	}
+
+	host, _ := cmd.Flags().GetString("host")
+	port, _ := cmd.Flags().GetInt("port")
+	keys, err := scanSSHKeys(host, port)
+	if err != nil {
+		return err
+	}
+
+	mismatched := 0
+	for _, key := range keys {
+		fingerprint := key.Fingerprint()
+		_, ok := published[fingerprint]
+		if !ok {
+			mismatched++
+		}
+		if silent {
+			continue
+		}
+		if ok {
+			fmt.Printf("SSH key %s (%s) served by %s matches GitHub's published host keys\n", fingerprint, key.Type, host)
+		} else {
+			fmt.Printf("SSH key %s (%s) served by %s does not match any GitHub SSH host key\n", fingerprint, key.Type, host)
+		}
+	}
+
+	if mismatched > 0 {
+		return notGitHubError(fmt.Sprintf("%d of %d SSH host keys served by %s don't match GitHub's published keys", mismatched, len(keys), host))
+	}
+	return nil
+}
+
+// scanSSHKeys returns the host keys served by host, fetched with ssh-keyscan
+func scanSSHKeys(host string, port int) ([]sshKey, error) {
+	cmd := exec.Command(sshKeyscanCommand, "-p", strconv.Itoa(port), host)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, withCategory(errorCategoryNetwork, fmt.Errorf("ssh-keyscan failed: %s", msg))
+		}
+		return nil, withCategory(errorCategoryNetwork, fmt.Errorf("failed to run ssh-keyscan: %w", err))
+	}
+
+	// Each line is "<host> <type> <base64>"
+	var keys []sshKey
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		_, rest, _ := strings.Cut(line, " ")
+		key, err := parseSSHKey(rest)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, withCategory(errorCategoryNetwork, fmt.Errorf("ssh-keyscan found no host keys for %s:%d", host, port))
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Type < keys[j].Type })
+	return keys, nil
+}
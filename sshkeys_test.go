@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	testSSHKey         = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+	testSSHFingerprint = "SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"
+)
+
+// fakeSSHKeyscan replaces ssh-keyscan with a script printing stdout
+func fakeSSHKeyscan(t *testing.T, stdout string) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "ssh-keyscan")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf '%s' '"+stdout+"'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	original := sshKeyscanCommand
+	sshKeyscanCommand = script
+	t.Cleanup(func() { sshKeyscanCommand = original })
+}
+
+func TestSSHKeyFingerprint(t *testing.T) {
+	key, err := parseSSHKey(testSSHKey + " comment")
+	if err != nil {
+		t.Fatalf("parseSSHKey() error = %v", err)
+	}
+	if key.Type != "ssh-ed25519" {
+		t.Errorf("parseSSHKey() type = %q, want ssh-ed25519", key.Type)
+	}
+	if got := key.Fingerprint(); got != testSSHFingerprint {
+		t.Errorf("Fingerprint() = %q, want %q", got, testSSHFingerprint)
+	}
+
+	if _, err := parseSSHKey("ssh-ed25519"); err == nil {
+		t.Error("parseSSHKey() of a key without data should fail")
+	}
+	if _, err := parseSSHKey("ssh-ed25519 not-base64!"); err == nil {
+		t.Error("parseSSHKey() of invalid base64 should fail")
+	}
+}
+
+func TestNormalizeFingerprint(t *testing.T) {
+	for _, fp := range []string{testSSHFingerprint, "+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU=", " " + testSSHFingerprint + "\n"} {
+		if got := normalizeFingerprint(fp); got != testSSHFingerprint {
+			t.Errorf("normalizeFingerprint(%q) = %q, want %q", fp, got, testSSHFingerprint)
+		}
+	}
+}
+
+func TestPublishedSSHKeys(t *testing.T) {
+	var meta GitHubMeta
+	err := json.Unmarshal([]byte(`{
+		"ssh_key_fingerprints": {
+			"SHA256_ECDSA": "p2QAMXNIC1TJYWeIOttrVc98/R1BUFWu3/LiyKgUfQM",
+			"SHA256_ED25519": "+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"
+		},
+		"ssh_keys": ["`+testSSHKey+`"]
+	}`), &meta)
+	if err != nil {
+		t.Fatalf("failed to decode meta: %v", err)
+	}
+
+	want := map[string]string{
+		"SHA256:p2QAMXNIC1TJYWeIOttrVc98/R1BUFWu3/LiyKgUfQM": "ecdsa",
+		testSSHFingerprint: "ssh-ed25519",
+	}
+	if got := meta.PublishedSSHKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PublishedSSHKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestRunVerifySSHKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ssh_keys": ["` + testSSHKey + `"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name          string
+		args          []string
+		scan          string // ssh-keyscan output, if the test scans
+		wantErr       bool
+		wantNotGitHub bool
+	}{
+		{
+			name: "Matching fingerprint",
+			args: []string{"+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"},
+		},
+		{
+			name:          "Unknown fingerprint",
+			args:          []string{"SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s"},
+			wantErr:       true,
+			wantNotGitHub: true,
+		},
+		{
+			name: "Scanned keys match",
+			scan: "# github.com:22 SSH-2.0-babeld\ngithub.com " + testSSHKey + "\n",
+		},
+		{
+			name:          "Scanned key mismatch",
+			scan:          "github.com " + testSSHKey + "\ngithub.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIKrvNa4P8vHbqXKfHXsBbbUJFqvfofsFCTOD2yl3yQxC\n",
+			wantErr:       true,
+			wantNotGitHub: true,
+		},
+		{
+			name:    "No keys scanned",
+			scan:    "# github.com:22 SSH-2.0-babeld\n",
+			wantErr: true,
+		},
+		{
+			name:    "Neither fingerprint nor scan",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().BoolP("silent", "s", true, "")
+			cmd.Flags().Bool("scan", tt.scan != "", "")
+			cmd.Flags().String("host", "github.com", "")
+			cmd.Flags().Int("port", 22, "")
+			if tt.scan != "" {
+				fakeSSHKeyscan(t, tt.scan)
+			}
+
+			err := runVerifySSHKey(cmd, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runVerifySSHKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(notGitHubError); ok != tt.wantNotGitHub {
+				t.Errorf("runVerifySSHKey() error = %v, want notGitHubError %v", err, tt.wantNotGitHub)
+			}
+		})
+	}
+}
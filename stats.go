@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// areaStats is the address space of a functional area
+type areaStats struct {
+	Key      string      `json:"key,omitempty"`
+	Name     string      `json:"name"`
+	Prefixes int         `json:"prefixes"`
+	IPv4     familyStats `json:"ipv4"`
+	IPv6     familyStats `json:"ipv6"`
+}
+
+// familyStats is the address space of an area in one address family
+type familyStats struct {
+	Prefixes  int     `json:"prefixes"`
+	Addresses string  `json:"addresses"`                 // Distinct addresses, as a decimal string since IPv6 counts overflow JSON numbers
+	Smallest  int     `json:"smallest_prefix,omitempty"` // Longest prefix length, e.g. 32
+	Largest   int     `json:"largest_prefix,omitempty"`  // Shortest prefix length, e.g. 20
+	Overlap   float64 `json:"overlap_percent"`           // Share of the addresses also in other areas
+}
+
+// statsReport is the output of the stats subcommand
+type statsReport struct {
+	Areas []areaStats `json:"areas"`
+	Total areaStats   `json:"total"` // Distinct address space of all areas
+}
+
+// newStatsCommand creates the stats subcommand
+func newStatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Report address-space statistics for each functional area",
+		Long: `Report for each functional area the number of prefixes, the number of distinct
+addresses, the smallest and largest prefix and the share of the addresses that
+other areas also publish, separately for IPv4 and IPv6.
+
+The text output leaves out IPv6 address counts, which are too large to read;
+the JSON output includes them as decimal strings.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runStats,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	meta, err := NewIPChecker().Meta()
+	if err != nil {
+		return err
+	}
+	report := newStatsReport(meta.Areas())
+
+	if output == outputJSON {
+		out, err := marshalExportJSON(report)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	writeStats(os.Stdout, report)
+	return nil
+}
+
+// newStatsReport computes the statistics of each area and of all areas together
+func newStatsReport(areas []Area) statsReport {
+	sets := make([]rangeSet, len(areas))
+	for i, area := range areas {
+		sets[i] = newPrefixSet(parsePrefixes(area.Ranges))
+	}
+
+	report := statsReport{Areas: []areaStats{}}
+	for i, area := range areas {
+		var others []addrRange
+		for j, set := range sets {
+			if j != i {
+				others = append(others, set...)
+			}
+		}
+		report.Areas = append(report.Areas, newAreaStats(area.Key, area.Name, area.Ranges, newRangeSet(others)))
+	}
+	report.Total = newAreaStats("", "All areas", uniqueRanges(areas), nil)
+	return report
+}
+
+// newAreaStats computes the statistics of ranges, with the overlap relative to
+// the addresses in others
+func newAreaStats(key, name string, ranges []string, others rangeSet) areaStats {
+	prefixes := parsePrefixes(ranges)
+	stats := areaStats{Key: key, Name: name, Prefixes: len(prefixes)}
+	set := newPrefixSet(prefixes)
+	stats.IPv4 = newFamilyStats(prefixes, set.family(true), others.family(true), true)
+	stats.IPv6 = newFamilyStats(prefixes, set.family(false), others.family(false), false)
+	return stats
+}
+
+// newFamilyStats computes the statistics of the IPv4 or IPv6 prefixes
+func newFamilyStats(prefixes []netip.Prefix, set, others rangeSet, ipv4 bool) familyStats {
+	var stats familyStats
+	for _, p := range prefixes {
+		if p.Addr().Is4() != ipv4 {
+			continue
+		}
+		stats.Prefixes++
+		if bits := p.Bits(); stats.Prefixes == 1 {
+			stats.Smallest, stats.Largest = bits, bits
+		} else {
+			stats.Smallest, stats.Largest = max(stats.Smallest, bits), min(stats.Largest, bits)
+		}
+	}
+
+	size := set.size()
+	stats.Addresses = size.String()
+	stats.Overlap = percentOf(set.intersectionSize(others), size)
+	return stats
+}
+
+// writeStats writes the statistics as a table
+func writeStats(w io.Writer, report statsReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Area\tIPv4 prefixes\tIPv4 addresses\tIPv4 sizes\tIPv4 overlap\tIPv6 prefixes\tIPv6 sizes\tIPv6 overlap")
+	for _, area := range append(report.Areas, report.Total) {
+		overlap4, overlap6 := fmt.Sprintf("%.1f%%", area.IPv4.Overlap), fmt.Sprintf("%.1f%%", area.IPv6.Overlap)
+		if area.Key == "" {
+			overlap4, overlap6 = "", ""
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\t%s\t%s\n", area.Name,
+			area.IPv4.Prefixes, area.IPv4.Addresses, prefixSizes(area.IPv4), overlap4,
+			area.IPv6.Prefixes, prefixSizes(area.IPv6), overlap6)
+	}
+	tw.Flush()
+}
+
+// prefixSizes formats the range of prefix lengths, e.g. "/20-/32"
+func prefixSizes(stats familyStats) string {
+	switch {
+	case stats.Prefixes == 0:
+		return "-"
+	case stats.Smallest == stats.Largest:
+		return fmt.Sprintf("/%d", stats.Smallest)
+	}
+	return fmt.Sprintf("/%d-/%d", stats.Largest, stats.Smallest)
+}
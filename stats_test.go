@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestNewStatsReport(t *testing.T) {
+	areas := []Area{
+		{Key: "hooks", Name: "Hooks", Ranges: []string{"192.30.252.0/22", "2a0a:a440::/29"}},
+		{Key: "web", Name: "Web", Ranges: []string{"192.30.252.0/22", "140.82.112.0/20", "140.82.121.3/32"}},
+		{Key: "pages", Name: "Pages", Ranges: []string{}},
+	}
+
+	report := newStatsReport(areas)
+	want := statsReport{
+		Areas: []areaStats{
+			{
+				Key: "hooks", Name: "Hooks", Prefixes: 2,
+				IPv4: familyStats{Prefixes: 1, Addresses: "1024", Smallest: 22, Largest: 22, Overlap: 100},
+				IPv6: familyStats{Prefixes: 1, Addresses: "633825300114114700748351602688", Smallest: 29, Largest: 29},
+			},
+			{
+				Key: "web", Name: "Web", Prefixes: 3,
+				IPv4: familyStats{Prefixes: 3, Addresses: "5120", Smallest: 32, Largest: 20, Overlap: 20},
+				IPv6: familyStats{Addresses: "0"},
+			},
+			{
+				Key: "pages", Name: "Pages",
+				IPv4: familyStats{Addresses: "0"},
+				IPv6: familyStats{Addresses: "0"},
+			},
+		},
+		Total: areaStats{
+			Name: "All areas", Prefixes: 4,
+			IPv4: familyStats{Prefixes: 3, Addresses: "5120", Smallest: 32, Largest: 20},
+			IPv6: familyStats{Prefixes: 1, Addresses: "633825300114114700748351602688", Smallest: 29, Largest: 29},
+		},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("newStatsReport() = %+v, want %+v", report, want)
+	}
+
+	var buf bytes.Buffer
+	writeStats(&buf, report)
+	lines := strings.Split(buf.String(), "\n")
+	for i, want := range []string{
+		"Area       IPv4 prefixes  IPv4 addresses  IPv4 sizes  IPv4 overlap  IPv6 prefixes  IPv6 sizes  IPv6 overlap",
+		"Hooks      1              1024            /22         100.0%        1              /29         0.0%",
+		"Web        3              5120            /20-/32     20.0%         0              -           0.0%",
+		"Pages      0              0               -           0.0%          0              -           0.0%",
+		"All areas  3              5120            /20-/32                   1              /29",
+	} {
+		if strings.TrimRight(lines[i], " ") != want {
+			t.Errorf("writeStats() line %d = %q, want %q", i, lines[i], want)
+		}
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "web": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	for _, output := range []string{outputText, outputJSON} {
+		cmd := &cobra.Command{}
+		cmd.Flags().StringP("output", "o", output, "")
+		if err := runStats(cmd, nil); err != nil {
+			t.Errorf("runStats() with %s output error = %v", output, err)
+		}
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringP("output", "o", "csv", "")
+	if err := runStats(cmd, nil); errorCategory(err) != errorCategoryUsage {
+		t.Errorf("runStats() with csv output error = %v, want usage error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// subtractJSON is the JSON output of the subtract subcommand
+type subtractJSON struct {
+	Prefix    string   `json:"prefix"`
+	Addresses string   `json:"addresses"`  // Addresses in the prefix, as a decimal string since IPv6 counts overflow JSON numbers
+	Uncovered string   `json:"not_github"` // Addresses outside GitHub's ranges
+	Gaps      []string `json:"not_github_prefixes"`
+}
+
+// newSubtractCommand creates the subtract subcommand
+func newSubtractCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "subtract <prefix>",
+		Short: "Find the parts of a prefix not covered by GitHub's ranges",
+		Long: `Subtract GitHub's published ranges from a prefix, such as a provider's block
+140.82.112.0/20, and print the smallest set of CIDRs covering the addresses that
+remain, one per line. This shows whether the whole block can be treated as
+GitHub's. Exits with status 1 if any part of the prefix is not covered.`,
+		Args:         usageArgs(cobra.ExactArgs(1)),
+		RunE:         runSubtract,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().StringSliceP("area", "a", nil, "Functional areas to subtract, e.g. hooks,git (default all)")
+	cmd.Flags().StringP("output", "o", outputText, "Output format (text or json)")
+
+	return cmd
+}
+
+func runSubtract(cmd *cobra.Command, args []string) error {
+	areaNames, _ := cmd.Flags().GetStringSlice("area")
+	output, _ := cmd.Flags().GetString("output")
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("unsupported output format %q", output))
+	}
+
+	prefix, err := parsePrefix(args[0])
+	if err != nil {
+		return withCategory(errorCategoryInput, err)
+	}
+
+	meta, err := NewIPChecker().Meta()
+	if err != nil {
+		return err
+	}
+	areas, err := selectAreas(meta.Areas(), areaNames)
+	if err != nil {
+		return withCategory(errorCategoryUsage, err)
+	}
+
+	result := subtractRanges(prefix, uniqueRanges(areas))
+
+	if output == outputJSON {
+		out, err := marshalExportJSON(result)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(out)
+	} else {
+		writeSubtract(os.Stdout, result)
+	}
+
+	if len(result.Gaps) > 0 {
+		return notGitHubError(fmt.Sprintf("%s of the %s addresses in %s are not in GitHub's ranges", result.Uncovered, result.Addresses, result.Prefix))
+	}
+	return nil
+}
+
+// subtractRanges returns the parts of prefix not covered by ranges
+func subtractRanges(prefix netip.Prefix, ranges []string) subtractJSON {
+	r := prefixRange(prefix)
+	result := subtractJSON{Prefix: prefix.String(), Addresses: r.size().String(), Gaps: []string{}}
+	uncovered := new(big.Int)
+	for _, gap := range newPrefixSet(parsePrefixes(ranges)).subtract(r) {
+		uncovered.Add(uncovered, gap.size())
+		for _, p := range rangePrefixes(gap) {
+			result.Gaps = append(result.Gaps, p.String())
+		}
+	}
+	result.Uncovered = uncovered.String()
+	return result
+}
+
+// writeSubtract writes the uncovered prefixes, one per line. The error returned for
+// them summarizes how many addresses they hold.
+func writeSubtract(w io.Writer, result subtractJSON) {
+	if len(result.Gaps) == 0 {
+		fmt.Fprintf(w, "%s is entirely covered by GitHub's ranges\n", result.Prefix)
+		return
+	}
+	for _, gap := range result.Gaps {
+		fmt.Fprintln(w, gap)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSubtractRanges(t *testing.T) {
+	ranges := []string{"140.82.112.0/21", "140.82.121.0/24", "140.82.122.0/23", "2a0a:a440::/29"}
+
+	tests := []struct {
+		prefix string
+		want   subtractJSON
+	}{
+		{
+			prefix: "140.82.112.0/20",
+			want: subtractJSON{
+				Prefix:    "140.82.112.0/20",
+				Addresses: "4096",
+				Uncovered: "1280",
+				Gaps:      []string{"140.82.120.0/24", "140.82.124.0/22"},
+			},
+		},
+		{
+			prefix: "140.82.114.0/24",
+			want:   subtractJSON{Prefix: "140.82.114.0/24", Addresses: "256", Uncovered: "0", Gaps: []string{}},
+		},
+		{
+			prefix: "2a0a:a440::/28",
+			want: subtractJSON{
+				Prefix:    "2a0a:a440::/28",
+				Addresses: "1267650600228229401496703205376",
+				Uncovered: "633825300114114700748351602688",
+				Gaps:      []string{"2a0a:a448::/29"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := subtractRanges(netip.MustParsePrefix(tt.prefix), ranges)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("subtractRanges(%s) = %+v, want %+v", tt.prefix, got, tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	writeSubtract(&buf, subtractRanges(netip.MustParsePrefix("140.82.112.0/20"), ranges))
+	if want := "140.82.120.0/24\n140.82.124.0/22\n"; buf.String() != want {
+		t.Errorf("writeSubtract() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunSubtract(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	tests := []struct {
+		name          string
+		prefix        string
+		areas         []string
+		wantErr       bool
+		wantNotGitHub bool
+	}{
+		{name: "Covered", prefix: "140.82.112.0/21"},
+		{name: "Single address", prefix: "192.30.252.1"},
+		{name: "Partly covered", prefix: "140.82.0.0/16", wantErr: true, wantNotGitHub: true},
+		{name: "Other area", prefix: "140.82.112.0/21", areas: []string{"hooks"}, wantErr: true, wantNotGitHub: true},
+		{name: "Invalid prefix", prefix: "140.82.112.0/40", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().StringSlice("area", tt.areas, "")
+			cmd.Flags().StringP("output", "o", outputJSON, "")
+
+			err := runSubtract(cmd, []string{tt.prefix})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runSubtract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(notGitHubError); ok != tt.wantNotGitHub {
+				t.Errorf("runSubtract() error = %v, want notGitHubError %v", err, tt.wantNotGitHub)
+			}
+		})
+	}
+}
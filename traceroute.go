@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Traceroute probes are UDP datagrams to consecutive ports from this base, so the
+// port quoted in an ICMP reply identifies the hop
+const tracerouteBasePort = 33434
+
+// tracerouteTimeout bounds the wait for the reply to each hop's probe
+var tracerouteTimeout = 2 * time.Second
+
+// For testing purposes
+var (
+	lookupTXT  = net.LookupTXT
+	lookupAddr = net.LookupAddr
+)
+
+// tracerouteHop is a router on the path to a traced address
+type tracerouteHop struct {
+	TTL      int     `json:"ttl"`
+	Address  string  `json:"address,omitempty"` // Empty if the hop didn't reply
+	Hostname string  `json:"hostname,omitempty"`
+	ASN      string  `json:"asn,omitempty"` // e.g. "AS3356"
+	ASName   string  `json:"as_name,omitempty"`
+	RTT      float64 `json:"rtt_ms,omitempty"`
+}
+
+// tracerouteReport is the path to an address that isn't GitHub-owned, with hints
+// about the networks it crosses
+type tracerouteReport struct {
+	Target  string          `json:"target"`
+	Reached bool            `json:"reached"`
+	Hops    []tracerouteHop `json:"hops"`
+	ASPath  []string        `json:"as_path"` // Distinct consecutive ASNs along the path
+	Error   string          `json:"error,omitempty"`
+}
+
+// traceRoute traces the path to ip and looks up the hostname and origin AS of each
+// hop. Failures are recorded in the report, since it only supplements a verdict.
+func traceRoute(ip string, maxHops int) *tracerouteReport {
+	report := &tracerouteReport{Target: ip, Hops: []tracerouteHop{}, ASPath: []string{}}
+
+	dst, err := netip.ParseAddr(ip)
+	if dst = dst.Unmap(); err != nil || !dst.Is4() {
+		report.Error = "traceroute supports IPv4 addresses only"
+		return report
+	}
+
+	hops, reached, err := sendTraceroute(dst, maxHops, tracerouteTimeout)
+	report.Hops = append(report.Hops, hops...)
+	report.Reached = reached
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	asNames := make(map[string]string)
+	for i := range report.Hops {
+		hop := &report.Hops[i]
+		if hop.Address == "" {
+			continue
+		}
+		if names, err := lookupAddr(hop.Address); err == nil && len(names) > 0 {
+			hop.Hostname = strings.TrimSuffix(names[0], ".")
+		}
+		addr, _ := netip.ParseAddr(hop.Address)
+		hop.ASN = originASN(addr)
+		if hop.ASN == "" {
+			continue
+		}
+		if _, ok := asNames[hop.ASN]; !ok {
+			asNames[hop.ASN] = asName(hop.ASN)
+		}
+		hop.ASName = asNames[hop.ASN]
+		if n := len(report.ASPath); n == 0 || report.ASPath[n-1] != hop.ASN {
+			report.ASPath = append(report.ASPath, hop.ASN)
+		}
+	}
+	return report
+}
+
+// originASN returns the AS announcing ip, e.g. "AS3356", according to Team Cymru's
+// IP to ASN DNS service, or "" for private addresses and failed lookups
+func originASN(ip netip.Addr) string {
+	ip = ip.Unmap()
+	if !ip.Is4() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return ""
+	}
+	// A record of "<reversed address>.origin.asn.cymru.com" reads
+	// "3356 | 4.0.0.0/9 | US | arin | 1992-12-01"
+	b := ip.As4()
+	records, err := lookupTXT(fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", b[3], b[2], b[1], b[0]))
+	if err != nil || len(records) == 0 {
+		return ""
+	}
+	asn := strings.Fields(strings.TrimSpace(strings.SplitN(records[0], "|", 2)[0]))
+	if len(asn) == 0 {
+		return ""
+	}
+	// Addresses announced by several ASes list them all; the first is enough for a hint
+	return "AS" + asn[0]
+}
+
+// asName returns the name of an AS, e.g. "LEVEL3 - Level 3 Parent, LLC, US", or ""
+// if the lookup fails
+func asName(asn string) string {
+	// A record of "AS3356.asn.cymru.com" reads
+	// "3356 | US | arin | 2000-03-10 | LEVEL3 - Level 3 Parent, LLC, US"
+	records, err := lookupTXT(asn + ".asn.cymru.com")
+	if err != nil || len(records) == 0 {
+		return ""
+	}
+	fields := strings.Split(records[0], "|")
+	return strings.TrimSpace(fields[len(fields)-1])
+}
+
+// parseICMPReply reports whether msg, an ICMP message without IP header, is a
+// reply to the UDP probe sent from srcPort to dstPort, and whether it came from
+// the traced host itself rather than a router on the way
+func parseICMPReply(msg []byte, srcPort, dstPort int) (match, reached bool) {
+	const (
+		icmpDestinationUnreachable = 3
+		icmpTimeExceeded           = 11
+	)
+	if len(msg) < 8 || (msg[0] != icmpTimeExceeded && msg[0] != icmpDestinationUnreachable) {
+		return false, false
+	}
+
+	// The reply quotes the probe's IP header and the first 8 bytes of its payload,
+	// the UDP header
+	quoted := msg[8:]
+	if len(quoted) < 20 || quoted[0]>>4 != 4 {
+		return false, false
+	}
+	headerLen := int(quoted[0]&0x0f) * 4
+	if len(quoted) < headerLen+4 {
+		return false, false
+	}
+	udp := quoted[headerLen:]
+	if int(binary.BigEndian.Uint16(udp[0:])) != srcPort || int(binary.BigEndian.Uint16(udp[2:])) != dstPort {
+		return false, false
+	}
+	return true, msg[0] == icmpDestinationUnreachable
+}
+
+// writeTracedResult writes the result of checking ip along with the route traced
+// to it, in text or JSON
+func writeTracedResult(w io.Writer, format, ip string, result *CheckResult, report *tracerouteReport) {
+	if format == outputJSON {
+		out := newResultJSON(ip, result)
+		out.Traceroute = report
+		json.NewEncoder(w).Encode(out)
+		return
+	}
+	writeResult(w, format, ip, result)
+	writeTraceroute(w, report)
+}
+
+// writeTraceroute writes the traced path as text
+func writeTraceroute(w io.Writer, report *tracerouteReport) {
+	fmt.Fprintf(w, "Traceroute to %s:\n", report.Target)
+	for _, hop := range report.Hops {
+		if hop.Address == "" {
+			fmt.Fprintf(w, "%3d  *\n", hop.TTL)
+			continue
+		}
+		line := fmt.Sprintf("%3d  %s", hop.TTL, hop.Address)
+		if hop.Hostname != "" {
+			line += " (" + hop.Hostname + ")"
+		}
+		if hop.ASN != "" {
+			line += "  [" + hop.ASN + "]"
+		}
+		fmt.Fprintf(w, "%s  %.1fms\n", line, hop.RTT)
+	}
+	if report.Error != "" {
+		fmt.Fprintf(w, "Traceroute failed: %s\n", report.Error)
+	} else if !report.Reached {
+		fmt.Fprintf(w, "%s did not reply within %d hops\n", report.Target, len(report.Hops))
+	}
+
+	if len(report.ASPath) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "AS path: %s\n", strings.Join(report.ASPath, " -> "))
+	for i := len(report.Hops) - 1; i >= 0; i-- {
+		if hop := report.Hops[i]; hop.ASName != "" {
+			fmt.Fprintf(w, "Last network: %s %s\n", hop.ASN, hop.ASName)
+			break
+		}
+	}
+}
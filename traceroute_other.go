@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"runtime"
+	"time"
+)
+
+// sendTraceroute is not implemented on this platform
+var sendTraceroute = func(dst netip.Addr, maxHops int, timeout time.Duration) ([]tracerouteHop, bool, error) {
+	return nil, false, fmt.Errorf("traceroute is not supported on %s", runtime.GOOS)
+}
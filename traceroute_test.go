@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// icmpReply builds an ICMP message of the given type quoting a UDP probe from
+// srcPort to dstPort
+func icmpReply(typ byte, srcPort, dstPort int) []byte {
+	msg := []byte{typ, 0, 0, 0, 0, 0, 0, 0}
+	header := make([]byte, 20)
+	header[0] = 0x45 // IPv4, 20 byte header
+	header[9] = 17   // UDP
+	msg = append(msg, header...)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(srcPort))
+	msg = binary.BigEndian.AppendUint16(msg, uint16(dstPort))
+	return append(msg, 0, 8, 0, 0)
+}
+
+func TestParseICMPReply(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         []byte
+		wantMatch   bool
+		wantReached bool
+	}{
+		{"Time exceeded", icmpReply(11, 50000, 33435), true, false},
+		{"Port unreachable", icmpReply(3, 50000, 33435), true, true},
+		{"Other probe", icmpReply(11, 50001, 33435), false, false},
+		{"Echo reply", icmpReply(0, 50000, 33435), false, false},
+		{"Truncated", icmpReply(11, 50000, 33435)[:20], false, false},
+	}
+	for _, tt := range tests {
+		match, reached := parseICMPReply(tt.msg, 50000, 33435)
+		if match != tt.wantMatch || reached != tt.wantReached {
+			t.Errorf("%s: parseICMPReply() = %t, %t, want %t, %t", tt.name, match, reached, tt.wantMatch, tt.wantReached)
+		}
+	}
+}
+
+// fakeTraceroute replaces the traceroute and its DNS lookups
+func fakeTraceroute(t *testing.T, hops []tracerouteHop, reached bool, err error) {
+	t.Helper()
+	oldSend, oldTXT, oldAddr := sendTraceroute, lookupTXT, lookupAddr
+	t.Cleanup(func() { sendTraceroute, lookupTXT, lookupAddr = oldSend, oldTXT, oldAddr })
+
+	sendTraceroute = func(dst netip.Addr, maxHops int, timeout time.Duration) ([]tracerouteHop, bool, error) {
+		if len(hops) > maxHops {
+			return hops[:maxHops], false, err
+		}
+		return hops, reached, err
+	}
+	records := map[string]string{
+		"1.0.0.198.origin.asn.cymru.com":   "64500 | 198.0.0.0/16 | US | arin | 2001-01-01",
+		"2.0.0.198.origin.asn.cymru.com":   "64500 | 198.0.0.0/16 | US | arin | 2001-01-01",
+		"7.113.0.203.origin.asn.cymru.com": "64511 64512 | 203.0.113.0/24 | NL | ripencc | 2005-05-05",
+		"AS64500.asn.cymru.com":            "64500 | US | arin | 2001-01-01 | TRANSIT-EXAMPLE - Transit, Inc., US",
+		"AS64511.asn.cymru.com":            "64511 | NL | ripencc | 2005-05-05 | HOSTING-EXAMPLE, NL",
+	}
+	lookupTXT = func(name string) ([]string, error) {
+		if record, ok := records[name]; ok {
+			return []string{record}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	lookupAddr = func(addr string) ([]string, error) {
+		if addr == "198.0.0.1" {
+			return []string{"core1.transit.example."}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+}
+
+func TestTraceRoute(t *testing.T) {
+	fakeTraceroute(t, []tracerouteHop{
+		{TTL: 1, Address: "192.168.1.1", RTT: 0.5},
+		{TTL: 2},
+		{TTL: 3, Address: "198.0.0.1", RTT: 8.2},
+		{TTL: 4, Address: "198.0.0.2", RTT: 9.1},
+		{TTL: 5, Address: "203.0.113.7", RTT: 12.3},
+	}, true, nil)
+
+	report := traceRoute("203.0.113.7", 30)
+	if !report.Reached || report.Error != "" {
+		t.Errorf("traceRoute() = %+v, want reached without error", report)
+	}
+	if want := []string{"AS64500", "AS64511"}; !reflect.DeepEqual(report.ASPath, want) {
+		t.Errorf("traceRoute() AS path = %v, want %v", report.ASPath, want)
+	}
+	if hop := report.Hops[2]; hop.Hostname != "core1.transit.example" || hop.ASName != "TRANSIT-EXAMPLE - Transit, Inc., US" {
+		t.Errorf("traceRoute() hop 3 = %+v", hop)
+	}
+	if hop := report.Hops[0]; hop.ASN != "" {
+		t.Errorf("traceRoute() private hop has ASN %s", hop.ASN)
+	}
+
+	var buf bytes.Buffer
+	writeTraceroute(&buf, report)
+	for _, want := range []string{
+		"  2  *\n",
+		"  3  198.0.0.1 (core1.transit.example)  [AS64500]  8.2ms\n",
+		"AS path: AS64500 -> AS64511\n",
+		"Last network: AS64511 HOSTING-EXAMPLE, NL\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeTraceroute() output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	report = traceRoute("203.0.113.7", 2)
+	buf.Reset()
+	writeTraceroute(&buf, report)
+	if !strings.Contains(buf.String(), "203.0.113.7 did not reply within 2 hops") {
+		t.Errorf("writeTraceroute() output of an incomplete trace:\n%s", buf.String())
+	}
+}
+
+func TestTraceRouteErrors(t *testing.T) {
+	fakeTraceroute(t, nil, false, errors.New("failed to open ICMP socket"))
+
+	if report := traceRoute("2001:db8::1", 30); !strings.Contains(report.Error, "IPv4") {
+		t.Errorf("traceRoute() of an IPv6 address error = %q", report.Error)
+	}
+
+	report := traceRoute("203.0.113.7", 30)
+	var buf bytes.Buffer
+	writeTracedResult(&buf, outputJSON, "203.0.113.7", &CheckResult{}, report)
+	var got resultJSON
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("writeTracedResult() wrote invalid JSON: %v", err)
+	}
+	if got.IP != "203.0.113.7" || got.Traceroute == nil || got.Traceroute.Error != "failed to open ICMP socket" {
+		t.Errorf("writeTracedResult() = %+v", got)
+	}
+}
@@ -0,0 +1,87 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// sendTraceroute sends a UDP probe to dst with increasing TTLs, collecting the
+// routers that report the probe's TTL exceeded until dst itself replies that the
+// port is unreachable. Receiving ICMP requires a raw socket, and thus root or
+// CAP_NET_RAW.
+var sendTraceroute = func(dst netip.Addr, maxHops int, timeout time.Duration) ([]tracerouteHop, bool, error) {
+	icmp, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open ICMP socket, which requires root or CAP_NET_RAW: %w", err)
+	}
+	defer icmp.Close()
+
+	var hops []tracerouteHop
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		hop, reached, err := traceHop(icmp, dst, ttl, timeout)
+		if err != nil {
+			return hops, false, err
+		}
+		hops = append(hops, hop)
+		if reached {
+			return hops, true, nil
+		}
+	}
+	return hops, false, nil
+}
+
+// traceHop sends the probe with the given TTL and waits for the matching reply
+func traceHop(icmp net.PacketConn, dst netip.Addr, ttl int, timeout time.Duration) (tracerouteHop, bool, error) {
+	hop := tracerouteHop{TTL: ttl}
+	dstPort := tracerouteBasePort + ttl
+
+	conn, err := net.DialUDP("udp4", nil, net.UDPAddrFromAddrPort(netip.AddrPortFrom(dst, uint16(dstPort))))
+	if err != nil {
+		return hop, false, fmt.Errorf("failed to send traceroute probe: %w", err)
+	}
+	defer conn.Close()
+	srcPort := conn.LocalAddr().(*net.UDPAddr).Port
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return hop, false, err
+	}
+	var serr error
+	if err := raw.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+	}); err != nil {
+		return hop, false, err
+	}
+	if serr != nil {
+		return hop, false, fmt.Errorf("failed to set probe TTL: %w", serr)
+	}
+
+	start := time.Now()
+	if _, err := conn.Write([]byte("gh-check-github-ip-ranges")); err != nil {
+		return hop, false, fmt.Errorf("failed to send traceroute probe: %w", err)
+	}
+
+	deadline := start.Add(timeout)
+	icmp.SetReadDeadline(deadline)
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := icmp.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				// No reply: the hop is reported as "*"
+				return hop, false, nil
+			}
+			return hop, false, fmt.Errorf("failed to read ICMP reply: %w", err)
+		}
+		if match, reached := parseICMPReply(buf[:n], srcPort, dstPort); match {
+			hop.Address = peer.(*net.IPAddr).IP.String()
+			hop.RTT = milliseconds(time.Since(start))
+			return hop, reached, nil
+		}
+	}
+}
@@ -0,0 +1,383 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// tuiTab is a view of the interactive interface
+type tuiTab int
+
+const (
+	tuiCheck tuiTab = iota
+	tuiAreas
+	tuiDiff
+)
+
+var tuiTabNames = []string{"Check", "Areas", "Diff"}
+
+// tuiModel is the bubbletea model of the interactive interface. Key presses
+// update it and it renders itself to a screen of a given size, so it can be
+// driven without a terminal.
+type tuiModel struct {
+	checker  *IPChecker
+	areas    []Area
+	diff     []areaDiff
+	diffNote string // Shown instead of the diff, e.g. when there is no history
+	previous time.Time
+
+	tab      tuiTab
+	input    []rune
+	results  []string // Lines of the checks so far, newest last
+	cursor   int      // Selected row of the areas tree
+	expanded map[string]bool
+	diffTop  int // First line of the diff on screen
+	checking int // Checks still running, which resolve hostnames
+
+	width, height int
+}
+
+// tuiResultMsg carries the result lines of a check run by a command
+type tuiResultMsg []string
+
+// newTUICommand creates the tui subcommand
+func newTUICommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Browse and check GitHub's IP ranges interactively",
+		Long: `Open a full-screen interface for checking IP addresses and hostnames as you
+type, browsing the areas and their ranges, and, with --history, seeing what
+changed since the previous stored snapshot.
+
+Keys: Tab switches views, Up/Down move or scroll the diff, Enter checks the
+input or expands an area, Left collapses it, Esc or Ctrl-C quits.`,
+		Args:         usageArgs(cobra.NoArgs),
+		RunE:         runTUI,
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().String("history", "", "SQLite snapshot history database written by 'export --format sqlite', for the diff view")
+
+	return cmd
+}
+
+func runTUI(cmd *cobra.Command, args []string) error {
+	history, _ := cmd.Flags().GetString("history")
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return withCategory(errorCategoryUsage, fmt.Errorf("tui requires a terminal"))
+	}
+
+	checker := NewIPChecker()
+	m, err := newTUIModel(checker, history)
+	if err != nil {
+		return err
+	}
+
+	// Draw on the alternate screen so the shell's scrollback survives
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("failed to run the interface: %w", err)
+	}
+	return nil
+}
+
+// newTUIModel fetches the ranges and, if history is set, diffs them against the
+// latest snapshot stored before them
+func newTUIModel(checker *IPChecker, history string) (*tuiModel, error) {
+	areas, err := checker.Areas()
+	if err != nil {
+		return nil, err
+	}
+	m := &tuiModel{checker: checker, areas: areas, expanded: make(map[string]bool), width: 80, height: 24}
+
+	if history == "" {
+		m.diffNote = "Run with --history to compare with the previous stored snapshot."
+		return m, nil
+	}
+	previous := NewIPChecker()
+	if err := previous.LoadHistory(history, checker.SnapshotTime().Add(-time.Second)); err != nil {
+		m.diffNote = err.Error()
+		return m, nil
+	}
+	previousAreas, _ := previous.Areas()
+	m.previous = previous.SnapshotTime()
+	m.diff = diffAreas(previousAreas, areas)
+	if len(m.diff) == 0 {
+		m.diffNote = "No ranges changed since the previous snapshot."
+	}
+	return m, nil
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update applies a key press, a new terminal size or the result of a check
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tuiResultMsg:
+		m.checking--
+		m.results = append(m.results, msg...)
+	case tea.KeyMsg:
+		return m, m.updateKey(msg)
+	}
+	return m, nil
+}
+
+// updateKey applies a key press, returning the command it starts, if any
+func (m *tuiModel) updateKey(key tea.KeyMsg) tea.Cmd {
+	switch key.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		return tea.Quit
+	case tea.KeyTab:
+		m.tab = (m.tab + 1) % tuiTab(len(tuiTabNames))
+		return nil
+	case tea.KeyShiftTab:
+		m.tab = (m.tab + tuiTab(len(tuiTabNames)) - 1) % tuiTab(len(tuiTabNames))
+		return nil
+	}
+	quit := key.Type == tea.KeyRunes && string(key.Runes) == "q"
+
+	switch m.tab {
+	case tuiCheck:
+		switch key.Type {
+		case tea.KeyEnter:
+			input := strings.TrimSpace(string(m.input))
+			m.input = m.input[:0]
+			if input == "" {
+				return nil
+			}
+			// Hostnames are resolved, so the check runs outside the update
+			m.checking++
+			return func() tea.Msg { return tuiResultMsg(m.check(input)) }
+		case tea.KeyBackspace:
+			if len(m.input) > 0 {
+				m.input = m.input[:len(m.input)-1]
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			m.input = append(m.input, key.Runes...)
+		}
+	case tuiAreas:
+		rows := m.treeRows()
+		switch {
+		case key.Type == tea.KeyUp:
+			m.cursor = max(m.cursor-1, 0)
+		case key.Type == tea.KeyDown:
+			m.cursor = min(m.cursor+1, len(rows)-1)
+		case key.Type == tea.KeyEnter || key.Type == tea.KeyRight:
+			if area := rows[m.cursor].area; area != "" {
+				m.expanded[area] = key.Type == tea.KeyRight || !m.expanded[area]
+			}
+		case key.Type == tea.KeyLeft:
+			// Collapse the area of the selected row and select the area itself
+			area := rows[m.cursor].area
+			if area == "" {
+				area = rows[m.cursor].parent
+			}
+			m.expanded[area] = false
+			for i, row := range m.treeRows() {
+				if row.area == area {
+					m.cursor = i
+				}
+			}
+		case quit:
+			return tea.Quit
+		}
+	case tuiDiff:
+		switch {
+		case key.Type == tea.KeyUp:
+			m.diffTop = max(m.diffTop-1, 0)
+		case key.Type == tea.KeyDown:
+			// Stop once the last line is at the bottom of the screen
+			m.diffTop = min(m.diffTop+1, max(len(m.diffLines())-tuiBodyHeight(m.height), 0))
+		case quit:
+			return tea.Quit
+		}
+	}
+	return nil
+}
+
+// check checks an address, or a hostname's domain patterns and addresses, and
+// returns the lines of the results
+func (m *tuiModel) check(input string) []string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "> %s\n", input)
+	m.checkInput(&buf, input)
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// checkInput writes the results of checking an address or hostname
+func (m *tuiModel) checkInput(w io.Writer, input string) {
+	if _, err := netip.ParseAddr(input); err == nil {
+		m.checkIP(w, input)
+		return
+	}
+
+	matches, err := m.checker.CheckDomain(input)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return
+	}
+	for _, match := range matches {
+		fmt.Fprintf(w, "Hostname %s matches GitHub's %s domain %s\n", input, match.Service, match.Pattern)
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(w, "Hostname %s does not match any GitHub domain\n", input)
+	}
+	ips, err := lookupIP(input)
+	if err != nil {
+		fmt.Fprintf(w, "Error: failed to resolve %s: %v\n", input, err)
+		return
+	}
+	for _, ip := range ips {
+		m.checkIP(w, ip.String())
+	}
+}
+
+// checkIP writes the result of checking an address
+func (m *tuiModel) checkIP(w io.Writer, ip string) {
+	result, err := m.checker.CheckIP(ip)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return
+	}
+	writeResult(w, outputText, ip, result)
+}
+
+// treeRow is a row of the areas tree: an area, or a range of an expanded area
+type treeRow struct {
+	area   string // Key of the area, for area rows
+	parent string // Key of the area, for range rows
+	text   string
+}
+
+// treeRows returns the visible rows of the areas tree
+func (m *tuiModel) treeRows() []treeRow {
+	var rows []treeRow
+	for _, a := range m.areas {
+		marker := "▸"
+		if m.expanded[a.Key] {
+			marker = "▾"
+		}
+		rows = append(rows, treeRow{area: a.Key, text: fmt.Sprintf("%s %s (%d)", marker, a.Name, len(a.Ranges))})
+		if m.expanded[a.Key] {
+			for _, cidr := range a.Ranges {
+				rows = append(rows, treeRow{parent: a.Key, text: "    " + cidr})
+			}
+		}
+	}
+	if len(rows) == 0 {
+		rows = append(rows, treeRow{text: "GitHub publishes no ranges."})
+	}
+	return rows
+}
+
+// View renders the model to the terminal
+func (m *tuiModel) View() string {
+	return m.view(m.width, m.height)
+}
+
+// view renders the model to a screen of the given size
+func (m *tuiModel) view(width, height int) string {
+	var header strings.Builder
+	for i, name := range tuiTabNames {
+		if tuiTab(i) == m.tab {
+			fmt.Fprintf(&header, "[%s] ", name)
+		} else {
+			fmt.Fprintf(&header, " %s  ", name)
+		}
+	}
+	if snapshot := m.checker.SnapshotTime(); !snapshot.IsZero() {
+		fmt.Fprintf(&header, "  snapshot %s", snapshot.Format(time.RFC3339))
+	}
+	footer := "Tab: switch view  ↑/↓: move  Enter: check/expand  ←: collapse  Esc: quit"
+
+	bodyHeight := tuiBodyHeight(height)
+	var body []string
+	switch m.tab {
+	case tuiCheck:
+		status := ""
+		if m.checking > 0 {
+			status = "Checking..."
+		}
+		body = append(body, "IP or hostname: "+string(m.input)+"█", status)
+		body = append(body, tail(m.results, bodyHeight-len(body))...)
+	case tuiAreas:
+		rows := m.treeRows()
+		// Scroll so the cursor stays on screen
+		start := max(m.cursor-bodyHeight+1, 0)
+		for i := start; i < len(rows) && i < start+bodyHeight; i++ {
+			prefix := "  "
+			if i == m.cursor {
+				prefix = "> "
+			}
+			body = append(body, prefix+rows[i].text)
+		}
+	case tuiDiff:
+		lines := m.diffLines()
+		start := min(m.diffTop, max(len(lines)-bodyHeight, 0))
+		body = lines[start:min(len(lines), start+bodyHeight)]
+	}
+	for len(body) < bodyHeight {
+		body = append(body, "")
+	}
+
+	lines := append([]string{header.String(), ""}, body...)
+	lines = append(lines, footer)
+	for i, line := range lines {
+		lines[i] = truncate(line, width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tuiBodyHeight returns the lines of a screen of the given height left to the
+// view, after the header, a blank line and the footer
+func tuiBodyHeight(height int) int {
+	return max(height-3, 1)
+}
+
+// diffLines returns the lines of the diff view
+func (m *tuiModel) diffLines() []string {
+	if m.diffNote != "" {
+		return []string{m.diffNote}
+	}
+	lines := []string{"Changes since " + m.previous.Format(time.RFC3339) + ":"}
+	for _, d := range m.diff {
+		lines = append(lines, fmt.Sprintf("%s: +%d -%d", d.Name, len(d.Added), len(d.Removed)))
+		for _, cidr := range d.Added {
+			lines = append(lines, "  + "+cidr)
+		}
+		for _, cidr := range d.Removed {
+			lines = append(lines, "  - "+cidr)
+		}
+	}
+	return lines
+}
+
+// tail returns the last n lines
+func tail(lines []string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	return lines[max(len(lines)-n, 0):]
+}
+
+// truncate shortens s to at most width runes
+func truncate(s string, width int) string {
+	if r := []rune(s); width > 0 && len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
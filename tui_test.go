@@ -0,0 +1,163 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// press sends a key press to the model, running the command it starts unless
+// it quits, and reports whether it quit
+func press(m *tuiModel, key tea.KeyMsg) bool {
+	_, cmd := m.Update(key)
+	if cmd == nil {
+		return false
+	}
+	msg := cmd()
+	if _, ok := msg.(tea.QuitMsg); ok {
+		return true
+	}
+	m.Update(msg)
+	return false
+}
+
+func typeKeys(m *tuiModel, s string) {
+	for _, r := range s {
+		press(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+func TestTUIModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22", "185.199.108.0/22"], "git": ["140.82.112.0/20"], "domains": {"website": ["*.github.com"]}}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	oldLookup := lookupIP
+	lookupIP = func(host string) ([]netip.Addr, error) { return []netip.Addr{netip.MustParseAddr("140.82.112.3")}, nil }
+	defer func() { lookupIP = oldLookup }()
+
+	m, err := newTUIModel(NewIPChecker(), "")
+	if err != nil {
+		t.Fatalf("newTUIModel() error = %v", err)
+	}
+
+	typeKeys(m, "192.30.252.x")
+	press(m, tea.KeyMsg{Type: tea.KeyBackspace})
+	typeKeys(m, "1")
+	press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	typeKeys(m, "api.github.com")
+	// The check runs as a command, which the program runs in the background
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if view := m.view(80, 8); !strings.Contains(view, "Checking...") {
+		t.Errorf("check view while checking = %q, want Checking...", view)
+	}
+	m.Update(cmd())
+	wantResults := []string{
+		"> 192.30.252.1",
+		"IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)",
+		"> api.github.com",
+		"Hostname api.github.com matches GitHub's website domain *.github.com",
+		"IP 140.82.112.3 belongs to GitHub's Git range (140.82.112.0/20)",
+	}
+	if !reflect.DeepEqual(m.results, wantResults) {
+		t.Errorf("results = %q, want %q", m.results, wantResults)
+	}
+	if view := m.view(80, 8); !strings.HasPrefix(view, "[Check]  Areas   Diff") ||
+		!strings.Contains(view, "IP or hostname: █\n\n> api.github.com\n") || strings.Contains(view, "192.30.252.1") {
+		t.Errorf("check view = %q", view)
+	}
+
+	press(m, tea.KeyMsg{Type: tea.KeyTab})
+	press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	press(m, tea.KeyMsg{Type: tea.KeyDown})
+	press(m, tea.KeyMsg{Type: tea.KeyDown})
+	want := "  ▾ Hooks (2)\n      192.30.252.0/22\n>     185.199.108.0/22\n  ▸ Web (0)\n"
+	if view := m.view(80, 10); !strings.Contains(view, want) {
+		t.Errorf("areas view = %q, want it to contain %q", view, want)
+	}
+	press(m, tea.KeyMsg{Type: tea.KeyLeft})
+	want = "> ▸ Hooks (2)\n  ▸ Web (0)\n"
+	if view := m.view(80, 10); !strings.Contains(view, want) {
+		t.Errorf("areas view after collapsing = %q, want it to contain %q", view, want)
+	}
+
+	press(m, tea.KeyMsg{Type: tea.KeyTab})
+	if view := m.view(80, 10); !strings.Contains(view, "Run with --history") {
+		t.Errorf("diff view without history = %q", view)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 30, Height: 10})
+	if lines := strings.Split(m.View(), "\n"); len(lines) != 10 || len([]rune(lines[len(lines)-1])) != 30 {
+		t.Errorf("view(30, 10) = %q, want 10 lines of at most 30 characters", lines)
+	}
+
+	if !press(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}}) {
+		t.Error("Update(q) in the diff view should quit")
+	}
+	press(m, tea.KeyMsg{Type: tea.KeyShiftTab})
+	press(m, tea.KeyMsg{Type: tea.KeyShiftTab})
+	if press(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}}) {
+		t.Error("Update(q) in the check view should type q")
+	}
+}
+
+func TestTUIModelHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Wed, 01 Jul 2026 08:00:00 GMT")
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22", "143.55.64.0/20"]}`))
+	}))
+	defer server.Close()
+
+	oldURL := githubMetaURL
+	githubMetaURL = server.URL
+	defer func() { githubMetaURL = oldURL }()
+
+	fakeSQLite(t, `[{"snapshot_time":"2026-06-30T08:00:00Z","source_hash":"abc123","area":"hooks","cidr":"192.30.252.0/22"},`+
+		`{"snapshot_time":"2026-06-30T08:00:00Z","source_hash":"abc123","area":"hooks","cidr":"185.199.108.0/22"}]`)
+
+	m, err := newTUIModel(NewIPChecker(), "ranges.db")
+	if err != nil {
+		t.Fatalf("newTUIModel() error = %v", err)
+	}
+	press(m, tea.KeyMsg{Type: tea.KeyShiftTab})
+	want := "Changes since 2026-06-30T08:00:00Z:\nHooks: +1 -1\n  + 143.55.64.0/20\n  - 185.199.108.0/22\n"
+	if view := m.view(80, 10); !strings.Contains(view, want) {
+		t.Errorf("diff view = %q, want it to contain %q", view, want)
+	}
+}
+
+func TestTUIModelDiffScroll(t *testing.T) {
+	var added []string
+	for i := range 10 {
+		added = append(added, fmt.Sprintf("192.0.%d.0/24", i))
+	}
+	m := &tuiModel{checker: NewIPChecker(), tab: tuiDiff, diff: []areaDiff{{Key: "hooks", Name: "Hooks", Added: added}}, width: 80, height: 6}
+
+	// The header, blank line and footer leave 3 of the 12 lines on screen
+	body := func() string { return strings.Join(strings.Split(m.View(), "\n")[2:5], "\n") }
+	if got, want := body(), "Changes since 0001-01-01T00:00:00Z:\nHooks: +10 -0\n  + 192.0.0.0/24"; got != want {
+		t.Errorf("diff view = %q, want %q", got, want)
+	}
+	for range 20 {
+		press(m, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	if got, want := body(), "  + 192.0.7.0/24\n  + 192.0.8.0/24\n  + 192.0.9.0/24"; got != want {
+		t.Errorf("diff view scrolled to the end = %q, want %q", got, want)
+	}
+	press(m, tea.KeyMsg{Type: tea.KeyUp})
+	if got, want := body(), "  + 192.0.6.0/24\n  + 192.0.7.0/24\n  + 192.0.8.0/24"; got != want {
+		t.Errorf("diff view scrolled up = %q, want %q", got, want)
+	}
+}
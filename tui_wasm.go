@@ -0,0 +1,22 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// newTUICommand creates the tui subcommand, which needs a terminal
+func newTUICommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tui",
+		Short: "Browse and check GitHub's IP ranges interactively",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withCategory(errorCategoryUsage, fmt.Errorf("tui requires a terminal, which WebAssembly doesn't have"))
+		},
+		SilenceUsage: true,
+	}
+}
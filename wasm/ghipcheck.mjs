@@ -0,0 +1,68 @@
+// JavaScript wrapper for the WebAssembly build of gh-check-github-ip-ranges.
+//
+// Build the module and copy Go's support script next to it:
+//
+//   GOOS=js GOARCH=wasm go build -o wasm/ghipcheck.wasm .
+//   cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" wasm/
+//
+// wasm_exec.js defines the global Go class and must be loaded before this
+// module, e.g. with a <script> tag or `import "./wasm_exec.js"`.
+
+/**
+ * Error thrown by checkIP, with the category the command line tool reports in
+ * JSON errors, e.g. "invalid_input".
+ */
+export class CheckError extends Error {
+  constructor(category, message) {
+    super(message);
+    this.name = "CheckError";
+    this.category = category;
+  }
+}
+
+/**
+ * Starts the module and returns its functions.
+ *
+ * @param {string|URL|Response|BufferSource|WebAssembly.Module} source the
+ *   compiled module, or where to fetch ghipcheck.wasm from. Cloudflare Workers
+ *   import the .wasm file as a WebAssembly.Module.
+ */
+export async function load(source) {
+  const go = new globalThis.Go();
+  let instance;
+  if (source instanceof WebAssembly.Module) {
+    instance = await WebAssembly.instantiate(source, go.importObject);
+  } else if (source instanceof ArrayBuffer || ArrayBuffer.isView(source)) {
+    ({ instance } = await WebAssembly.instantiate(source, go.importObject));
+  } else {
+    const response = source instanceof Response ? source : await fetch(source);
+    ({ instance } = await WebAssembly.instantiate(await response.arrayBuffer(), go.importObject));
+  }
+
+  // run resolves only when the Go program exits, which it doesn't: it keeps
+  // serving calls. The functions are registered by the time run returns.
+  go.run(instance);
+  const bindings = globalThis.ghipcheck;
+
+  return {
+    /**
+     * Checks ip against a meta document, as fetched from
+     * https://api.github.com/meta, with exactly the matching of the command
+     * line tool.
+     *
+     * @param {string} ip the IPv4 address to check
+     * @param {string|object} metaJSON the meta document, as text or parsed
+     * @returns {{ip: string, is_github: boolean, area?: string, range?: string,
+     *   also?: {area: string, range: string}[]}} the result, as printed by
+     *   `--output json`
+     */
+    checkIP(ip, metaJSON) {
+      const meta = typeof metaJSON === "string" ? metaJSON : JSON.stringify(metaJSON);
+      const out = bindings.checkIP(String(ip), meta);
+      if (out.error) {
+        throw new CheckError(out.error.category, out.error.message);
+      }
+      return JSON.parse(out.result);
+    },
+  };
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// minWatchInterval keeps --watch and serve's --refresh well within the meta API's unauthenticated
+// rate limit of 60 requests an hour
+const minWatchInterval = time.Minute
+
+// For testing purposes
+var (
+	watchSleep = time.Sleep
+	watchNow   = time.Now
+	// watchLimit stops watching after this many refreshes; 0 watches forever
+	watchLimit = 0
+)
+
+// watchEventJSON is the JSON output of a status change seen with --watch
+type watchEventJSON struct {
+	Time     time.Time  `json:"time"`
+	IP       string     `json:"ip"`
+	Previous resultJSON `json:"previous"`
+	Current  resultJSON `json:"current"`
+}
+
+// rangeChangeJSON is the document written to the stdin of --watch-hook
+// commands when GitHub's ranges change
+type rangeChangeJSON struct {
+	Time             time.Time        `json:"time"`
+	PreviousSnapshot *time.Time       `json:"previous_snapshot,omitempty"`
+	Snapshot         *time.Time       `json:"snapshot,omitempty"`
+	SourceHash       string           `json:"source_hash"`
+	Changes          []areaChangeJSON `json:"changes"`
+}
+
+// areaChangeJSON is the change of an area's ranges
+type areaChangeJSON struct {
+	Key     string   `json:"key"`
+	Name    string   `json:"name"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// validateWatch checks the flags of a check with --watch or --schedule
+func validateWatch(cmd *cobra.Command, output string, inputs []string) error {
+	watch, scheduled := cmd.Flags().Changed("watch"), cmd.Flags().Changed("schedule")
+	switch {
+	case !watch && !scheduled && cmd.Flags().Changed("watch-hook"):
+		return withCategory(errorCategoryUsage, fmt.Errorf("--watch-hook requires --watch or --schedule"))
+	case !watch && !scheduled && cmd.Flags().Changed("notify"):
+		return withCategory(errorCategoryUsage, fmt.Errorf("--notify requires --watch or --schedule"))
+	case !watch && !scheduled:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--watch-exec requires --watch or --schedule"))
+	case watch && scheduled:
+		return withCategory(errorCategoryUsage, fmt.Errorf("--watch and --schedule can't be combined"))
+	}
+	if timeout, _ := cmd.Flags().GetDuration("watch-hook-timeout"); timeout <= 0 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--watch-hook-timeout must be positive"))
+	}
+	if scheduled {
+		spec, _ := cmd.Flags().GetString("schedule")
+		if _, err := validateSchedule(spec); err != nil {
+			return err
+		}
+	} else if interval, _ := cmd.Flags().GetDuration("watch"); interval < minWatchInterval {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--watch interval must be at least %s", minWatchInterval))
+	}
+	if len(inputs) != 1 {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--watch checks a single address"))
+	}
+	if output != outputText && output != outputJSON {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--watch requires text or json output"))
+	}
+	if asOf, _ := cmd.Flags().GetString("as-of"); asOf != "" {
+		return withCategory(errorCategoryUsage, fmt.Errorf("--watch can't be combined with --as-of"))
+	}
+	return nil
+}
+
+// runWatch checks ip, then checks it again against freshly fetched ranges every
+// interval, or at the times of the --schedule, until its status or area
+// changes. The command exits as a single check of the new status would, unless
+// --watch-exec is set, in which case the command is run on every change and
+// watching continues. The --watch-hook commands are run whenever the ranges
+// themselves change, and the --notify plugins are told of both changes.
+func runWatch(cmd *cobra.Command, checker *IPChecker, output, ip string) error {
+	interval, _ := cmd.Flags().GetDuration("watch")
+	spec, _ := cmd.Flags().GetString("schedule")
+	silent, _ := cmd.Flags().GetBool("silent")
+	notify, _ := cmd.Flags().GetString("watch-exec")
+	hooks, _ := cmd.Flags().GetStringArray("watch-hook")
+	hookTimeout, _ := cmd.Flags().GetDuration("watch-hook-timeout")
+	names, _ := cmd.Flags().GetStringArray("notify")
+	notifiers, err := lookupNotifyPlugins(names)
+	if err != nil {
+		return err
+	}
+	if silent {
+		output = ""
+	}
+
+	result, err := checker.CheckIP(ip)
+	if err != nil {
+		return err
+	}
+	every := fmt.Sprintf("every %s", interval)
+	wait := func() { watchSleep(interval) }
+	if spec != "" {
+		s, _ := parseSchedule(spec)
+		every = fmt.Sprintf("at %q", spec)
+		wait = func() {
+			now := watchNow()
+			watchSleep(s.next(now).Sub(now))
+		}
+	}
+	if output == outputText {
+		fmt.Printf("Watching IP %s %s\n", ip, every)
+	}
+	if output != "" {
+		writeResult(os.Stdout, output, ip, result)
+	}
+	areas, _ := checker.Areas()
+	snapshot := checker.SnapshotTime()
+
+	for refreshes := 0; watchLimit == 0 || refreshes < watchLimit; refreshes++ {
+		wait()
+
+		// A failed fetch shouldn't end a long watch, so keep the last ranges
+		if err := checker.Refresh(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v, checking against the previous ranges\n", err)
+			continue
+		}
+		if len(hooks) > 0 || len(notifiers) > 0 {
+			currentAreas, _ := checker.Areas()
+			if changes := diffAreas(areas, currentAreas); len(changes) > 0 {
+				change := newRangeChange(watchNow().UTC(), snapshot, checker, changes)
+				errs := runWatchHooks(hooks, hookTimeout, change)
+				errs = append(errs, runNotifyPlugins(notifiers, hookTimeout, notifyPluginInput{Event: notifyRangesChanged, Ranges: &change})...)
+				for _, err := range errs {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}
+			areas, snapshot = currentAreas, checker.SnapshotTime()
+		}
+		current, err := checker.CheckIP(ip)
+		if err != nil {
+			return err
+		}
+		if formatPorcelain(current) == formatPorcelain(result) {
+			continue
+		}
+
+		now := watchNow().UTC()
+		writeWatchEvent(os.Stdout, output, ip, result, current, now)
+		event := newWatchEvent(ip, result, current, now)
+		for _, err := range runNotifyPlugins(notifiers, hookTimeout, notifyPluginInput{Event: notifyAddressChanged, Address: &event}) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		previous := result
+		result = current
+		if notify == "" {
+			break
+		}
+		if err := runWatchExec(notify, ip, previous, current); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if !result.IsGitHubIP {
+		return notGitHubError("the provided IP address is not a GitHub-owned address")
+	}
+	return nil
+}
+
+// writeWatchEvent writes a change of a watched address's status
+func writeWatchEvent(w io.Writer, format, ip string, previous, current *CheckResult, now time.Time) {
+	switch format {
+	case outputJSON:
+		json.NewEncoder(w).Encode(newWatchEvent(ip, previous, current, now))
+	case outputText:
+		fmt.Fprintf(w, "%s: IP %s changed from %s to %s\n", now.Format(time.RFC3339), ip,
+			describeWatchStatus(previous), describeWatchStatus(current))
+	}
+}
+
+// newWatchEvent returns the JSON of a change of a watched address's status
+func newWatchEvent(ip string, previous, current *CheckResult, now time.Time) watchEventJSON {
+	return watchEventJSON{
+		Time:     now,
+		IP:       ip,
+		Previous: newResultJSON(ip, previous),
+		Current:  newResultJSON(ip, current),
+	}
+}
+
+// describeWatchStatus describes a result for a change message
+func describeWatchStatus(result *CheckResult) string {
+	if !result.IsGitHubIP {
+		return "not GitHub-owned"
+	}
+	return fmt.Sprintf("GitHub's %s range (%s)", result.FunctionalArea, result.Range)
+}
+
+// runWatchExec runs the --watch-exec command with sh, passing the change in
+// its environment as porcelain tokens
+func runWatchExec(command, ip string, previous, current *CheckResult) error {
+	c := exec.Command("sh", "-c", command)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(),
+		"GH_IP_WATCH_IP="+ip,
+		"GH_IP_WATCH_PREVIOUS="+formatPorcelain(previous),
+		"GH_IP_WATCH_CURRENT="+formatPorcelain(current),
+	)
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("--watch-exec command failed: %w", err)
+	}
+	return nil
+}
+
+// newRangeChange describes the change of the ranges since the previous
+// snapshot, for --watch-hook commands
+func newRangeChange(now, previous time.Time, checker *IPChecker, changes []areaDiff) rangeChangeJSON {
+	change := rangeChangeJSON{Time: now, SourceHash: checker.SourceHash(), Changes: []areaChangeJSON{}}
+	if !previous.IsZero() {
+		change.PreviousSnapshot = &previous
+	}
+	if snapshot := checker.SnapshotTime(); !snapshot.IsZero() {
+		change.Snapshot = &snapshot
+	}
+	for _, d := range changes {
+		change.Changes = append(change.Changes, areaChangeJSON{
+			Key:     d.Key,
+			Name:    d.Name,
+			Added:   append([]string{}, d.Added...),
+			Removed: append([]string{}, d.Removed...),
+		})
+	}
+	return change
+}
+
+// runWatchHooks runs each --watch-hook command with sh in turn, passing the
+// change as JSON on its stdin and summarized in its environment, and returns
+// the errors of the commands that failed or didn't finish within timeout. The
+// environment only counts the changed ranges: a large rotation lists more than
+// an environment variable can hold.
+func runWatchHooks(hooks []string, timeout time.Duration, change rangeChangeJSON) []error {
+	stdin, err := json.Marshal(change)
+	if err != nil {
+		return []error{fmt.Errorf("failed to encode --watch-hook input: %w", err)}
+	}
+	var keys []string
+	added, removed := 0, 0
+	for _, c := range change.Changes {
+		keys = append(keys, c.Key)
+		added += len(c.Added)
+		removed += len(c.Removed)
+	}
+	env := append(os.Environ(),
+		"GH_IP_WATCH_AREAS="+strings.Join(keys, " "),
+		"GH_IP_WATCH_ADDED_COUNT="+strconv.Itoa(added),
+		"GH_IP_WATCH_REMOVED_COUNT="+strconv.Itoa(removed),
+		"GH_IP_WATCH_SOURCE_HASH="+change.SourceHash,
+	)
+
+	var errs []error
+	for _, hook := range hooks {
+		if err := runWatchHook(hook, timeout, env, stdin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// runWatchHook runs a --watch-hook command, killing it after timeout
+func runWatchHook(hook string, timeout time.Duration, env []string, stdin []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, "sh", "-c", hook)
+	c.Stdin = bytes.NewReader(stdin)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = env
+	// Don't wait long for processes the command left behind holding its output
+	c.WaitDelay = time.Second
+	err := c.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("--watch-hook command %q timed out after %s", hook, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("--watch-hook command %q failed: %w", hook, err)
+	}
+	return nil
+}
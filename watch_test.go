@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestValidateWatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		watch   string
+		spec    string
+		exec    string
+		hook    string
+		notify  string
+		timeout string
+		output  string
+		inputs  []string
+		asOf    string
+		wantErr bool
+	}{
+		{name: "Valid", watch: "1h", output: outputText, inputs: []string{"192.30.252.1"}},
+		{name: "Too frequent", watch: "30s", output: outputText, inputs: []string{"192.30.252.1"}, wantErr: true},
+		{name: "Several addresses", watch: "1h", output: outputText, inputs: []string{"192.30.252.1", "8.8.8.8"}, wantErr: true},
+		{name: "Unsupported output", watch: "1h", output: outputCEF, inputs: []string{"192.30.252.1"}, wantErr: true},
+		{name: "As of", watch: "1h", output: outputJSON, inputs: []string{"192.30.252.1"}, asOf: "2026-07-01", wantErr: true},
+		{name: "Exec without watch", exec: "true", output: outputText, inputs: []string{"192.30.252.1"}, wantErr: true},
+		{name: "Hook without watch", hook: "true", output: outputText, inputs: []string{"192.30.252.1"}, wantErr: true},
+		{name: "Scheduled", spec: "*/30 * * * *", output: outputText, inputs: []string{"192.30.252.1"}},
+		{name: "Invalid schedule", spec: "*/30 * *", output: outputText, inputs: []string{"192.30.252.1"}, wantErr: true},
+		{name: "Interval and schedule", watch: "1h", spec: "@hourly", output: outputText, inputs: []string{"192.30.252.1"}, wantErr: true},
+		{name: "Hook with schedule", spec: "@hourly", hook: "true", output: outputText, inputs: []string{"192.30.252.1"}},
+		{name: "Notify without watch", notify: "chat", output: outputText, inputs: []string{"192.30.252.1"}, wantErr: true},
+		{name: "No hook timeout", watch: "1h", timeout: "0s", output: outputText, inputs: []string{"192.30.252.1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().Duration("watch", 0, "")
+			cmd.Flags().String("schedule", "", "")
+			cmd.Flags().String("watch-exec", "", "")
+			cmd.Flags().StringArray("watch-hook", nil, "")
+			cmd.Flags().StringArray("notify", nil, "")
+			cmd.Flags().Duration("watch-hook-timeout", time.Minute, "")
+			cmd.Flags().String("as-of", tt.asOf, "")
+			if tt.watch != "" {
+				cmd.Flags().Set("watch", tt.watch)
+			}
+			if tt.spec != "" {
+				cmd.Flags().Set("schedule", tt.spec)
+			}
+			cmd.Flags().Set("watch-exec", tt.exec)
+			if tt.hook != "" {
+				cmd.Flags().Set("watch-hook", tt.hook)
+			}
+			if tt.notify != "" {
+				cmd.Flags().Set("notify", tt.notify)
+			}
+			if tt.timeout != "" {
+				cmd.Flags().Set("watch-hook-timeout", tt.timeout)
+			}
+
+			err := validateWatch(cmd, tt.output, tt.inputs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateWatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && errorCategory(err) != errorCategoryUsage {
+				t.Errorf("validateWatch() error category = %s, want %s", errorCategory(err), errorCategoryUsage)
+			}
+		})
+	}
+}
+
+func TestWriteWatchEvent(t *testing.T) {
+	previous := &CheckResult{IsGitHubIP: true, FunctionalArea: "Hooks", AreaKey: "hooks", Range: "192.30.252.0/22"}
+	current := &CheckResult{}
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	writeWatchEvent(&buf, outputText, "192.30.252.1", previous, current, now)
+	want := "2026-07-01T08:00:00Z: IP 192.30.252.1 changed from GitHub's Hooks range (192.30.252.0/22) to not GitHub-owned\n"
+	if buf.String() != want {
+		t.Errorf("writeWatchEvent() text = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeWatchEvent(&buf, outputJSON, "192.30.252.1", previous, current, now)
+	want = `{"time":"2026-07-01T08:00:00Z","ip":"192.30.252.1","previous":{"ip":"192.30.252.1","is_github":true,"area":"Hooks","range":"192.30.252.0/22"},` +
+		`"current":{"ip":"192.30.252.1","is_github":false}}` + "\n"
+	if buf.String() != want {
+		t.Errorf("writeWatchEvent() JSON = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunWatch(t *testing.T) {
+	// The meta document moves the address from Hooks to Git on the third fetch,
+	// fails on the second and drops it on the fourth
+	responses := []string{
+		`{"hooks": ["192.30.252.0/22"]}`,
+		``,
+		`{"hooks": ["192.30.252.0/22"]}`,
+		`{"git": ["192.30.252.0/22"]}`,
+		`{"git": ["140.82.112.0/20"]}`,
+	}
+
+	tests := []struct {
+		name       string
+		exec       bool
+		limit      int
+		wantErr    bool
+		wantStdout string
+		wantCalls  string
+	}{
+		{
+			name: "Exits on change",
+			wantStdout: "Watching IP 192.30.252.1 every 1h0m0s\n" +
+				"IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)\n" +
+				"2026-07-01T08:00:00Z: IP 192.30.252.1 changed from GitHub's Hooks range (192.30.252.0/22) to GitHub's Git range (192.30.252.0/22)\n",
+		},
+		{
+			name:    "Runs the command on every change",
+			exec:    true,
+			limit:   4,
+			wantErr: true,
+			wantStdout: "Watching IP 192.30.252.1 every 1h0m0s\n" +
+				"IP 192.30.252.1 belongs to GitHub's Hooks range (192.30.252.0/22)\n" +
+				"2026-07-01T08:00:00Z: IP 192.30.252.1 changed from GitHub's Hooks range (192.30.252.0/22) to GitHub's Git range (192.30.252.0/22)\n" +
+				"2026-07-01T08:00:00Z: IP 192.30.252.1 changed from GitHub's Git range (192.30.252.0/22) to not GitHub-owned\n",
+			wantCalls: "192.30.252.1 github:hooks github:git\n192.30.252.1 github:git not-github\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetches := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := responses[min(fetches, len(responses)-1)]
+				fetches++
+				if body == "" {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			oldURL, oldSleep, oldNow, oldLimit := githubMetaURL, watchSleep, watchNow, watchLimit
+			githubMetaURL = server.URL
+			watchSleep = func(time.Duration) {}
+			watchNow = func() time.Time { return time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC) }
+			watchLimit = tt.limit
+			defer func() { githubMetaURL, watchSleep, watchNow, watchLimit = oldURL, oldSleep, oldNow, oldLimit }()
+
+			calls := filepath.Join(t.TempDir(), "calls")
+			cmd := &cobra.Command{}
+			cmd.Flags().Duration("watch", time.Hour, "")
+			cmd.Flags().Bool("silent", false, "")
+			cmd.Flags().String("watch-exec", "", "")
+			cmd.Flags().StringArray("watch-hook", nil, "")
+			cmd.Flags().Duration("watch-hook-timeout", time.Minute, "")
+			if tt.exec {
+				cmd.Flags().Set("watch-exec", `echo "$GH_IP_WATCH_IP $GH_IP_WATCH_PREVIOUS $GH_IP_WATCH_CURRENT" >> `+calls)
+			}
+
+			oldStdout, oldStderr := os.Stdout, os.Stderr
+			rOut, wOut, _ := os.Pipe()
+			_, wErr, _ := os.Pipe()
+			os.Stdout, os.Stderr = wOut, wErr
+			err := runWatch(cmd, NewIPChecker(), outputText, "192.30.252.1")
+			wOut.Close()
+			wErr.Close()
+			os.Stdout, os.Stderr = oldStdout, oldStderr
+
+			var stdout bytes.Buffer
+			stdout.ReadFrom(rOut)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runWatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(notGitHubError); err != nil && !ok {
+				t.Errorf("runWatch() error = %v, want notGitHubError", err)
+			}
+			if stdout.String() != tt.wantStdout {
+				t.Errorf("runWatch() stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if got, _ := os.ReadFile(calls); string(got) != tt.wantCalls {
+				t.Errorf("--watch-exec calls = %q, want %q", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRunWatch_Schedule(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22"]}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 7, 1, 8, 10, 0, 0, time.Local)
+	var slept []time.Duration
+	oldURL, oldSleep, oldNow, oldLimit := githubMetaURL, watchSleep, watchNow, watchLimit
+	githubMetaURL = server.URL
+	watchSleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+	watchNow = func() time.Time { return now }
+	watchLimit = 2
+	defer func() { githubMetaURL, watchSleep, watchNow, watchLimit = oldURL, oldSleep, oldNow, oldLimit }()
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Duration("watch", 0, "")
+	cmd.Flags().String("schedule", "*/30 * * * *", "")
+	cmd.Flags().Bool("silent", false, "")
+	cmd.Flags().String("watch-exec", "", "")
+	cmd.Flags().StringArray("watch-hook", nil, "")
+	cmd.Flags().Duration("watch-hook-timeout", time.Minute, "")
+
+	oldStdout := os.Stdout
+	rOut, wOut, _ := os.Pipe()
+	os.Stdout = wOut
+	err := runWatch(cmd, NewIPChecker(), outputText, "192.30.252.1")
+	wOut.Close()
+	os.Stdout = oldStdout
+
+	var stdout bytes.Buffer
+	stdout.ReadFrom(rOut)
+	if err != nil {
+		t.Fatalf("runWatch() error = %v", err)
+	}
+	if want := "Watching IP 192.30.252.1 at \"*/30 * * * *\"\n"; !strings.HasPrefix(stdout.String(), want) {
+		t.Errorf("runWatch() stdout = %q, want prefix %q", stdout.String(), want)
+	}
+	if want := []time.Duration{20 * time.Minute, 30 * time.Minute}; fmt.Sprint(slept) != fmt.Sprint(want) {
+		t.Errorf("runWatch() slept %v, want %v", slept, want)
+	}
+}
+
+func TestRunWatchHooks(t *testing.T) {
+	// The address stays in Hooks while the ranges change on the second fetch
+	responses := []string{
+		`{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`,
+		`{"hooks": ["192.30.252.0/22"], "git": ["140.82.112.0/20"]}`,
+		`{"hooks": ["192.30.252.0/22", "185.199.108.0/22"]}`,
+	}
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		day := min(fetches, len(responses)-1)
+		w.Header().Set("Last-Modified", time.Date(2026, 7, 1+day, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+		w.Write([]byte(responses[day]))
+		fetches++
+	}))
+	defer server.Close()
+
+	oldURL, oldSleep, oldNow, oldLimit := githubMetaURL, watchSleep, watchNow, watchLimit
+	githubMetaURL = server.URL
+	watchSleep = func(time.Duration) {}
+	watchNow = func() time.Time { return time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC) }
+	watchLimit = 3
+	defer func() { githubMetaURL, watchSleep, watchNow, watchLimit = oldURL, oldSleep, oldNow, oldLimit }()
+
+	dir := t.TempDir()
+	env, stdin := filepath.Join(dir, "env"), filepath.Join(dir, "stdin")
+	cmd := &cobra.Command{}
+	cmd.Flags().Duration("watch", time.Hour, "")
+	cmd.Flags().Bool("silent", true, "")
+	cmd.Flags().String("watch-exec", "", "")
+	cmd.Flags().StringArray("watch-hook", nil, "")
+	cmd.Flags().Duration("watch-hook-timeout", 100*time.Millisecond, "")
+	cmd.Flags().Set("watch-hook", `echo "$GH_IP_WATCH_AREAS|$GH_IP_WATCH_ADDED_COUNT|$GH_IP_WATCH_REMOVED_COUNT" >> `+env+`; cat >> `+stdin)
+	cmd.Flags().Set("watch-hook", "exit 3")
+	cmd.Flags().Set("watch-hook", "exec sleep 5")
+
+	oldStderr := os.Stderr
+	rErr, wErr, _ := os.Pipe()
+	os.Stderr = wErr
+	err := runWatch(cmd, NewIPChecker(), outputText, "192.30.252.1")
+	wErr.Close()
+	os.Stderr = oldStderr
+
+	var stderr bytes.Buffer
+	stderr.ReadFrom(rErr)
+	if err != nil {
+		t.Fatalf("runWatch() error = %v", err)
+	}
+	if got, _ := os.ReadFile(env); string(got) != "hooks git|1|1\n" {
+		t.Errorf("--watch-hook environment = %q, want one change", got)
+	}
+	want := `{"time":"2026-07-01T08:00:00Z","previous_snapshot":"2026-07-02T00:00:00Z","snapshot":"2026-07-03T00:00:00Z","source_hash":"` + fmt.Sprintf("%x", sha256.Sum256([]byte(responses[2]))) + `","changes":[` +
+		`{"key":"hooks","name":"Hooks","added":["185.199.108.0/22"],"removed":[]},` +
+		`{"key":"git","name":"Git","added":[],"removed":["140.82.112.0/20"]}]}`
+	if got, _ := os.ReadFile(stdin); string(got) != want {
+		t.Errorf("--watch-hook stdin = %s, want %s", got, want)
+	}
+	for _, want := range []string{`"exit 3" failed: exit status 3`, `"exec sleep 5" timed out after 100ms`} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("runWatch() stderr = %q, should report %s", stderr.String(), want)
+		}
+	}
+}